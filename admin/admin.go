@@ -0,0 +1,145 @@
+package main
+
+import (
+	"ticket-booking-app/server/api"
+	_ "github.com/mattn/go-sqlite3"
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Maintenance commands that run directly against the booking database.
+// Usage: go run ./admin <command> [flags]
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	switch os.Args[1] {
+	case "merge-users":
+		mergeUsers(os.Args[2:])
+	case "backup":
+		backup(os.Args[2:])
+	case "restore":
+		restore(os.Args[2:])
+	default:
+		usage()
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: admin <command> [flags]\n\nCommands:\n")
+	fmt.Fprintf(os.Stderr, "  merge-users   merge users sharing the same normalized email and reassign their tickets\n")
+	fmt.Fprintf(os.Stderr, "  backup        copy the database to a file, also while the server is running\n")
+	fmt.Fprintf(os.Stderr, "  restore       check a backup and copy it over the database of a stopped server\n")
+	os.Exit(2)
+}
+
+func mergeUsers(args []string) {
+	flags := flag.NewFlagSet("merge-users", flag.ExitOnError)
+	dbPath := flags.String("db", "./ticket_booking.db", "path of the booking database")
+	dryRun := flags.Bool("dry-run", false, "only report the duplicates")
+	flags.Parse(args)
+
+	db := openDatabase(*dbPath)
+	defer db.Close()
+
+	merged, err := api.MergeDuplicateUsers(db, *dryRun)
+	if err != nil {
+		log.Fatalf("Failed to merge duplicate users: %v", err)
+	}
+	for _, group := range merged {
+		log.Printf("%s: keeping user %s, merging %v, %d tickets reassigned", group.Email, group.SurvivorId,
+			group.MergedIds, group.TicketsMoved)
+	}
+	if *dryRun {
+		log.Printf("Dry run, %d duplicate emails found and nothing changed", len(merged))
+		return
+	}
+
+	// with duplicates gone the unique email index can be created
+	if err := api.MigrateDatabase(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	for _, group := range merged {
+		if err := api.RecordAdminAction(db, "admin-cli", api.AuditActionAdminMergeUsers, group.SurvivorId, nil, group); err != nil {
+			log.Printf("Failed to record merge of %s in the audit log: %v", group.SurvivorId, err)
+		}
+	}
+	log.Printf("Merged %d duplicate emails", len(merged))
+}
+
+func backup(args []string) {
+	flags := flag.NewFlagSet("backup", flag.ExitOnError)
+	dbPath := flags.String("db", "./ticket_booking.db", "path of the booking database")
+	out := flags.String("out", "", "path of the backup, ticket_booking-<time>.db when empty")
+	flags.Parse(args)
+	if *out == "" {
+		*out = api.BackupName(time.Now())
+	}
+
+	db := openDatabase(*dbPath)
+	defer db.Close()
+
+	if err := api.BackupDatabase(context.Background(), db, *out); err != nil {
+		log.Fatalf("Failed to back up database: %v", err)
+	}
+	log.Printf("Backed up %s to %s", *dbPath, *out)
+}
+
+// restore works on the file of a stopped server, which rebuilds its seats from
+// the restored bookings when it starts. A running server restores its backups
+// with the RestoreDatabase RPC.
+func restore(args []string) {
+	flags := flag.NewFlagSet("restore", flag.ExitOnError)
+	dbPath := flags.String("db", "./ticket_booking.db", "path of the booking database, created when missing")
+	from := flags.String("from", "", "path of the backup")
+	dryRun := flags.Bool("dry-run", false, "only check the backup")
+	flags.Parse(args)
+	if *from == "" {
+		log.Fatalf("-from is required")
+	}
+
+	version, err := api.VerifyBackup(*from)
+	if err != nil {
+		log.Fatalf("Backup can't be restored: %v", err)
+	}
+	if *dryRun {
+		log.Printf("Backup %s is intact, schema version %d", *from, version)
+		return
+	}
+
+	db, err := sql.Open("sqlite3", *dbPath+"?_busy_timeout=5000")
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := api.RestoreDatabase(context.Background(), db, *from); err != nil {
+		log.Fatalf("Failed to restore database: %v", err)
+	}
+	if err := api.MigrateDatabase(db); err != nil {
+		log.Fatalf("Failed to migrate database: %v", err)
+	}
+	if err := api.RecordAdminAction(db, "admin-cli", api.AuditActionAdminRestore, filepath.Base(*from), nil,
+		map[string]int{"schema_version": version}); err != nil {
+		log.Printf("Failed to record the restore in the audit log: %v", err)
+	}
+	log.Printf("Restored %s from %s, schema version %d", *dbPath, *from, version)
+}
+
+// openDatabase waits for the locks of a running server instead of failing
+func openDatabase(path string) *sql.DB {
+	if _, err := os.Stat(path); err != nil {
+		log.Fatalf("Database %s not found: %v", path, err)
+	}
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000")
+	if err != nil {
+		log.Fatalf("Failed to open database: %v", err)
+	}
+	return db
+}
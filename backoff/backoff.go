@@ -0,0 +1,20 @@
+// Package backoff computes the waits between the attempts of a retried call,
+// shared by the client retries and the webhook deliveries of the server.
+package backoff
+
+import (
+	"time"
+)
+
+// Exponential is base after the first failed attempt and doubles after every
+// further one, up to max
+func Exponential(base, max time.Duration, attempt int) time.Duration {
+	wait := base
+	for i := 1; i < attempt && wait < max; i++ {
+		wait *= 2
+	}
+	if wait > max {
+		wait = max
+	}
+	return wait
+}
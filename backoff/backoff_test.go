@@ -0,0 +1,14 @@
+package backoff_test
+
+import (
+	"ticket-booking-app/backoff"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestShouldDoubleTheWaitUpToTheMaximum(t *testing.T) {
+	assert.Equal(t, time.Second, backoff.Exponential(time.Second, time.Minute, 1))
+	assert.Equal(t, 4*time.Second, backoff.Exponential(time.Second, time.Minute, 3))
+	assert.Equal(t, time.Minute, backoff.Exponential(time.Second, time.Minute, 50), "The wait shouldn't overflow past the maximum")
+}
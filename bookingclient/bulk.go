@@ -0,0 +1,63 @@
+package bookingclient
+
+import (
+	pb "ticket-booking-app/domain"
+	"context"
+	"io"
+)
+
+// importChunkSize is the size of the parts an import is sent in
+const importChunkSize = 32 * 1024
+
+// ExportBookings writes the bookings matching req to w, as CSV or NDJSON.
+// Like every stream it has no deadline and isn't retried.
+func (c *Client) ExportBookings(ctx context.Context, req *pb.ExportBookingsRequest, w io.Writer) error {
+	stream, err := c.admin.ExportBookings(c.outgoing(ctx), req)
+	if err != nil {
+		return convert(err)
+	}
+	return receive(stream, func(chunk *pb.ExportChunk) error {
+		_, err := w.Write(chunk.GetData())
+		return err
+	})
+}
+
+// ImportBookings sends the CSV or NDJSON file read from r with the options of
+// req, and returns the report of the rows. It isn't retried, as the batches
+// imported before a failure stay imported.
+func (c *Client) ImportBookings(ctx context.Context, req *pb.ImportBookingsRequest, r io.Reader) (*pb.ImportBookingsResponse, error) {
+	// a file that can't be read cancels the import
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := c.admin.ImportBookings(c.outgoing(ctx))
+	if err != nil {
+		return nil, convert(err)
+	}
+	message := &pb.ImportBookingsRequest{Format: req.GetFormat(), DryRun: req.GetDryRun(), BatchSize: req.GetBatchSize()}
+	buffer := make([]byte, importChunkSize)
+	for {
+		n, readErr := io.ReadFull(r, buffer)
+		if readErr == io.ErrUnexpectedEOF {
+			readErr = io.EOF
+		}
+		if readErr != nil && readErr != io.EOF {
+			return nil, readErr
+		}
+		message.Data = buffer[:n]
+		// io.EOF means the server ended the call, CloseAndRecv returns why
+		if err := stream.Send(message); err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, convert(err)
+		}
+		if readErr == io.EOF {
+			break
+		}
+		message = &pb.ImportBookingsRequest{}
+	}
+	response, err := stream.CloseAndRecv()
+	if err != nil {
+		return nil, convert(err)
+	}
+	return response, nil
+}
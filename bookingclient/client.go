@@ -0,0 +1,251 @@
+// Package bookingclient calls the BookingService of the booking server from
+// other Go programs. Every unary call gets its own deadline, idempotent calls
+// are retried while the server is unreachable or asks to retry later, and the
+// errors of the server are returned as *Error, which matches the Err values of
+// this package with errors.Is.
+package bookingclient
+
+import (
+	pb "ticket-booking-app/domain"
+	"ticket-booking-app/tlsconfig"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"github.com/google/uuid"
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// DefaultTimeout is the deadline of a call when Options.Timeout is zero
+const DefaultTimeout = 5 * time.Second
+
+// Options configure the connection and the calls of a Client.
+type Options struct {
+	// TLS secures the connection when TLS.CAFile is set
+	TLS TLSOptions
+	// AuthToken is sent as bearer token in the authorization metadata
+	AuthToken string
+	// APIKey is sent in the x-api-key metadata
+	APIKey string
+	// Timeout is the deadline of every attempt of a unary call. A deadline of
+	// the caller's context still bounds the call with all its retries.
+	Timeout time.Duration
+	// MethodTimeouts replace Timeout for the methods they name, e.g. "GetSeatMap"
+	MethodTimeouts map[string]time.Duration
+	// Retry retries idempotent calls, DefaultRetryPolicy when zero
+	Retry RetryPolicy
+	// DialOptions are added to the options Dial connects with
+	DialOptions []grpc.DialOption
+}
+
+// TLSOptions are the files of the CA that signed the server certificate, and
+// of the client certificate for servers that require one.
+type TLSOptions struct {
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+// Client calls the BookingService, and the bulk import and export of the
+// AdminService. It is safe for concurrent use.
+type Client struct {
+	service pb.BookingServiceClient
+	admin   pb.AdminServiceClient
+	options Options
+	// conn is closed by Close when Dial opened it
+	conn *grpc.ClientConn
+}
+
+// Dial connects to the server at address, a host:port. The connection is made
+// lazily by the first call, so an unreachable server fails the calls with
+// ErrUnavailable rather than Dial.
+func Dial(address string, options Options) (*Client, error) {
+	creds := insecure.NewCredentials()
+	if options.TLS.CAFile != "" {
+		certificates, err := tlsconfig.NewReloader(options.TLS.CertFile, options.TLS.KeyFile, options.TLS.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("loading TLS certificates: %w", err)
+		}
+		creds = credentials.NewTLS(tlsconfig.ClientConfig(certificates, options.TLS.ServerName))
+	}
+	// the trace context of every call is sent to the server in the metadata
+	dialOptions := append([]grpc.DialOption{grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(otelgrpc.NewClientHandler())}, options.DialOptions...)
+	conn, err := grpc.NewClient(address, dialOptions...)
+	if err != nil {
+		return nil, err
+	}
+	client := New(conn, options)
+	client.conn = conn
+	return client, nil
+}
+
+// New calls the BookingService over conn, which the caller keeps owning. The
+// TLS and DialOptions of options don't apply.
+func New(conn grpc.ClientConnInterface, options Options) *Client {
+	if options.Timeout <= 0 {
+		options.Timeout = DefaultTimeout
+	}
+	if options.Retry.MaxAttempts <= 0 {
+		options.Retry = DefaultRetryPolicy
+	}
+	return &Client{service: pb.NewBookingServiceClient(conn), admin: pb.NewAdminServiceClient(conn), options: options}
+}
+
+// Close closes the connection opened by Dial
+func (c *Client) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// WithIdempotencyKey sets the key under which the server stores the response
+// of the next CreateBooking, ModifySeatByUser or RemoveBookingByUser made with
+// ctx. The server replays that response to calls with the same key, so a call
+// repeated after a crash must reuse the key. Without one a key is generated
+// per call, which still makes the retries of the call safe.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return metadata.AppendToOutgoingContext(ctx, idempotencyKeyHeader, key)
+}
+
+const idempotencyKeyHeader = "idempotency-key"
+
+// CreateBooking books a seat, the held one when req has a hold id.
+// Retried, as it is made idempotent with a key.
+func (c *Client) CreateBooking(ctx context.Context, req *pb.BookingRequest) (*pb.BookingResponse, error) {
+	return invoke(withGeneratedIdempotencyKey(ctx), c, "CreateBooking", true, c.service.CreateBooking, req)
+}
+
+func (c *Client) GetBooking(ctx context.Context, req *pb.GetBookingRequest) (*pb.BookingResponse, error) {
+	return invoke(ctx, c, "GetBooking", true, c.service.GetBooking, req)
+}
+
+func (c *Client) GetBookingByUser(ctx context.Context, req *pb.GetBookingByUserRequest) (*pb.BookingResponse, error) {
+	return invoke(ctx, c, "GetBookingByUser", true, c.service.GetBookingByUser, req)
+}
+
+func (c *Client) GetBookingsBySection(ctx context.Context, req *pb.GetBookingsBySectionRequest) (*pb.BookingListResponse, error) {
+	return invoke(ctx, c, "GetBookingsBySection", true, c.service.GetBookingsBySection, req)
+}
+
+func (c *Client) ListMyBookings(ctx context.Context, req *pb.ListMyBookingsRequest) (*pb.BookingListResponse, error) {
+	return invoke(ctx, c, "ListMyBookings", true, c.service.ListMyBookings, req)
+}
+
+// ModifySeatByUser moves a booking to another seat. Retried, as it is made
+// idempotent with a key.
+func (c *Client) ModifySeatByUser(ctx context.Context, req *pb.SeatModificationRequest) (*pb.SeatModificationResponse, error) {
+	return invoke(withGeneratedIdempotencyKey(ctx), c, "ModifySeatByUser", true, c.service.ModifySeatByUser, req)
+}
+
+// RemoveBookingByUser cancels a booking. Retried, as it is made idempotent
+// with a key.
+func (c *Client) RemoveBookingByUser(ctx context.Context, req *pb.RemoveBookingByUserRequest) (*pb.RemoveBookingResponse, error) {
+	return invoke(withGeneratedIdempotencyKey(ctx), c, "RemoveBookingByUser", true, c.service.RemoveBookingByUser, req)
+}
+
+func (c *Client) GetETicket(ctx context.Context, req *pb.GetETicketRequest) (*pb.ETicket, error) {
+	return invoke(ctx, c, "GetETicket", true, c.service.GetETicket, req)
+}
+
+func (c *Client) GetSeatMap(ctx context.Context, req *pb.GetSeatMapRequest) (*pb.SeatMap, error) {
+	return invoke(ctx, c, "GetSeatMap", true, c.service.GetSeatMap, req)
+}
+
+// HoldSeat isn't retried, a retry could leave a second hold behind.
+func (c *Client) HoldSeat(ctx context.Context, req *pb.HoldSeatRequest) (*pb.SeatHold, error) {
+	return invoke(ctx, c, "HoldSeat", false, c.service.HoldSeat, req)
+}
+
+// ReleaseSeatHold isn't retried, a retry after a lost response would fail
+// with ErrSeatHoldNotFound.
+func (c *Client) ReleaseSeatHold(ctx context.Context, req *pb.ReleaseSeatHoldRequest) (*pb.ReleaseSeatHoldResponse, error) {
+	return invoke(ctx, c, "ReleaseSeatHold", false, c.service.ReleaseSeatHold, req)
+}
+
+// WatchBookings calls handle with every booking change until ctx is done,
+// the server ends the stream or handle fails. Streams have no deadline and
+// aren't retried; a cancelled ctx returns an error matching context.Canceled.
+func (c *Client) WatchBookings(ctx context.Context, handle func(*pb.BookingEvent) error) error {
+	stream, err := c.service.WatchBookings(c.outgoing(ctx), &pb.WatchBookingsRequest{})
+	if err != nil {
+		return convert(err)
+	}
+	return receive(stream, handle)
+}
+
+// StreamGroupETicket calls handle with every chunk of the e-ticket PDF of a
+// group, until the last one or until handle fails.
+func (c *Client) StreamGroupETicket(ctx context.Context, req *pb.GetGroupETicketRequest, handle func(*pb.ETicketChunk) error) error {
+	stream, err := c.service.StreamGroupETicket(c.outgoing(ctx), req)
+	if err != nil {
+		return convert(err)
+	}
+	return receive(stream, handle)
+}
+
+func receive[T any](stream grpc.ServerStreamingClient[T], handle func(*T) error) error {
+	for {
+		message, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return convert(err)
+		}
+		if err := handle(message); err != nil {
+			return err
+		}
+	}
+}
+
+// invoke makes a unary call with a deadline per attempt, retrying idempotent
+// calls that failed with a retryable error.
+func invoke[Req, Resp any](ctx context.Context, c *Client, method string, idempotent bool,
+	call func(context.Context, Req, ...grpc.CallOption) (Resp, error), req Req) (Resp, error) {
+	ctx = c.outgoing(ctx)
+	timeout := c.options.Timeout
+	if methodTimeout, ok := c.options.MethodTimeouts[method]; ok {
+		timeout = methodTimeout
+	}
+	for attempt := 1; ; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		response, err := call(attemptCtx, req)
+		cancel()
+		if err == nil {
+			return response, nil
+		}
+		if !idempotent || attempt >= c.options.Retry.MaxAttempts || ctx.Err() != nil || !retryable(err) {
+			return response, convert(err)
+		}
+		if waitErr := c.options.Retry.wait(ctx, attempt, err); waitErr != nil {
+			return response, convert(err)
+		}
+	}
+}
+
+// outgoing adds the credentials of the client to the metadata of a call
+func (c *Client) outgoing(ctx context.Context) context.Context {
+	if c.options.AuthToken != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+c.options.AuthToken)
+	}
+	if c.options.APIKey != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", c.options.APIKey)
+	}
+	return ctx
+}
+
+// withGeneratedIdempotencyKey adds a key unless the caller set one, before the
+// retries so that every attempt sends the same key
+func withGeneratedIdempotencyKey(ctx context.Context) context.Context {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok && len(md.Get(idempotencyKeyHeader)) > 0 {
+		return ctx
+	}
+	return WithIdempotencyKey(ctx, uuid.NewString())
+}
@@ -0,0 +1,197 @@
+package bookingclient_test
+
+import (
+	"ticket-booking-app/bookingclient"
+	pb "ticket-booking-app/domain"
+	"ticket-booking-app/server/api"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flakyService fails the first calls of CreateBooking and HoldSeat as if the
+// server was unreachable, delays the first CreateBooking that gets through,
+// and lets GetSeatMap hang until the deadline.
+type flakyService struct {
+    *api.BookingService
+    mu          sync.Mutex
+    failures    int
+    delay       time.Duration
+    calls       map[string]int
+    keys        []string
+    authorities []string
+}
+
+func (s *flakyService) fail(ctx context.Context, method string) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    s.calls[method]++
+    md, _ := metadata.FromIncomingContext(ctx)
+    s.keys = append(s.keys, md.Get("idempotency-key")...)
+    s.authorities = append(s.authorities, md.Get("authorization")...)
+    if s.calls[method] <= s.failures {
+        return status.Error(codes.Unavailable, "Connection reset")
+    }
+    return nil
+}
+
+// callsOf returns the number of calls of method, which handlers still running
+// may change
+func (s *flakyService) callsOf(method string) int {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return s.calls[method]
+}
+
+// received returns the idempotency keys and authorization headers received
+func (s *flakyService) received() ([]string, []string) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]string(nil), s.keys...), append([]string(nil), s.authorities...)
+}
+
+func (s *flakyService) CreateBooking(ctx context.Context, req *pb.BookingRequest) (*pb.BookingResponse, error) {
+    if err := s.fail(ctx, "CreateBooking"); err != nil {
+        return nil, err
+    }
+    if s.callsOf("CreateBooking") == s.failures+1 {
+        time.Sleep(s.delay)
+    }
+    return s.BookingService.CreateBooking(context.WithoutCancel(ctx), req)
+}
+
+func (s *flakyService) HoldSeat(ctx context.Context, req *pb.HoldSeatRequest) (*pb.SeatHold, error) {
+    if err := s.fail(ctx, "HoldSeat"); err != nil {
+        return nil, err
+    }
+    return s.BookingService.HoldSeat(ctx, req)
+}
+
+func (s *flakyService) GetSeatMap(ctx context.Context, req *pb.GetSeatMapRequest) (*pb.SeatMap, error) {
+    s.fail(ctx, "GetSeatMap")
+    <-ctx.Done()
+    return nil, status.FromContextError(ctx.Err()).Err()
+}
+
+func newTestClient(t *testing.T, failures int, delay time.Duration, options bookingclient.Options) (*bookingclient.Client, *flakyService) {
+    t.Helper()
+    db, err := sql.Open("sqlite3", ":memory:")
+    if err != nil {
+        t.Fatalf("Error in opening database %v ", err)
+    }
+    db.SetMaxOpenConns(1)
+    t.Cleanup(func() { db.Close() })
+    if err := api.MigrateDatabase(db); err != nil {
+        t.Fatalf("Error in migrating database %v ", err)
+    }
+
+    service := &flakyService{BookingService: api.NewBookingService(db), failures: failures, delay: delay, calls: map[string]int{}}
+    listener := bufconn.Listen(1 << 20)
+    server := grpc.NewServer(grpc.UnaryInterceptor(api.NewIdempotencyInterceptor(db, time.Hour)))
+    pb.RegisterBookingServiceServer(server, service)
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    conn, err := grpc.NewClient("passthrough:///bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }))
+    if err != nil {
+        t.Fatalf("Error in connecting to server %v ", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    return bookingclient.New(conn, options), service
+}
+
+func bookingRequest(email string) *pb.BookingRequest {
+    return &pb.BookingRequest{From: "London", To: "France", Price: 20,
+        User: &pb.User{Firstname: "Vrushali", Lastname: "Ghadge", Email: email}}
+}
+
+func TestShouldRetryIdempotentCallsWithTheSameKey(t *testing.T) {
+    client, service := newTestClient(t, 2, 0, bookingclient.Options{
+        AuthToken: "secret",
+        Retry:     bookingclient.RetryPolicy{MaxAttempts: 3, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+    })
+
+    booking, err := client.CreateBooking(context.Background(), bookingRequest("vg@gmail.com"))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    assert.NotEmpty(t, booking.GetPnr())
+    assert.Equal(t, 3, service.callsOf("CreateBooking"))
+    keys, authorities := service.received()
+    if assert.Len(t, keys, 3) {
+        assert.NotEmpty(t, keys[0])
+        assert.Equal(t, keys[0], keys[2], "Retries should send the idempotency key of the first attempt")
+    }
+    assert.Equal(t, []string{"Bearer secret", "Bearer secret", "Bearer secret"}, authorities)
+
+    _, err = client.HoldSeat(context.Background(), &pb.HoldSeatRequest{Section: "A", Seat: 5})
+    assert.ErrorIs(t, err, bookingclient.ErrUnavailable)
+    assert.Equal(t, 1, service.callsOf("HoldSeat"), "Holding a seat isn't idempotent and shouldn't be retried")
+}
+
+func TestShouldRetryWhileTheTimedOutAttemptIsInProgress(t *testing.T) {
+    client, service := newTestClient(t, 0, 100*time.Millisecond, bookingclient.Options{
+        Timeout: 20 * time.Millisecond,
+        Retry:   bookingclient.RetryPolicy{MaxAttempts: 20, BaseBackoff: 20 * time.Millisecond, MaxBackoff: 20 * time.Millisecond},
+    })
+
+    booking, err := client.CreateBooking(context.Background(), bookingRequest("vg@gmail.com"))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    assert.Equal(t, 1, service.callsOf("CreateBooking"), "Only the first attempt should have run the call")
+    seatMap, err := service.BookingService.GetSeatMap(context.Background(), &pb.GetSeatMapRequest{Section: booking.GetSection()})
+    if err != nil {
+        t.Fatalf("Error in getting seat map %v ", err)
+    }
+    assert.Equal(t, []int32{booking.GetSeat()}, seatMap.GetSections()[0].GetOccupied(), "The retries should replay the first booking")
+}
+
+func TestShouldGiveEveryAttemptItsOwnDeadline(t *testing.T) {
+    client, service := newTestClient(t, 0, 0, bookingclient.Options{
+        MethodTimeouts: map[string]time.Duration{"GetSeatMap": 20 * time.Millisecond},
+        Retry:          bookingclient.RetryPolicy{MaxAttempts: 2, BaseBackoff: time.Millisecond, MaxBackoff: time.Millisecond},
+    })
+
+    _, err := client.GetSeatMap(context.Background(), &pb.GetSeatMapRequest{})
+    assert.ErrorIs(t, err, context.DeadlineExceeded)
+    assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+    assert.Eventually(t, func() bool { return service.callsOf("GetSeatMap") == 2 }, time.Second, time.Millisecond,
+        "Every attempt should have reached the server")
+}
+
+func TestShouldConvertStatusToTypedErrors(t *testing.T) {
+    client, _ := newTestClient(t, 0, 0, bookingclient.Options{})
+
+    _, err := client.GetBooking(context.Background(), &pb.GetBookingRequest{BookingId: "NOPE42"})
+    assert.ErrorIs(t, err, bookingclient.ErrNotFound)
+
+    if _, err := client.HoldSeat(context.Background(), &pb.HoldSeatRequest{Section: "A", Seat: 5}); err != nil {
+        t.Fatalf("Error in holding seat %v ", err)
+    }
+    _, err = client.HoldSeat(context.Background(), &pb.HoldSeatRequest{Section: "A", Seat: 5})
+    assert.ErrorIs(t, err, bookingclient.ErrSeatNotAvailable)
+    assert.ErrorIs(t, err, bookingclient.ErrInvalidArgument, "Errors should also match the error of their code")
+    assert.False(t, errors.Is(err, bookingclient.ErrNotFound))
+    var callErr *bookingclient.Error
+    if assert.True(t, errors.As(err, &callErr)) {
+        assert.Equal(t, pb.ErrorReason_SEAT_NOT_AVAILABLE.String(), callErr.Reason)
+        assert.Equal(t, "Seat number 5 in section A is not available", callErr.Message)
+    }
+
+    _, err = client.ReleaseSeatHold(context.Background(), &pb.ReleaseSeatHoldRequest{HoldId: "expired"})
+    assert.ErrorIs(t, err, bookingclient.ErrSeatHoldNotFound)
+}
@@ -0,0 +1,109 @@
+package bookingclient
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"context"
+	"errors"
+	"time"
+)
+
+// Errors of the server, matched by errors.Is. An error with a reason matches
+// the error of its reason and the one of its code, e.g. ErrSeatNotAvailable
+// and ErrInvalidArgument.
+var (
+	ErrNotFound           = errors.New("not found")
+	ErrAlreadyExists      = errors.New("already exists")
+	ErrInvalidArgument    = errors.New("invalid argument")
+	ErrFailedPrecondition = errors.New("failed precondition")
+	// ErrConflict is a booking changed by another request since its etag was
+	// read, or a request still running under the same idempotency key
+	ErrConflict = errors.New("conflict")
+	// ErrQuotaExceeded is a rate limit or the limit of active bookings
+	ErrQuotaExceeded    = errors.New("quota exceeded")
+	ErrUnauthenticated  = errors.New("unauthenticated")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrUnavailable      = errors.New("server unavailable")
+
+	// ErrSeatNotAvailable is a seat that is taken, held or doesn't exist
+	ErrSeatNotAvailable = errors.New("seat not available")
+	// ErrSeatHoldNotFound is a seat hold that was booked, released or expired
+	ErrSeatHoldNotFound = errors.New("seat hold not found")
+	// ErrRequestInProgress is a call whose idempotency key is held by another
+	// call that hasn't finished yet
+	ErrRequestInProgress = errors.New("request in progress")
+)
+
+var codeErrors = map[codes.Code]error{
+	codes.NotFound:           ErrNotFound,
+	codes.AlreadyExists:      ErrAlreadyExists,
+	codes.InvalidArgument:    ErrInvalidArgument,
+	codes.FailedPrecondition: ErrFailedPrecondition,
+	codes.Aborted:            ErrConflict,
+	codes.ResourceExhausted:  ErrQuotaExceeded,
+	codes.Unauthenticated:    ErrUnauthenticated,
+	codes.PermissionDenied:   ErrPermissionDenied,
+	codes.Unavailable:        ErrUnavailable,
+	codes.DeadlineExceeded:   context.DeadlineExceeded,
+	codes.Canceled:           context.Canceled,
+}
+
+var reasonErrors = map[string]error{
+	pb.ErrorReason_SEAT_NOT_AVAILABLE.String():          ErrSeatNotAvailable,
+	pb.ErrorReason_SEAT_HOLD_NOT_FOUND.String():         ErrSeatHoldNotFound,
+	pb.ErrorReason_IDEMPOTENCY_KEY_IN_PROGRESS.String(): ErrRequestInProgress,
+}
+
+// Error is a failed call. It keeps the gRPC status, so status.FromError and
+// status.Code work on it as on the error of the generated client.
+type Error struct {
+	Code    codes.Code
+	Message string
+	// Reason is the reason of the ErrorInfo detail, empty without one
+	Reason string
+	// RetryAfter is when the server accepts the call again, zero if it didn't say
+	RetryAfter time.Duration
+
+	status *status.Status
+	kinds  []error
+}
+
+func (e *Error) Error() string {
+	return e.Message + " (" + e.Code.String() + ")"
+}
+
+// Unwrap returns the Err values the error matches
+func (e *Error) Unwrap() []error {
+	return e.kinds
+}
+
+func (e *Error) GRPCStatus() *status.Status {
+	return e.status
+}
+
+// convert turns the status of err into an *Error, other errors are returned
+// unchanged
+func convert(err error) error {
+	st, ok := status.FromError(err)
+	if !ok || st == nil {
+		return err
+	}
+	converted := &Error{Code: st.Code(), Message: st.Message(), status: st}
+	for _, detail := range st.Details() {
+		switch detail := detail.(type) {
+		case *errdetails.ErrorInfo:
+			converted.Reason = detail.GetReason()
+		case *errdetails.RetryInfo:
+			converted.RetryAfter = detail.GetRetryDelay().AsDuration()
+		}
+	}
+	if kind, ok := reasonErrors[converted.Reason]; ok {
+		converted.kinds = append(converted.kinds, kind)
+	}
+	if kind, ok := codeErrors[st.Code()]; ok {
+		converted.kinds = append(converted.kinds, kind)
+	}
+	return converted
+}
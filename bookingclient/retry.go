@@ -0,0 +1,71 @@
+package bookingclient
+
+import (
+	"ticket-booking-app/backoff"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"context"
+	"errors"
+	"time"
+)
+
+// RetryPolicy retries idempotent calls that failed because the server was
+// unreachable, the attempt ran out of time, the server was rate limiting and
+// told when to retry, or a timed out attempt was still running under the same
+// idempotency key. Other errors, like ErrConflict, need the caller to
+// read again before retrying and are returned at once.
+type RetryPolicy struct {
+	// MaxAttempts counts the first call too, 1 turns retries off
+	MaxAttempts int
+	// BaseBackoff is the wait before the first retry, it doubles after every
+	// further attempt up to MaxBackoff. A longer wait asked for by the server
+	// is honoured.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy of a Client without one
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseBackoff: 100 * time.Millisecond, MaxBackoff: 2 * time.Second}
+
+func retryable(err error) bool {
+	switch status.Code(err) {
+	case codes.Unavailable, codes.DeadlineExceeded:
+		return true
+	case codes.ResourceExhausted:
+		// quotas like the active bookings of a passenger don't free up by waiting
+		_, ok := retryDelay(err)
+		return ok
+	case codes.Aborted:
+		// the previous attempt still holds the key, the retry replays its
+		// response once it is stored
+		return errors.Is(convert(err), ErrRequestInProgress)
+	}
+	return false
+}
+
+// wait sleeps before the retry that follows attempt, or until ctx is done
+func (r RetryPolicy) wait(ctx context.Context, attempt int, err error) error {
+	wait := backoff.Exponential(r.BaseBackoff, r.MaxBackoff, attempt)
+	if delay, ok := retryDelay(err); ok && delay > wait {
+		wait = delay
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// retryDelay returns the delay of the RetryInfo detail of err
+func retryDelay(err error) (time.Duration, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		if retryInfo, ok := detail.(*errdetails.RetryInfo); ok {
+			return retryInfo.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
@@ -1,129 +1,140 @@
 package main
 
 import (
-	pb "ticket-booking-app/domain"
-	"google.golang.org/grpc/credentials/insecure"
-	"google.golang.org/grpc"
+	"ticket-booking-app/bookingclient"
+	"ticket-booking-app/config"
+	"ticket-booking-app/telemetry"
+	"ticket-booking-app/logging"
+	"google.golang.org/grpc/status"
+	"go.opentelemetry.io/otel"
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
-var bookingClient pb.BookingServiceClient
-var serverContext context.Context
+// Exit codes of the client. Errors returned by the server exit with
+// exitStatusBase plus their gRPC status code, e.g. 15 for NotFound (5), so
+// scripts can tell them apart.
+const (
+	exitOK         = 0
+	exitFailure    = 1
+	exitUsage      = 2
+	exitStatusBase = 10
+)
 
+// Command line client of the booking server.
+// Usage: go run ./client [flags] <command> [arguments]
 func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	serverConn, err := grpc.Dial("localhost:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+func run(args []string) int {
+	cfg, command, printOnly, err := config.LoadClient(args)
+	if errors.Is(err, flag.ErrHelp) {
+		printCommands()
+		return exitOK
+	}
 	if err != nil {
-		log.Fatalf("Failed to connect server: localhost:50051: %v", err)
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		return exitUsage
+	}
+	if printOnly {
+		fmt.Print(cfg)
+		return exitOK
+	}
+	if len(command) == 0 {
+		printCommands()
+		return exitUsage
+	}
+	cmd, ok := commands[command[0]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n", command[0])
+		printCommands()
+		return exitUsage
 	}
-	defer serverConn.Close()
-	bookingClient = pb.NewBookingServiceClient(serverConn)
-
-    var cancel context.CancelFunc
-	serverContext, cancel = context.WithTimeout(context.Background(), time.Second)
-	defer cancel()
-
-    // List of bookings by section
-    log.Printf("\n ***********************************\n")
-    retrieveTrainBookingBySection("A")
-    retrieveTrainBookingBySection("B")
-    log.Printf("***********************************\n")
-
-    //create new train bookings
-	createNewTrainBooking("vrushali", "ghadge", "vg@gmail.com")
-	createNewTrainBooking("vikram", "ghadge", "vkg@gmail.com")
-
-	// Modify seat for user1
-    updateExistingTrainBooking("A", 19, retrieveTrainBookingByUser("vrushali", "ghadge", "vg@gmail.com").GetUser())
-
-	// Remove user2 booking
-    cancelExistingTrainBooking(retrieveTrainBookingByUser("vikram", "ghadge", "vkg@gmail.com").GetUser())
-
-    // List of bookings by section
-    log.Printf("\n ***********************************\n")
-    retrieveTrainBookingBySection("A")
-    retrieveTrainBookingBySection("B")
-     log.Printf("***********************************\n")
-}
-
-func cancelExistingTrainBooking(user *pb.User) {
-    cancelBookingRequest := &pb.RemoveBookingByUserRequest{User: user}
-
-    _, cancelBookingErr := bookingClient.RemoveBookingByUser(serverContext, cancelBookingRequest)
-    if cancelBookingErr != nil {
-    	log.Fatalf("Error while cancelling booking : %v", cancelBookingErr)
-    }
-
-    log.Printf("\nBooking for user %s successfully cancelled", user.GetEmail())
-}
-
-func updateExistingTrainBooking(sectionTitle string, seatNumber int32, user *pb.User) {
-    updateSeatRequest := &pb.SeatModificationRequest{
-    		Section: sectionTitle,
-    		Seat:    seatNumber,
-    		User:    user,
-    	}
-    updatedSeat, updateSeatErr := bookingClient.ModifySeatByUser(serverContext, updateSeatRequest)
-    if updateSeatErr != nil {
-    	log.Fatalf("Error in updating the booking : %v", updateSeatErr)
-    }
-
-   	log.Printf("\nUpdated booking of user %s successfully with seat %s",  user.GetEmail(), updatedSeat)
-}
-
-func retrieveTrainBookingBySection(sectionTitle string) (*pb.BookingListResponse) {
-    getBookingsBySectionRequest := &pb.GetBookingsBySectionRequest{
-    		Section: sectionTitle,
-    	}
 
-    bookingsBySection, bookingsBySectionErr := bookingClient.GetBookingsBySection(serverContext, getBookingsBySectionRequest)
-    if bookingsBySectionErr != nil {
-    	log.Fatalf("Error in retrieving the bookings : %v", bookingsBySectionErr)
-    }
+	level, _ := config.ParseLogLevel(cfg.LogLevel)
+	logger, err := logging.New(os.Stderr, cfg.LogFormat, level, cfg.LogRedact)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid configuration: %v\n", err)
+		return exitUsage
+	}
+	slog.SetDefault(logger)
 
-    log.Printf("\nRetrieved booked user for section %s, Details : %s", sectionTitle, bookingsBySection)
-    return bookingsBySection
+	exporter, err := telemetry.NewExporter(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.Endpoint, cfg.Tracing.Insecure, os.Stderr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create trace exporter: %v\n", err)
+		return exitFailure
+	}
+	tracerProvider := telemetry.Setup("ticket-booking-client", exporter, cfg.Tracing.SampleRatio)
+	defer tracerProvider.Shutdown(context.Background())
+
+	client, err := bookingclient.Dial(cfg.ServerAddress, bookingclient.Options{
+		TLS: bookingclient.TLSOptions{
+			CAFile:     cfg.TLS.CAFile,
+			CertFile:   cfg.TLS.CertFile,
+			KeyFile:    cfg.TLS.KeyFile,
+			ServerName: cfg.TLS.ServerName,
+		},
+		AuthToken: cfg.AuthToken,
+		APIKey:    cfg.APIKey,
+		Timeout:   time.Duration(cfg.Timeout),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to connect server %s: %v\n", cfg.ServerAddress, err)
+		return exitFailure
+	}
+	defer client.Close()
+
+	// an interrupt cancels the running call, and ends watch without an error
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// the calls of a command are spans of one trace
+	ctx, span := otel.Tracer("ticket-booking-app/client").Start(ctx, "client "+command[0])
+	defer span.End()
+	cl := &cli{client: client, ctx: ctx, out: newPrinter(os.Stdout, cfg.Output)}
+	err = cmd.run(cl, command[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return exitOK
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", errorMessage(err))
+	}
+	return exitCode(err)
 }
 
-func retrieveTrainBookingByUser(fName, lName, email string) (*pb.BookingResponse) {
-    getBookingByUserRequest := &pb.GetBookingByUserRequest{
-    		User: &pb.User{
-    			Firstname: fName,
-    			Lastname:  lName,
-    			Email:     email,
-    		},
-    	}
-
-    getBookingByUser, getBookingErr := bookingClient.GetBookingByUser(serverContext, getBookingByUserRequest)
-    if getBookingErr != nil {
-    	log.Fatalf("Error in retrieving the booking: %v", getBookingErr)
-    }
-
-    log.Printf("\nBooked user detail: %s", getBookingByUser)
-    return getBookingByUser
+// exitCode maps err onto the exit code of the client
+func exitCode(err error) int {
+	var usage usageError
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.As(err, &usage):
+		return exitUsage
+	}
+	if st, ok := status.FromError(err); ok {
+		return exitStatusBase + int(st.Code())
+	}
+	return exitFailure
 }
 
-func createNewTrainBooking(fName, lName, email string) {
-    userRequest := createNewTrainBookingRequest(fName, lName, email)
-    bookingResponse, bookingErr := bookingClient.CreateBooking(serverContext, userRequest)
-    if bookingErr != nil {
-        log.Fatalf("Error in creating train booking request : %v , Error: %v", bookingErr, userRequest)
-    }
-
-    log.Printf("\nBooking completed Successfully, For user %s", bookingResponse.User.GetEmail())
+func errorMessage(err error) string {
+	if st, ok := status.FromError(err); ok {
+		return fmt.Sprintf("%s (%s)", st.Message(), st.Code())
+	}
+	return err.Error()
 }
 
-func createNewTrainBookingRequest(fName, lName, email string) (*pb.BookingRequest) {
-    return &pb.BookingRequest{
-           		From:  "London",
-           		To:    "France",
-           		Price: 20,
-           		User: &pb.User{
-           			Firstname: fName,
-           			Lastname:  lName,
-           			Email:     email,
-           		},
-           	};
+func printCommands() {
+	fmt.Fprintf(os.Stderr, "\nUsage: client [flags] <command> [arguments]\n\nCommands:\n")
+	for _, name := range commandNames {
+		fmt.Fprintf(os.Stderr, "  %-9s %s\n", name, commands[name].summary)
+	}
+	fmt.Fprintf(os.Stderr, "\nRun client <command> -h for the arguments of a command. Server errors exit with %d plus their gRPC status code.\n", exitStatusBase)
 }
@@ -0,0 +1,46 @@
+package main
+
+import (
+	pb "ticket-booking-app/domain"
+	"ticket-booking-app/config"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestShouldMapErrorsToExitCodes(t *testing.T) {
+    assert.Equal(t, 0, exitCode(nil))
+    assert.Equal(t, 2, exitCode(usageError{errors.New("missing -email")}))
+    assert.Equal(t, 15, exitCode(status.Error(codes.NotFound, "No booking exists")), "Server errors should exit with 10 plus their code")
+    assert.Equal(t, 16, exitCode(status.Error(codes.AlreadyExists, "Ticket already exists")))
+    assert.Equal(t, 1, exitCode(errors.New("connection refused")))
+}
+
+func TestShouldPrintResponsesInEveryFormat(t *testing.T) {
+    seatMap := &pb.SeatMap{Sections: []*pb.SectionSeats{{Name: "A", Seats: 12, Occupied: []int32{0, 11}, Held: []int32{3}}}}
+
+    var out bytes.Buffer
+    if err := newPrinter(&out, config.OutputTable).print(seatMap); err != nil {
+        t.Fatalf("Error in printing seat map %v ", err)
+    }
+    assert.Equal(t, "Section A: 10 of 12 seats free, 1 held\n   x  1  2  h  4  5  6  7  8  9\n  10  x\n", out.String())
+
+    out.Reset()
+    booking := &pb.BookingResponse{Pnr: "ABC123", Seat: 3, Section: "A", Etag: "2"}
+    if err := newPrinter(&out, config.OutputYAML).print(booking); err != nil {
+        t.Fatalf("Error in printing booking %v ", err)
+    }
+    assert.Equal(t, "seat: 3\nsection: A\npnr: ABC123\netag: \"2\"\n", out.String(), "YAML should keep the field order and types of the JSON")
+
+    out.Reset()
+    printer := newPrinter(&out, config.OutputJSON)
+    for i := 0; i < 2; i++ {
+        if err := printer.printEvent(&pb.BookingEvent{Type: "booking.created"}); err != nil {
+            t.Fatalf("Error in printing event %v ", err)
+        }
+    }
+    assert.Equal(t, 2, bytes.Count(out.Bytes(), []byte("\n")), "Every event should be a line of JSON")
+}
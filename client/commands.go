@@ -0,0 +1,360 @@
+package main
+
+import (
+	"ticket-booking-app/bookingclient"
+	pb "ticket-booking-app/domain"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// cli runs the commands against the server. The client gives every unary call
+// the timeout of the configuration, streams last until they end or ctx is
+// cancelled.
+type cli struct {
+	client *bookingclient.Client
+	ctx    context.Context
+	out    *printer
+}
+
+type command struct {
+	summary string
+	run     func(c *cli, args []string) error
+}
+
+var commands = map[string]command{
+	"book":    {summary: "book a seat for a passenger", run: runBook},
+	"get":     {summary: "show a booking by ticket id or PNR", run: runGet},
+	"list":    {summary: "list the bookings of a section or of a passenger", run: runList},
+	"modify":  {summary: "move a booking to another seat", run: runModify},
+	"cancel":  {summary: "cancel a booking", run: runCancel},
+	"seatmap": {summary: "show the free and taken seats of every section", run: runSeatmap},
+	"watch":   {summary: "print booking changes as they happen, until interrupted", run: runWatch},
+	"pick":    {summary: "pick a seat for a new or existing booking on an interactive seat map", run: runPick},
+	"export":  {summary: "write the bookings with their passengers as CSV or NDJSON", run: runExport},
+	"import":  {summary: "book the rows of a CSV or NDJSON file, reporting the rows that failed", run: runImport},
+}
+
+// commandNames lists the commands in the order of the usage
+var commandNames = []string{"book", "get", "list", "modify", "cancel", "seatmap", "watch", "pick", "export", "import"}
+
+// usageError is a command invoked with missing or invalid arguments
+type usageError struct {
+	error
+}
+
+func newFlagSet(name, arguments string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: client [flags] %s %s\n", name, arguments)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses the flags of a command followed by exactly positional arguments
+func parse(fs *flag.FlagSet, args []string, positional int) error {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return err
+		}
+		return usageError{err}
+	}
+	if fs.NArg() != positional {
+		fs.Usage()
+		return usageError{fmt.Errorf("%s takes %d arguments, got %d", fs.Name(), positional, fs.NArg())}
+	}
+	return nil
+}
+
+// required reports the flags of fs that were left empty
+func required(fs *flag.FlagSet, names ...string) error {
+	var missing []string
+	for _, name := range names {
+		if fs.Lookup(name).Value.String() == "" {
+			missing = append(missing, "-"+name)
+		}
+	}
+	if len(missing) > 0 {
+		fs.Usage()
+		return usageError{fmt.Errorf("%s requires %v", fs.Name(), missing)}
+	}
+	return nil
+}
+
+// bookingOrEmail requires the booking to be addressed by its id or PNR, or by
+// the email of a passenger with a single booking
+func bookingOrEmail(fs *flag.FlagSet, bookingId, email string) error {
+	if bookingId == "" && email == "" {
+		fs.Usage()
+		return usageError{fmt.Errorf("%s requires -booking or -email", fs.Name())}
+	}
+	return nil
+}
+
+func runBook(c *cli, args []string) error {
+	fs := newFlagSet("book", "-first-name <name> -last-name <name> -email <email> -from <station> -to <station> [-departure <time>]")
+	firstName := fs.String("first-name", "", "first name of the passenger")
+	lastName := fs.String("last-name", "", "last name of the passenger")
+	email := fs.String("email", "", "email of the passenger")
+	from := fs.String("from", "", "departure station")
+	to := fs.String("to", "", "arrival station")
+	price := fs.Int("price", 20, "price of the ticket")
+	departure := fs.String("departure", "", "departure time in RFC 3339, e.g. 2026-11-02T09:30:00Z")
+	idempotencyKey := fs.String("idempotency-key", "", "key under which the server replays the booking to retries, generated when empty")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := required(fs, "first-name", "last-name", "email", "from", "to"); err != nil {
+		return err
+	}
+
+	req := &pb.BookingRequest{
+		From:  *from,
+		To:    *to,
+		Price: int32(*price),
+		User:  &pb.User{Firstname: *firstName, Lastname: *lastName, Email: *email},
+	}
+	if *departure != "" {
+		departureAt, err := time.Parse(time.RFC3339, *departure)
+		if err != nil {
+			return usageError{fmt.Errorf("invalid -departure: %w", err)}
+		}
+		req.Departure = timestamppb.New(departureAt)
+	}
+	ctx := c.ctx
+	if *idempotencyKey != "" {
+		// repeating this command with the key replays the booking instead of failing
+		ctx = bookingclient.WithIdempotencyKey(ctx, *idempotencyKey)
+	}
+	booking, err := c.client.CreateBooking(ctx, req)
+	if err != nil {
+		return err
+	}
+	return c.out.print(booking)
+}
+
+func runGet(c *cli, args []string) error {
+	fs := newFlagSet("get", "<booking id or PNR>")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	booking, err := c.client.GetBooking(c.ctx, &pb.GetBookingRequest{BookingId: fs.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return c.out.print(booking)
+}
+
+func runList(c *cli, args []string) error {
+	fs := newFlagSet("list", "-section <name> | -email <email>")
+	section := fs.String("section", "", "list the bookings of the section")
+	email := fs.String("email", "", "list the bookings of the passenger")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if (*section == "") == (*email == "") {
+		fs.Usage()
+		return usageError{errors.New("list requires either -section or -email")}
+	}
+
+	var bookings *pb.BookingListResponse
+	var err error
+	if *section != "" {
+		bookings, err = c.client.GetBookingsBySection(c.ctx, &pb.GetBookingsBySectionRequest{Section: *section})
+	} else {
+		bookings, err = c.client.ListMyBookings(c.ctx, &pb.ListMyBookingsRequest{User: &pb.User{Email: *email}})
+	}
+	if err != nil {
+		return err
+	}
+	return c.out.print(bookings)
+}
+
+func runModify(c *cli, args []string) error {
+	fs := newFlagSet("modify", "-email <email> [-booking <id or PNR>] -section <name> -seat <number> [-etag <etag>]")
+	bookingId := fs.String("booking", "", "ticket id or PNR, required when the passenger has several bookings")
+	email := fs.String("email", "", "email of the passenger, the booking must belong to them")
+	section := fs.String("section", "", "section of the new seat")
+	seat := fs.Int("seat", -1, "number of the new seat")
+	etag := fs.String("etag", "", "only move the booking if it is unchanged since this etag was read")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := required(fs, "section"); err != nil {
+		return err
+	}
+	if err := bookingOrEmail(fs, *bookingId, *email); err != nil {
+		return err
+	}
+	if *seat < 0 {
+		fs.Usage()
+		return usageError{errors.New("modify requires -seat")}
+	}
+
+	modified, err := c.client.ModifySeatByUser(c.ctx, &pb.SeatModificationRequest{
+		BookingId: *bookingId,
+		User:      &pb.User{Email: *email},
+		Section:   *section,
+		Seat:      int32(*seat),
+		Etag:      *etag,
+	})
+	if err != nil {
+		return err
+	}
+	return c.out.print(modified)
+}
+
+func runCancel(c *cli, args []string) error {
+	fs := newFlagSet("cancel", "-email <email> [-booking <id or PNR>] [-etag <etag>]")
+	bookingId := fs.String("booking", "", "ticket id or PNR, required when the passenger has several bookings")
+	email := fs.String("email", "", "email of the passenger, the booking must belong to them")
+	etag := fs.String("etag", "", "only cancel the booking if it is unchanged since this etag was read")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	if err := bookingOrEmail(fs, *bookingId, *email); err != nil {
+		return err
+	}
+
+	removed, err := c.client.RemoveBookingByUser(c.ctx, &pb.RemoveBookingByUserRequest{
+		BookingId: *bookingId,
+		User:      &pb.User{Email: *email},
+		Etag:      *etag,
+	})
+	if err != nil {
+		return err
+	}
+	return c.out.print(removed)
+}
+
+func runSeatmap(c *cli, args []string) error {
+	fs := newFlagSet("seatmap", "[-section <name>]")
+	section := fs.String("section", "", "only show this section")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	seatMap, err := c.client.GetSeatMap(c.ctx, &pb.GetSeatMapRequest{Section: *section})
+	if err != nil {
+		return err
+	}
+	return c.out.print(seatMap)
+}
+
+func runWatch(c *cli, args []string) error {
+	fs := newFlagSet("watch", "")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	fmt.Fprintln(os.Stderr, "Watching booking changes, press Ctrl+C to stop")
+	err := c.client.WatchBookings(c.ctx, c.out.printEvent)
+	if errors.Is(err, context.Canceled) && c.ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// bulkFormats are the values of the -format flag of export and import
+var bulkFormats = map[string]pb.BulkFormat{"csv": pb.BulkFormat_BULK_FORMAT_CSV, "ndjson": pb.BulkFormat_BULK_FORMAT_NDJSON}
+
+func bulkFormat(fs *flag.FlagSet, name string) (pb.BulkFormat, error) {
+	format, ok := bulkFormats[name]
+	if !ok {
+		fs.Usage()
+		return 0, usageError{fmt.Errorf("-format must be csv or ndjson, not %q", name)}
+	}
+	return format, nil
+}
+
+func runExport(c *cli, args []string) error {
+	fs := newFlagSet("export", "[-format csv|ndjson] [-out <file>] [filters]")
+	format := fs.String("format", "csv", "csv or ndjson")
+	out := fs.String("out", "", "file to write, standard output when empty")
+	section := fs.String("section", "", "only export this section")
+	from := fs.String("from", "", "only export journeys from this station")
+	to := fs.String("to", "", "only export journeys to this station")
+	email := fs.String("email", "", "only export the bookings of this passenger")
+	since := fs.String("departure-since", "", "only export departures at or after this RFC 3339 time")
+	until := fs.String("departure-until", "", "only export departures before this RFC 3339 time")
+	includeCancelled := fs.Bool("include-cancelled", false, "export cancelled bookings too")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	req := &pb.ExportBookingsRequest{Section: *section, From: *from, To: *to, Email: *email, IncludeCancelled: *includeCancelled}
+	var err error
+	if req.Format, err = bulkFormat(fs, *format); err != nil {
+		return err
+	}
+	if req.DepartureSince, err = timeFlag("departure-since", *since); err != nil {
+		return err
+	}
+	if req.DepartureUntil, err = timeFlag("departure-until", *until); err != nil {
+		return err
+	}
+
+	if *out == "" {
+		return c.client.ExportBookings(c.ctx, req, os.Stdout)
+	}
+	file, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := c.client.ExportBookings(c.ctx, req, file); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// timeFlag parses the RFC 3339 time of a flag, nil when it is empty
+func timeFlag(name, value string) (*timestamppb.Timestamp, error) {
+	if value == "" {
+		return nil, nil
+	}
+	at, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, usageError{fmt.Errorf("invalid -%s: %w", name, err)}
+	}
+	return timestamppb.New(at), nil
+}
+
+func runImport(c *cli, args []string) error {
+	fs := newFlagSet("import", "[-format csv|ndjson] [-dry-run] [-batch-size <rows>] <file, - for standard input>")
+	format := fs.String("format", "csv", "csv or ndjson")
+	dryRun := fs.Bool("dry-run", false, "check the rows and allocate their seats, but import nothing")
+	batchSize := fs.Int("batch-size", 100, "rows imported per transaction")
+	if err := parse(fs, args, 1); err != nil {
+		return err
+	}
+	req := &pb.ImportBookingsRequest{DryRun: *dryRun, BatchSize: int32(*batchSize)}
+	var err error
+	if req.Format, err = bulkFormat(fs, *format); err != nil {
+		return err
+	}
+
+	r := io.Reader(os.Stdin)
+	if fs.Arg(0) != "-" {
+		file, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		r = file
+	}
+	report, err := c.client.ImportBookings(c.ctx, req, r)
+	if err != nil {
+		return err
+	}
+	if err := c.out.print(report); err != nil {
+		return err
+	}
+	if report.GetFailed() > 0 {
+		return fmt.Errorf("%d of %d rows failed", report.GetFailed(), report.GetRows())
+	}
+	return nil
+}
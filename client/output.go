@@ -0,0 +1,216 @@
+package main
+
+import (
+	pb "ticket-booking-app/domain"
+	"ticket-booking-app/config"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// seatMapColumns is the number of seats per row of a printed seat map
+const seatMapColumns = 10
+
+// printer writes responses as tables for people, or as JSON or YAML for
+// scripts. JSON and YAML use the field names of the REST gateway.
+type printer struct {
+	w      io.Writer
+	format string
+	// events counts the printed events, the table header comes before the first
+	events int
+}
+
+func newPrinter(w io.Writer, format string) *printer {
+	return &printer{w: w, format: format}
+}
+
+func (p *printer) print(message proto.Message) error {
+	switch p.format {
+	case config.OutputJSON:
+		body, err := protojson.MarshalOptions{Multiline: true, Indent: "  "}.Marshal(message)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", body)
+		return err
+	case config.OutputYAML:
+		return p.printYAML(message)
+	}
+	return p.printTable(message)
+}
+
+// printEvent prints an event of a stream: JSON as one line per event, YAML as
+// one document per event and tables as one row per event.
+func (p *printer) printEvent(event *pb.BookingEvent) error {
+	defer func() { p.events++ }()
+	switch p.format {
+	case config.OutputJSON:
+		body, err := protojson.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(p.w, "%s\n", body)
+		return err
+	case config.OutputYAML:
+		if _, err := fmt.Fprintln(p.w, "---"); err != nil {
+			return err
+		}
+		return p.printYAML(event)
+	}
+	const row = "%-20s  %-21s  %-10s  %-8s  %4s  %s\n"
+	if p.events == 0 {
+		fmt.Fprintf(p.w, row, "TIME", "EVENT", "PNR", "SECTION", "SEAT", "PASSENGER")
+	}
+	booking := event.GetBooking()
+	_, err := fmt.Fprintf(p.w, row, event.GetOccurredAt().AsTime().Local().Format(time.DateTime), event.GetType(), booking.GetPnr(),
+		booking.GetSection(), strconv.Itoa(int(booking.GetSeat())), passenger(booking.GetUser()))
+	return err
+}
+
+// printYAML converts the JSON of message, which keeps the field order of the
+// proto unlike a map would.
+func (p *printer) printYAML(message proto.Message) error {
+	body, err := protojson.Marshal(message)
+	if err != nil {
+		return err
+	}
+	var document yaml.Node
+	if err := yaml.Unmarshal(body, &document); err != nil {
+		return err
+	}
+	blockStyle(&document)
+	encoder := yaml.NewEncoder(p.w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&document); err != nil {
+		return err
+	}
+	return encoder.Close()
+}
+
+// blockStyle drops the flow style and quotes of the parsed JSON
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+func (p *printer) printTable(message proto.Message) error {
+	switch response := message.(type) {
+	case *pb.BookingResponse:
+		return p.printBookings([]*pb.BookingResponse{response})
+	case *pb.BookingListResponse:
+		if len(response.GetBookings()) == 0 {
+			_, err := fmt.Fprintln(p.w, "No bookings")
+			return err
+		}
+		return p.printBookings(response.GetBookings())
+	case *pb.SeatModificationResponse:
+		table := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(table, "PNR\tSECTION\tSEAT\tPASSENGER\tETAG")
+		fmt.Fprintf(table, "%s\t%s\t%d\t%s\t%s\n", response.GetPnr(), response.GetSection(), response.GetSeat(),
+			passenger(response.GetUser()), response.GetEtag())
+		return table.Flush()
+	case *pb.RemoveBookingResponse:
+		_, err := fmt.Fprintln(p.w, "Booking cancelled")
+		return err
+	case *pb.SeatMap:
+		return p.printSeatMap(response)
+	case *pb.ImportBookingsResponse:
+		return p.printImport(response)
+	}
+	return fmt.Errorf("no table format for %s", message.ProtoReflect().Descriptor().FullName())
+}
+
+func (p *printer) printBookings(bookings []*pb.BookingResponse) error {
+	table := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "PNR\tFROM\tTO\tDEPARTURE\tSECTION\tSEAT\tPRICE\tPASSENGER\tETAG")
+	for _, booking := range bookings {
+		departure := "-"
+		if booking.GetDeparture() != nil {
+			departure = booking.GetDeparture().AsTime().Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(table, "%s\t%s\t%s\t%s\t%s\t%d\t%d\t%s\t%s\n", booking.GetPnr(), booking.GetFrom(), booking.GetTo(), departure,
+			booking.GetSection(), booking.GetSeat(), booking.GetPrice(), passenger(booking.GetUser()), booking.GetEtag())
+	}
+	return table.Flush()
+}
+
+// printSeatMap prints every section as rows of seat numbers, with taken seats
+// crossed out as x and held ones as h.
+func (p *printer) printSeatMap(seatMap *pb.SeatMap) error {
+	for i, section := range seatMap.GetSections() {
+		if i > 0 {
+			fmt.Fprintln(p.w)
+		}
+		taken := make(map[int32]bool, len(section.GetOccupied()))
+		for _, seat := range section.GetOccupied() {
+			taken[seat] = true
+		}
+		held := make(map[int32]bool, len(section.GetHeld()))
+		for _, seat := range section.GetHeld() {
+			held[seat] = true
+		}
+		fmt.Fprintf(p.w, "Section %s: %d of %d seats free", section.GetName(), section.GetSeats()-int32(len(taken)), section.GetSeats())
+		if len(held) > 0 {
+			fmt.Fprintf(p.w, ", %d held", len(held))
+		}
+		fmt.Fprintln(p.w)
+		width := len(strconv.Itoa(int(section.GetSeats() - 1)))
+		var row []string
+		for seat := int32(0); seat < section.GetSeats(); seat++ {
+			label := strconv.Itoa(int(seat))
+			if taken[seat] {
+				label = "x"
+			} else if held[seat] {
+				label = "h"
+			}
+			row = append(row, fmt.Sprintf("%*s", width, label))
+			if len(row) == seatMapColumns || seat == section.GetSeats()-1 {
+				if _, err := fmt.Fprintf(p.w, "  %s\n", strings.Join(row, " ")); err != nil {
+					return err
+				}
+				row = row[:0]
+			}
+		}
+	}
+	return nil
+}
+
+// printImport prints the counts of an import and the rows that failed
+func (p *printer) printImport(report *pb.ImportBookingsResponse) error {
+	imported := "imported"
+	if report.GetDryRun() {
+		imported = "would be imported (dry run)"
+	}
+	fmt.Fprintf(p.w, "%d rows, %d %s, %d failed\n", report.GetRows(), report.GetImported(), imported, report.GetFailed())
+	if len(report.GetErrors()) == 0 {
+		return nil
+	}
+	table := tabwriter.NewWriter(p.w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "LINE\tERROR")
+	for _, rowError := range report.GetErrors() {
+		fmt.Fprintf(table, "%d\t%s\n", rowError.GetLine(), rowError.GetError())
+	}
+	if omitted := report.GetFailed() - int64(len(report.GetErrors())); omitted > 0 {
+		fmt.Fprintf(table, "\t... %d more\n", omitted)
+	}
+	return table.Flush()
+}
+
+func passenger(user *pb.User) string {
+	name := strings.TrimSpace(user.GetFirstname() + " " + user.GetLastname())
+	if user.GetEmail() == "" {
+		return name
+	}
+	if name == "" {
+		return user.GetEmail()
+	}
+	return name + " <" + user.GetEmail() + ">"
+}
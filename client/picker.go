@@ -0,0 +1,443 @@
+package main
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"golang.org/x/term"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// pickerRefreshInterval also shows the holds of other agents, which aren't
+	// booking events
+	pickerRefreshInterval = 2 * time.Second
+	watchRetryInterval    = 2 * time.Second
+
+	keyUp    = "up"
+	keyDown  = "down"
+	keyLeft  = "left"
+	keyRight = "right"
+	keyTab   = "tab"
+	keyEnter = "enter"
+	keyEsc   = "esc"
+	keyQuit  = "quit"
+
+	ansiReset   = "\x1b[0m"
+	ansiReverse = "\x1b[7m"
+	ansiDim     = "\x1b[2m"
+	ansiYellow  = "\x1b[33m"
+	ansiGreen   = "\x1b[1;32m"
+	ansiCyan    = "\x1b[1;36m"
+)
+
+// picker is the state of the seat picker, apart from the terminal. The agent
+// moves the cursor over the seats of a section, holds a seat so no one else
+// gets it, and books it for the passenger or moves the booking to it.
+type picker struct {
+	seatMap *pb.SeatMap
+	section int
+	seat    int32
+	hold    *pb.SeatHold
+	// booking is the booking the seat is picked for, nil until one is made
+	booking *pb.BookingResponse
+	request *pb.BookingRequest
+	// email of the passenger, without it only a verified desk can move the booking
+	email  string
+	status string
+}
+
+func (p *picker) currentSection() *pb.SectionSeats {
+	sections := p.seatMap.GetSections()
+	if len(sections) == 0 {
+		return nil
+	}
+	return sections[p.section]
+}
+
+// move moves the cursor by columns and rows of the grid, staying within the section
+func (p *picker) move(columns, rows int32) {
+	section := p.currentSection()
+	if section == nil {
+		return
+	}
+	seat := p.seat + columns + rows*seatMapColumns
+	if seat >= 0 && seat < section.GetSeats() {
+		p.seat = seat
+	}
+}
+
+func (p *picker) nextSection() {
+	if len(p.seatMap.GetSections()) == 0 {
+		return
+	}
+	p.section = (p.section + 1) % len(p.seatMap.GetSections())
+	p.seat = min(p.seat, p.currentSection().GetSeats()-1)
+}
+
+// setSeatMap replaces the seat map, keeping the cursor on the same section
+func (p *picker) setSeatMap(seatMap *pb.SeatMap) {
+	var name string
+	if section := p.currentSection(); section != nil {
+		name = section.GetName()
+	}
+	p.seatMap = seatMap
+	p.section = 0
+	for i, section := range seatMap.GetSections() {
+		if section.GetName() == name {
+			p.section = i
+		}
+	}
+	if section := p.currentSection(); section != nil {
+		p.seat = min(p.seat, section.GetSeats()-1)
+	}
+}
+
+// focus puts the cursor on a seat
+func (p *picker) focus(sectionName string, seat int32) {
+	for i, section := range p.seatMap.GetSections() {
+		if section.GetName() == sectionName {
+			p.section, p.seat = i, seat
+		}
+	}
+}
+
+func (p *picker) isHeldByMe(section string, seat int32) bool {
+	return p.hold != nil && p.hold.GetSection() == section && p.hold.GetSeat() == seat
+}
+
+// render draws the picker with \r\n line ends, as the terminal is in raw mode
+func (p *picker) render() string {
+	var out strings.Builder
+	if p.booking != nil {
+		fmt.Fprintf(&out, "Booking %s for %s, seat %d in section %s\r\n", p.booking.GetPnr(), passenger(p.booking.GetUser()),
+			p.booking.GetSeat(), p.booking.GetSection())
+	} else {
+		fmt.Fprintf(&out, "New booking from %s to %s for %s\r\n", p.request.GetFrom(), p.request.GetTo(), passenger(p.request.GetUser()))
+	}
+	out.WriteString("\r\nSections:")
+	for i, section := range p.seatMap.GetSections() {
+		if i == p.section {
+			fmt.Fprintf(&out, " [%s]", section.GetName())
+		} else {
+			fmt.Fprintf(&out, "  %s ", section.GetName())
+		}
+	}
+	out.WriteString("\r\n\r\n")
+
+	section := p.currentSection()
+	if section != nil {
+		taken := make(map[int32]bool)
+		for _, seat := range section.GetOccupied() {
+			taken[seat] = true
+		}
+		held := make(map[int32]bool)
+		for _, seat := range section.GetHeld() {
+			held[seat] = true
+		}
+		width := len(strconv.Itoa(int(section.GetSeats() - 1)))
+		for seat := int32(0); seat < section.GetSeats(); seat++ {
+			label, color := strconv.Itoa(int(seat)), ""
+			switch {
+			case p.booking != nil && p.booking.GetSection() == section.GetName() && p.booking.GetSeat() == seat:
+				label, color = "@", ansiCyan
+			case p.isHeldByMe(section.GetName(), seat):
+				label, color = "*", ansiGreen
+			case taken[seat]:
+				label, color = "x", ansiDim
+			case held[seat]:
+				label, color = "h", ansiYellow
+			}
+			if seat == p.seat {
+				color += ansiReverse
+			}
+			fmt.Fprintf(&out, " %s%*s%s", color, width+1, label, ansiReset)
+			if (seat+1)%seatMapColumns == 0 || seat == section.GetSeats()-1 {
+				out.WriteString("\r\n")
+			}
+		}
+	}
+
+	out.WriteString("\r\nfree seats by number, x taken, h held by another agent, * held by you, @ the booking's seat\r\n")
+	out.WriteString("arrows move, tab next section, space hold, enter book or move, esc release, r refresh, q quit\r\n")
+	if p.status != "" {
+		fmt.Fprintf(&out, "\r\n%s\r\n", p.status)
+	}
+	return out.String()
+}
+
+// parseKeys splits what the terminal sent into key names; other keys are
+// returned as their characters.
+func parseKeys(input []byte) []string {
+	var keys []string
+	for len(input) > 0 {
+		switch {
+		case strings.HasPrefix(string(input), "\x1b[A") || strings.HasPrefix(string(input), "\x1bOA"):
+			keys, input = append(keys, keyUp), input[3:]
+		case strings.HasPrefix(string(input), "\x1b[B") || strings.HasPrefix(string(input), "\x1bOB"):
+			keys, input = append(keys, keyDown), input[3:]
+		case strings.HasPrefix(string(input), "\x1b[C") || strings.HasPrefix(string(input), "\x1bOC"):
+			keys, input = append(keys, keyRight), input[3:]
+		case strings.HasPrefix(string(input), "\x1b[D") || strings.HasPrefix(string(input), "\x1bOD"):
+			keys, input = append(keys, keyLeft), input[3:]
+		default:
+			switch input[0] {
+			case '\x1b':
+				keys = append(keys, keyEsc)
+			case '\t':
+				keys = append(keys, keyTab)
+			case '\r', '\n':
+				keys = append(keys, keyEnter)
+			case 3, 'q':
+				keys = append(keys, keyQuit)
+			case 'k':
+				keys = append(keys, keyUp)
+			case 'j':
+				keys = append(keys, keyDown)
+			case 'h':
+				keys = append(keys, keyLeft)
+			case 'l':
+				keys = append(keys, keyRight)
+			default:
+				keys = append(keys, string(input[0]))
+			}
+			input = input[1:]
+		}
+	}
+	return keys
+}
+
+func runPick(c *cli, args []string) error {
+	fs := newFlagSet("pick", "-booking <id or PNR> [-email <email>] | -first-name <name> -last-name <name> -email <email> -from <station> -to <station> [-departure <time>]")
+	bookingId := fs.String("booking", "", "ticket id or PNR of the booking to move")
+	firstName := fs.String("first-name", "", "first name of the passenger to book for")
+	lastName := fs.String("last-name", "", "last name of the passenger to book for")
+	email := fs.String("email", "", "email of the passenger to book for, or of the booking to move")
+	from := fs.String("from", "", "departure station")
+	to := fs.String("to", "", "arrival station")
+	price := fs.Int("price", 20, "price of the ticket")
+	departure := fs.String("departure", "", "departure time in RFC 3339, e.g. 2026-11-02T09:30:00Z")
+	if err := parse(fs, args, 0); err != nil {
+		return err
+	}
+	p := &picker{email: *email}
+	if *bookingId == "" {
+		if err := required(fs, "first-name", "last-name", "email", "from", "to"); err != nil {
+			return err
+		}
+		p.request = &pb.BookingRequest{From: *from, To: *to, Price: int32(*price),
+			User: &pb.User{Firstname: *firstName, Lastname: *lastName, Email: *email}}
+		if *departure != "" {
+			departureAt, err := time.Parse(time.RFC3339, *departure)
+			if err != nil {
+				return usageError{fmt.Errorf("invalid -departure: %w", err)}
+			}
+			p.request.Departure = timestamppb.New(departureAt)
+		}
+	}
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return usageError{errors.New("pick needs a terminal")}
+	}
+
+	if *bookingId != "" {
+		booking, err := c.client.GetBooking(c.ctx, &pb.GetBookingRequest{BookingId: *bookingId})
+		if err != nil {
+			return err
+		}
+		p.booking = booking
+	}
+	if err := c.refreshPicker(p); err != nil {
+		return err
+	}
+	if p.booking != nil {
+		p.focus(p.booking.GetSection(), p.booking.GetSeat())
+	}
+
+	state, err := term.MakeRaw(fd)
+	if err != nil {
+		return err
+	}
+	// the cursor is hidden while picking and the screen cleared afterwards
+	fmt.Print("\x1b[?25l")
+	defer func() {
+		fmt.Print("\x1b[2J\x1b[H\x1b[?25h")
+		term.Restore(fd, state)
+	}()
+
+	ctx, stop := context.WithCancel(c.ctx)
+	defer stop()
+	keys := make(chan []string)
+	go func() {
+		buffer := make([]byte, 64)
+		for {
+			n, err := os.Stdin.Read(buffer)
+			if err != nil {
+				return
+			}
+			select {
+			case keys <- parseKeys(buffer[:n]):
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	changes := make(chan struct{}, 1)
+	go c.watchChanges(ctx, changes)
+	ticker := time.NewTicker(pickerRefreshInterval)
+	defer ticker.Stop()
+	defer c.releaseHold(p)
+
+	for {
+		fmt.Print("\x1b[2J\x1b[H" + p.render())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-changes:
+			c.refreshOrReport(p)
+		case <-ticker.C:
+			c.refreshOrReport(p)
+		case pressed := <-keys:
+			for _, key := range pressed {
+				if key == keyQuit {
+					return nil
+				}
+				c.handlePickerKey(p, key)
+			}
+		}
+	}
+}
+
+func (c *cli) handlePickerKey(p *picker, key string) {
+	switch key {
+	case keyUp:
+		p.move(0, -1)
+	case keyDown:
+		p.move(0, 1)
+	case keyLeft:
+		p.move(-1, 0)
+	case keyRight:
+		p.move(1, 0)
+	case keyTab:
+		p.nextSection()
+	case "r":
+		c.refreshOrReport(p)
+	case keyEsc:
+		c.releaseHold(p)
+		p.status = "Hold released"
+		c.refreshOrReport(p)
+	case " ":
+		if err := c.holdSeat(p); err != nil {
+			p.status = "Error: " + errorMessage(err)
+		}
+		c.refreshOrReport(p)
+	case keyEnter:
+		if err := c.pickSeat(p); err != nil {
+			p.status = "Error: " + errorMessage(err)
+		}
+		c.refreshOrReport(p)
+	}
+}
+
+// holdSeat holds the seat under the cursor instead of the one held before
+func (c *cli) holdSeat(p *picker) error {
+	section := p.currentSection()
+	if section == nil || p.isHeldByMe(section.GetName(), p.seat) {
+		return nil
+	}
+	c.releaseHold(p)
+	hold, err := c.client.HoldSeat(c.ctx, &pb.HoldSeatRequest{Section: section.GetName(), Seat: p.seat})
+	if err != nil {
+		return err
+	}
+	p.hold = hold
+	p.status = fmt.Sprintf("Seat %d in section %s held until %s, press enter to confirm", hold.GetSeat(), hold.GetSection(),
+		hold.GetExpiresAt().AsTime().Local().Format(time.TimeOnly))
+	return nil
+}
+
+// pickSeat books the seat under the cursor, or moves the booking to it,
+// holding it first unless it already is.
+func (c *cli) pickSeat(p *picker) error {
+	if err := c.holdSeat(p); err != nil || p.hold == nil {
+		return err
+	}
+	if p.booking == nil {
+		request := proto.Clone(p.request).(*pb.BookingRequest)
+		request.HoldId = p.hold.GetHoldId()
+		booking, err := c.client.CreateBooking(c.ctx, request)
+		if err != nil {
+			return err
+		}
+		p.booking, p.hold = booking, nil
+		p.status = fmt.Sprintf("Booked seat %d in section %s, PNR %s", booking.GetSeat(), booking.GetSection(), booking.GetPnr())
+		return nil
+	}
+	modified, err := c.client.ModifySeatByUser(c.ctx, &pb.SeatModificationRequest{
+		BookingId: p.booking.GetId(),
+		User:      &pb.User{Email: p.email},
+		HoldId:    p.hold.GetHoldId(),
+		Etag:      p.booking.GetEtag(),
+	})
+	if err != nil {
+		return err
+	}
+	p.booking.Seat, p.booking.Section, p.booking.Etag = modified.GetSeat(), modified.GetSection(), modified.GetEtag()
+	p.hold = nil
+	p.status = fmt.Sprintf("Moved %s to seat %d in section %s", modified.GetPnr(), modified.GetSeat(), modified.GetSection())
+	return nil
+}
+
+func (c *cli) releaseHold(p *picker) {
+	if p.hold == nil {
+		return
+	}
+	// the hold is released even when the picker was interrupted
+	c.client.ReleaseSeatHold(context.WithoutCancel(c.ctx), &pb.ReleaseSeatHoldRequest{HoldId: p.hold.GetHoldId()})
+	p.hold = nil
+}
+
+func (c *cli) refreshPicker(p *picker) error {
+	seatMap, err := c.client.GetSeatMap(c.ctx, &pb.GetSeatMapRequest{})
+	if err != nil {
+		return err
+	}
+	p.setSeatMap(seatMap)
+	if p.booking != nil {
+		// the booking may have been changed by someone else
+		if booking, err := c.client.GetBooking(c.ctx, &pb.GetBookingRequest{BookingId: p.booking.GetId()}); err == nil {
+			p.booking = booking
+		}
+	}
+	return nil
+}
+
+func (c *cli) refreshOrReport(p *picker) {
+	if err := c.refreshPicker(p); err != nil {
+		p.status = "Error in refreshing seats: " + errorMessage(err)
+	}
+}
+
+// watchChanges signals every booking change until ctx is done, watching again
+// when the stream breaks, e.g. because the server restarted.
+func (c *cli) watchChanges(ctx context.Context, changes chan<- struct{}) {
+	for ctx.Err() == nil {
+		c.client.WatchBookings(ctx, func(*pb.BookingEvent) error {
+			select {
+			case changes <- struct{}{}:
+			default:
+			}
+			return nil
+		})
+		select {
+		case <-ctx.Done():
+		case <-time.After(watchRetryInterval):
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	pb "ticket-booking-app/domain"
+	"github.com/stretchr/testify/assert"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestShouldParseTerminalKeys(t *testing.T) {
+    keys := parseKeys([]byte("\x1b[A\x1b[Bj\x1bOC\t \r\x1bq"))
+    assert.Equal(t, []string{keyUp, keyDown, keyDown, keyRight, keyTab, " ", keyEnter, keyEsc, keyQuit}, keys)
+}
+
+func TestShouldMoveCursorOverSeatGrid(t *testing.T) {
+    p := &picker{request: &pb.BookingRequest{From: "London", To: "France"}}
+    p.setSeatMap(&pb.SeatMap{Sections: []*pb.SectionSeats{
+        {Name: "A", Seats: 12, Occupied: []int32{1}, Held: []int32{2}},
+        {Name: "B", Seats: 5},
+    }})
+
+    p.move(0, 1)
+    assert.EqualValues(t, 10, p.seat, "Down should move a row of seats")
+    p.move(0, 1)
+    assert.EqualValues(t, 10, p.seat, "The cursor should stay within the section")
+    p.nextSection()
+    assert.Equal(t, "B", p.currentSection().GetName())
+    assert.EqualValues(t, 4, p.seat, "The cursor should move to the last seat of a smaller section")
+
+    p.nextSection()
+    p.seat = 0
+    p.hold = &pb.SeatHold{Section: "A", Seat: 3}
+    grid := regexp.MustCompile("\x1b\\[[0-9;]*m").ReplaceAllString(p.render(), "")
+    assert.True(t, strings.Contains(grid, "   0   x   h   *   4"), "Taken, held and own held seats should be marked: %q", grid)
+    assert.Contains(t, grid, "Sections: [A]  B")
+}
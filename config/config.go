@@ -0,0 +1,529 @@
+// Package config loads the configuration of the server and the client from
+// flags, environment variables and an optional YAML or TOML file.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AllocationRandom     = "random"
+	AllocationSequential = "sequential"
+
+	ClientAuthNone     = "none"
+	ClientAuthOptional = "optional"
+	ClientAuthRequire  = "require"
+
+	RateLimitKeyActor  = "actor"
+	RateLimitKeyEmail  = "email"
+	RateLimitKeyIP     = "ip"
+	RateLimitKeyAPIKey = "api_key"
+
+	TraceExporterNone   = "none"
+	TraceExporterOTLP   = "otlp"
+	TraceExporterStdout = "stdout"
+
+	LogFormatText = "text"
+	LogFormatJSON = "json"
+
+	OutputTable = "table"
+	OutputJSON  = "json"
+	OutputYAML  = "yaml"
+
+	maxSeatsPerSection = 1000
+)
+
+// Server is the configuration of the booking server.
+type Server struct {
+	ListenAddress      string     `yaml:"listen_address" toml:"listen_address"`
+	RESTAddress        string     `yaml:"rest_address" toml:"rest_address"`
+	MetricsAddress     string     `yaml:"metrics_address" toml:"metrics_address"`
+	DSN                string     `yaml:"dsn" toml:"dsn"`
+	TLS                ServerTLS  `yaml:"tls" toml:"tls"`
+	Sections           Sections   `yaml:"sections" toml:"sections"`
+	AllocationStrategy string     `yaml:"allocation_strategy" toml:"allocation_strategy"`
+	ShutdownTimeout    Duration   `yaml:"shutdown_timeout" toml:"shutdown_timeout"`
+	IdempotencyKeyTTL  Duration   `yaml:"idempotency_key_ttl" toml:"idempotency_key_ttl"`
+	SeatHoldTTL        Duration   `yaml:"seat_hold_ttl" toml:"seat_hold_ttl"`
+	RateLimits         RateLimits `yaml:"rate_limits" toml:"rate_limits"`
+	// MaxActiveBookingsPerJourney is the number of booked tickets a user may
+	// hold from one station to another, 0 for no limit
+	MaxActiveBookingsPerJourney int `yaml:"max_active_bookings_per_journey" toml:"max_active_bookings_per_journey"`
+	// MaxSeatHoldsPerCaller is the number of seats a caller may hold at once,
+	// 0 for no limit
+	MaxSeatHoldsPerCaller int      `yaml:"max_seat_holds_per_caller" toml:"max_seat_holds_per_caller"`
+	LogLevel              string   `yaml:"log_level" toml:"log_level"`
+	LogFormat             string   `yaml:"log_format" toml:"log_format"`
+	LogRedact             bool     `yaml:"log_redact" toml:"log_redact"`
+	Tracing               Tracing  `yaml:"tracing" toml:"tracing"`
+	Backup                Backup   `yaml:"backup" toml:"backup"`
+	Webhooks              Webhooks `yaml:"webhooks" toml:"webhooks"`
+	SMTP                  SMTP     `yaml:"smtp" toml:"smtp"`
+}
+
+// SMTP sends the notification emails through Address, with PLAIN auth when
+// Username is set. Without Address notifications are only logged.
+type SMTP struct {
+	Address  string `yaml:"address" toml:"address"`
+	From     string `yaml:"from" toml:"from"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+}
+
+// Webhooks restricts the urls webhooks may be registered for. Without
+// AllowedHosts only https urls of hosts with public addresses are accepted;
+// AllowPrivate also accepts http and private addresses, for development.
+type Webhooks struct {
+	AllowedHosts Hosts `yaml:"allowed_hosts" toml:"allowed_hosts"`
+	AllowPrivate bool  `yaml:"allow_private" toml:"allow_private"`
+}
+
+// Backup enables the backup RPCs when Dir is set. A backup is taken every
+// Interval, never when it is 0, and the newest Keep backups are kept, all of
+// them when it is 0.
+type Backup struct {
+	Dir      string   `yaml:"dir" toml:"dir"`
+	Interval Duration `yaml:"interval" toml:"interval"`
+	Keep     int      `yaml:"keep" toml:"keep"`
+}
+
+// ServerTLS enables TLS when both files are set. The files are read again
+// every ReloadInterval when they change. ClientAuth is none, optional or
+// require; client certificates are verified against ClientCAFile and Identities
+// maps their common names to service identities.
+type ServerTLS struct {
+	CertFile       string     `yaml:"cert_file" toml:"cert_file"`
+	KeyFile        string     `yaml:"key_file" toml:"key_file"`
+	ReloadInterval Duration   `yaml:"reload_interval" toml:"reload_interval"`
+	ClientAuth     string     `yaml:"client_auth" toml:"client_auth"`
+	ClientCAFile   string     `yaml:"client_ca_file" toml:"client_ca_file"`
+	Identities     Identities `yaml:"identities" toml:"identities"`
+}
+
+// Client is the configuration of the booking client.
+type Client struct {
+	ServerAddress string    `yaml:"server_address" toml:"server_address"`
+	Timeout       Duration  `yaml:"timeout" toml:"timeout"`
+	AuthToken     string    `yaml:"auth_token" toml:"auth_token"`
+	APIKey        string    `yaml:"api_key" toml:"api_key"`
+	Output        string    `yaml:"output" toml:"output"`
+	TLS           ClientTLS `yaml:"tls" toml:"tls"`
+	LogLevel      string    `yaml:"log_level" toml:"log_level"`
+	LogFormat     string    `yaml:"log_format" toml:"log_format"`
+	LogRedact     bool      `yaml:"log_redact" toml:"log_redact"`
+	Tracing       Tracing   `yaml:"tracing" toml:"tracing"`
+}
+
+// ClientTLS enables TLS when the CA file is set. ServerName overrides the name
+// the server certificate is checked against, CertFile and KeyFile are the
+// client certificate for servers that require one.
+type ClientTLS struct {
+	CAFile     string `yaml:"ca_file" toml:"ca_file"`
+	ServerName string `yaml:"server_name" toml:"server_name"`
+	CertFile   string `yaml:"cert_file" toml:"cert_file"`
+	KeyFile    string `yaml:"key_file" toml:"key_file"`
+}
+
+// Tracing selects where OpenTelemetry spans go: nowhere, to an OTLP collector
+// at Endpoint over gRPC (without TLS when Insecure is set), or to stdout.
+// SampleRatio is the share of new traces that are recorded.
+type Tracing struct {
+	Exporter    string  `yaml:"exporter" toml:"exporter"`
+	Endpoint    string  `yaml:"endpoint" toml:"endpoint"`
+	Insecure    bool    `yaml:"insecure" toml:"insecure"`
+	SampleRatio float64 `yaml:"sample_ratio" toml:"sample_ratio"`
+}
+
+// RateLimit allows Burst calls at once and Rate calls per second after that to
+// Method, a method name or "*" for every RPC, for each value of Key: actor,
+// email, ip or api_key.
+type RateLimit struct {
+	Method string  `yaml:"method" toml:"method"`
+	Key    string  `yaml:"key" toml:"key"`
+	Rate   float64 `yaml:"rate" toml:"rate"`
+	Burst  int     `yaml:"burst" toml:"burst"`
+}
+
+// Section is a section of the train and its number of seats.
+type Section struct {
+	Name  string `yaml:"name" toml:"name"`
+	Seats int32  `yaml:"seats" toml:"seats"`
+}
+
+func DefaultServer() *Server {
+	return &Server{
+		ListenAddress:  ":50051",
+		RESTAddress:    ":8080",
+		MetricsAddress: ":2112",
+		// immediate transactions make concurrent writers queue for the lock instead
+		// of failing, which keeps the audit log chain in order
+		DSN:                "./ticket_booking.db?_txlock=immediate&_busy_timeout=5000",
+		TLS:                ServerTLS{ReloadInterval: Duration(time.Minute), ClientAuth: ClientAuthNone},
+		Sections:           Sections{{Name: "A", Seats: 20}, {Name: "B", Seats: 20}},
+		AllocationStrategy: AllocationRandom,
+		ShutdownTimeout:    Duration(30 * time.Second),
+		IdempotencyKeyTTL:  Duration(24 * time.Hour),
+		SeatHoldTTL:        Duration(2 * time.Minute),
+		// a script booking with fresh emails is held back by its address
+		RateLimits: RateLimits{
+			{Method: "*", Key: RateLimitKeyIP, Rate: 20, Burst: 100},
+			{Method: "CreateBooking", Key: RateLimitKeyIP, Rate: 0.2, Burst: 10},
+			{Method: "CreateBooking", Key: RateLimitKeyEmail, Rate: 0.05, Burst: 3},
+			// every resent code unlocks more guesses and sends an email
+			{Method: "ResendVerificationCode", Key: RateLimitKeyIP, Rate: 1.0 / 60, Burst: 3},
+			// holds take seats off the map without an identity
+			{Method: "HoldSeat", Key: RateLimitKeyIP, Rate: 0.1, Burst: 5},
+		},
+		MaxActiveBookingsPerJourney: 4,
+		MaxSeatHoldsPerCaller:       2,
+		LogLevel:                    "info",
+		LogFormat:                   LogFormatText,
+		LogRedact:                   true,
+		Tracing:                     defaultTracing(),
+		Backup:                      Backup{Keep: 7},
+		SMTP:                        SMTP{From: "bookings@ticket-booking-app.local"},
+	}
+}
+
+func DefaultClient() *Client {
+	return &Client{
+		ServerAddress: "localhost:50051",
+		Timeout:       Duration(time.Second),
+		Output:        OutputTable,
+		LogLevel:      "info",
+		LogFormat:     LogFormatText,
+		LogRedact:     true,
+		Tracing:       defaultTracing(),
+	}
+}
+
+func defaultTracing() Tracing {
+	return Tracing{Exporter: TraceExporterNone, Endpoint: "localhost:4317", SampleRatio: 1}
+}
+
+// Validate reports every invalid setting at once.
+func (c *Server) Validate() error {
+	var errs []error
+	errs = append(errs, validateAddress("listen_address", c.ListenAddress))
+	if c.RESTAddress != "" {
+		errs = append(errs, validateAddress("rest_address", c.RESTAddress))
+	}
+	if c.MetricsAddress != "" {
+		errs = append(errs, validateAddress("metrics_address", c.MetricsAddress))
+	}
+	if c.DSN == "" {
+		errs = append(errs, errors.New("dsn is required"))
+	}
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls.cert_file and tls.key_file must be set together"))
+	}
+	errs = append(errs, validateFile("tls.cert_file", c.TLS.CertFile), validateFile("tls.key_file", c.TLS.KeyFile),
+		validateFile("tls.client_ca_file", c.TLS.ClientCAFile))
+	if c.TLS.ReloadInterval <= 0 {
+		errs = append(errs, errors.New("tls.reload_interval must be positive"))
+	}
+	switch c.TLS.ClientAuth {
+	case ClientAuthNone:
+		if len(c.TLS.Identities) > 0 {
+			errs = append(errs, errors.New("tls.identities need tls.client_auth optional or require"))
+		}
+	case ClientAuthOptional, ClientAuthRequire:
+		if c.TLS.CertFile == "" || c.TLS.ClientCAFile == "" {
+			errs = append(errs, errors.New("tls.client_auth needs tls.cert_file and tls.client_ca_file"))
+		}
+	default:
+		errs = append(errs, fmt.Errorf("tls.client_auth must be %s, %s or %s", ClientAuthNone, ClientAuthOptional, ClientAuthRequire))
+	}
+
+	if len(c.Sections) == 0 {
+		errs = append(errs, errors.New("at least one section is required"))
+	}
+	names := map[string]bool{}
+	for _, section := range c.Sections {
+		if section.Name == "" {
+			errs = append(errs, errors.New("sections must have a name"))
+		}
+		if names[section.Name] {
+			errs = append(errs, fmt.Errorf("section %q is defined twice", section.Name))
+		}
+		names[section.Name] = true
+		if section.Seats <= 0 || section.Seats > maxSeatsPerSection {
+			errs = append(errs, fmt.Errorf("section %q must have between 1 and %d seats", section.Name, maxSeatsPerSection))
+		}
+	}
+	if c.AllocationStrategy != AllocationRandom && c.AllocationStrategy != AllocationSequential {
+		errs = append(errs, fmt.Errorf("allocation_strategy must be %s or %s", AllocationRandom, AllocationSequential))
+	}
+	if c.ShutdownTimeout <= 0 {
+		errs = append(errs, errors.New("shutdown_timeout must be positive"))
+	}
+	if c.IdempotencyKeyTTL <= 0 {
+		errs = append(errs, errors.New("idempotency_key_ttl must be positive"))
+	}
+	if c.SeatHoldTTL <= 0 {
+		errs = append(errs, errors.New("seat_hold_ttl must be positive"))
+	}
+	for _, limit := range c.RateLimits {
+		switch limit.Key {
+		case RateLimitKeyActor, RateLimitKeyEmail, RateLimitKeyIP, RateLimitKeyAPIKey:
+		default:
+			errs = append(errs, fmt.Errorf("rate limit key of %s must be %s, %s, %s or %s, not %q", limit.Method,
+				RateLimitKeyActor, RateLimitKeyEmail, RateLimitKeyIP, RateLimitKeyAPIKey, limit.Key))
+		}
+		if limit.Method == "" || limit.Rate <= 0 || limit.Burst <= 0 {
+			errs = append(errs, fmt.Errorf("rate limit of %s by %s needs a method, a positive rate and a positive burst", limit.Method, limit.Key))
+		}
+	}
+	if c.MaxActiveBookingsPerJourney < 0 {
+		errs = append(errs, errors.New("max_active_bookings_per_journey must not be negative"))
+	}
+	if c.MaxSeatHoldsPerCaller < 0 {
+		errs = append(errs, errors.New("max_seat_holds_per_caller must not be negative"))
+	}
+	if c.Backup.Interval < 0 || c.Backup.Keep < 0 {
+		errs = append(errs, errors.New("backup.interval and backup.keep must not be negative"))
+	}
+	if c.Backup.Interval > 0 && c.Backup.Dir == "" {
+		errs = append(errs, errors.New("backup.interval needs backup.dir"))
+	}
+	if c.SMTP.Address != "" {
+		errs = append(errs, validateAddress("smtp.address", c.SMTP.Address))
+		if c.SMTP.From == "" {
+			errs = append(errs, errors.New("smtp.from is required with smtp.address"))
+		}
+	} else if c.SMTP.Username != "" {
+		errs = append(errs, errors.New("smtp.username needs smtp.address"))
+	}
+	if c.SMTP.Password != "" && c.SMTP.Username == "" {
+		errs = append(errs, errors.New("smtp.password needs smtp.username"))
+	}
+	for _, host := range c.Webhooks.AllowedHosts {
+		if host == "" || strings.ContainsAny(host, ":/") {
+			errs = append(errs, fmt.Errorf("webhooks.allowed_hosts must be host names, not %q", host))
+		}
+	}
+	_, err := ParseLogLevel(c.LogLevel)
+	errs = append(errs, err, validateLogFormat(c.LogFormat), c.Tracing.validate())
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid setting at once.
+func (c *Client) Validate() error {
+	var errs []error
+	errs = append(errs, validateAddress("server_address", c.ServerAddress))
+	if c.Timeout <= 0 {
+		errs = append(errs, errors.New("timeout must be positive"))
+	}
+	errs = append(errs, validateFile("tls.ca_file", c.TLS.CAFile))
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("tls.cert_file and tls.key_file must be set together"))
+	}
+	if c.TLS.CertFile != "" && c.TLS.CAFile == "" {
+		errs = append(errs, errors.New("tls.cert_file needs tls.ca_file"))
+	}
+	errs = append(errs, validateFile("tls.cert_file", c.TLS.CertFile), validateFile("tls.key_file", c.TLS.KeyFile))
+	switch c.Output {
+	case OutputTable, OutputJSON, OutputYAML:
+	default:
+		errs = append(errs, fmt.Errorf("output must be %s, %s or %s, not %q", OutputTable, OutputJSON, OutputYAML, c.Output))
+	}
+	_, err := ParseLogLevel(c.LogLevel)
+	errs = append(errs, err, validateLogFormat(c.LogFormat), c.Tracing.validate())
+	return errors.Join(errs...)
+}
+
+func (t Tracing) validate() error {
+	var errs []error
+	switch t.Exporter {
+	case TraceExporterNone, TraceExporterStdout:
+	case TraceExporterOTLP:
+		errs = append(errs, validateAddress("tracing.endpoint", t.Endpoint))
+	default:
+		errs = append(errs, fmt.Errorf("tracing.exporter must be %s, %s or %s", TraceExporterNone, TraceExporterOTLP, TraceExporterStdout))
+	}
+	if t.SampleRatio < 0 || t.SampleRatio > 1 {
+		errs = append(errs, errors.New("tracing.sample_ratio must be between 0 and 1"))
+	}
+	return errors.Join(errs...)
+}
+
+// ParseLogLevel accepts debug, info, warn and error.
+func ParseLogLevel(level string) (slog.Level, error) {
+	var parsed slog.Level
+	switch level {
+	case "debug", "info", "warn", "error":
+		return parsed, parsed.UnmarshalText([]byte(level))
+	}
+	return parsed, fmt.Errorf("log_level must be debug, info, warn or error, not %q", level)
+}
+
+func validateLogFormat(format string) error {
+	if format != LogFormatText && format != LogFormatJSON {
+		return fmt.Errorf("log_format must be %s or %s, not %q", LogFormatText, LogFormatJSON, format)
+	}
+	return nil
+}
+
+func validateAddress(name, address string) error {
+	if _, port, err := net.SplitHostPort(address); err != nil || port == "" {
+		return fmt.Errorf("%s must be host:port, not %q", name, address)
+	}
+	return nil
+}
+
+func validateFile(name, path string) error {
+	if path == "" {
+		return nil
+	}
+	if _, err := os.Stat(path); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+// Duration is a time.Duration written as "30s" in files, flags and env.
+type Duration time.Duration
+
+func (d Duration) String() string {
+	return time.Duration(d).String()
+}
+
+func (d *Duration) Set(value string) error {
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	return d.Set(string(text))
+}
+
+// Sections is written as "A:20,B:20" in flags and env.
+type Sections []Section
+
+func (s Sections) String() string {
+	parts := make([]string, len(s))
+	for i, section := range s {
+		parts[i] = section.Name + ":" + strconv.Itoa(int(section.Seats))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (s *Sections) Set(value string) error {
+	var sections Sections
+	for _, part := range strings.Split(value, ",") {
+		name, seats, found := strings.Cut(strings.TrimSpace(part), ":")
+		if !found {
+			return fmt.Errorf("section %q must be name:seats", part)
+		}
+		count, err := strconv.ParseInt(seats, 10, 32)
+		if err != nil {
+			return fmt.Errorf("section %q must be name:seats", part)
+		}
+		sections = append(sections, Section{Name: name, Seats: int32(count)})
+	}
+	*s = sections
+	return nil
+}
+
+// RateLimits are written as "method:key=rate/burst,..." in flags and env, e.g.
+// "CreateBooking:ip=0.2/10".
+type RateLimits []RateLimit
+
+func (r RateLimits) String() string {
+	parts := make([]string, len(r))
+	for i, limit := range r {
+		parts[i] = fmt.Sprintf("%s:%s=%s/%d", limit.Method, limit.Key, strconv.FormatFloat(limit.Rate, 'g', -1, 64), limit.Burst)
+	}
+	return strings.Join(parts, ",")
+}
+
+func (r *RateLimits) Set(value string) error {
+	limits := RateLimits{}
+	if value == "" {
+		*r = limits
+		return nil
+	}
+	for _, part := range strings.Split(value, ",") {
+		method, rest, found := strings.Cut(strings.TrimSpace(part), ":")
+		key, bucket, foundKey := strings.Cut(rest, "=")
+		rate, burst, foundBurst := strings.Cut(bucket, "/")
+		if !found || !foundKey || !foundBurst {
+			return fmt.Errorf("rate limit %q must be method:key=rate/burst", part)
+		}
+		parsedRate, err := strconv.ParseFloat(rate, 64)
+		if err != nil {
+			return fmt.Errorf("rate limit %q must be method:key=rate/burst", part)
+		}
+		parsedBurst, err := strconv.Atoi(burst)
+		if err != nil {
+			return fmt.Errorf("rate limit %q must be method:key=rate/burst", part)
+		}
+		limits = append(limits, RateLimit{Method: method, Key: key, Rate: parsedRate, Burst: parsedBurst})
+	}
+	*r = limits
+	return nil
+}
+
+// Hosts are host names, written as "host,..." in flags and env.
+type Hosts []string
+
+func (h Hosts) String() string {
+	return strings.Join(h, ",")
+}
+
+func (h *Hosts) Set(value string) error {
+	hosts := Hosts{}
+	for _, host := range strings.Split(value, ",") {
+		if host = strings.TrimSpace(host); host != "" {
+			hosts = append(hosts, host)
+		}
+	}
+	*h = hosts
+	return nil
+}
+
+// Identities maps certificate common names to identity names, written as
+// "common-name=identity,..." in flags and env.
+type Identities map[string]string
+
+func (i Identities) String() string {
+	names := make([]string, 0, len(i))
+	for commonName := range i {
+		names = append(names, commonName)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for j, commonName := range names {
+		parts[j] = commonName + "=" + i[commonName]
+	}
+	return strings.Join(parts, ",")
+}
+
+func (i *Identities) Set(value string) error {
+	identities := Identities{}
+	for _, part := range strings.Split(value, ",") {
+		commonName, identity, found := strings.Cut(strings.TrimSpace(part), "=")
+		if !found || commonName == "" || identity == "" {
+			return fmt.Errorf("identity %q must be common-name=identity", part)
+		}
+		identities[commonName] = identity
+	}
+	*i = identities
+	return nil
+}
@@ -0,0 +1,129 @@
+package config_test
+
+import (
+	"ticket-booking-app/config"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+    t.Helper()
+    path := filepath.Join(t.TempDir(), name)
+    if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+        t.Fatalf("Error in writing config file %v ", err)
+    }
+    return path
+}
+
+func TestShouldUseDefaultsWithoutConfiguration(t *testing.T) {
+    cfg, printOnly, err := config.LoadServer(nil)
+    if err != nil {
+        t.Fatalf("Error in loading config %v ", err)
+    }
+    assert.False(t, printOnly)
+    assert.Equal(t, ":50051", cfg.ListenAddress)
+    assert.Equal(t, config.Sections{{Name: "A", Seats: 20}, {Name: "B", Seats: 20}}, cfg.Sections)
+    assert.Equal(t, config.Duration(30*time.Second), cfg.ShutdownTimeout)
+    assert.Equal(t, config.Duration(2*time.Minute), cfg.SeatHoldTTL)
+    assert.Equal(t, 2, cfg.MaxSeatHoldsPerCaller)
+}
+
+func TestShouldPreferFlagsOverEnvOverFile(t *testing.T) {
+    path := writeConfigFile(t, "server.yaml", `
+listen_address: ":6000"
+dsn: file.db
+sections:
+  - name: Front
+    seats: 12
+allocation_strategy: sequential
+shutdown_timeout: 10s
+log_level: warn
+`)
+    t.Setenv("BOOKING_SERVER_DSN", "env.db")
+    t.Setenv("BOOKING_SERVER_LOG_LEVEL", "debug")
+
+    cfg, _, err := config.LoadServer([]string{"-config", path, "-log-level", "error"})
+    if err != nil {
+        t.Fatalf("Error in loading config %v ", err)
+    }
+    assert.Equal(t, ":6000", cfg.ListenAddress, "File should override defaults")
+    assert.Equal(t, config.Sections{{Name: "Front", Seats: 12}}, cfg.Sections)
+    assert.Equal(t, config.AllocationSequential, cfg.AllocationStrategy)
+    assert.Equal(t, config.Duration(10*time.Second), cfg.ShutdownTimeout)
+    assert.Equal(t, "env.db", cfg.DSN, "Env should override the file")
+    assert.Equal(t, "error", cfg.LogLevel, "Flags should override env")
+}
+
+func TestShouldLoadTOMLFileFromEnv(t *testing.T) {
+    path := writeConfigFile(t, "client.toml", `
+server_address = "booking.example.com:443"
+timeout = "3s"
+
+[tls]
+server_name = "booking.example.com"
+`)
+    t.Setenv("BOOKING_CLIENT_CONFIG", path)
+
+    cfg, command, _, err := config.LoadClient([]string{"-print-config", "-auth-token", "secret", "seatmap", "-section", "A"})
+    if err != nil {
+        t.Fatalf("Error in loading config %v ", err)
+    }
+    assert.Equal(t, "booking.example.com:443", cfg.ServerAddress)
+    assert.Equal(t, config.Duration(3*time.Second), cfg.Timeout)
+    assert.Equal(t, "booking.example.com", cfg.TLS.ServerName)
+    assert.Contains(t, cfg.String(), "timeout: 3s")
+    assert.Equal(t, []string{"seatmap", "-section", "A"}, command, "Arguments after the flags should be the command")
+    assert.NotContains(t, cfg.String(), "secret", "The auth token shouldn't be printed")
+}
+
+func TestShouldRejectInvalidConfiguration(t *testing.T) {
+    path := writeConfigFile(t, "server.yaml", "listen_adress: \":6000\"\n")
+    _, _, err := config.LoadServer([]string{"-config", path})
+    assert.ErrorContains(t, err, "listen_adress", "Unknown keys should be rejected")
+
+    _, _, err = config.LoadServer([]string{"-sections", "A:20,A:5", "-allocation-strategy", "nearest", "-tls-cert-file", "server.pem"})
+    assert.ErrorContains(t, err, `section "A" is defined twice`)
+    assert.ErrorContains(t, err, "allocation_strategy")
+    assert.ErrorContains(t, err, "tls.cert_file and tls.key_file must be set together")
+
+    _, _, _, err = config.LoadClient([]string{"-tracing-exporter", "jaeger", "-tracing-sample-ratio", "2", "-log-format", "xml", "-output", "csv"})
+    assert.ErrorContains(t, err, "tracing.exporter")
+    assert.ErrorContains(t, err, "output")
+    assert.ErrorContains(t, err, "log_format")
+    assert.ErrorContains(t, err, "tracing.sample_ratio")
+
+    cfg, _, err := config.LoadServer([]string{"-rate-limits", "CreateBooking:ip=0.5/10,*:api_key=20/100"})
+    assert.NoError(t, err)
+    assert.Equal(t, config.RateLimits{{Method: "CreateBooking", Key: "ip", Rate: 0.5, Burst: 10},
+        {Method: "*", Key: "api_key", Rate: 20, Burst: 100}}, cfg.RateLimits)
+    _, _, err = config.LoadServer([]string{"-rate-limits", "CreateBooking:cookie=1/0"})
+    assert.ErrorContains(t, err, "rate limit key")
+    assert.ErrorContains(t, err, "positive burst")
+
+    _, _, err = config.LoadServer([]string{"-backup-interval", "24h"})
+    assert.ErrorContains(t, err, "backup.interval needs backup.dir")
+
+    cfg, _, err = config.LoadServer([]string{"-webhook-allowed-hosts", "hooks.example.com, partner.example.com"})
+    assert.NoError(t, err)
+    assert.Equal(t, config.Hosts{"hooks.example.com", "partner.example.com"}, cfg.Webhooks.AllowedHosts)
+    _, _, err = config.LoadServer([]string{"-webhook-allowed-hosts", "https://hooks.example.com"})
+    assert.ErrorContains(t, err, "webhooks.allowed_hosts")
+
+    t.Setenv("BOOKING_SERVER_SMTP_PASSWORD", "hunter2")
+    cfg, _, err = config.LoadServer([]string{"-smtp-address", "smtp.example.com:587", "-smtp-username", "bookings"})
+    assert.NoError(t, err)
+    assert.Equal(t, "hunter2", cfg.SMTP.Password)
+    assert.Equal(t, "bookings@ticket-booking-app.local", cfg.SMTP.From)
+    assert.NotContains(t, cfg.String(), "hunter2", "The SMTP password shouldn't be printed")
+    _, _, err = config.LoadServer([]string{"-smtp-address", "smtp.example.com"})
+    assert.ErrorContains(t, err, "smtp.address")
+    _, _, err = config.LoadServer([]string{"-smtp-address", ""})
+    assert.ErrorContains(t, err, "smtp.password needs smtp.username")
+
+    t.Setenv("BOOKING_SERVER_SHUTDOWN_TIMEOUT", "soon")
+    _, _, err = config.LoadServer(nil)
+    assert.ErrorContains(t, err, "BOOKING_SERVER_SHUTDOWN_TIMEOUT")
+}
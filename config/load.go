@@ -0,0 +1,210 @@
+package config
+
+import (
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	ServerEnvPrefix = "BOOKING_SERVER_"
+	ClientEnvPrefix = "BOOKING_CLIENT_"
+)
+
+// LoadServer reads the server configuration from args, the BOOKING_SERVER_*
+// environment variables and the file given by -config. Flags override the
+// environment, which overrides the file, which overrides the defaults.
+// printOnly is set when -print-config asks for the configuration to be shown
+// instead of starting the server.
+func LoadServer(args []string) (cfg *Server, printOnly bool, err error) {
+	cfg = DefaultServer()
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	fs.StringVar(&cfg.ListenAddress, "listen-address", cfg.ListenAddress, "`host:port` the gRPC server listens on")
+	fs.StringVar(&cfg.RESTAddress, "rest-address", cfg.RESTAddress, "`host:port` the REST gateway listens on, empty to disable it")
+	fs.StringVar(&cfg.MetricsAddress, "metrics-address", cfg.MetricsAddress, "`host:port` serving Prometheus metrics on /metrics, empty to disable them")
+	fs.StringVar(&cfg.DSN, "dsn", cfg.DSN, "SQLite data source name")
+	fs.StringVar(&cfg.TLS.CertFile, "tls-cert-file", cfg.TLS.CertFile, "PEM server certificate, enables TLS")
+	fs.StringVar(&cfg.TLS.KeyFile, "tls-key-file", cfg.TLS.KeyFile, "PEM server private key")
+	fs.Var(&cfg.TLS.ReloadInterval, "tls-reload-interval", "how often the TLS files are checked for changes")
+	fs.StringVar(&cfg.TLS.ClientAuth, "tls-client-auth", cfg.TLS.ClientAuth, "client certificates: none, optional or require")
+	fs.StringVar(&cfg.TLS.ClientCAFile, "tls-client-ca-file", cfg.TLS.ClientCAFile, "PEM CA certificates client certificates are verified with")
+	fs.Var(&cfg.TLS.Identities, "tls-identities", "allowed client certificates as `common-name=identity,...`")
+	fs.Var(&cfg.Sections, "sections", "section layout as `name:seats,...`")
+	fs.StringVar(&cfg.AllocationStrategy, "allocation-strategy", cfg.AllocationStrategy, "seat allocation strategy, random or sequential")
+	fs.Var(&cfg.ShutdownTimeout, "shutdown-timeout", "time in-flight requests get to finish on SIGTERM")
+	fs.Var(&cfg.IdempotencyKeyTTL, "idempotency-key-ttl", "how long responses are replayed for an idempotency key")
+	fs.Var(&cfg.SeatHoldTTL, "seat-hold-ttl", "how long a held seat is kept for a booking")
+	fs.Var(&cfg.RateLimits, "rate-limits", "token buckets as `method:key=rate/burst,...`, keyed by actor, email, ip or api_key, empty to disable them")
+	fs.IntVar(&cfg.MaxActiveBookingsPerJourney, "max-active-bookings-per-journey", cfg.MaxActiveBookingsPerJourney, "booked tickets a user may hold for one journey, 0 for no limit")
+	fs.IntVar(&cfg.MaxSeatHoldsPerCaller, "max-seat-holds-per-caller", cfg.MaxSeatHoldsPerCaller, "seats a caller may hold at once, 0 for no limit")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "debug, info, warn or error")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "log output, text or json")
+	fs.BoolVar(&cfg.LogRedact, "log-redact", cfg.LogRedact, "redact emails and names in logs")
+	tracingFlags(fs, &cfg.Tracing)
+	fs.StringVar(&cfg.Backup.Dir, "backup-dir", cfg.Backup.Dir, "directory of database backups, enables the backup RPCs")
+	fs.Var(&cfg.Backup.Interval, "backup-interval", "how often the database is backed up, 0 for no scheduled backups")
+	fs.IntVar(&cfg.Backup.Keep, "backup-keep", cfg.Backup.Keep, "number of newest backups kept, 0 to keep all")
+	fs.Var(&cfg.Webhooks.AllowedHosts, "webhook-allowed-hosts", "the only hosts webhooks may be registered for, as `host,...`")
+	fs.BoolVar(&cfg.Webhooks.AllowPrivate, "webhook-allow-private", cfg.Webhooks.AllowPrivate, "allow webhooks over http and to private addresses, for development")
+	fs.StringVar(&cfg.SMTP.Address, "smtp-address", cfg.SMTP.Address, "`host:port` of the SMTP server notification emails are sent through, empty to only log them")
+	fs.StringVar(&cfg.SMTP.From, "smtp-from", cfg.SMTP.From, "sender address of notification emails")
+	fs.StringVar(&cfg.SMTP.Username, "smtp-username", cfg.SMTP.Username, "SMTP user, enables PLAIN auth")
+	fs.StringVar(&cfg.SMTP.Password, "smtp-password", cfg.SMTP.Password, "SMTP password")
+
+	remaining, printOnly, err := load(fs, ServerEnvPrefix, args, cfg)
+	if err != nil {
+		return nil, false, err
+	}
+	if len(remaining) > 0 {
+		return nil, false, fmt.Errorf("unexpected arguments %v", remaining)
+	}
+	return cfg, printOnly, cfg.Validate()
+}
+
+// LoadClient reads the client configuration like LoadServer, from the
+// BOOKING_CLIENT_* environment variables. The arguments following the flags
+// are returned as the command to run.
+func LoadClient(args []string) (cfg *Client, command []string, printOnly bool, err error) {
+	cfg = DefaultClient()
+	fs := flag.NewFlagSet("client", flag.ContinueOnError)
+	fs.StringVar(&cfg.ServerAddress, "server-address", cfg.ServerAddress, "`host:port` of the booking server")
+	fs.Var(&cfg.Timeout, "timeout", "timeout of the requests")
+	fs.StringVar(&cfg.AuthToken, "auth-token", cfg.AuthToken, "bearer token sent in the authorization header")
+	fs.StringVar(&cfg.APIKey, "api-key", cfg.APIKey, "key identifying the client application, sent as x-api-key")
+	fs.StringVar(&cfg.Output, "output", cfg.Output, "output format, table, json or yaml")
+	fs.StringVar(&cfg.TLS.CAFile, "tls-ca-file", cfg.TLS.CAFile, "PEM CA certificates the server is verified with, enables TLS")
+	fs.StringVar(&cfg.TLS.ServerName, "tls-server-name", cfg.TLS.ServerName, "name the server certificate must be valid for, defaults to the host of -server-address")
+	fs.StringVar(&cfg.TLS.CertFile, "tls-cert-file", cfg.TLS.CertFile, "PEM client certificate for mutual TLS")
+	fs.StringVar(&cfg.TLS.KeyFile, "tls-key-file", cfg.TLS.KeyFile, "PEM client private key")
+	fs.StringVar(&cfg.LogLevel, "log-level", cfg.LogLevel, "debug, info, warn or error")
+	fs.StringVar(&cfg.LogFormat, "log-format", cfg.LogFormat, "log output, text or json")
+	fs.BoolVar(&cfg.LogRedact, "log-redact", cfg.LogRedact, "redact emails and names in logs")
+	tracingFlags(fs, &cfg.Tracing)
+
+	command, printOnly, err = load(fs, ClientEnvPrefix, args, cfg)
+	if err != nil {
+		return nil, nil, false, err
+	}
+	return cfg, command, printOnly, cfg.Validate()
+}
+
+func tracingFlags(fs *flag.FlagSet, cfg *Tracing) {
+	fs.StringVar(&cfg.Exporter, "tracing-exporter", cfg.Exporter, "where spans are exported: none, otlp or stdout")
+	fs.StringVar(&cfg.Endpoint, "tracing-endpoint", cfg.Endpoint, "`host:port` of the OTLP collector")
+	fs.BoolVar(&cfg.Insecure, "tracing-insecure", cfg.Insecure, "connect to the OTLP collector without TLS")
+	fs.Float64Var(&cfg.SampleRatio, "tracing-sample-ratio", cfg.SampleRatio, "share of new traces that are recorded, from 0 to 1")
+}
+
+// load parses the flags of fs, which are bound to the fields of cfg, and returns
+// the arguments following them. The file and the environment are applied
+// afterwards, so the flags that were given are set once more at the end to
+// take precedence.
+func load(fs *flag.FlagSet, envPrefix string, args []string, cfg any) ([]string, bool, error) {
+	var names []string
+	fs.VisitAll(func(f *flag.Flag) { names = append(names, f.Name) })
+	configPath := fs.String("config", "", "YAML (.yaml, .yml) or TOML (.toml) configuration `file`")
+	printConfig := fs.Bool("print-config", false, "print the effective configuration and exit")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage of %s:\n", fs.Name())
+		fs.PrintDefaults()
+		fmt.Fprintf(fs.Output(), "\nEvery flag can also be set through the environment, e.g. -%s as %s.\n", names[0], envName(envPrefix, names[0]))
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, false, err
+	}
+
+	explicit := map[string]string{}
+	fs.Visit(func(f *flag.Flag) { explicit[f.Name] = f.Value.String() })
+
+	path := *configPath
+	if _, given := explicit["config"]; !given {
+		if value, ok := os.LookupEnv(envName(envPrefix, "config")); ok {
+			path = value
+		}
+	}
+	if path != "" {
+		if err := decodeFile(path, cfg); err != nil {
+			return nil, false, err
+		}
+	}
+
+	for _, name := range names {
+		value, ok := os.LookupEnv(envName(envPrefix, name))
+		if !ok {
+			continue
+		}
+		if err := fs.Set(name, value); err != nil {
+			return nil, false, fmt.Errorf("%s: %w", envName(envPrefix, name), err)
+		}
+	}
+	for name, value := range explicit {
+		if err := fs.Set(name, value); err != nil {
+			return nil, false, err
+		}
+	}
+	return fs.Args(), *printConfig, nil
+}
+
+// decodeFile rejects unknown keys so that typos don't go unnoticed.
+func decodeFile(path string, cfg any) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(content))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+	case ".toml":
+		metadata, err := toml.Decode(string(content), cfg)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		if undecoded := metadata.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("%s: unknown keys %v", path, undecoded)
+		}
+	default:
+		return fmt.Errorf("%s: configuration files must be .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+func envName(prefix, flagName string) string {
+	return prefix + strings.ToUpper(strings.ReplaceAll(flagName, "-", "_"))
+}
+
+// String returns the configuration as YAML, without the SMTP password.
+func (c *Server) String() string {
+	redacted := *c
+	if redacted.SMTP.Password != "" {
+		redacted.SMTP.Password = "REDACTED"
+	}
+	return marshalYAML(&redacted)
+}
+
+// String returns the configuration as YAML, without the auth token.
+func (c *Client) String() string {
+	redacted := *c
+	if redacted.AuthToken != "" {
+		redacted.AuthToken = "REDACTED"
+	}
+	return marshalYAML(&redacted)
+}
+
+func marshalYAML(cfg any) string {
+	content, err := yaml.Marshal(cfg)
+	if err != nil {
+		return err.Error()
+	}
+	return string(content)
+}
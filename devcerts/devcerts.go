@@ -0,0 +1,41 @@
+// Command devcerts writes a development CA with a server certificate and
+// client certificates, for trying out TLS and mutual TLS locally and in tests:
+//
+//	go run ./devcerts -out ./certs -hosts localhost,127.0.0.1 -clients booking-client,conductor
+package main
+
+import (
+	"ticket-booking-app/tlsconfig"
+	"flag"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+func main() {
+	out := flag.String("out", "./certs", "directory the certificates and keys are written to")
+	hosts := flag.String("hosts", "localhost,127.0.0.1", "comma separated names and addresses of the server")
+	clients := flag.String("clients", "booking-client", "comma separated client names, used as certificate common names")
+	flag.Parse()
+
+	var clientNames []string
+	if *clients != "" {
+		clientNames = strings.Split(*clients, ",")
+	}
+	files, err := tlsconfig.GenerateDevCertificates(*out, strings.Split(*hosts, ","), clientNames)
+	if err != nil {
+		log.Fatalf("Error in generating certificates: %v", err)
+	}
+
+	path := func(file string) string { return filepath.Join(*out, file) }
+	fmt.Printf("Wrote a development CA, valid for %v, to %s. Start the server with\n\n", tlsconfig.DevCertificateValidity, *out)
+	fmt.Printf("    go run ./server -tls-cert-file %s -tls-key-file %s -tls-client-auth require -tls-client-ca-file %s\n\n",
+		path(files.ServerCertFile), path(files.ServerKeyFile), path(files.CAFile))
+	sort.Strings(clientNames)
+	for _, client := range clientNames {
+		fmt.Printf("and connect as %s with\n\n    go run ./client -tls-ca-file %s -tls-cert-file %s -tls-key-file %s\n\n",
+			client, path(files.CAFile), path(files.ClientCertFiles[client]), path(files.ClientKeyFiles[client]))
+	}
+}
@@ -24,19 +24,33 @@ const (
 	BookingService_GetBookingByUser_FullMethodName     = "/booking.BookingService/GetBookingByUser"
 	BookingService_ModifySeatByUser_FullMethodName     = "/booking.BookingService/ModifySeatByUser"
 	BookingService_RemoveBookingByUser_FullMethodName  = "/booking.BookingService/RemoveBookingByUser"
+	BookingService_GetBooking_FullMethodName           = "/booking.BookingService/GetBooking"
+	BookingService_ListMyBookings_FullMethodName       = "/booking.BookingService/ListMyBookings"
+	BookingService_GetETicket_FullMethodName           = "/booking.BookingService/GetETicket"
+	BookingService_StreamGroupETicket_FullMethodName   = "/booking.BookingService/StreamGroupETicket"
+	BookingService_GetSeatMap_FullMethodName           = "/booking.BookingService/GetSeatMap"
+	BookingService_WatchBookings_FullMethodName        = "/booking.BookingService/WatchBookings"
+	BookingService_HoldSeat_FullMethodName             = "/booking.BookingService/HoldSeat"
+	BookingService_ReleaseSeatHold_FullMethodName      = "/booking.BookingService/ReleaseSeatHold"
 )
 
 // BookingServiceClient is the client API for BookingService service.
 //
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
-//
-// Train ticket booking APIs
 type BookingServiceClient interface {
 	CreateBooking(ctx context.Context, in *BookingRequest, opts ...grpc.CallOption) (*BookingResponse, error)
 	GetBookingsBySection(ctx context.Context, in *GetBookingsBySectionRequest, opts ...grpc.CallOption) (*BookingListResponse, error)
 	GetBookingByUser(ctx context.Context, in *GetBookingByUserRequest, opts ...grpc.CallOption) (*BookingResponse, error)
 	ModifySeatByUser(ctx context.Context, in *SeatModificationRequest, opts ...grpc.CallOption) (*SeatModificationResponse, error)
 	RemoveBookingByUser(ctx context.Context, in *RemoveBookingByUserRequest, opts ...grpc.CallOption) (*RemoveBookingResponse, error)
+	GetBooking(ctx context.Context, in *GetBookingRequest, opts ...grpc.CallOption) (*BookingResponse, error)
+	ListMyBookings(ctx context.Context, in *ListMyBookingsRequest, opts ...grpc.CallOption) (*BookingListResponse, error)
+	GetETicket(ctx context.Context, in *GetETicketRequest, opts ...grpc.CallOption) (*ETicket, error)
+	StreamGroupETicket(ctx context.Context, in *GetGroupETicketRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ETicketChunk], error)
+	GetSeatMap(ctx context.Context, in *GetSeatMapRequest, opts ...grpc.CallOption) (*SeatMap, error)
+	WatchBookings(ctx context.Context, in *WatchBookingsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BookingEvent], error)
+	HoldSeat(ctx context.Context, in *HoldSeatRequest, opts ...grpc.CallOption) (*SeatHold, error)
+	ReleaseSeatHold(ctx context.Context, in *ReleaseSeatHoldRequest, opts ...grpc.CallOption) (*ReleaseSeatHoldResponse, error)
 }
 
 type bookingServiceClient struct {
@@ -97,17 +111,121 @@ func (c *bookingServiceClient) RemoveBookingByUser(ctx context.Context, in *Remo
 	return out, nil
 }
 
+func (c *bookingServiceClient) GetBooking(ctx context.Context, in *GetBookingRequest, opts ...grpc.CallOption) (*BookingResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BookingResponse)
+	err := c.cc.Invoke(ctx, BookingService_GetBooking_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ListMyBookings(ctx context.Context, in *ListMyBookingsRequest, opts ...grpc.CallOption) (*BookingListResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BookingListResponse)
+	err := c.cc.Invoke(ctx, BookingService_ListMyBookings_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) GetETicket(ctx context.Context, in *GetETicketRequest, opts ...grpc.CallOption) (*ETicket, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ETicket)
+	err := c.cc.Invoke(ctx, BookingService_GetETicket_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) StreamGroupETicket(ctx context.Context, in *GetGroupETicketRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[ETicketChunk], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BookingService_ServiceDesc.Streams[0], BookingService_StreamGroupETicket_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[GetGroupETicketRequest, ETicketChunk]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BookingService_StreamGroupETicketClient = grpc.ServerStreamingClient[ETicketChunk]
+
+func (c *bookingServiceClient) GetSeatMap(ctx context.Context, in *GetSeatMapRequest, opts ...grpc.CallOption) (*SeatMap, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SeatMap)
+	err := c.cc.Invoke(ctx, BookingService_GetSeatMap_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) WatchBookings(ctx context.Context, in *WatchBookingsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[BookingEvent], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &BookingService_ServiceDesc.Streams[1], BookingService_WatchBookings_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchBookingsRequest, BookingEvent]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BookingService_WatchBookingsClient = grpc.ServerStreamingClient[BookingEvent]
+
+func (c *bookingServiceClient) HoldSeat(ctx context.Context, in *HoldSeatRequest, opts ...grpc.CallOption) (*SeatHold, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SeatHold)
+	err := c.cc.Invoke(ctx, BookingService_HoldSeat_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *bookingServiceClient) ReleaseSeatHold(ctx context.Context, in *ReleaseSeatHoldRequest, opts ...grpc.CallOption) (*ReleaseSeatHoldResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ReleaseSeatHoldResponse)
+	err := c.cc.Invoke(ctx, BookingService_ReleaseSeatHold_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // BookingServiceServer is the server API for BookingService service.
 // All implementations should embed UnimplementedBookingServiceServer
 // for forward compatibility.
-//
-// Train ticket booking APIs
 type BookingServiceServer interface {
 	CreateBooking(context.Context, *BookingRequest) (*BookingResponse, error)
 	GetBookingsBySection(context.Context, *GetBookingsBySectionRequest) (*BookingListResponse, error)
 	GetBookingByUser(context.Context, *GetBookingByUserRequest) (*BookingResponse, error)
 	ModifySeatByUser(context.Context, *SeatModificationRequest) (*SeatModificationResponse, error)
 	RemoveBookingByUser(context.Context, *RemoveBookingByUserRequest) (*RemoveBookingResponse, error)
+	GetBooking(context.Context, *GetBookingRequest) (*BookingResponse, error)
+	ListMyBookings(context.Context, *ListMyBookingsRequest) (*BookingListResponse, error)
+	GetETicket(context.Context, *GetETicketRequest) (*ETicket, error)
+	StreamGroupETicket(*GetGroupETicketRequest, grpc.ServerStreamingServer[ETicketChunk]) error
+	GetSeatMap(context.Context, *GetSeatMapRequest) (*SeatMap, error)
+	WatchBookings(*WatchBookingsRequest, grpc.ServerStreamingServer[BookingEvent]) error
+	HoldSeat(context.Context, *HoldSeatRequest) (*SeatHold, error)
+	ReleaseSeatHold(context.Context, *ReleaseSeatHoldRequest) (*ReleaseSeatHoldResponse, error)
 }
 
 // UnimplementedBookingServiceServer should be embedded to have
@@ -132,6 +250,30 @@ func (UnimplementedBookingServiceServer) ModifySeatByUser(context.Context, *Seat
 func (UnimplementedBookingServiceServer) RemoveBookingByUser(context.Context, *RemoveBookingByUserRequest) (*RemoveBookingResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method RemoveBookingByUser not implemented")
 }
+func (UnimplementedBookingServiceServer) GetBooking(context.Context, *GetBookingRequest) (*BookingResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetBooking not implemented")
+}
+func (UnimplementedBookingServiceServer) ListMyBookings(context.Context, *ListMyBookingsRequest) (*BookingListResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListMyBookings not implemented")
+}
+func (UnimplementedBookingServiceServer) GetETicket(context.Context, *GetETicketRequest) (*ETicket, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetETicket not implemented")
+}
+func (UnimplementedBookingServiceServer) StreamGroupETicket(*GetGroupETicketRequest, grpc.ServerStreamingServer[ETicketChunk]) error {
+	return status.Errorf(codes.Unimplemented, "method StreamGroupETicket not implemented")
+}
+func (UnimplementedBookingServiceServer) GetSeatMap(context.Context, *GetSeatMapRequest) (*SeatMap, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetSeatMap not implemented")
+}
+func (UnimplementedBookingServiceServer) WatchBookings(*WatchBookingsRequest, grpc.ServerStreamingServer[BookingEvent]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchBookings not implemented")
+}
+func (UnimplementedBookingServiceServer) HoldSeat(context.Context, *HoldSeatRequest) (*SeatHold, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method HoldSeat not implemented")
+}
+func (UnimplementedBookingServiceServer) ReleaseSeatHold(context.Context, *ReleaseSeatHoldRequest) (*ReleaseSeatHoldResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ReleaseSeatHold not implemented")
+}
 func (UnimplementedBookingServiceServer) testEmbeddedByValue() {}
 
 // UnsafeBookingServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -242,6 +384,136 @@ func _BookingService_RemoveBookingByUser_Handler(srv interface{}, ctx context.Co
 	return interceptor(ctx, in, info, handler)
 }
 
+func _BookingService_GetBooking_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetBookingRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).GetBooking(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_GetBooking_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).GetBooking(ctx, req.(*GetBookingRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_ListMyBookings_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListMyBookingsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ListMyBookings(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ListMyBookings_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ListMyBookings(ctx, req.(*ListMyBookingsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_GetETicket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetETicketRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).GetETicket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_GetETicket_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).GetETicket(ctx, req.(*GetETicketRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_StreamGroupETicket_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(GetGroupETicketRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BookingServiceServer).StreamGroupETicket(m, &grpc.GenericServerStream[GetGroupETicketRequest, ETicketChunk]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BookingService_StreamGroupETicketServer = grpc.ServerStreamingServer[ETicketChunk]
+
+func _BookingService_GetSeatMap_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetSeatMapRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).GetSeatMap(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_GetSeatMap_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).GetSeatMap(ctx, req.(*GetSeatMapRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_WatchBookings_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchBookingsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(BookingServiceServer).WatchBookings(m, &grpc.GenericServerStream[WatchBookingsRequest, BookingEvent]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type BookingService_WatchBookingsServer = grpc.ServerStreamingServer[BookingEvent]
+
+func _BookingService_HoldSeat_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(HoldSeatRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).HoldSeat(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_HoldSeat_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).HoldSeat(ctx, req.(*HoldSeatRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _BookingService_ReleaseSeatHold_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReleaseSeatHoldRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(BookingServiceServer).ReleaseSeatHold(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: BookingService_ReleaseSeatHold_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(BookingServiceServer).ReleaseSeatHold(ctx, req.(*ReleaseSeatHoldRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // BookingService_ServiceDesc is the grpc.ServiceDesc for BookingService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -269,7 +541,42 @@ var BookingService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "RemoveBookingByUser",
 			Handler:    _BookingService_RemoveBookingByUser_Handler,
 		},
+		{
+			MethodName: "GetBooking",
+			Handler:    _BookingService_GetBooking_Handler,
+		},
+		{
+			MethodName: "ListMyBookings",
+			Handler:    _BookingService_ListMyBookings_Handler,
+		},
+		{
+			MethodName: "GetETicket",
+			Handler:    _BookingService_GetETicket_Handler,
+		},
+		{
+			MethodName: "GetSeatMap",
+			Handler:    _BookingService_GetSeatMap_Handler,
+		},
+		{
+			MethodName: "HoldSeat",
+			Handler:    _BookingService_HoldSeat_Handler,
+		},
+		{
+			MethodName: "ReleaseSeatHold",
+			Handler:    _BookingService_ReleaseSeatHold_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "StreamGroupETicket",
+			Handler:       _BookingService_StreamGroupETicket_Handler,
+			ServerStreams: true,
+		},
+		{
+			StreamName:    "WatchBookings",
+			Handler:       _BookingService_WatchBookings_Handler,
+			ServerStreams: true,
+		},
 	},
-	Streams:  []grpc.StreamDesc{},
 	Metadata: "domain/booking.proto",
 }
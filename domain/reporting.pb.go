@@ -0,0 +1,1024 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.34.2
+// 	protoc        v5.28.0
+// source: reporting.proto
+
+package domain
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ReportInterval int32
+
+const (
+	// a day
+	ReportInterval_REPORT_INTERVAL_UNSPECIFIED ReportInterval = 0
+	ReportInterval_REPORT_INTERVAL_DAY         ReportInterval = 1
+	// weeks start on Monday
+	ReportInterval_REPORT_INTERVAL_WEEK  ReportInterval = 2
+	ReportInterval_REPORT_INTERVAL_MONTH ReportInterval = 3
+)
+
+// Enum value maps for ReportInterval.
+var (
+	ReportInterval_name = map[int32]string{
+		0: "REPORT_INTERVAL_UNSPECIFIED",
+		1: "REPORT_INTERVAL_DAY",
+		2: "REPORT_INTERVAL_WEEK",
+		3: "REPORT_INTERVAL_MONTH",
+	}
+	ReportInterval_value = map[string]int32{
+		"REPORT_INTERVAL_UNSPECIFIED": 0,
+		"REPORT_INTERVAL_DAY":         1,
+		"REPORT_INTERVAL_WEEK":        2,
+		"REPORT_INTERVAL_MONTH":       3,
+	}
+)
+
+func (x ReportInterval) Enum() *ReportInterval {
+	p := new(ReportInterval)
+	*p = x
+	return p
+}
+
+func (x ReportInterval) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (ReportInterval) Descriptor() protoreflect.EnumDescriptor {
+	return file_reporting_proto_enumTypes[0].Descriptor()
+}
+
+func (ReportInterval) Type() protoreflect.EnumType {
+	return &file_reporting_proto_enumTypes[0]
+}
+
+func (x ReportInterval) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use ReportInterval.Descriptor instead.
+func (ReportInterval) EnumDescriptor() ([]byte, []int) {
+	return file_reporting_proto_rawDescGZIP(), []int{0}
+}
+
+// All filters are optional. since and until select the departures for the
+// occupancy report and the bookings made in that time for the other reports.
+// Periods are in UTC.
+type ReportRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Since   *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=since,proto3" json:"since,omitempty"`
+	Until   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=until,proto3" json:"until,omitempty"`
+	From    string                 `protobuf:"bytes,3,opt,name=from,proto3" json:"from,omitempty"`
+	To      string                 `protobuf:"bytes,4,opt,name=to,proto3" json:"to,omitempty"`
+	Section string                 `protobuf:"bytes,5,opt,name=section,proto3" json:"section,omitempty"`
+	// the length of the periods rows are grouped by, not used for occupancy
+	Interval ReportInterval `protobuf:"varint,6,opt,name=interval,proto3,enum=booking.ReportInterval" json:"interval,omitempty"`
+}
+
+func (x *ReportRequest) Reset() {
+	*x = ReportRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reporting_proto_msgTypes[0]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *ReportRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ReportRequest) ProtoMessage() {}
+
+func (x *ReportRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_reporting_proto_msgTypes[0]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ReportRequest.ProtoReflect.Descriptor instead.
+func (*ReportRequest) Descriptor() ([]byte, []int) {
+	return file_reporting_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ReportRequest) GetSince() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Since
+	}
+	return nil
+}
+
+func (x *ReportRequest) GetUntil() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Until
+	}
+	return nil
+}
+
+func (x *ReportRequest) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *ReportRequest) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *ReportRequest) GetSection() string {
+	if x != nil {
+		return x.Section
+	}
+	return ""
+}
+
+func (x *ReportRequest) GetInterval() ReportInterval {
+	if x != nil {
+		return x.Interval
+	}
+	return ReportInterval_REPORT_INTERVAL_UNSPECIFIED
+}
+
+// A journey is a route with its departure, which is unset for bookings
+// without one. seats is the size of the section in the current layout.
+type OccupancyRow struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	From      string                 `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To        string                 `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	Departure *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=departure,proto3" json:"departure,omitempty"`
+	Section   string                 `protobuf:"bytes,4,opt,name=section,proto3" json:"section,omitempty"`
+	Seats     int32                  `protobuf:"varint,5,opt,name=seats,proto3" json:"seats,omitempty"`
+	Booked    int64                  `protobuf:"varint,6,opt,name=booked,proto3" json:"booked,omitempty"`
+	// booked divided by seats
+	LoadFactor float64 `protobuf:"fixed64,7,opt,name=load_factor,json=loadFactor,proto3" json:"load_factor,omitempty"`
+}
+
+func (x *OccupancyRow) Reset() {
+	*x = OccupancyRow{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reporting_proto_msgTypes[1]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OccupancyRow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OccupancyRow) ProtoMessage() {}
+
+func (x *OccupancyRow) ProtoReflect() protoreflect.Message {
+	mi := &file_reporting_proto_msgTypes[1]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OccupancyRow.ProtoReflect.Descriptor instead.
+func (*OccupancyRow) Descriptor() ([]byte, []int) {
+	return file_reporting_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *OccupancyRow) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *OccupancyRow) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *OccupancyRow) GetDeparture() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Departure
+	}
+	return nil
+}
+
+func (x *OccupancyRow) GetSection() string {
+	if x != nil {
+		return x.Section
+	}
+	return ""
+}
+
+func (x *OccupancyRow) GetSeats() int32 {
+	if x != nil {
+		return x.Seats
+	}
+	return 0
+}
+
+func (x *OccupancyRow) GetBooked() int64 {
+	if x != nil {
+		return x.Booked
+	}
+	return 0
+}
+
+func (x *OccupancyRow) GetLoadFactor() float64 {
+	if x != nil {
+		return x.LoadFactor
+	}
+	return 0
+}
+
+// One row per section of every journey with a booking
+type OccupancyReport struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Rows []*OccupancyRow `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
+}
+
+func (x *OccupancyReport) Reset() {
+	*x = OccupancyReport{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reporting_proto_msgTypes[2]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *OccupancyReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OccupancyReport) ProtoMessage() {}
+
+func (x *OccupancyReport) ProtoReflect() protoreflect.Message {
+	mi := &file_reporting_proto_msgTypes[2]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OccupancyReport.ProtoReflect.Descriptor instead.
+func (*OccupancyReport) Descriptor() ([]byte, []int) {
+	return file_reporting_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *OccupancyReport) GetRows() []*OccupancyRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+// period is the first day of the period the bookings were made in, as
+// YYYY-MM-DD. Sections are the classes of the train.
+type RevenueRow struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Period  string `protobuf:"bytes,1,opt,name=period,proto3" json:"period,omitempty"`
+	From    string `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To      string `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	Section string `protobuf:"bytes,4,opt,name=section,proto3" json:"section,omitempty"`
+	Tickets int64  `protobuf:"varint,5,opt,name=tickets,proto3" json:"tickets,omitempty"`
+	Revenue int64  `protobuf:"varint,6,opt,name=revenue,proto3" json:"revenue,omitempty"`
+}
+
+func (x *RevenueRow) Reset() {
+	*x = RevenueRow{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reporting_proto_msgTypes[3]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RevenueRow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevenueRow) ProtoMessage() {}
+
+func (x *RevenueRow) ProtoReflect() protoreflect.Message {
+	mi := &file_reporting_proto_msgTypes[3]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevenueRow.ProtoReflect.Descriptor instead.
+func (*RevenueRow) Descriptor() ([]byte, []int) {
+	return file_reporting_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *RevenueRow) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+func (x *RevenueRow) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *RevenueRow) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *RevenueRow) GetSection() string {
+	if x != nil {
+		return x.Section
+	}
+	return ""
+}
+
+func (x *RevenueRow) GetTickets() int64 {
+	if x != nil {
+		return x.Tickets
+	}
+	return 0
+}
+
+func (x *RevenueRow) GetRevenue() int64 {
+	if x != nil {
+		return x.Revenue
+	}
+	return 0
+}
+
+// Revenue is the price of the tickets that are still booked
+type RevenueReport struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Rows         []*RevenueRow `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
+	TotalRevenue int64         `protobuf:"varint,2,opt,name=total_revenue,json=totalRevenue,proto3" json:"total_revenue,omitempty"`
+}
+
+func (x *RevenueReport) Reset() {
+	*x = RevenueReport{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reporting_proto_msgTypes[4]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *RevenueReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RevenueReport) ProtoMessage() {}
+
+func (x *RevenueReport) ProtoReflect() protoreflect.Message {
+	mi := &file_reporting_proto_msgTypes[4]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RevenueReport.ProtoReflect.Descriptor instead.
+func (*RevenueReport) Descriptor() ([]byte, []int) {
+	return file_reporting_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *RevenueReport) GetRows() []*RevenueRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+func (x *RevenueReport) GetTotalRevenue() int64 {
+	if x != nil {
+		return x.TotalRevenue
+	}
+	return 0
+}
+
+type CancellationRow struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Period    string `protobuf:"bytes,1,opt,name=period,proto3" json:"period,omitempty"`
+	From      string `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To        string `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	Bookings  int64  `protobuf:"varint,4,opt,name=bookings,proto3" json:"bookings,omitempty"`
+	Cancelled int64  `protobuf:"varint,5,opt,name=cancelled,proto3" json:"cancelled,omitempty"`
+	// cancelled divided by bookings
+	CancellationRate float64 `protobuf:"fixed64,6,opt,name=cancellation_rate,json=cancellationRate,proto3" json:"cancellation_rate,omitempty"`
+}
+
+func (x *CancellationRow) Reset() {
+	*x = CancellationRow{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reporting_proto_msgTypes[5]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CancellationRow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancellationRow) ProtoMessage() {}
+
+func (x *CancellationRow) ProtoReflect() protoreflect.Message {
+	mi := &file_reporting_proto_msgTypes[5]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancellationRow.ProtoReflect.Descriptor instead.
+func (*CancellationRow) Descriptor() ([]byte, []int) {
+	return file_reporting_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *CancellationRow) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+func (x *CancellationRow) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *CancellationRow) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *CancellationRow) GetBookings() int64 {
+	if x != nil {
+		return x.Bookings
+	}
+	return 0
+}
+
+func (x *CancellationRow) GetCancelled() int64 {
+	if x != nil {
+		return x.Cancelled
+	}
+	return 0
+}
+
+func (x *CancellationRow) GetCancellationRate() float64 {
+	if x != nil {
+		return x.CancellationRate
+	}
+	return 0
+}
+
+type CancellationReport struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Rows []*CancellationRow `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
+}
+
+func (x *CancellationReport) Reset() {
+	*x = CancellationReport{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reporting_proto_msgTypes[6]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *CancellationReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CancellationReport) ProtoMessage() {}
+
+func (x *CancellationReport) ProtoReflect() protoreflect.Message {
+	mi := &file_reporting_proto_msgTypes[6]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CancellationReport.ProtoReflect.Descriptor instead.
+func (*CancellationReport) Descriptor() ([]byte, []int) {
+	return file_reporting_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *CancellationReport) GetRows() []*CancellationRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+// Lead time is the time from booking to departure, of bookings that have a
+// departure after they were made.
+type LeadTimeRow struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Period               string  `protobuf:"bytes,1,opt,name=period,proto3" json:"period,omitempty"`
+	From                 string  `protobuf:"bytes,2,opt,name=from,proto3" json:"from,omitempty"`
+	To                   string  `protobuf:"bytes,3,opt,name=to,proto3" json:"to,omitempty"`
+	Bookings             int64   `protobuf:"varint,4,opt,name=bookings,proto3" json:"bookings,omitempty"`
+	AverageLeadTimeHours float64 `protobuf:"fixed64,5,opt,name=average_lead_time_hours,json=averageLeadTimeHours,proto3" json:"average_lead_time_hours,omitempty"`
+}
+
+func (x *LeadTimeRow) Reset() {
+	*x = LeadTimeRow{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reporting_proto_msgTypes[7]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LeadTimeRow) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeadTimeRow) ProtoMessage() {}
+
+func (x *LeadTimeRow) ProtoReflect() protoreflect.Message {
+	mi := &file_reporting_proto_msgTypes[7]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeadTimeRow.ProtoReflect.Descriptor instead.
+func (*LeadTimeRow) Descriptor() ([]byte, []int) {
+	return file_reporting_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *LeadTimeRow) GetPeriod() string {
+	if x != nil {
+		return x.Period
+	}
+	return ""
+}
+
+func (x *LeadTimeRow) GetFrom() string {
+	if x != nil {
+		return x.From
+	}
+	return ""
+}
+
+func (x *LeadTimeRow) GetTo() string {
+	if x != nil {
+		return x.To
+	}
+	return ""
+}
+
+func (x *LeadTimeRow) GetBookings() int64 {
+	if x != nil {
+		return x.Bookings
+	}
+	return 0
+}
+
+func (x *LeadTimeRow) GetAverageLeadTimeHours() float64 {
+	if x != nil {
+		return x.AverageLeadTimeHours
+	}
+	return 0
+}
+
+type LeadTimeReport struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	Rows []*LeadTimeRow `protobuf:"bytes,1,rep,name=rows,proto3" json:"rows,omitempty"`
+}
+
+func (x *LeadTimeReport) Reset() {
+	*x = LeadTimeReport{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_reporting_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *LeadTimeReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*LeadTimeReport) ProtoMessage() {}
+
+func (x *LeadTimeReport) ProtoReflect() protoreflect.Message {
+	mi := &file_reporting_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use LeadTimeReport.ProtoReflect.Descriptor instead.
+func (*LeadTimeReport) Descriptor() ([]byte, []int) {
+	return file_reporting_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *LeadTimeReport) GetRows() []*LeadTimeRow {
+	if x != nil {
+		return x.Rows
+	}
+	return nil
+}
+
+var File_reporting_proto protoreflect.FileDescriptor
+
+var file_reporting_proto_rawDesc = []byte{
+	0x0a, 0x0f, 0x72, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x69, 0x6e, 0x67, 0x2e, 0x70, 0x72, 0x6f, 0x74,
+	0x6f, 0x12, 0x07, 0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x1a, 0x1f, 0x67, 0x6f, 0x6f, 0x67,
+	0x6c, 0x65, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2f, 0x74, 0x69, 0x6d, 0x65,
+	0x73, 0x74, 0x61, 0x6d, 0x70, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x22, 0xe6, 0x01, 0x0a, 0x0d,
+	0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x30, 0x0a,
+	0x05, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54,
+	0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x05, 0x73, 0x69, 0x6e, 0x63, 0x65, 0x12,
+	0x30, 0x0a, 0x05, 0x75, 0x6e, 0x74, 0x69, 0x6c, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a,
+	0x2e, 0x67, 0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66,
+	0x2e, 0x54, 0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x05, 0x75, 0x6e, 0x74, 0x69,
+	0x6c, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x02, 0x74, 0x6f, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x33, 0x0a, 0x08, 0x69, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x18, 0x06, 0x20, 0x01, 0x28,
+	0x0e, 0x32, 0x17, 0x2e, 0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x52, 0x65, 0x70, 0x6f,
+	0x72, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x52, 0x08, 0x69, 0x6e, 0x74, 0x65,
+	0x72, 0x76, 0x61, 0x6c, 0x22, 0xd5, 0x01, 0x0a, 0x0c, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e,
+	0x63, 0x79, 0x52, 0x6f, 0x77, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18,
+	0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x74, 0x6f, 0x12, 0x38, 0x0a, 0x09, 0x64, 0x65, 0x70,
+	0x61, 0x72, 0x74, 0x75, 0x72, 0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x67,
+	0x6f, 0x6f, 0x67, 0x6c, 0x65, 0x2e, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x62, 0x75, 0x66, 0x2e, 0x54,
+	0x69, 0x6d, 0x65, 0x73, 0x74, 0x61, 0x6d, 0x70, 0x52, 0x09, 0x64, 0x65, 0x70, 0x61, 0x72, 0x74,
+	0x75, 0x72, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12, 0x14, 0x0a,
+	0x05, 0x73, 0x65, 0x61, 0x74, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x05, 0x52, 0x05, 0x73, 0x65,
+	0x61, 0x74, 0x73, 0x12, 0x16, 0x0a, 0x06, 0x62, 0x6f, 0x6f, 0x6b, 0x65, 0x64, 0x18, 0x06, 0x20,
+	0x01, 0x28, 0x03, 0x52, 0x06, 0x62, 0x6f, 0x6f, 0x6b, 0x65, 0x64, 0x12, 0x1f, 0x0a, 0x0b, 0x6c,
+	0x6f, 0x61, 0x64, 0x5f, 0x66, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x18, 0x07, 0x20, 0x01, 0x28, 0x01,
+	0x52, 0x0a, 0x6c, 0x6f, 0x61, 0x64, 0x46, 0x61, 0x63, 0x74, 0x6f, 0x72, 0x22, 0x3c, 0x0a, 0x0f,
+	0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12,
+	0x29, 0x0a, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x15, 0x2e,
+	0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63,
+	0x79, 0x52, 0x6f, 0x77, 0x52, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x22, 0x96, 0x01, 0x0a, 0x0a, 0x52,
+	0x65, 0x76, 0x65, 0x6e, 0x75, 0x65, 0x52, 0x6f, 0x77, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x65, 0x72,
+	0x69, 0x6f, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x65, 0x72, 0x69, 0x6f,
+	0x64, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x03, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x02, 0x74, 0x6f, 0x12, 0x18, 0x0a, 0x07, 0x73, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e,
+	0x18, 0x04, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x73, 0x65, 0x63, 0x74, 0x69, 0x6f, 0x6e, 0x12,
+	0x18, 0x0a, 0x07, 0x74, 0x69, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x03,
+	0x52, 0x07, 0x74, 0x69, 0x63, 0x6b, 0x65, 0x74, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x72, 0x65, 0x76,
+	0x65, 0x6e, 0x75, 0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x72, 0x65, 0x76, 0x65,
+	0x6e, 0x75, 0x65, 0x22, 0x5d, 0x0a, 0x0d, 0x52, 0x65, 0x76, 0x65, 0x6e, 0x75, 0x65, 0x52, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x12, 0x27, 0x0a, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x18, 0x01, 0x20, 0x03,
+	0x28, 0x0b, 0x32, 0x13, 0x2e, 0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x52, 0x65, 0x76,
+	0x65, 0x6e, 0x75, 0x65, 0x52, 0x6f, 0x77, 0x52, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x12, 0x23, 0x0a,
+	0x0d, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x5f, 0x72, 0x65, 0x76, 0x65, 0x6e, 0x75, 0x65, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x0c, 0x74, 0x6f, 0x74, 0x61, 0x6c, 0x52, 0x65, 0x76, 0x65, 0x6e,
+	0x75, 0x65, 0x22, 0xb4, 0x01, 0x0a, 0x0f, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x6c, 0x61, 0x74,
+	0x69, 0x6f, 0x6e, 0x52, 0x6f, 0x77, 0x12, 0x16, 0x0a, 0x06, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64,
+	0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x12, 0x12,
+	0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x72,
+	0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02,
+	0x74, 0x6f, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x1c,
+	0x0a, 0x09, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x6c, 0x65, 0x64, 0x18, 0x05, 0x20, 0x01, 0x28,
+	0x03, 0x52, 0x09, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x6c, 0x65, 0x64, 0x12, 0x2b, 0x0a, 0x11,
+	0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x5f, 0x72, 0x61, 0x74,
+	0x65, 0x18, 0x06, 0x20, 0x01, 0x28, 0x01, 0x52, 0x10, 0x63, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x6c,
+	0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x61, 0x74, 0x65, 0x22, 0x42, 0x0a, 0x12, 0x43, 0x61, 0x6e,
+	0x63, 0x65, 0x6c, 0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12,
+	0x2c, 0x0a, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x18, 0x2e,
+	0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x6c, 0x61,
+	0x74, 0x69, 0x6f, 0x6e, 0x52, 0x6f, 0x77, 0x52, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x22, 0x9c, 0x01,
+	0x0a, 0x0b, 0x4c, 0x65, 0x61, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x52, 0x6f, 0x77, 0x12, 0x16, 0x0a,
+	0x06, 0x70, 0x65, 0x72, 0x69, 0x6f, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x06, 0x70,
+	0x65, 0x72, 0x69, 0x6f, 0x64, 0x12, 0x12, 0x0a, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x18, 0x02, 0x20,
+	0x01, 0x28, 0x09, 0x52, 0x04, 0x66, 0x72, 0x6f, 0x6d, 0x12, 0x0e, 0x0a, 0x02, 0x74, 0x6f, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x02, 0x74, 0x6f, 0x12, 0x1a, 0x0a, 0x08, 0x62, 0x6f, 0x6f,
+	0x6b, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x08, 0x62, 0x6f, 0x6f,
+	0x6b, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x35, 0x0a, 0x17, 0x61, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65,
+	0x5f, 0x6c, 0x65, 0x61, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x5f, 0x68, 0x6f, 0x75, 0x72, 0x73,
+	0x18, 0x05, 0x20, 0x01, 0x28, 0x01, 0x52, 0x14, 0x61, 0x76, 0x65, 0x72, 0x61, 0x67, 0x65, 0x4c,
+	0x65, 0x61, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x48, 0x6f, 0x75, 0x72, 0x73, 0x22, 0x3a, 0x0a, 0x0e,
+	0x4c, 0x65, 0x61, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x28,
+	0x0a, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0b, 0x32, 0x14, 0x2e, 0x62,
+	0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x4c, 0x65, 0x61, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x52,
+	0x6f, 0x77, 0x52, 0x04, 0x72, 0x6f, 0x77, 0x73, 0x2a, 0x7f, 0x0a, 0x0e, 0x52, 0x65, 0x70, 0x6f,
+	0x72, 0x74, 0x49, 0x6e, 0x74, 0x65, 0x72, 0x76, 0x61, 0x6c, 0x12, 0x1f, 0x0a, 0x1b, 0x52, 0x45,
+	0x50, 0x4f, 0x52, 0x54, 0x5f, 0x49, 0x4e, 0x54, 0x45, 0x52, 0x56, 0x41, 0x4c, 0x5f, 0x55, 0x4e,
+	0x53, 0x50, 0x45, 0x43, 0x49, 0x46, 0x49, 0x45, 0x44, 0x10, 0x00, 0x12, 0x17, 0x0a, 0x13, 0x52,
+	0x45, 0x50, 0x4f, 0x52, 0x54, 0x5f, 0x49, 0x4e, 0x54, 0x45, 0x52, 0x56, 0x41, 0x4c, 0x5f, 0x44,
+	0x41, 0x59, 0x10, 0x01, 0x12, 0x18, 0x0a, 0x14, 0x52, 0x45, 0x50, 0x4f, 0x52, 0x54, 0x5f, 0x49,
+	0x4e, 0x54, 0x45, 0x52, 0x56, 0x41, 0x4c, 0x5f, 0x57, 0x45, 0x45, 0x4b, 0x10, 0x02, 0x12, 0x19,
+	0x0a, 0x15, 0x52, 0x45, 0x50, 0x4f, 0x52, 0x54, 0x5f, 0x49, 0x4e, 0x54, 0x45, 0x52, 0x56, 0x41,
+	0x4c, 0x5f, 0x4d, 0x4f, 0x4e, 0x54, 0x48, 0x10, 0x03, 0x32, 0xba, 0x02, 0x0a, 0x10, 0x52, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x69, 0x6e, 0x67, 0x53, 0x65, 0x72, 0x76, 0x69, 0x63, 0x65, 0x12, 0x48,
+	0x0a, 0x12, 0x47, 0x65, 0x74, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79, 0x52, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x12, 0x16, 0x2e, 0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x52,
+	0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x62,
+	0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x4f, 0x63, 0x63, 0x75, 0x70, 0x61, 0x6e, 0x63, 0x79,
+	0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x22, 0x00, 0x12, 0x44, 0x0a, 0x10, 0x47, 0x65, 0x74, 0x52,
+	0x65, 0x76, 0x65, 0x6e, 0x75, 0x65, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x16, 0x2e, 0x62,
+	0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71,
+	0x75, 0x65, 0x73, 0x74, 0x1a, 0x16, 0x2e, 0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x52,
+	0x65, 0x76, 0x65, 0x6e, 0x75, 0x65, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x22, 0x00, 0x12, 0x4e,
+	0x0a, 0x15, 0x47, 0x65, 0x74, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c, 0x6c, 0x61, 0x74, 0x69, 0x6f,
+	0x6e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x12, 0x16, 0x2e, 0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e,
+	0x67, 0x2e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x1b, 0x2e, 0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x43, 0x61, 0x6e, 0x63, 0x65, 0x6c,
+	0x6c, 0x61, 0x74, 0x69, 0x6f, 0x6e, 0x52, 0x65, 0x70, 0x6f, 0x72, 0x74, 0x22, 0x00, 0x12, 0x46,
+	0x0a, 0x11, 0x47, 0x65, 0x74, 0x4c, 0x65, 0x61, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x52, 0x65, 0x70,
+	0x6f, 0x72, 0x74, 0x12, 0x16, 0x2e, 0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x52, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x17, 0x2e, 0x62, 0x6f,
+	0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2e, 0x4c, 0x65, 0x61, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x52, 0x65,
+	0x70, 0x6f, 0x72, 0x74, 0x22, 0x00, 0x42, 0x1b, 0x5a, 0x19, 0x74, 0x69, 0x63, 0x6b, 0x65, 0x74,
+	0x2d, 0x62, 0x6f, 0x6f, 0x6b, 0x69, 0x6e, 0x67, 0x2d, 0x61, 0x70, 0x70, 0x2f, 0x64, 0x6f, 0x6d,
+	0x61, 0x69, 0x6e, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+}
+
+var (
+	file_reporting_proto_rawDescOnce sync.Once
+	file_reporting_proto_rawDescData = file_reporting_proto_rawDesc
+)
+
+func file_reporting_proto_rawDescGZIP() []byte {
+	file_reporting_proto_rawDescOnce.Do(func() {
+		file_reporting_proto_rawDescData = protoimpl.X.CompressGZIP(file_reporting_proto_rawDescData)
+	})
+	return file_reporting_proto_rawDescData
+}
+
+var file_reporting_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_reporting_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_reporting_proto_goTypes = []any{
+	(ReportInterval)(0),           // 0: booking.ReportInterval
+	(*ReportRequest)(nil),         // 1: booking.ReportRequest
+	(*OccupancyRow)(nil),          // 2: booking.OccupancyRow
+	(*OccupancyReport)(nil),       // 3: booking.OccupancyReport
+	(*RevenueRow)(nil),            // 4: booking.RevenueRow
+	(*RevenueReport)(nil),         // 5: booking.RevenueReport
+	(*CancellationRow)(nil),       // 6: booking.CancellationRow
+	(*CancellationReport)(nil),    // 7: booking.CancellationReport
+	(*LeadTimeRow)(nil),           // 8: booking.LeadTimeRow
+	(*LeadTimeReport)(nil),        // 9: booking.LeadTimeReport
+	(*timestamppb.Timestamp)(nil), // 10: google.protobuf.Timestamp
+}
+var file_reporting_proto_depIdxs = []int32{
+	10, // 0: booking.ReportRequest.since:type_name -> google.protobuf.Timestamp
+	10, // 1: booking.ReportRequest.until:type_name -> google.protobuf.Timestamp
+	0,  // 2: booking.ReportRequest.interval:type_name -> booking.ReportInterval
+	10, // 3: booking.OccupancyRow.departure:type_name -> google.protobuf.Timestamp
+	2,  // 4: booking.OccupancyReport.rows:type_name -> booking.OccupancyRow
+	4,  // 5: booking.RevenueReport.rows:type_name -> booking.RevenueRow
+	6,  // 6: booking.CancellationReport.rows:type_name -> booking.CancellationRow
+	8,  // 7: booking.LeadTimeReport.rows:type_name -> booking.LeadTimeRow
+	1,  // 8: booking.ReportingService.GetOccupancyReport:input_type -> booking.ReportRequest
+	1,  // 9: booking.ReportingService.GetRevenueReport:input_type -> booking.ReportRequest
+	1,  // 10: booking.ReportingService.GetCancellationReport:input_type -> booking.ReportRequest
+	1,  // 11: booking.ReportingService.GetLeadTimeReport:input_type -> booking.ReportRequest
+	3,  // 12: booking.ReportingService.GetOccupancyReport:output_type -> booking.OccupancyReport
+	5,  // 13: booking.ReportingService.GetRevenueReport:output_type -> booking.RevenueReport
+	7,  // 14: booking.ReportingService.GetCancellationReport:output_type -> booking.CancellationReport
+	9,  // 15: booking.ReportingService.GetLeadTimeReport:output_type -> booking.LeadTimeReport
+	12, // [12:16] is the sub-list for method output_type
+	8,  // [8:12] is the sub-list for method input_type
+	8,  // [8:8] is the sub-list for extension type_name
+	8,  // [8:8] is the sub-list for extension extendee
+	0,  // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_reporting_proto_init() }
+func file_reporting_proto_init() {
+	if File_reporting_proto != nil {
+		return
+	}
+	if !protoimpl.UnsafeEnabled {
+		file_reporting_proto_msgTypes[0].Exporter = func(v any, i int) any {
+			switch v := v.(*ReportRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reporting_proto_msgTypes[1].Exporter = func(v any, i int) any {
+			switch v := v.(*OccupancyRow); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reporting_proto_msgTypes[2].Exporter = func(v any, i int) any {
+			switch v := v.(*OccupancyReport); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reporting_proto_msgTypes[3].Exporter = func(v any, i int) any {
+			switch v := v.(*RevenueRow); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reporting_proto_msgTypes[4].Exporter = func(v any, i int) any {
+			switch v := v.(*RevenueReport); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reporting_proto_msgTypes[5].Exporter = func(v any, i int) any {
+			switch v := v.(*CancellationRow); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reporting_proto_msgTypes[6].Exporter = func(v any, i int) any {
+			switch v := v.(*CancellationReport); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reporting_proto_msgTypes[7].Exporter = func(v any, i int) any {
+			switch v := v.(*LeadTimeRow); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_reporting_proto_msgTypes[8].Exporter = func(v any, i int) any {
+			switch v := v.(*LeadTimeReport); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: file_reporting_proto_rawDesc,
+			NumEnums:      1,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_reporting_proto_goTypes,
+		DependencyIndexes: file_reporting_proto_depIdxs,
+		EnumInfos:         file_reporting_proto_enumTypes,
+		MessageInfos:      file_reporting_proto_msgTypes,
+	}.Build()
+	File_reporting_proto = out.File
+	file_reporting_proto_rawDesc = nil
+	file_reporting_proto_goTypes = nil
+	file_reporting_proto_depIdxs = nil
+}
@@ -0,0 +1,237 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.0
+// source: reporting.proto
+
+package domain
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	ReportingService_GetOccupancyReport_FullMethodName    = "/booking.ReportingService/GetOccupancyReport"
+	ReportingService_GetRevenueReport_FullMethodName      = "/booking.ReportingService/GetRevenueReport"
+	ReportingService_GetCancellationReport_FullMethodName = "/booking.ReportingService/GetCancellationReport"
+	ReportingService_GetLeadTimeReport_FullMethodName     = "/booking.ReportingService/GetLeadTimeReport"
+)
+
+// ReportingServiceClient is the client API for ReportingService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Aggregated reports on the bookings, served as CSV by the REST gateway
+type ReportingServiceClient interface {
+	GetOccupancyReport(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (*OccupancyReport, error)
+	GetRevenueReport(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (*RevenueReport, error)
+	GetCancellationReport(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (*CancellationReport, error)
+	GetLeadTimeReport(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (*LeadTimeReport, error)
+}
+
+type reportingServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewReportingServiceClient(cc grpc.ClientConnInterface) ReportingServiceClient {
+	return &reportingServiceClient{cc}
+}
+
+func (c *reportingServiceClient) GetOccupancyReport(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (*OccupancyReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(OccupancyReport)
+	err := c.cc.Invoke(ctx, ReportingService_GetOccupancyReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportingServiceClient) GetRevenueReport(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (*RevenueReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RevenueReport)
+	err := c.cc.Invoke(ctx, ReportingService_GetRevenueReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportingServiceClient) GetCancellationReport(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (*CancellationReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CancellationReport)
+	err := c.cc.Invoke(ctx, ReportingService_GetCancellationReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *reportingServiceClient) GetLeadTimeReport(ctx context.Context, in *ReportRequest, opts ...grpc.CallOption) (*LeadTimeReport, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LeadTimeReport)
+	err := c.cc.Invoke(ctx, ReportingService_GetLeadTimeReport_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// ReportingServiceServer is the server API for ReportingService service.
+// All implementations should embed UnimplementedReportingServiceServer
+// for forward compatibility.
+//
+// Aggregated reports on the bookings, served as CSV by the REST gateway
+type ReportingServiceServer interface {
+	GetOccupancyReport(context.Context, *ReportRequest) (*OccupancyReport, error)
+	GetRevenueReport(context.Context, *ReportRequest) (*RevenueReport, error)
+	GetCancellationReport(context.Context, *ReportRequest) (*CancellationReport, error)
+	GetLeadTimeReport(context.Context, *ReportRequest) (*LeadTimeReport, error)
+}
+
+// UnimplementedReportingServiceServer should be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedReportingServiceServer struct{}
+
+func (UnimplementedReportingServiceServer) GetOccupancyReport(context.Context, *ReportRequest) (*OccupancyReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetOccupancyReport not implemented")
+}
+func (UnimplementedReportingServiceServer) GetRevenueReport(context.Context, *ReportRequest) (*RevenueReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetRevenueReport not implemented")
+}
+func (UnimplementedReportingServiceServer) GetCancellationReport(context.Context, *ReportRequest) (*CancellationReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetCancellationReport not implemented")
+}
+func (UnimplementedReportingServiceServer) GetLeadTimeReport(context.Context, *ReportRequest) (*LeadTimeReport, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetLeadTimeReport not implemented")
+}
+func (UnimplementedReportingServiceServer) testEmbeddedByValue() {}
+
+// UnsafeReportingServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to ReportingServiceServer will
+// result in compilation errors.
+type UnsafeReportingServiceServer interface {
+	mustEmbedUnimplementedReportingServiceServer()
+}
+
+func RegisterReportingServiceServer(s grpc.ServiceRegistrar, srv ReportingServiceServer) {
+	// If the following call pancis, it indicates UnimplementedReportingServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&ReportingService_ServiceDesc, srv)
+}
+
+func _ReportingService_GetOccupancyReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportingServiceServer).GetOccupancyReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportingService_GetOccupancyReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportingServiceServer).GetOccupancyReport(ctx, req.(*ReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportingService_GetRevenueReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportingServiceServer).GetRevenueReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportingService_GetRevenueReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportingServiceServer).GetRevenueReport(ctx, req.(*ReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportingService_GetCancellationReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportingServiceServer).GetCancellationReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportingService_GetCancellationReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportingServiceServer).GetCancellationReport(ctx, req.(*ReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _ReportingService_GetLeadTimeReport_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ReportRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(ReportingServiceServer).GetLeadTimeReport(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: ReportingService_GetLeadTimeReport_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(ReportingServiceServer).GetLeadTimeReport(ctx, req.(*ReportRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// ReportingService_ServiceDesc is the grpc.ServiceDesc for ReportingService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var ReportingService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "booking.ReportingService",
+	HandlerType: (*ReportingServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetOccupancyReport",
+			Handler:    _ReportingService_GetOccupancyReport_Handler,
+		},
+		{
+			MethodName: "GetRevenueReport",
+			Handler:    _ReportingService_GetRevenueReport_Handler,
+		},
+		{
+			MethodName: "GetCancellationReport",
+			Handler:    _ReportingService_GetCancellationReport_Handler,
+		},
+		{
+			MethodName: "GetLeadTimeReport",
+			Handler:    _ReportingService_GetLeadTimeReport_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "reporting.proto",
+}
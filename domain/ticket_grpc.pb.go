@@ -0,0 +1,237 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v5.28.0
+// source: ticket.proto
+
+package domain
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	TicketService_ValidateTicket_FullMethodName         = "/booking.TicketService/ValidateTicket"
+	TicketService_ListTicketSigningKeys_FullMethodName  = "/booking.TicketService/ListTicketSigningKeys"
+	TicketService_RotateTicketSigningKey_FullMethodName = "/booking.TicketService/RotateTicketSigningKey"
+	TicketService_RetireTicketSigningKey_FullMethodName = "/booking.TicketService/RetireTicketSigningKey"
+)
+
+// TicketServiceClient is the client API for TicketService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Ticket checks for conductors and management of the keys tokens are signed with
+type TicketServiceClient interface {
+	ValidateTicket(ctx context.Context, in *ValidateTicketRequest, opts ...grpc.CallOption) (*ValidateTicketResponse, error)
+	ListTicketSigningKeys(ctx context.Context, in *ListTicketSigningKeysRequest, opts ...grpc.CallOption) (*ListTicketSigningKeysResponse, error)
+	RotateTicketSigningKey(ctx context.Context, in *RotateTicketSigningKeyRequest, opts ...grpc.CallOption) (*TicketSigningKey, error)
+	RetireTicketSigningKey(ctx context.Context, in *RetireTicketSigningKeyRequest, opts ...grpc.CallOption) (*TicketSigningKey, error)
+}
+
+type ticketServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewTicketServiceClient(cc grpc.ClientConnInterface) TicketServiceClient {
+	return &ticketServiceClient{cc}
+}
+
+func (c *ticketServiceClient) ValidateTicket(ctx context.Context, in *ValidateTicketRequest, opts ...grpc.CallOption) (*ValidateTicketResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ValidateTicketResponse)
+	err := c.cc.Invoke(ctx, TicketService_ValidateTicket_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ticketServiceClient) ListTicketSigningKeys(ctx context.Context, in *ListTicketSigningKeysRequest, opts ...grpc.CallOption) (*ListTicketSigningKeysResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListTicketSigningKeysResponse)
+	err := c.cc.Invoke(ctx, TicketService_ListTicketSigningKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ticketServiceClient) RotateTicketSigningKey(ctx context.Context, in *RotateTicketSigningKeyRequest, opts ...grpc.CallOption) (*TicketSigningKey, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TicketSigningKey)
+	err := c.cc.Invoke(ctx, TicketService_RotateTicketSigningKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *ticketServiceClient) RetireTicketSigningKey(ctx context.Context, in *RetireTicketSigningKeyRequest, opts ...grpc.CallOption) (*TicketSigningKey, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TicketSigningKey)
+	err := c.cc.Invoke(ctx, TicketService_RetireTicketSigningKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// TicketServiceServer is the server API for TicketService service.
+// All implementations should embed UnimplementedTicketServiceServer
+// for forward compatibility.
+//
+// Ticket checks for conductors and management of the keys tokens are signed with
+type TicketServiceServer interface {
+	ValidateTicket(context.Context, *ValidateTicketRequest) (*ValidateTicketResponse, error)
+	ListTicketSigningKeys(context.Context, *ListTicketSigningKeysRequest) (*ListTicketSigningKeysResponse, error)
+	RotateTicketSigningKey(context.Context, *RotateTicketSigningKeyRequest) (*TicketSigningKey, error)
+	RetireTicketSigningKey(context.Context, *RetireTicketSigningKeyRequest) (*TicketSigningKey, error)
+}
+
+// UnimplementedTicketServiceServer should be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedTicketServiceServer struct{}
+
+func (UnimplementedTicketServiceServer) ValidateTicket(context.Context, *ValidateTicketRequest) (*ValidateTicketResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ValidateTicket not implemented")
+}
+func (UnimplementedTicketServiceServer) ListTicketSigningKeys(context.Context, *ListTicketSigningKeysRequest) (*ListTicketSigningKeysResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTicketSigningKeys not implemented")
+}
+func (UnimplementedTicketServiceServer) RotateTicketSigningKey(context.Context, *RotateTicketSigningKeyRequest) (*TicketSigningKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateTicketSigningKey not implemented")
+}
+func (UnimplementedTicketServiceServer) RetireTicketSigningKey(context.Context, *RetireTicketSigningKeyRequest) (*TicketSigningKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RetireTicketSigningKey not implemented")
+}
+func (UnimplementedTicketServiceServer) testEmbeddedByValue() {}
+
+// UnsafeTicketServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to TicketServiceServer will
+// result in compilation errors.
+type UnsafeTicketServiceServer interface {
+	mustEmbedUnimplementedTicketServiceServer()
+}
+
+func RegisterTicketServiceServer(s grpc.ServiceRegistrar, srv TicketServiceServer) {
+	// If the following call pancis, it indicates UnimplementedTicketServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&TicketService_ServiceDesc, srv)
+}
+
+func _TicketService_ValidateTicket_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ValidateTicketRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TicketServiceServer).ValidateTicket(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TicketService_ValidateTicket_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TicketServiceServer).ValidateTicket(ctx, req.(*ValidateTicketRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TicketService_ListTicketSigningKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListTicketSigningKeysRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TicketServiceServer).ListTicketSigningKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TicketService_ListTicketSigningKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TicketServiceServer).ListTicketSigningKeys(ctx, req.(*ListTicketSigningKeysRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TicketService_RotateTicketSigningKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateTicketSigningKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TicketServiceServer).RotateTicketSigningKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TicketService_RotateTicketSigningKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TicketServiceServer).RotateTicketSigningKey(ctx, req.(*RotateTicketSigningKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _TicketService_RetireTicketSigningKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RetireTicketSigningKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(TicketServiceServer).RetireTicketSigningKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: TicketService_RetireTicketSigningKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(TicketServiceServer).RetireTicketSigningKey(ctx, req.(*RetireTicketSigningKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// TicketService_ServiceDesc is the grpc.ServiceDesc for TicketService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var TicketService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "booking.TicketService",
+	HandlerType: (*TicketServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ValidateTicket",
+			Handler:    _TicketService_ValidateTicket_Handler,
+		},
+		{
+			MethodName: "ListTicketSigningKeys",
+			Handler:    _TicketService_ListTicketSigningKeys_Handler,
+		},
+		{
+			MethodName: "RotateTicketSigningKey",
+			Handler:    _TicketService_RotateTicketSigningKey_Handler,
+		},
+		{
+			MethodName: "RetireTicketSigningKey",
+			Handler:    _TicketService_RetireTicketSigningKey_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "ticket.proto",
+}
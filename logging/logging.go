@@ -0,0 +1,117 @@
+// Package logging builds the slog loggers of the server and the client. Log
+// records carry the request id of their context, and the attributes holding
+// personal data are redacted unless redaction is turned off.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+
+	// RequestIdKey is the attribute of the request id
+	RequestIdKey = "request_id"
+
+	// attributes with these keys hold personal data
+	emailKey     = "email"
+	firstNameKey = "first_name"
+	lastNameKey  = "last_name"
+)
+
+// Email is the attribute of an email address, which is redacted.
+func Email(email string) slog.Attr {
+	return slog.String(emailKey, email)
+}
+
+// FirstName is the attribute of a first name, which is redacted.
+func FirstName(name string) slog.Attr {
+	return slog.String(firstNameKey, name)
+}
+
+// LastName is the attribute of a last name, which is redacted.
+func LastName(name string) slog.Attr {
+	return slog.String(lastNameKey, name)
+}
+
+// New creates a logger writing records of at least level to w as text or JSON.
+// With redact, emails keep only their first letter and domain, and names only
+// their first letter.
+func New(w io.Writer, format string, level slog.Level, redact bool) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level}
+	if redact {
+		options.ReplaceAttr = redactAttr
+	}
+	var handler slog.Handler
+	switch format {
+	case FormatText:
+		handler = slog.NewTextHandler(w, options)
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", format)
+	}
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+type requestIdContextKey struct{}
+
+// WithRequestId attaches the correlation id of a request to ctx, so that it
+// is logged with every record logged with ctx.
+func WithRequestId(ctx context.Context, requestId string) context.Context {
+	return context.WithValue(ctx, requestIdContextKey{}, requestId)
+}
+
+// RequestId returns the correlation id attached to ctx, or "" without one.
+func RequestId(ctx context.Context) string {
+	requestId, _ := ctx.Value(requestIdContextKey{}).(string)
+	return requestId
+}
+
+// contextHandler adds the request id of the context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestId := RequestId(ctx); requestId != "" {
+		record.AddAttrs(slog.String(RequestIdKey, requestId))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
+
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	switch attr.Key {
+	case emailKey:
+		local, domain, found := strings.Cut(attr.Value.String(), "@")
+		if found {
+			return slog.String(attr.Key, redactName(local)+"@"+domain)
+		}
+		return slog.String(attr.Key, redactName(local))
+	case firstNameKey, lastNameKey:
+		return slog.String(attr.Key, redactName(attr.Value.String()))
+	}
+	return attr
+}
+
+func redactName(name string) string {
+	if name == "" {
+		return ""
+	}
+	first, _ := utf8.DecodeRuneInString(name)
+	return string(first) + "***"
+}
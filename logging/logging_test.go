@@ -0,0 +1,43 @@
+package logging_test
+
+import (
+	"ticket-booking-app/logging"
+	"github.com/stretchr/testify/assert"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+)
+
+func TestShouldRedactPersonalDataAndAddTheRequestId(t *testing.T) {
+    var out bytes.Buffer
+    logger, err := logging.New(&out, logging.FormatJSON, slog.LevelInfo, true)
+    if err != nil {
+        t.Fatalf("Error in creating logger %v ", err)
+    }
+    ctx := logging.WithRequestId(context.Background(), "req-1")
+    logger.InfoContext(ctx, "Added new user", logging.Email("vg@gmail.com"), logging.FirstName("Vrushali"),
+        logging.LastName("Ghadge"), "pnr", "ABC123")
+
+    var record map[string]any
+    if err := json.Unmarshal(out.Bytes(), &record); err != nil {
+        t.Fatalf("Error in decoding log record %v ", err)
+    }
+    assert.Equal(t, "v***@gmail.com", record["email"])
+    assert.Equal(t, "V***", record["first_name"])
+    assert.Equal(t, "G***", record["last_name"])
+    assert.Equal(t, "ABC123", record["pnr"], "Other attributes should be kept")
+    assert.Equal(t, "req-1", record[logging.RequestIdKey])
+}
+
+func TestShouldLogPersonalDataWhenRedactionIsOff(t *testing.T) {
+    var out bytes.Buffer
+    logger, _ := logging.New(&out, logging.FormatText, slog.LevelInfo, false)
+    logger.With(logging.Email("vg@gmail.com")).Info("Notification")
+    assert.Contains(t, out.String(), "email=vg@gmail.com")
+    assert.NotContains(t, out.String(), logging.RequestIdKey, "Records without a request should not have an id")
+
+    _, err := logging.New(&out, "xml", slog.LevelInfo, true)
+    assert.Error(t, err)
+}
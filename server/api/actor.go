@@ -0,0 +1,80 @@
+package api
+
+import (
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"context"
+	"net"
+)
+
+const (
+	// ActorHeader carries the name a client claims to act as
+	ActorHeader = "x-actor"
+	// ForwardedForHeader carries the client address of calls relayed by a local proxy such as the REST gateway
+	ForwardedForHeader = "x-forwarded-for"
+
+	anonymousActor = "anonymous"
+)
+
+type actorContextKey struct{}
+
+// WithActor attaches the authenticated identity of the caller to ctx.
+func WithActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// isVerifiedActor reports whether the caller was authenticated, such as a
+// service identified by its client certificate.
+func isVerifiedActor(ctx context.Context) bool {
+	actor, ok := ctx.Value(actorContextKey{}).(string)
+	return ok && actor != ""
+}
+
+// ActorFromContext returns the authenticated identity of the caller. Without
+// one, the identity claimed in the x-actor header is returned with an
+// "unverified:" prefix, and "anonymous" when there is no header either.
+func ActorFromContext(ctx context.Context) string {
+	if actor, ok := ctx.Value(actorContextKey{}).(string); ok && actor != "" {
+		return actor
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(ActorHeader); len(values) > 0 && values[0] != "" {
+		return "unverified:" + values[0]
+	}
+	return anonymousActor
+}
+
+// clientAddress returns the address of the caller. The forwarded address is
+// only trusted from local peers, which is where the REST gateway connects from.
+func clientAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	if tcpAddr, isTCP := p.Addr.(*net.TCPAddr); isTCP && tcpAddr.IP.IsLoopback() {
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get(ForwardedForHeader); len(values) > 0 && values[0] != "" {
+			return values[0]
+		}
+	}
+	return p.Addr.String()
+}
+
+// clientHost is the address of the caller without its port, which changes
+// with every connection.
+func clientHost(ctx context.Context) string {
+	address := clientAddress(ctx)
+	if host, _, err := net.SplitHostPort(address); err == nil {
+		return host
+	}
+	return address
+}
+
+// callerKey tells callers apart: authenticated actors by their name, other
+// callers by their address, as the x-actor header they send is their choice.
+func callerKey(ctx context.Context) string {
+	if isVerifiedActor(ctx) {
+		return ActorFromContext(ctx)
+	}
+	return "ip:" + clientHost(ctx)
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"database/sql"
+	"context"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAuditPageSize = 100
+	maxAuditPageSize     = 1000
+)
+
+// AdminService exposes operational data such as the audit log, imports and
+// exports bookings in bulk and backs up and restores the database.
+type AdminService struct {
+	db *sql.DB
+	// bookingService allocates the seats of imported and restored bookings
+	bookingService *BookingService
+	backups        *BackupManager
+}
+
+func NewAdminService(dbInstance *sql.DB, bookingService *BookingService) *AdminService {
+	return &AdminService{
+		db: dbInstance,
+		bookingService: bookingService,
+	}
+}
+
+// SetBackupManager enables the backup RPCs, which fail without one.
+func (a *AdminService) SetBackupManager(backups *BackupManager) {
+	a.backups = backups
+}
+
+// ListAuditEntries returns audit entries in the order they were written. A
+// resource may be given as a booking id or its PNR. Entries hold the state of
+// bookings, so only services may list them.
+func (a *AdminService) ListAuditEntries(ctx context.Context, req *pb.ListAuditEntriesRequest) (*pb.ListAuditEntriesResponse, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	where := []string{"a_seq > ?"}
+	args := []any{req.GetAfterSequence()}
+
+	if resource := req.GetResource(); resource != "" {
+		var ticketId string
+		if err := a.db.QueryRowContext(ctx, "SELECT t_id FROM tickets WHERE t_pnr = ?", normalizePnr(resource)).Scan(&ticketId); err == nil {
+			resource = ticketId
+		}
+		where = append(where, "a_resource = ?")
+		args = append(args, resource)
+	}
+	if req.GetActor() != "" {
+		where = append(where, "a_actor = ?")
+		args = append(args, req.GetActor())
+	}
+	if req.GetAction() != "" {
+		where = append(where, "a_action = ?")
+		args = append(args, req.GetAction())
+	}
+	if req.GetSince() != nil {
+		where = append(where, "a_at >= ?")
+		args = append(args, req.GetSince().GetSeconds())
+	}
+	if req.GetUntil() != nil {
+		where = append(where, "a_at < ?")
+		args = append(args, req.GetUntil().GetSeconds())
+	}
+
+	pageSize := int(req.GetPageSize())
+	if pageSize <= 0 {
+		pageSize = defaultAuditPageSize
+	} else if pageSize > maxAuditPageSize {
+		pageSize = maxAuditPageSize
+	}
+	args = append(args, pageSize)
+
+	rows, err := a.db.QueryContext(ctx, "SELECT "+auditColumns+" FROM audit_log WHERE "+strings.Join(where, " AND ")+
+		" ORDER BY a_seq LIMIT ?", args...)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while reading audit log: %v", err)
+	}
+	defer rows.Close()
+
+	response := &pb.ListAuditEntriesResponse{}
+	for rows.Next() {
+		entry, at, err := scanAuditEntry(rows)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Error while reading audit log: %v", err)
+		}
+		entry.Time = timestamppb.New(time.Unix(at, 0))
+		response.Entries = append(response.Entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while reading audit log: %v", err)
+	}
+	if len(response.Entries) == pageSize {
+		response.NextAfterSequence = response.Entries[pageSize-1].GetSequence()
+	}
+	return response, nil
+}
+
+// VerifyAuditLog recomputes the hash chain of the whole audit log.
+func (a *AdminService) VerifyAuditLog(ctx context.Context, req *pb.VerifyAuditLogRequest) (*pb.VerifyAuditLogResponse, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	checked, firstInvalid, err := VerifyAuditChain(a.db)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while verifying audit log: %v", err)
+	}
+	return &pb.VerifyAuditLogResponse{
+		Valid:                firstInvalid == 0,
+		EntriesChecked:       checked,
+		FirstInvalidSequence: firstInvalid,
+	}, nil
+}
@@ -0,0 +1,87 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"context"
+	"testing"
+)
+
+func TestShouldAuditEveryBookingMutation(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    adminService := api.NewAdminService(db, bookingService)
+    ctx := api.WithActor(context.TODO(), "agent-7")
+
+    booking, err := bookingService.CreateBooking(ctx, createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    newSeat := (booking.GetSeat() + 1) % api.MaxSeatsPerSection
+    if _, err := bookingService.ModifySeatByUser(ctx, &pb.SeatModificationRequest{BookingId: booking.GetPnr(),
+        Section: booking.GetSection(), Seat: newSeat}); err != nil {
+        t.Fatalf("Error in modifying booking %v ", err)
+    }
+    if _, err := bookingService.RemoveBookingByUser(ctx, &pb.RemoveBookingByUserRequest{BookingId: booking.GetPnr()}); err != nil {
+        t.Fatalf("Error in cancelling booking %v ", err)
+    }
+
+    _, err = adminService.ListAuditEntries(ctx, &pb.ListAuditEntriesRequest{Resource: booking.GetPnr()})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "Only services should read the audit log")
+    auditor := api.WithActor(context.TODO(), "service:auditor")
+    audit, err := adminService.ListAuditEntries(auditor, &pb.ListAuditEntriesRequest{Resource: booking.GetPnr()})
+    if err != nil {
+        t.Fatalf("Error in listing audit entries %v ", err)
+    }
+    var actions []string
+    for _, entry := range audit.GetEntries() {
+        actions = append(actions, entry.GetAction())
+        assert.Equal(t, "agent-7", entry.GetActor())
+        assert.Equal(t, booking.GetId(), entry.GetResource())
+    }
+    assert.Equal(t, []string{api.AuditActionBookingCreate, api.AuditActionBookingModifySeat, api.AuditActionBookingCancel}, actions)
+    assert.Empty(t, audit.GetEntries()[0].GetBefore(), "Creation has no previous state")
+    assert.Equal(t, audit.GetEntries()[0].GetHash(), audit.GetEntries()[1].GetPreviousHash(), "Entries should be chained")
+    assert.NotContains(t, audit.GetEntries()[0].GetAfter(), EMAIL, "Audit state should not copy personal data")
+
+    _, err = adminService.VerifyAuditLog(context.TODO(), &pb.VerifyAuditLogRequest{})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "Only services should verify the audit log")
+    verified, err := adminService.VerifyAuditLog(auditor, &pb.VerifyAuditLogRequest{})
+    if err != nil {
+        t.Fatalf("Error in verifying audit log %v ", err)
+    }
+    assert.True(t, verified.GetValid())
+    assert.EqualValues(t, 3, verified.GetEntriesChecked())
+}
+
+func TestShouldDetectTamperedAuditLog(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    adminService := api.NewAdminService(db, bookingService)
+    booking, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    if _, err := bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: booking.GetPnr(), User: &pb.User{Email: EMAIL}}); err != nil {
+        t.Fatalf("Error in cancelling booking %v ", err)
+    }
+
+    _, err = db.Exec("UPDATE audit_log SET a_actor = 'someone-else'")
+    assert.Error(t, err, "Audit log should be append-only")
+    _, err = db.Exec("DELETE FROM audit_log")
+    assert.Error(t, err, "Audit log should be append-only")
+
+    // bypass the triggers the way someone with file access could
+    mustExec(t, db, "DROP TRIGGER audit_log_no_update")
+    mustExec(t, db, "UPDATE audit_log SET a_actor = 'someone-else' WHERE a_seq = 2")
+
+    verified, err := adminService.VerifyAuditLog(api.WithActor(context.TODO(), "service:auditor"), &pb.VerifyAuditLogRequest{})
+    if err != nil {
+        t.Fatalf("Error in verifying audit log %v ", err)
+    }
+    assert.False(t, verified.GetValid())
+    assert.EqualValues(t, 2, verified.GetFirstInvalidSequence())
+}
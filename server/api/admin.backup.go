@@ -0,0 +1,86 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// BackupDatabase takes a backup of the running database into the backup
+// directory.
+func (a *AdminService) BackupDatabase(ctx context.Context, req *pb.BackupDatabaseRequest) (*pb.Backup, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	if a.backups == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "Backups are not configured")
+	}
+	backup, err := a.backups.Backup(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while backing up database: %v", err)
+	}
+	slog.InfoContext(ctx, "Database backed up", "backup", backup.Name, "size", backup.Size)
+	return backupMessage(backup), nil
+}
+
+func (a *AdminService) ListBackups(ctx context.Context, req *pb.ListBackupsRequest) (*pb.ListBackupsResponse, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	if a.backups == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "Backups are not configured")
+	}
+	backups, err := a.backups.List()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while listing backups: %v", err)
+	}
+	response := &pb.ListBackupsResponse{}
+	for _, backup := range backups {
+		response.Backups = append(response.Backups, backupMessage(backup))
+	}
+	return response, nil
+}
+
+// RestoreDatabase replaces the database with a verified backup, migrates it to
+// the current schema, rebuilds the seat allocator from its bookings and signs
+// tickets with its signing keys. The restore is recorded in the restored audit
+// log.
+func (a *AdminService) RestoreDatabase(ctx context.Context, req *pb.RestoreDatabaseRequest) (*pb.RestoreDatabaseResponse, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	if a.backups == nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "Backups are not configured")
+	}
+	backup, version, err := a.backups.Restore(ctx, req.GetName())
+	if errors.Is(err, ErrBackupNotFound) {
+		return nil, status.Errorf(codes.NotFound, "Backup %s doesn't exist", req.GetName())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.FailedPrecondition, "Backup %s can't be restored: %v", req.GetName(), err)
+	}
+	if err := MigrateDatabase(a.db); err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while migrating restored database: %v", err)
+	}
+	seats, err := a.bookingService.ReloadSeats(ctx)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while reloading seats: %v", err)
+	}
+	// the active signing key may not exist in the restored database
+	a.bookingService.ticketSigner.reload()
+
+	response := &pb.RestoreDatabaseResponse{Backup: backupMessage(backup), SchemaVersion: int32(version), BookedSeats: int32(seats)}
+	if err := appendAuditEntry(ctx, a.db, AuditActionAdminRestore, backup.Name, nil, response); err != nil {
+		slog.ErrorContext(ctx, "Restore could not be audited", "backup", backup.Name, "error", err)
+	}
+	slog.WarnContext(ctx, "Database restored", "backup", backup.Name, "schema_version", version, "booked_seats", seats)
+	return response, nil
+}
+
+func backupMessage(backup BackupFile) *pb.Backup {
+	return &pb.Backup{Name: backup.Name, SizeBytes: backup.Size, CreatedAt: timestamppb.New(backup.CreatedAt)}
+}
@@ -0,0 +1,181 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShouldRestoreBackupAndRebuildSeats(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    allocator, _ := api.NewSeatAllocatorWithLayout([]api.Section{{Name: "A", Seats: 10}}, api.AllocationSequential)
+    bookingService.SetSeatAllocator(allocator)
+    adminService := api.NewAdminService(db, bookingService)
+    operator := api.WithActor(context.TODO(), "service:operator")
+    _, err := adminService.BackupDatabase(operator, &pb.BackupDatabaseRequest{})
+    assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Backups should need a directory")
+
+    backups, err := api.NewBackupManager(db, t.TempDir(), 2)
+    if err != nil {
+        t.Fatalf("Error in creating backup manager %v ", err)
+    }
+    adminService.SetBackupManager(backups)
+    _, err = adminService.BackupDatabase(context.TODO(), &pb.BackupDatabaseRequest{})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "Only services should back up the database")
+    _, err = adminService.ListBackups(context.TODO(), &pb.ListBackupsRequest{})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "Only services should list the backups")
+
+    kept, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    backup, err := adminService.BackupDatabase(operator, &pb.BackupDatabaseRequest{})
+    if err != nil {
+        t.Fatalf("Error in backing up database %v ", err)
+    }
+    assert.Positive(t, backup.GetSizeBytes())
+
+    lost, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, "lost@test.com"))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    if _, err := bookingService.HoldSeat(context.TODO(), &pb.HoldSeatRequest{Section: "A", Seat: 5}); err != nil {
+        t.Fatalf("Error in holding seat %v ", err)
+    }
+
+    _, err = adminService.RestoreDatabase(context.TODO(), &pb.RestoreDatabaseRequest{Name: backup.GetName()})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "Only services should restore the database")
+    restored, err := adminService.RestoreDatabase(operator, &pb.RestoreDatabaseRequest{Name: backup.GetName()})
+    if err != nil {
+        t.Fatalf("Error in restoring database %v ", err)
+    }
+    assert.EqualValues(t, 1, restored.GetBookedSeats())
+    assert.Positive(t, restored.GetSchemaVersion())
+    _, err = bookingService.GetBooking(context.TODO(), &pb.GetBookingRequest{BookingId: lost.GetPnr()})
+    assert.Equal(t, codes.NotFound, status.Code(err), "Bookings made after the backup should be gone")
+    _, err = bookingService.GetBooking(context.TODO(), &pb.GetBookingRequest{BookingId: kept.GetPnr()})
+    assert.NoError(t, err)
+    assert.Equal(t, []int32{kept.GetSeat()}, allocator.OccupiedSeats("A"), "Seats should be rebuilt from the restored bookings")
+    assert.Empty(t, allocator.HeldSeats("A"), "Holds should be released")
+
+    audit, err := adminService.ListAuditEntries(api.WithActor(context.TODO(), "service:auditor"), &pb.ListAuditEntriesRequest{Action: api.AuditActionAdminRestore})
+    if err != nil {
+        t.Fatalf("Error in listing audit entries %v ", err)
+    }
+    if assert.Len(t, audit.GetEntries(), 1) {
+        assert.Equal(t, backup.GetName(), audit.GetEntries()[0].GetResource())
+    }
+
+    _, err = adminService.RestoreDatabase(operator, &pb.RestoreDatabaseRequest{Name: "../" + backup.GetName()})
+    assert.Equal(t, codes.NotFound, status.Code(err), "Only backups of the backup directory should be restored")
+}
+
+func TestShouldSignTicketsWithTheKeysOfTheRestoredBackup(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    ticketService := api.NewTicketService(db, bookingService)
+    adminService := api.NewAdminService(db, bookingService)
+    backups, err := api.NewBackupManager(db, t.TempDir(), 2)
+    if err != nil {
+        t.Fatalf("Error in creating backup manager %v ", err)
+    }
+    adminService.SetBackupManager(backups)
+    operator := api.WithActor(context.TODO(), "service:operator")
+
+    booking, _ := createTicketForValidation(t, bookingService, "Paris")
+    backup, err := adminService.BackupDatabase(operator, &pb.BackupDatabaseRequest{})
+    if err != nil {
+        t.Fatalf("Error in backing up database %v ", err)
+    }
+    if _, err := ticketService.RotateTicketSigningKey(api.WithActor(context.TODO(), "service:key-manager"), &pb.RotateTicketSigningKeyRequest{}); err != nil {
+        t.Fatalf("Error in rotating key %v ", err)
+    }
+    if _, err := adminService.RestoreDatabase(operator, &pb.RestoreDatabaseRequest{Name: backup.GetName()}); err != nil {
+        t.Fatalf("Error in restoring database %v ", err)
+    }
+
+    eTicket, err := bookingService.GetETicket(context.TODO(), &pb.GetETicketRequest{BookingId: booking.GetId(), User: &pb.User{Email: EMAIL}})
+    if err != nil {
+        t.Fatalf("Error in getting e-ticket %v ", err)
+    }
+    validated := validateTicket(t, ticketService, &pb.ValidateTicketRequest{Token: eTicket.GetToken()})
+    assert.Equal(t, pb.TicketValidationResult_TICKET_VALID, validated.GetResult(), "The key rotated after the backup doesn't exist anymore")
+}
+
+func TestShouldKeepTheNewestBackups(t *testing.T) {
+    db := newTestDatabase(t)
+    backups, err := api.NewBackupManager(db, t.TempDir(), 2)
+    if err != nil {
+        t.Fatalf("Error in creating backup manager %v ", err)
+    }
+    var names []string
+    for i := 0; i < 3; i++ {
+        backup, err := backups.Backup(context.TODO())
+        if err != nil {
+            t.Fatalf("Error in backing up database %v ", err)
+        }
+        names = append(names, backup.Name)
+        time.Sleep(2 * time.Millisecond)
+    }
+
+    listed, err := backups.List()
+    if err != nil {
+        t.Fatalf("Error in listing backups %v ", err)
+    }
+    if assert.Len(t, listed, 2) {
+        assert.Equal(t, names[2], listed[0].Name, "The newest backup should come first")
+        assert.Equal(t, names[1], listed[1].Name)
+    }
+}
+
+func TestShouldListBackupsTakenByTheAdminTool(t *testing.T) {
+    db := newTestDatabase(t)
+    dir := t.TempDir()
+    takenAt := time.Date(2026, 10, 19, 8, 30, 0, 0, time.UTC)
+    if err := api.BackupDatabase(context.TODO(), db, filepath.Join(dir, api.BackupName(takenAt))); err != nil {
+        t.Fatalf("Error in backing up database %v ", err)
+    }
+
+    backups, err := api.NewBackupManager(db, dir, 0)
+    if err != nil {
+        t.Fatalf("Error in creating backup manager %v ", err)
+    }
+    listed, err := backups.List()
+    if err != nil {
+        t.Fatalf("Error in listing backups %v ", err)
+    }
+    if assert.Len(t, listed, 1) {
+        assert.Equal(t, takenAt, listed[0].CreatedAt, "The backup should be dated by its name")
+    }
+}
+
+func TestShouldRejectBackupsThatCantBeRestored(t *testing.T) {
+    dir := t.TempDir()
+    corrupt := filepath.Join(dir, "corrupt.db")
+    os.WriteFile(corrupt, []byte("not a database"), 0o600)
+    _, err := api.VerifyBackup(corrupt)
+    assert.Error(t, err)
+
+    newer := filepath.Join(dir, "newer.db")
+    if err := api.BackupDatabase(context.TODO(), newTestDatabase(t), newer); err != nil {
+        t.Fatalf("Error in backing up database %v ", err)
+    }
+    version, err := api.VerifyBackup(newer)
+    assert.NoError(t, err)
+    assert.Positive(t, version)
+
+    backup, _ := sql.Open("sqlite3", newer)
+    defer backup.Close()
+    backup.Exec("INSERT INTO schema_migrations (version) VALUES (999)")
+    _, err = api.VerifyBackup(newer)
+    assert.ErrorContains(t, err, "schema version 999")
+}
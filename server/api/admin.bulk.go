@@ -0,0 +1,546 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"github.com/google/uuid"
+	"bufio"
+	"cmp"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AuditActionBookingImport = "booking.import"
+
+	defaultImportBatchSize = 100
+	maxImportBatchSize     = 1000
+	// maxImportErrors caps the row errors of a response, the counts cover all rows
+	maxImportErrors = 1000
+	exportChunkSize = 32 * 1024
+	// maxNDJSONLine is the longest line of an NDJSON import
+	maxNDJSONLine = 64 * 1024
+
+	exportColumns = "COALESCE(t_pnr, ''), t_id, t_status, t_from, t_to, COALESCE(t_departure_at, 0), t_section, t_seat, t_price, " +
+		"u_user_fname, u_user_lname, COALESCE(u_user_email, ''), COALESCE(t_created_at, 0)"
+)
+
+// bulkColumns are the CSV columns of an export, and the NDJSON keys
+var bulkColumns = []string{"pnr", "ticket_id", "status", "from", "to", "departure", "section", "seat", "price",
+	"first_name", "last_name", "email", "booked_at"}
+
+// importColumns must be in the header of a CSV import
+var importColumns = []string{"first_name", "last_name", "email", "from", "to", "price"}
+
+// bulkRow is a booking of an export or an import. Imports only read the
+// passenger, the journey, the price and optionally the seat.
+type bulkRow struct {
+	Pnr       string `json:"pnr,omitempty"`
+	TicketId  string `json:"ticket_id,omitempty"`
+	Status    string `json:"status,omitempty"`
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Departure string `json:"departure,omitempty"`
+	Section   string `json:"section,omitempty"`
+	Seat      *int32 `json:"seat,omitempty"`
+	Price     int32  `json:"price"`
+	FirstName string `json:"first_name"`
+	LastName  string `json:"last_name"`
+	Email     string `json:"email"`
+	BookedAt  string `json:"booked_at,omitempty"`
+}
+
+func (r *bulkRow) csvRecord() []string {
+	return []string{r.Pnr, r.TicketId, r.Status, r.From, r.To, r.Departure, r.Section, strconv.Itoa(int(*r.Seat)),
+		strconv.Itoa(int(r.Price)), r.FirstName, r.LastName, r.Email, r.BookedAt}
+}
+
+// ExportBookings streams the booked tickets matching the filters with their
+// passengers, ordered by departure and seat, as CSV or NDJSON. Only services
+// may export them.
+func (a *AdminService) ExportBookings(req *pb.ExportBookingsRequest, stream grpc.ServerStreamingServer[pb.ExportChunk]) error {
+	if err := requireServiceActor(stream.Context()); err != nil {
+		return err
+	}
+	where := []string{"1 = 1"}
+	var args []any
+	if !req.GetIncludeCancelled() {
+		where = append(where, "t_status = ?")
+		args = append(args, TicketStatusBooked)
+	}
+	if req.GetSection() != "" {
+		where = append(where, "t_section = ?")
+		args = append(args, req.GetSection())
+	}
+	if req.GetFrom() != "" {
+		where = append(where, "t_from = ?")
+		args = append(args, req.GetFrom())
+	}
+	if req.GetTo() != "" {
+		where = append(where, "t_to = ?")
+		args = append(args, req.GetTo())
+	}
+	if req.GetEmail() != "" {
+		where = append(where, "u_user_email = ?")
+		args = append(args, normalizeEmail(req.GetEmail()))
+	}
+	if req.GetDepartureSince() != nil {
+		where = append(where, "t_departure_at >= ?")
+		args = append(args, req.GetDepartureSince().GetSeconds())
+	}
+	if req.GetDepartureUntil() != nil {
+		where = append(where, "t_departure_at < ?")
+		args = append(args, req.GetDepartureUntil().GetSeconds())
+	}
+
+	rows, err := a.db.QueryContext(stream.Context(), "SELECT "+exportColumns+" FROM tickets JOIN users ON u_id = t_user_id WHERE "+
+		strings.Join(where, " AND ")+" ORDER BY t_departure_at, t_from, t_to, t_section, t_seat", args...)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Error while reading bookings: %v", err)
+	}
+	defer rows.Close()
+
+	out := bufio.NewWriterSize(&exportChunkWriter{stream: stream}, exportChunkSize)
+	var writeRow func(*bulkRow) error
+	if req.GetFormat() == pb.BulkFormat_BULK_FORMAT_NDJSON {
+		encoder := json.NewEncoder(out)
+		writeRow = func(row *bulkRow) error {
+			return encoder.Encode(row)
+		}
+	} else {
+		writer := csv.NewWriter(out)
+		writer.Write(bulkColumns)
+		writeRow = func(row *bulkRow) error {
+			writer.Write(row.csvRecord())
+			writer.Flush()
+			return writer.Error()
+		}
+	}
+	exported := 0
+	for rows.Next() {
+		row := &bulkRow{Seat: new(int32)}
+		var departureAt, bookedAt int64
+		if err := rows.Scan(&row.Pnr, &row.TicketId, &row.Status, &row.From, &row.To, &departureAt, &row.Section, row.Seat,
+			&row.Price, &row.FirstName, &row.LastName, &row.Email, &bookedAt); err != nil {
+			return status.Errorf(codes.Internal, "Error while reading bookings: %v", err)
+		}
+		row.Departure, row.BookedAt = formatUnix(departureAt), formatUnix(bookedAt)
+		if err := writeRow(row); err != nil {
+			return err
+		}
+		exported++
+	}
+	if err := rows.Err(); err != nil {
+		return status.Errorf(codes.Internal, "Error while reading bookings: %v", err)
+	}
+	if err := out.Flush(); err != nil {
+		return err
+	}
+	slog.InfoContext(stream.Context(), "Exported bookings", "bookings", exported, "format", req.GetFormat().String())
+	return nil
+}
+
+func formatUnix(unix int64) string {
+	if unix == 0 {
+		return ""
+	}
+	return time.Unix(unix, 0).UTC().Format(time.RFC3339)
+}
+
+// exportChunkWriter sends everything written to it as an ExportChunk
+type exportChunkWriter struct {
+	stream grpc.ServerStreamingServer[pb.ExportChunk]
+}
+
+func (w *exportChunkWriter) Write(data []byte) (int, error) {
+	if err := w.stream.Send(&pb.ExportChunk{Data: data}); err != nil {
+		return 0, err
+	}
+	return len(data), nil
+}
+
+// ImportBookings books the rows of a CSV or NDJSON file, streamed in parts.
+// Rows are checked and booked in batches, every batch in a transaction. Seats
+// are allocated like CreateBooking does, unless a row names its seat. A row
+// that fails doesn't stop the import and is reported with its line. A dry run
+// rolls every batch back but keeps its seats and tickets until it ends, so it
+// reports what the import would do. Imported bookings are audited but, being
+// known to their passengers already, neither emailed nor sent to webhooks.
+// Only services may import bookings.
+func (a *AdminService) ImportBookings(stream grpc.ClientStreamingServer[pb.ImportBookingsRequest, pb.ImportBookingsResponse]) error {
+	if err := requireServiceActor(stream.Context()); err != nil {
+		return err
+	}
+	first, err := stream.Recv()
+	if err == io.EOF {
+		return status.Errorf(codes.InvalidArgument, "Import requires a file")
+	}
+	if err != nil {
+		return err
+	}
+	batchSize := int(first.GetBatchSize())
+	if batchSize == 0 {
+		batchSize = defaultImportBatchSize
+	}
+	if batchSize < 0 || batchSize > maxImportBatchSize {
+		return status.Errorf(codes.InvalidArgument, "Batch size must be between 1 and %d", maxImportBatchSize)
+	}
+
+	decoder, err := newRowDecoder(first.GetFormat(), &importReader{stream: stream, data: first.GetData()})
+	if err != nil {
+		return err
+	}
+	bulk := &bookingImport{admin: a, ctx: stream.Context(), response: &pb.ImportBookingsResponse{DryRun: first.GetDryRun()},
+		dryRunTickets: map[string]string{}}
+	// batches committed before an error stay imported
+	defer bulk.releaseSeats(0)
+	var batch []importRow
+	for {
+		row, line, err := decoder.next()
+		if err == io.EOF {
+			break
+		}
+		var invalid invalidRowError
+		if errors.As(err, &invalid) {
+			bulk.response.Rows++
+			bulk.reportRowError(line, err)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		bulk.response.Rows++
+		batch = append(batch, importRow{line: line, row: row})
+		if len(batch) == batchSize {
+			bulk.importBatch(batch)
+			batch = batch[:0]
+		}
+	}
+	bulk.importBatch(batch)
+	response := bulk.response
+	// rows that couldn't be read are reported before the rows of their batch
+	slices.SortStableFunc(response.Errors, func(a, b *pb.ImportRowError) int { return cmp.Compare(a.GetLine(), b.GetLine()) })
+	slog.InfoContext(stream.Context(), "Imported bookings", "rows", response.GetRows(), "imported", response.GetImported(),
+		"failed", response.GetFailed(), "dry_run", response.GetDryRun())
+	return stream.SendAndClose(response)
+}
+
+type importRow struct {
+	line int64
+	row  *bulkRow
+	// ticket and pnr identify the booking of a row that was booked
+	ticket string
+	pnr    string
+}
+
+// invalidRowError is a row that can't be read, which fails the row only
+type invalidRowError struct {
+	error
+}
+
+// bookingImport books the rows of an import batch by batch
+type bookingImport struct {
+	admin    *AdminService
+	ctx      context.Context
+	response *pb.ImportBookingsResponse
+	// seats were allocated for rows that weren't committed. A dry run keeps
+	// them until it ends, so its later batches don't get the same seats.
+	seats []bookedSeat
+	// dryRunTickets are the PNRs of the tickets the rolled back batches of a
+	// dry run booked, so that its later batches don't book them again
+	dryRunTickets map[string]string
+}
+
+type bookedSeat struct {
+	seat    int32
+	section string
+}
+
+func (i *bookingImport) reportRowError(line int64, err error) {
+	i.response.Failed++
+	if len(i.response.GetErrors()) < maxImportErrors {
+		i.response.Errors = append(i.response.Errors, &pb.ImportRowError{Line: line, Error: err.Error()})
+	}
+}
+
+// importBatch books the rows of a batch in a transaction, which a dry run
+// rolls back so that it holds the write lock for one batch at a time. A failed
+// batch only fails its rows.
+func (i *bookingImport) importBatch(batch []importRow) {
+	if len(batch) == 0 {
+		return
+	}
+	batchSeats := len(i.seats)
+	tx, err := i.admin.db.BeginTx(i.ctx, nil)
+	var imported []importRow
+	if err == nil {
+		imported, err = i.importRows(tx, batch)
+		if err != nil || i.response.GetDryRun() {
+			tx.Rollback()
+		} else {
+			err = tx.Commit()
+		}
+	}
+	if err != nil {
+		slog.ErrorContext(i.ctx, "Import batch failed", "first_line", batch[0].line, "error", err)
+		i.releaseSeats(batchSeats)
+		for _, row := range imported {
+			i.reportRowError(row.line, fmt.Errorf("Batch was not committed: %v", err))
+		}
+		return
+	}
+	if i.response.GetDryRun() {
+		for _, row := range imported {
+			i.dryRunTickets[row.ticket] = row.pnr
+		}
+		i.response.Imported += int64(len(imported))
+		return
+	}
+	i.seats = i.seats[:0]
+	i.response.Imported += int64(len(imported))
+	for range imported {
+		i.admin.bookingService.metrics.countBooking(BookingOperationImport)
+	}
+}
+
+// importRows books the rows on tx, every row in a savepoint so that a failed
+// one leaves nothing behind. It returns the rows that were booked.
+func (i *bookingImport) importRows(tx *sql.Tx, batch []importRow) ([]importRow, error) {
+	var imported []importRow
+	for _, row := range batch {
+		if _, err := tx.Exec("SAVEPOINT import_row"); err != nil {
+			return imported, err
+		}
+		booking, rowErr := i.admin.importBooking(i.ctx, tx, row.row, i.dryRunTickets)
+		if rowErr != nil {
+			if _, err := tx.Exec("ROLLBACK TO import_row"); err != nil {
+				return imported, err
+			}
+			i.reportRowError(row.line, rowErr)
+		} else {
+			row.ticket = importedTicket(row.row.Email, booking.GetFrom(), booking.GetTo(), booking.GetDepartureAt())
+			row.pnr = booking.GetPnr()
+			imported = append(imported, row)
+			i.seats = append(i.seats, bookedSeat{booking.GetSeat(), booking.GetSection()})
+		}
+		if _, err := tx.Exec("RELEASE import_row"); err != nil {
+			return imported, err
+		}
+	}
+	return imported, nil
+}
+
+// releaseSeats releases the seats allocated for rows that weren't committed,
+// from the one at index from on
+func (i *bookingImport) releaseSeats(from int) {
+	for _, seat := range i.seats[from:] {
+		i.admin.bookingService.seatAllocator.DeallocateSeat(seat.seat, seat.section)
+	}
+	i.seats = i.seats[:from]
+}
+
+// importBooking books row on tx, unless the ticket exists or is one of the
+// dryRunTickets. The allocated seat is released again when the booking can't
+// be stored.
+func (a *AdminService) importBooking(ctx context.Context, tx *sql.Tx, row *bulkRow, dryRunTickets map[string]string) (*pb.BookingDbResponse, error) {
+	if row.FirstName == "" || row.LastName == "" || row.Email == "" || row.From == "" || row.To == "" {
+		return nil, errors.New("First name, last name, email, from and to are required")
+	}
+	if !isValidEmail(row.Email) {
+		return nil, fmt.Errorf("Email %q is not valid", row.Email)
+	}
+	if row.Price <= 0 {
+		return nil, errors.New("Price must be positive")
+	}
+	if (row.Section == "") != (row.Seat == nil) {
+		return nil, errors.New("Section and seat must be given together")
+	}
+	var departureAt int64
+	if row.Departure != "" {
+		departure, err := time.Parse(time.RFC3339, row.Departure)
+		if err != nil {
+			return nil, fmt.Errorf("Departure %q is not an RFC 3339 time", row.Departure)
+		}
+		departureAt = departure.Unix()
+	}
+	if pnr, ok := dryRunTickets[importedTicket(row.Email, row.From, row.To, departureAt)]; ok {
+		return nil, fmt.Errorf("Ticket from %s to %s already exists with PNR %s", row.From, row.To, pnr)
+	}
+
+	dbUser, isUserExists := retrieveUserIfExists(tx, row.Email)
+	if isUserExists {
+		if existing, isBookingExists := retrieveBookingIfExists(tx, dbUser.GetId(), row.From, row.To, departureAt); isBookingExists {
+			return nil, fmt.Errorf("Ticket from %s to %s already exists with PNR %s", row.From, row.To, existing.GetPnr())
+		}
+	} else {
+		dbUser = &pb.User{Firstname: row.FirstName, Lastname: row.LastName, Email: normalizeEmail(row.Email)}
+		userId, err := insertUser(tx, dbUser, "")
+		if err != nil {
+			return nil, fmt.Errorf("Error while adding user: %v", err)
+		}
+		dbUser.Id = userId
+	}
+
+	var seat int32
+	var section string
+	if row.Seat != nil {
+		seat, section = *row.Seat, row.Section
+		if err := a.bookingService.allocateSpecificSeat(ctx, seat, section); err != nil {
+			return nil, fmt.Errorf("Seat number %d in section %s is not available", seat, section)
+		}
+	} else {
+		var err error
+		if seat, section, err = a.bookingService.allocateSeat(ctx); err != nil {
+			return nil, fmt.Errorf("Error while allocating seat: %v", err)
+		}
+	}
+
+	ticketId := uuid.NewString()
+	pnr, err := insertTicketWithPnr(tx, "INSERT INTO tickets (t_id, t_from, t_to, t_price, t_seat, t_section, t_user_id, t_status, t_departure_at, t_created_at, t_pnr) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		ticketId, row.From, row.To, row.Price, seat, section, dbUser.GetId(), TicketStatusBooked, nullableUnix(departureAt), time.Now().Unix())
+	booking := &pb.BookingDbResponse{Id: ticketId, From: row.From, To: row.To, Price: row.Price, Seat: seat, Section: section,
+		Userid: dbUser.GetId(), DepartureAt: departureAt, Pnr: pnr, Version: 1}
+	if err == nil {
+		err = appendAuditEntry(ctx, tx, AuditActionBookingImport, ticketId, nil, booking)
+	}
+	if err != nil {
+		a.bookingService.seatAllocator.DeallocateSeat(seat, section)
+		return nil, fmt.Errorf("Error while storing booking: %v", err)
+	}
+	return booking, nil
+}
+
+// importedTicket identifies the ticket of a passenger for a journey
+func importedTicket(email, from, to string, departureAt int64) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%d", normalizeEmail(email), from, to, departureAt)
+}
+
+// importReader reads the data of the messages of an import stream
+type importReader struct {
+	stream grpc.ClientStreamingServer[pb.ImportBookingsRequest, pb.ImportBookingsResponse]
+	data   []byte
+}
+
+func (r *importReader) Read(p []byte) (int, error) {
+	for len(r.data) == 0 {
+		req, err := r.stream.Recv()
+		if err != nil {
+			return 0, err
+		}
+		r.data = req.GetData()
+	}
+	n := copy(p, r.data)
+	r.data = r.data[n:]
+	return n, nil
+}
+
+// rowDecoder returns the rows of an import with their line. Rows that can't
+// be read are returned as invalidRowError.
+type rowDecoder interface {
+	next() (*bulkRow, int64, error)
+}
+
+func newRowDecoder(format pb.BulkFormat, reader io.Reader) (rowDecoder, error) {
+	if format == pb.BulkFormat_BULK_FORMAT_NDJSON {
+		scanner := bufio.NewScanner(reader)
+		scanner.Buffer(nil, maxNDJSONLine)
+		return &ndjsonDecoder{scanner: scanner}, nil
+	}
+	decoder := &csvDecoder{reader: csv.NewReader(reader), columns: map[string]int{}}
+	decoder.reader.FieldsPerRecord = -1
+	decoder.reader.TrimLeadingSpace = true
+	header, err := decoder.reader.Read()
+	if err == io.EOF {
+		return nil, status.Errorf(codes.InvalidArgument, "CSV import requires a header row")
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Error while reading CSV header: %v", err)
+	}
+	for i, column := range header {
+		decoder.columns[strings.ToLower(strings.TrimSpace(column))] = i
+	}
+	for _, column := range importColumns {
+		if _, ok := decoder.columns[column]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "CSV header lacks the column %s, it needs %s", column,
+				strings.Join(importColumns, ", "))
+		}
+	}
+	return decoder, nil
+}
+
+type csvDecoder struct {
+	reader  *csv.Reader
+	columns map[string]int
+}
+
+func (d *csvDecoder) next() (*bulkRow, int64, error) {
+	record, err := d.reader.Read()
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		return nil, int64(parseErr.StartLine), invalidRowError{fmt.Errorf("Invalid CSV: %v", parseErr.Err)}
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+	line, _ := d.reader.FieldPos(0)
+	value := func(column string) string {
+		if i, ok := d.columns[column]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	row := &bulkRow{From: value("from"), To: value("to"), Departure: value("departure"), Section: value("section"),
+		FirstName: value("first_name"), LastName: value("last_name"), Email: value("email")}
+	price, err := strconv.ParseInt(value("price"), 10, 32)
+	if err != nil {
+		return nil, int64(line), invalidRowError{fmt.Errorf("Price %q is not a number", value("price"))}
+	}
+	row.Price = int32(price)
+	if value("seat") != "" {
+		seat, err := strconv.ParseInt(value("seat"), 10, 32)
+		if err != nil {
+			return nil, int64(line), invalidRowError{fmt.Errorf("Seat %q is not a number", value("seat"))}
+		}
+		row.Seat = new(int32)
+		*row.Seat = int32(seat)
+	}
+	return row, int64(line), nil
+}
+
+type ndjsonDecoder struct {
+	scanner *bufio.Scanner
+	line    int64
+}
+
+func (d *ndjsonDecoder) next() (*bulkRow, int64, error) {
+	for d.scanner.Scan() {
+		d.line++
+		if strings.TrimSpace(d.scanner.Text()) == "" {
+			continue
+		}
+		row := &bulkRow{}
+		if err := json.Unmarshal(d.scanner.Bytes(), row); err != nil {
+			return nil, d.line, invalidRowError{fmt.Errorf("Invalid JSON: %v", err)}
+		}
+		return row, d.line, nil
+	}
+	if err := d.scanner.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, 0, status.Errorf(codes.InvalidArgument, "Line %d is longer than %d bytes", d.line+1, maxNDJSONLine)
+		}
+		return nil, 0, err
+	}
+	return nil, 0, io.EOF
+}
@@ -0,0 +1,229 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// importStream sends the file to the server in parts of a few bytes, and
+// calls beforeEOF once the file was sent. It calls as the back office service
+// without a ctx.
+type importStream struct {
+    grpc.ServerStream
+    ctx       context.Context
+    requests  []*pb.ImportBookingsRequest
+    response  *pb.ImportBookingsResponse
+    beforeEOF func()
+}
+
+func newImportStream(options *pb.ImportBookingsRequest, file string) *importStream {
+    stream := &importStream{requests: []*pb.ImportBookingsRequest{options}}
+    for len(file) > 0 {
+        n := min(len(file), 7)
+        stream.requests = append(stream.requests, &pb.ImportBookingsRequest{Data: []byte(file[:n])})
+        file = file[n:]
+    }
+    return stream
+}
+
+func (s *importStream) Recv() (*pb.ImportBookingsRequest, error) {
+    if len(s.requests) == 0 {
+        if s.beforeEOF != nil {
+            s.beforeEOF()
+            s.beforeEOF = nil
+        }
+        return nil, io.EOF
+    }
+    request := s.requests[0]
+    s.requests = s.requests[1:]
+    return request, nil
+}
+
+func (s *importStream) SendAndClose(response *pb.ImportBookingsResponse) error {
+    s.response = response
+    return nil
+}
+
+func (s *importStream) Context() context.Context {
+    return streamContext(s.ctx)
+}
+
+type exportStream struct {
+    grpc.ServerStream
+    ctx  context.Context
+    data bytes.Buffer
+}
+
+func (s *exportStream) Send(chunk *pb.ExportChunk) error {
+    s.data.Write(chunk.GetData())
+    return nil
+}
+
+func (s *exportStream) Context() context.Context {
+    return streamContext(s.ctx)
+}
+
+func streamContext(ctx context.Context) context.Context {
+    if ctx == nil {
+        return api.WithActor(context.Background(), "service:back-office")
+    }
+    return ctx
+}
+
+const importFile = `first_name,last_name,email,from,to,price,departure,section,seat,legacy_id
+Vrushali,Ghadge,vg@gmail.com,London,France,20,2026-11-02T09:30:00Z,,,L-1
+Anna,Smith,anna@test.com,London,Paris,25,,B,3,L-2
+Bob,Jones,bob@test.com,London,Paris,25,,B,3,L-3
+Carl,Berg,,London,Paris,25,,,,L-4
+Dora,Lee,dora@test.com,London,Paris,free,,,,L-5
+Vrushali,Ghadge,VG@gmail.com,London,France,20,2026-11-02T09:30:00Z,,,L-6
+`
+
+func TestShouldImportValidRowsAndReportTheOthers(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    allocator, _ := api.NewSeatAllocatorWithLayout([]api.Section{{Name: "A", Seats: 10}, {Name: "B", Seats: 10}}, api.AllocationSequential)
+    bookingService.SetSeatAllocator(allocator)
+    adminService := api.NewAdminService(db, bookingService)
+
+    dryRun := newImportStream(&pb.ImportBookingsRequest{DryRun: true, BatchSize: 2}, importFile)
+    if err := adminService.ImportBookings(dryRun); err != nil {
+        t.Fatalf("Error in importing bookings %v ", err)
+    }
+    assert.True(t, dryRun.response.GetDryRun())
+    assert.EqualValues(t, 2, dryRun.response.GetImported())
+    assert.Empty(t, allocator.OccupiedSeats("A"), "A dry run should release the seats it allocated")
+    assert.Empty(t, allocator.OccupiedSeats("B"))
+
+    stream := newImportStream(&pb.ImportBookingsRequest{BatchSize: 2}, importFile)
+    if err := adminService.ImportBookings(stream); err != nil {
+        t.Fatalf("Error in importing bookings %v ", err)
+    }
+    report := stream.response
+    assert.EqualValues(t, 6, report.GetRows())
+    assert.EqualValues(t, 2, report.GetImported())
+    assert.EqualValues(t, 4, report.GetFailed())
+    var lines []int64
+    for _, rowError := range report.GetErrors() {
+        lines = append(lines, rowError.GetLine())
+    }
+    assert.Equal(t, []int64{4, 5, 6, 7}, lines, "Errors should name the line of the row: %v", report.GetErrors())
+    var dryRunLines []int64
+    for _, rowError := range dryRun.response.GetErrors() {
+        dryRunLines = append(dryRunLines, rowError.GetLine())
+    }
+    assert.Equal(t, lines, dryRunLines, "A dry run should report what the import does")
+    assert.Equal(t, []int32{0}, allocator.OccupiedSeats("A"))
+    assert.Equal(t, []int32{3}, allocator.OccupiedSeats("B"), "The seat of a row should be honoured")
+
+    imported, err := bookingService.ListMyBookings(context.TODO(), &pb.ListMyBookingsRequest{User: &pb.User{Email: "anna@test.com"}})
+    if err != nil {
+        t.Fatalf("Error in listing bookings %v ", err)
+    }
+    if assert.Len(t, imported.GetBookings(), 1) {
+        assert.Equal(t, "Paris", imported.GetBookings()[0].GetTo())
+    }
+    _, err = bookingService.ListMyBookings(context.TODO(), &pb.ListMyBookingsRequest{User: &pb.User{Email: "bob@test.com"}})
+    assert.Error(t, err, "A failed row should leave no user behind")
+}
+
+func TestShouldNotHoldTheDatabaseBetweenTheBatchesOfADryRun(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    adminService := api.NewAdminService(db, bookingService)
+
+    dryRun := newImportStream(&pb.ImportBookingsRequest{DryRun: true, BatchSize: 1}, importFile)
+    var bookingErr error
+    dryRun.beforeEOF = func() {
+        ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+        defer cancel()
+        _, bookingErr = bookingService.CreateBooking(ctx, createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    }
+    if err := adminService.ImportBookings(dryRun); err != nil {
+        t.Fatalf("Error in importing bookings %v ", err)
+    }
+    assert.NoError(t, bookingErr, "Bookings shouldn't wait for a dry run to end")
+    assert.EqualValues(t, 2, dryRun.response.GetImported())
+    assert.Equal(t, 1, countRows(t, db, "SELECT COUNT(*) FROM tickets"), "A dry run should leave no booking behind")
+}
+
+func TestShouldExportBookingsAsCSVAndNDJSON(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    adminService := api.NewAdminService(db, bookingService)
+    for _, destination := range []string{"France", "Paris"} {
+        request := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+        request.To = destination
+        if _, err := bookingService.CreateBooking(context.TODO(), request); err != nil {
+            t.Fatalf("Error in creating booking %v ", err)
+        }
+    }
+
+    stream := &exportStream{}
+    if err := adminService.ExportBookings(&pb.ExportBookingsRequest{To: "Paris"}, stream); err != nil {
+        t.Fatalf("Error in exporting bookings %v ", err)
+    }
+    records, err := csv.NewReader(&stream.data).ReadAll()
+    if err != nil {
+        t.Fatalf("Error in reading CSV %v ", err)
+    }
+    if assert.Len(t, records, 2, "The header and the booking to Paris should be exported") {
+        assert.Equal(t, "pnr", records[0][0])
+        assert.Equal(t, "Paris", records[1][4])
+        assert.Equal(t, EMAIL, records[1][11])
+    }
+
+    stream = &exportStream{}
+    if err := adminService.ExportBookings(&pb.ExportBookingsRequest{Format: pb.BulkFormat_BULK_FORMAT_NDJSON}, stream); err != nil {
+        t.Fatalf("Error in exporting bookings %v ", err)
+    }
+    lines := strings.Split(strings.TrimSpace(stream.data.String()), "\n")
+    assert.Len(t, lines, 2)
+    var row map[string]any
+    if err := json.Unmarshal([]byte(lines[0]), &row); err != nil {
+        t.Fatalf("Error in decoding NDJSON %v ", err)
+    }
+    assert.Equal(t, FIRST_NAME, row["first_name"])
+    assert.Equal(t, "BOOKED", row["status"])
+
+    // an export can be imported into another server
+    otherDb := newTestDatabase(t)
+    reimport := newImportStream(&pb.ImportBookingsRequest{Format: pb.BulkFormat_BULK_FORMAT_NDJSON}, stream.data.String())
+    if err := api.NewAdminService(otherDb, api.NewBookingService(otherDb)).ImportBookings(reimport); err != nil {
+        t.Fatalf("Error in importing bookings %v ", err)
+    }
+    assert.EqualValues(t, 2, reimport.response.GetImported(), "%v", reimport.response.GetErrors())
+}
+
+func TestShouldOnlyLetServicesExportAndImportBookings(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    adminService := api.NewAdminService(db, bookingService)
+    if _, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)); err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+
+    for _, ctx := range []context.Context{context.Background(), api.WithActor(context.Background(), "user:someone")} {
+        export := &exportStream{ctx: ctx}
+        err := adminService.ExportBookings(&pb.ExportBookingsRequest{}, export)
+        assert.Equal(t, codes.PermissionDenied, status.Code(err))
+        assert.Zero(t, export.data.Len(), "No booking should be exported to a caller that isn't a service")
+
+        imports := newImportStream(&pb.ImportBookingsRequest{}, "first_name,last_name,email,from,to\n")
+        imports.ctx = ctx
+        err = adminService.ImportBookings(imports)
+        assert.Equal(t, codes.PermissionDenied, status.Code(err))
+    }
+}
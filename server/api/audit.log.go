@@ -0,0 +1,117 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	AuditActionBookingCreate     = "booking.create"
+	AuditActionBookingModifySeat = "booking.modify_seat"
+	AuditActionBookingCancel     = "booking.cancel"
+	AuditActionAccountDelete     = "account.delete"
+	AuditActionAdminMergeUsers   = "admin.merge_users"
+	AuditActionAdminRestore      = "admin.restore_database"
+
+	auditColumns = "a_seq, a_at, a_actor, a_action, a_resource, COALESCE(a_before, ''), COALESCE(a_after, ''), COALESCE(a_client_addr, ''), a_prev_hash, a_hash"
+)
+
+// appendAuditEntry adds an entry for action on resource to the audit log. It
+// must run on the transaction that makes the change so that both are stored
+// or neither. before and after are the resource state around the action and
+// may be nil.
+func appendAuditEntry(ctx context.Context, db dbExecutor, action, resource string, before, after any) error {
+	entry := &pb.AuditEntry{
+		Actor:         ActorFromContext(ctx),
+		Action:        action,
+		Resource:      resource,
+		ClientAddress: clientAddress(ctx),
+	}
+	var err error
+	if entry.Before, err = marshalAuditState(before); err != nil {
+		return err
+	}
+	if entry.After, err = marshalAuditState(after); err != nil {
+		return err
+	}
+
+	err = db.QueryRow("SELECT a_hash FROM audit_log ORDER BY a_seq DESC LIMIT 1").Scan(&entry.PreviousHash)
+	if err != nil && err != sql.ErrNoRows {
+		return err
+	}
+
+	at := time.Now().Unix()
+	entry.Hash = auditEntryHash(entry, at)
+	_, err = db.Exec(`INSERT INTO audit_log (a_at, a_actor, a_action, a_resource, a_before, a_after, a_client_addr, a_prev_hash, a_hash)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`, at, entry.GetActor(), entry.GetAction(), entry.GetResource(), entry.GetBefore(),
+		entry.GetAfter(), entry.GetClientAddress(), entry.GetPreviousHash(), entry.GetHash())
+	return err
+}
+
+// RecordAdminAction appends an audit entry for an administrative change made
+// outside of an RPC, such as a maintenance command.
+func RecordAdminAction(db *sql.DB, actor, action, resource string, before, after any) error {
+	return appendAuditEntry(WithActor(context.Background(), actor), db, action, resource, before, after)
+}
+
+// VerifyAuditChain recomputes the hash of every entry in order. It returns the
+// number of entries checked and the sequence of the first entry whose hash or
+// link to its predecessor does not match, or 0 if the chain is intact.
+func VerifyAuditChain(db dbExecutor) (int64, int64, error) {
+	rows, err := db.Query("SELECT " + auditColumns + " FROM audit_log ORDER BY a_seq")
+	if err != nil {
+		return 0, 0, err
+	}
+	defer rows.Close()
+
+	var checked int64
+	previousHash := ""
+	for rows.Next() {
+		entry, at, err := scanAuditEntry(rows)
+		if err != nil {
+			return checked, 0, err
+		}
+		checked++
+		if entry.GetPreviousHash() != previousHash || entry.GetHash() != auditEntryHash(entry, at) {
+			return checked, entry.GetSequence(), nil
+		}
+		previousHash = entry.GetHash()
+	}
+	return checked, 0, rows.Err()
+}
+
+func auditEntryHash(entry *pb.AuditEntry, at int64) string {
+	fields := []string{entry.GetPreviousHash(), strconv.FormatInt(at, 10), entry.GetActor(), entry.GetAction(),
+		entry.GetResource(), entry.GetBefore(), entry.GetAfter(), entry.GetClientAddress()}
+	sum := sha256.Sum256([]byte(strings.Join(fields, "\x1f")))
+	return hex.EncodeToString(sum[:])
+}
+
+func marshalAuditState(state any) (string, error) {
+	if state == nil {
+		return "", nil
+	}
+	if message, ok := state.(proto.Message); ok {
+		body, err := protojson.Marshal(message)
+		return string(body), err
+	}
+	body, err := json.Marshal(state)
+	return string(body), err
+}
+
+func scanAuditEntry(row rowScanner) (*pb.AuditEntry, int64, error) {
+	var entry pb.AuditEntry
+	var at int64
+	err := row.Scan(&entry.Sequence, &at, &entry.Actor, &entry.Action, &entry.Resource, &entry.Before, &entry.After,
+		&entry.ClientAddress, &entry.PreviousHash, &entry.Hash)
+	return &entry, at, err
+}
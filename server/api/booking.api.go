@@ -2,226 +2,575 @@ package api
 
 import (
 	pb "ticket-booking-app/domain"
+	bookingv2 "ticket-booking-app/domain/booking/v2"
+	"ticket-booking-app/logging"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
 	_ "github.com/mattn/go-sqlite3"
-	"github.com/google/uuid"
 	"database/sql"
 	"context"
-	"log"
+	"cmp"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	ticketColumns = "t_id, t_from, t_to, t_price, t_seat, t_section, t_user_id, COALESCE(t_departure_at, 0), COALESCE(t_pnr, ''), t_version"
+	userColumns   = "u_id, u_user_fname, u_user_lname, COALESCE(u_user_email, '')"
+
+	TicketStatusBooked    = "BOOKED"
+	TicketStatusCancelled = "CANCELLED"
 )
 
 type BookingService struct {
 	seatAllocator *SeatAllocator
 	db            *sql.DB
+	notifications *NotificationQueue
+	ticketSigner  *TicketSigner
+	metrics       *Metrics
+	// maxActiveBookings is the number of booked tickets a user may hold for
+	// the same journey, 0 for no limit
+	maxActiveBookings int
+	// maxSeatHolds is the number of seats a caller may hold at once, 0 for no
+	// limit
+	maxSeatHolds int
+	seatHoldTTL  time.Duration
+	// watchesClosed is closed when the WatchBookings streams have to end
+	watchesClosed chan struct{}
+	closeWatches  sync.Once
+	// v2 implements the bookings, the v1 methods adapt their requests to it
+	v2 *BookingServiceV2
 }
 
 func NewBookingService(dbInstance *sql.DB) *BookingService {
-	return &BookingService{
+	b := &BookingService{
 		seatAllocator: NewSeatAllocator(),
 		db: dbInstance,
+		ticketSigner: NewTicketSigner(dbInstance),
+		seatHoldTTL: DefaultSeatHoldTTL,
+		watchesClosed: make(chan struct{}),
 	}
+	b.v2 = &BookingServiceV2{bookings: b}
+	return b
 }
 
-func (b *BookingService) CreateBooking(ctx context.Context, req *pb.BookingRequest) (*pb.BookingResponse, error) {
-	if req.GetFrom() == "" || req.GetTo() == "" || req.GetPrice() == 0 || req.GetUser() == nil {
-	    log.Fatalf("Invalid create request : %v", req)
-		return nil, status.Errorf(codes.InvalidArgument, "Invalid create booking request")
-	}
-
-    //check if user exists before inserting new record
-    var userId string
-    dbUser, isUserExists := retrieveUserIfExists(b, req.GetUser().GetFirstname(), req.GetUser().GetLastname(), req.GetUser().GetEmail())
-    if isUserExists {
-        userId = dbUser.GetId()
-    } else {
-        userId = uuid.NewString()
-        _, userDbErr := b.db.Exec("INSERT INTO users (u_id, u_user_fname, u_user_lname, u_user_email) VALUES (?, ?, ?, ?)",
-                    userId, req.GetUser().GetFirstname(), req.GetUser().GetLastname(), req.GetUser().GetEmail())
-        if userDbErr != nil {
-            return nil, userDbErr
-        }
-        log.Printf("Added new user with email %v \n", req.GetUser().GetEmail())
-    }
+// V2 returns the booking.v2 API of the service, which shares its settings
+func (b *BookingService) V2() *BookingServiceV2 {
+	return b.v2
+}
 
-    //check if booking already exists for user with requested location details
-    dbBooking, isBookingExists := retrieveBookingIfExists(b, userId, req.GetFrom(), req.GetTo())
-    if isBookingExists {
-        log.Printf("Ticket already exsits from %s to %s for user %s with seat number %d\n", dbBooking.GetFrom(),
-            dbBooking.GetTo(), userId, dbBooking.GetSeat())
-        return nil, nil
-    }
-    seat, section, err := b.seatAllocator.AllocateSeat()
-    if err != nil {
-    	return nil, status.Errorf(codes.Internal, "Error while allocating seat: %v", err)
-    }
+// SetNotificationQueue makes the service email passengers about their bookings.
+func (b *BookingService) SetNotificationQueue(queue *NotificationQueue) {
+	b.notifications = queue
+}
 
-    ticketId := uuid.NewString()
-    _, dbErr := b.db.Exec("INSERT INTO tickets (t_id, t_from, t_to, t_price, t_seat, t_section, t_user_id) VALUES (?, ?, ?, ?, ?, ?, ?)",
-           ticketId, req.GetFrom(), req.GetTo(), req.GetPrice(), seat, section, userId)
-    if dbErr != nil {
-        return nil, dbErr
-    }
-    log.Printf("Booked new ticket from %s to %s for user %s with seat number %v\n", req.From,
-         req.To, req.GetUser().GetEmail(), seat)
-    return nil, nil
+// SetMetrics counts committed bookings, modifications and cancellations.
+func (b *BookingService) SetMetrics(metrics *Metrics) {
+	b.metrics = metrics
 }
 
-func (b *BookingService) GetBookingByUser(ctx context.Context, req *pb.GetBookingByUserRequest) (*pb.BookingResponse, error) {
-	booking, _ := b.getBookingFromDataByUser(ctx, req.GetUser())
-	if booking == nil {
-		return nil, status.Errorf(codes.NotFound, "No booking exists with email %s", req.GetUser().GetEmail())
+// SetMaxActiveBookingsPerJourney limits the booked tickets a user may hold from
+// one station to another, across departures; 0 removes the limit.
+func (b *BookingService) SetMaxActiveBookingsPerJourney(max int) {
+	b.maxActiveBookings = max
+}
+
+// SetMaxSeatHoldsPerCaller limits the seats a caller may hold at once; 0
+// removes the limit.
+func (b *BookingService) SetMaxSeatHoldsPerCaller(max int) {
+	b.maxSeatHolds = max
+}
+
+// SetSeatHoldTTL sets how long HoldSeat keeps a seat.
+func (b *BookingService) SetSeatHoldTTL(ttl time.Duration) {
+	b.seatHoldTTL = ttl
+}
+
+// SetSeatAllocator replaces the default layout; it must be called before the
+// service is warmed up and serving.
+func (b *BookingService) SetSeatAllocator(allocator *SeatAllocator) {
+	b.seatAllocator = allocator
+}
+
+// WarmUp marks the seats of booked tickets as taken, so a restarted server
+// doesn't hand them out again.
+func (b *BookingService) WarmUp(ctx context.Context) (int, error) {
+	return b.allocateBookedSeats(ctx, b.seatAllocator)
+}
+
+// ReloadSeats rebuilds the taken seats from the booked tickets, e.g. after the
+// database was restored. Seat holds are released, as their seats may be booked
+// in the restored data.
+func (b *BookingService) ReloadSeats(ctx context.Context) (int, error) {
+	allocator, err := NewSeatAllocatorWithLayout(b.seatAllocator.Sections(), b.seatAllocator.strategy)
+	if err != nil {
+		return 0, err
 	}
-	return transformAsBookingResponse(booking), nil
+	seats, err := b.allocateBookedSeats(ctx, allocator)
+	if err != nil {
+		return seats, err
+	}
+	b.seatAllocator.replaceSeats(allocator)
+	return seats, nil
 }
 
-func (b *BookingService) GetBookingsBySection(ctx context.Context, req *pb.GetBookingsBySectionRequest) (*pb.BookingListResponse, error) {
-    var bookings []*pb.BookingResponse
-    rows, err := b.db.Query("SELECT * FROM tickets WHERE t_section = ?", req.GetSection())
-     if err != nil {
-        log.Fatalf("List :: ticket error : %v", err)
-         return nil, err
-     }
-    defer rows.Close()
-
-    for rows.Next() {
-         var response pb.BookingDbResponse
-         //log.Printf("List :: indv row : %v", rows)
-         if err := rows.Scan(&response.Id, &response.From, &response.To, &response.Price, &response.Seat, &response.Section, &response.Userid); err != nil {
-             log.Fatalf("List :: error : %v", err)
-             return nil, err
-         }
-
-         var dbUser pb.User
-         dbRow := b.db.QueryRow("SELECT * FROM users WHERE u_id = ?", response.GetUserid())
-         if dbErr := dbRow.Scan(&dbUser.Id , &dbUser.Firstname, &dbUser.Lastname, &dbUser.Email); dbErr != nil {
-             log.Fatalf("List :: Error in retrieving user of id : %s, Error : %v", dbUser.Id, dbErr)
-             return nil, dbErr
-         }
-         bookings = append(bookings, transformDbResponseToBookingResponse(response, dbUser))
-    }
+func (b *BookingService) allocateBookedSeats(ctx context.Context, allocator *SeatAllocator) (int, error) {
+	rows, err := b.db.QueryContext(ctx, "SELECT t_seat, t_section FROM tickets WHERE t_status = ?", TicketStatusBooked)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
 
-	return &pb.BookingListResponse{Bookings: bookings}, nil
+	seats := 0
+	for rows.Next() {
+		var seat int32
+		var section string
+		if err := rows.Scan(&seat, &section); err != nil {
+			return seats, err
+		}
+		if err := allocator.AllocateSpecificSeat(seat, section); err != nil {
+			slog.WarnContext(ctx, "Booked seat could not be restored", "seat", seat, "section", section, "error", err)
+			continue
+		}
+		seats++
+	}
+	return seats, rows.Err()
 }
 
-func (b *BookingService) RemoveBookingByUser(ctx context.Context, req *pb.RemoveBookingByUserRequest) (*pb.RemoveBookingResponse, error) {
-	booking, _ := b.getBookingFromDataByUser(ctx, req.GetUser())
-	if booking == nil {
-		return nil, status.Errorf(codes.NotFound, "No booking exists with email %s", req.GetUser().GetEmail())
+func (b *BookingService) CreateBooking(ctx context.Context, req *pb.BookingRequest) (*pb.BookingResponse, error) {
+	if req.GetUser() != nil && !isValidEmail(req.GetUser().GetEmail()) {
+		return nil, status.Errorf(codes.InvalidArgument, "Passenger email %q is not valid", req.GetUser().GetEmail())
+	}
+	var passenger *bookingv2.Passenger
+	if req.GetUser() != nil {
+		passenger = &bookingv2.Passenger{GivenName: req.GetUser().GetFirstname(), FamilyName: req.GetUser().GetLastname(),
+			Email: req.GetUser().GetEmail()}
 	}
+	booking, err := b.v2.CreateBooking(ctx, &bookingv2.CreateBookingRequest{
+		Booking: &bookingv2.Booking{Origin: req.GetFrom(), Destination: req.GetTo(), Price: req.GetPrice(), Passenger: passenger,
+			DepartureTime: req.GetDeparture()},
+		SeatHoldId: req.GetHoldId(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return transformV2Booking(booking), nil
+}
 
-	b.seatAllocator.DeallocateSeat(booking.GetSeat(), booking.GetSection())
+func (b *BookingService) GetBookingByUser(ctx context.Context, req *pb.GetBookingByUserRequest) (*pb.BookingResponse, error) {
+	booking, err := b.lookupBooking(ctx, "", req.GetUser())
+	if err != nil {
+		return nil, err
+	}
+	return transformV2Booking(booking), nil
+}
 
-	_, err := b.db.Exec("DELETE FROM tickets WHERE t_id = ?", booking.GetId())
-    if err != nil {
-        return nil, err
-    }
-    log.Printf("Cancelled booking for user %v \n", req.GetUser().GetEmail())
+func (b *BookingService) GetBooking(ctx context.Context, req *pb.GetBookingRequest) (*pb.BookingResponse, error) {
+	if req.GetBookingId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Booking id or PNR is required")
+	}
+	booking, err := b.lookupBooking(ctx, req.GetBookingId(), nil)
+	if err != nil {
+		return nil, err
+	}
+	return transformV2Booking(booking), nil
+}
+
+func (b *BookingService) ListMyBookings(ctx context.Context, req *pb.ListMyBookingsRequest) (*pb.BookingListResponse, error) {
+	var dbUser *pb.User
+	if req.GetUserId() != "" {
+		user, err := findUserById(withContext(ctx, b.db), req.GetUserId())
+		if err != nil || user.GetEmail() == "" {
+			return nil, status.Errorf(codes.NotFound, "No user exists with id %s", req.GetUserId())
+		}
+		dbUser = user
+	} else {
+		user, isUserExists := retrieveUserIfExists(withContext(ctx, b.db), req.GetUser().GetEmail())
+		if !isUserExists {
+			return nil, status.Errorf(codes.NotFound, "No user exists with email %s", req.GetUser().GetEmail())
+		}
+		dbUser = user
+	}
 
+	// emails of active users are unique, deleted users have none
+	bookings, err := b.listAllBookings(ctx, &bookingv2.ListBookingsRequest{PassengerEmail: dbUser.GetEmail()})
+	if err != nil {
+		return nil, err
+	}
+	slices.SortStableFunc(bookings, func(x, y *bookingv2.Booking) int {
+		return cmp.Compare(x.GetDepartureTime().GetSeconds(), y.GetDepartureTime().GetSeconds())
+	})
+	return transformV2Bookings(bookings), nil
+}
+
+func (b *BookingService) GetBookingsBySection(ctx context.Context, req *pb.GetBookingsBySectionRequest) (*pb.BookingListResponse, error) {
+	// every ticket has a section, while v2 lists all of them without one
+	if req.GetSection() == "" {
+		return &pb.BookingListResponse{}, nil
+	}
+	bookings, err := b.listAllBookings(ctx, &bookingv2.ListBookingsRequest{Section: req.GetSection()})
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in listing tickets of section", "section", req.GetSection(), "error", err)
+		return nil, err
+	}
+	return transformV2Bookings(bookings), nil
+}
+
+func (b *BookingService) RemoveBookingByUser(ctx context.Context, req *pb.RemoveBookingByUserRequest) (*pb.RemoveBookingResponse, error) {
+	booking, err := b.lookupBooking(ctx, req.GetBookingId(), req.GetUser())
+	if err != nil {
+		return nil, err
+	}
+	// the etag that was read makes the cancellation fail if the booking
+	// changes in between
+	etag := req.GetEtag()
+	if etag == "" {
+		etag = booking.GetEtag()
+	}
+	if _, err := b.v2.DeleteBooking(ctx, &bookingv2.DeleteBookingRequest{Name: booking.GetName(), Etag: etag,
+		PassengerEmail: req.GetUser().GetEmail()}); err != nil {
+		return nil, err
+	}
 	return &pb.RemoveBookingResponse{}, nil
 }
 
 func (b *BookingService) ModifySeatByUser(ctx context.Context, req *pb.SeatModificationRequest) (*pb.SeatModificationResponse, error) {
-    log.Printf("Received booking modification request for user %v \n", req.GetUser().GetEmail())
+    slog.InfoContext(ctx, "Received booking modification request", "booking_id", req.GetBookingId(), logging.Email(req.GetUser().GetEmail()))
 
-	booking, _ := b.getBookingFromDataByUser(ctx, req.GetUser())
-	if booking == nil {
-		return nil, status.Errorf(codes.NotFound, "No booking exists with email %s", req.GetUser().GetEmail())
+	booking, err := b.lookupBooking(ctx, req.GetBookingId(), req.GetUser())
+	if err != nil {
+		return nil, err
 	}
+	update := &bookingv2.Booking{Name: booking.GetName(), Etag: req.GetEtag()}
+	if update.Etag == "" {
+		update.Etag = booking.GetEtag()
+	}
+	// with a hold the seat is only given to check it against the held one
+	if req.GetHoldId() == "" || req.GetSection() != "" {
+		update.Seat = &bookingv2.Seat{Section: req.GetSection(), Number: req.GetSeat()}
+	}
+	modified, err := b.v2.UpdateBooking(ctx, &bookingv2.UpdateBookingRequest{Booking: update, SeatHoldId: req.GetHoldId(),
+		PassengerEmail: req.GetUser().GetEmail()})
+	if err != nil {
+		return nil, err
+	}
+
+	return &pb.SeatModificationResponse{Seat: modified.GetSeat().GetNumber(), Section: modified.GetSeat().GetSection(),
+		User: transformV2Passenger(modified.GetPassenger()), BookingId: modified.GetUid(), Pnr: modified.GetPnr(), Etag: modified.GetEtag()}, nil
+}
 
-	if booking.GetSection() == req.GetSection() && booking.GetSeat() == req.GetSeat() {
-		return nil, status.Errorf(codes.InvalidArgument, "Old and new seats can't be same")
+// lookupBooking finds the active booking addressed by bookingId (ticket id or
+// PNR) through the v2 API, like resolveBooking. Without a bookingId the user's
+// only booking is used. When a user is given the booking must belong to that
+// user.
+func (b *BookingService) lookupBooking(ctx context.Context, bookingId string, user *pb.User) (*bookingv2.Booking, error) {
+	if bookingId != "" {
+		booking, err := b.v2.GetBooking(ctx, &bookingv2.GetBookingRequest{Name: bookingNamePrefix + bookingId})
+		if err != nil {
+			return nil, err
+		}
+		if booking.GetState() != bookingv2.BookingState_BOOKING_STATE_BOOKED ||
+			(user.GetEmail() != "" && normalizeEmail(user.GetEmail()) != booking.GetPassenger().GetEmail()) {
+			return nil, status.Errorf(codes.NotFound, "No booking exists with id %s", bookingId)
+		}
+		return booking, nil
 	}
 
-	allocationErr := b.seatAllocator.AllocateSpecificSeat(req.GetSeat(), req.GetSection())
-	if allocationErr != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "Seat number %d in section %s is not available", req.GetSeat(), req.GetSection())
+	if user.GetEmail() == "" {
+		return nil, status.Errorf(codes.NotFound, "No booking exists with email %s", user.GetEmail())
 	}
+	bookings, err := b.listAllBookings(ctx, &bookingv2.ListBookingsRequest{PassengerEmail: user.GetEmail()})
+	if err != nil {
+		return nil, err
+	}
+	if len(bookings) == 0 {
+		return nil, status.Errorf(codes.NotFound, "No booking exists with email %s", user.GetEmail())
+	}
+	if len(bookings) > 1 {
+		return nil, status.Errorf(codes.FailedPrecondition, "User %s has %d bookings, specify the booking id or PNR", user.GetEmail(), len(bookings))
+	}
+	return bookings[0], nil
+}
 
-	booking.Seat = req.GetSeat()
-	booking.Section = req.GetSection()
-	return &pb.SeatModificationResponse{Seat: booking.GetSeat(), Section: booking.GetSection(), User: booking.GetUser()}, nil
+// listAllBookings reads every page of a v2 listing
+func (b *BookingService) listAllBookings(ctx context.Context, req *bookingv2.ListBookingsRequest) ([]*bookingv2.Booking, error) {
+	req.PageSize = maxBookingPageSize
+	var bookings []*bookingv2.Booking
+	for {
+		page, err := b.v2.ListBookings(ctx, req)
+		if err != nil {
+			return nil, err
+		}
+		bookings = append(bookings, page.GetBookings()...)
+		if page.GetNextPageToken() == "" {
+			return bookings, nil
+		}
+		req.PageToken = page.GetNextPageToken()
+	}
 }
 
-func (b *BookingService) getBookingFromDataByUser(ctx context.Context, user *pb.User) (*pb.Booking, int) {
-    var dbUser pb.User
-    dbRow := b.db.QueryRow("SELECT * FROM users WHERE u_user_email = ?", user.GetEmail())
-    if dbErr := dbRow.Scan(&dbUser.Id , &dbUser.Firstname, &dbUser.Lastname, &dbUser.Email); dbErr != nil {
-        log.Fatalf("Error in retrieving user : %s, Error : %v", dbUser.Id, dbErr)
-        return nil, -1
-    }
+// notify queues a notification about a committed booking change. Failures are
+// only logged, the change itself already succeeded.
+func (b *BookingService) notify(kind string, booking, previous *pb.BookingDbResponse, user *pb.User) {
+	if b.notifications == nil || user.GetEmail() == "" {
+		return
+	}
+	notification, err := renderBookingNotification(kind, booking, previous, user)
+	if err != nil {
+		slog.Error("Error in rendering notification", "kind", kind, "pnr", booking.GetPnr(), "error", err)
+		return
+	}
+	b.notifications.Enqueue(notification)
+}
 
-    //log.Printf("User response : %v , %s, %s, %s \n", dbUser.Id, dbUser.Firstname, dbUser.Lastname, dbUser.Email)
-    var response pb.BookingDbResponse
-    row := b.db.QueryRow("SELECT * FROM tickets WHERE t_user_id = ?", dbUser.GetId())
-    if err := row.Scan(&response.Id, &response.From, &response.To, &response.Price, &response.Seat, &response.Section, &response.Userid); err != nil {
-        log.Fatalf("Error in retrieving ticket for user of id : %s, Error : %v", dbUser.Id, err)
-        return nil, -1
-    }
+// updateSeat stores the seat of modified, provided the ticket is still at the
+// version of booking, and commits tx.
+func updateSeat(ctx context.Context, tx *sql.Tx, booking, modified *pb.BookingDbResponse, user *pb.User) error {
+	result, err := tx.Exec("UPDATE tickets SET t_seat = ?, t_section = ?, t_version = ? WHERE t_id = ? AND t_version = ?",
+		modified.GetSeat(), modified.GetSection(), modified.GetVersion(), booking.GetId(), booking.GetVersion())
+	if err != nil {
+		return err
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return concurrentModificationError(booking)
+	}
+	if err := appendAuditEntry(ctx, tx, AuditActionBookingModifySeat, booking.GetId(), booking, modified); err != nil {
+		return err
+	}
+	if err := recordBookingEvent(tx, BookingEventSeatModified, modified, user); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// cancelTicket marks the ticket cancelled, provided it is still at the version
+// that was read, and records the cancellation in the audit log and the outbox.
+func cancelTicket(ctx context.Context, db dbExecutor, booking *pb.BookingDbResponse, user *pb.User) error {
+	result, err := db.Exec("UPDATE tickets SET t_status = ?, t_version = t_version + 1 WHERE t_id = ? AND t_version = ?",
+		TicketStatusCancelled, booking.GetId(), booking.GetVersion())
+	if err != nil {
+		return err
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		return concurrentModificationError(booking)
+	}
+	if err := appendAuditEntry(ctx, db, AuditActionBookingCancel, booking.GetId(), booking, nil); err != nil {
+		return err
+	}
+	cancelled := proto.Clone(booking).(*pb.BookingDbResponse)
+	cancelled.Version++
+	return recordBookingEvent(db, BookingEventCancelled, cancelled, user)
+}
+
+// resolveBooking finds the active booking addressed by bookingId (ticket id or
+// PNR). Without a bookingId the user's only booking is used. When a user is
+// given the booking must belong to that user.
+func resolveBooking(db dbExecutor, bookingId string, user *pb.User) (*pb.BookingDbResponse, *pb.User, error) {
+	if bookingId != "" {
+		tickets, err := queryTickets(db, "(t_id = ? OR t_pnr = ?) AND t_status = ?", bookingId, normalizePnr(bookingId), TicketStatusBooked)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(tickets) == 0 {
+			return nil, nil, status.Errorf(codes.NotFound, "No booking exists with id %s", bookingId)
+		}
+		dbUser, err := findUserById(db, tickets[0].GetUserid())
+		if err != nil {
+			return nil, nil, err
+		}
+		if user.GetEmail() != "" && normalizeEmail(user.GetEmail()) != dbUser.GetEmail() {
+			return nil, nil, status.Errorf(codes.NotFound, "No booking exists with id %s", bookingId)
+		}
+		return tickets[0], dbUser, nil
+	}
+
+	dbUser, isUserExists := retrieveUserIfExists(db, user.GetEmail())
+	if !isUserExists {
+		return nil, nil, status.Errorf(codes.NotFound, "No booking exists with email %s", user.GetEmail())
+	}
+	tickets, err := queryTickets(db, "t_user_id = ? AND t_status = ?", dbUser.GetId(), TicketStatusBooked)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(tickets) == 0 {
+		return nil, nil, status.Errorf(codes.NotFound, "No booking exists with email %s", user.GetEmail())
+	}
+	if len(tickets) > 1 {
+		return nil, nil, status.Errorf(codes.FailedPrecondition, "User %s has %d bookings, specify the booking id or PNR", user.GetEmail(), len(tickets))
+	}
+	return tickets[0], dbUser, nil
+}
 
-    return transformDbResponseToBooking(response, dbUser), -1
+// authorizeBookingOwner checks that a booking addressed by its ticket id or PNR
+// is accessed by its passenger, who proves it with their email, or by a
+// verified service. Knowing the PNR alone isn't enough, it's printed on the
+// e-ticket.
+func authorizeBookingOwner(ctx context.Context, bookingId, email string, owner *pb.User) error {
+	if isVerifiedActor(ctx) {
+		return nil
+	}
+	if email == "" {
+		return status.Errorf(codes.PermissionDenied, "The passenger's email is required for booking %s", bookingId)
+	}
+	if normalizeEmail(email) != owner.GetEmail() {
+		return status.Errorf(codes.NotFound, "No booking exists with id %s", bookingId)
+	}
+	return nil
 }
 
-func retrieveUserIfExists(b *BookingService, firstName, lastName, userEmail string) (*pb.User, bool) {
+func findUserById(db dbExecutor, userId string) (*pb.User, error) {
+	var dbUser pb.User
+	if err := scanUser(db.QueryRow("SELECT "+userColumns+" FROM users WHERE u_id = ?", userId), &dbUser); err != nil {
+		return nil, err
+	}
+	return &dbUser, nil
+}
+
+func retrieveUserIfExists(db dbExecutor, userEmail string) (*pb.User, bool) {
     var dbUser pb.User
-    dbRow := b.db.QueryRow("SELECT * FROM users WHERE u_user_fname = ? and u_user_lname = ? and u_user_email = ?", firstName, lastName, userEmail)
-    if dbErr := dbRow.Scan(&dbUser.Id , &dbUser.Firstname, &dbUser.Lastname, &dbUser.Email); dbErr != nil {
+    dbRow := db.QueryRow("SELECT "+userColumns+" FROM users WHERE u_user_email = ? and u_status = ?",
+        normalizeEmail(userEmail), UserStatusActive)
+    if dbErr := scanUser(dbRow, &dbUser); dbErr != nil {
        return nil, false
     }
 
     return &dbUser, true
 }
 
-func retrieveBookingIfExists(b *BookingService, userId, fromLocation, toLocation string) (*pb.BookingDbResponse, bool){
+// checkActiveBookings rejects another booking of the journey once the user
+// holds the maximum number of booked tickets for it.
+func (b *BookingService) checkActiveBookings(db dbExecutor, userId, from, to string) error {
+	if b.maxActiveBookings <= 0 {
+		return nil
+	}
+	var active int
+	if err := db.QueryRow("SELECT COUNT(*) FROM tickets WHERE t_user_id = ? AND t_from = ? AND t_to = ? AND t_status = ?",
+		userId, from, to, TicketStatusBooked).Scan(&active); err != nil {
+		return err
+	}
+	if active < b.maxActiveBookings {
+		return nil
+	}
+	return quotaExceeded(fmt.Sprintf("At most %d bookings from %s to %s may be active at once", b.maxActiveBookings, from, to),
+		&errdetails.QuotaFailure_Violation{
+			Subject:     "active_bookings:" + userId,
+			Description: fmt.Sprintf("%d of %d bookings from %s to %s are active", active, b.maxActiveBookings, from, to),
+		}, 0)
+}
+
+func retrieveBookingIfExists(db dbExecutor, userId, fromLocation, toLocation string, departureAt int64) (*pb.BookingDbResponse, bool){
     var response pb.BookingDbResponse
-    row := b.db.QueryRow("SELECT * FROM tickets WHERE t_user_id = ? and t_from = ? and t_to = ?", userId, fromLocation, toLocation)
-    if err := row.Scan(&response.Id, &response.From, &response.To, &response.Price, &response.Seat, &response.Section, &response.Userid); err != nil {
+    row := db.QueryRow("SELECT "+ticketColumns+" FROM tickets WHERE t_user_id = ? and t_from = ? and t_to = ? and t_departure_at IS ? and t_status = ?",
+        userId, fromLocation, toLocation, nullableUnix(departureAt), TicketStatusBooked)
+    if err := scanTicket(row, &response); err != nil {
        return nil, false
     }
 
     return &response, true
 }
 
-func transformAsBookingResponse(booking *pb.Booking) *pb.BookingResponse {
+func transformDbResponseToBookingResponse(bookingDbResp *pb.BookingDbResponse, userDbResp *pb.User) *pb.BookingResponse {
 	return &pb.BookingResponse{
-		Id:      booking.GetId(),
-		From:    booking.GetFrom(),
-		To:      booking.GetTo(),
-		Price:   booking.GetPrice(),
-		Seat:    booking.GetSeat(),
-		Section: booking.GetSection(),
-		User:    booking.GetUser(),
-	}
-}
-
-func transformDbResponseToBooking(bookingDbResp pb.BookingDbResponse, userDbResp pb.User) *pb.Booking {
-	return &pb.Booking{
 		Id:      bookingDbResp.GetId(),
 		From:    bookingDbResp.GetFrom(),
 		To:      bookingDbResp.GetTo(),
 		Price:   bookingDbResp.GetPrice(),
 		Seat:    bookingDbResp.GetSeat(),
 		Section: bookingDbResp.GetSection(),
-        User: &pb.User{
-        	Firstname: userDbResp.GetFirstname(),
-        	Lastname:  userDbResp.GetLastname(),
-        	Email:     userDbResp.GetEmail(),
-        	},
+        User: transformDbUser(userDbResp),
+        Departure: departureTimestamp(bookingDbResp.GetDepartureAt()),
+        Pnr: bookingDbResp.GetPnr(),
+        Etag: bookingEtag(bookingDbResp.GetVersion()),
     }
 }
 
-func transformDbResponseToBookingResponse(bookingDbResp pb.BookingDbResponse, userDbResp pb.User) *pb.BookingResponse {
+// transformDbUser drops the internal user id before a user is returned to clients
+func transformDbUser(userDbResp *pb.User) *pb.User {
+	return &pb.User{
+		Firstname: userDbResp.GetFirstname(),
+		Lastname:  userDbResp.GetLastname(),
+		Email:     userDbResp.GetEmail(),
+	}
+}
+
+// transformV2Booking returns a v2 booking in the shape of the v1 API
+func transformV2Booking(booking *bookingv2.Booking) *pb.BookingResponse {
 	return &pb.BookingResponse{
-		Id:      bookingDbResp.GetId(),
-		From:    bookingDbResp.GetFrom(),
-		To:      bookingDbResp.GetTo(),
-		Price:   bookingDbResp.GetPrice(),
-		Seat:    bookingDbResp.GetSeat(),
-		Section: bookingDbResp.GetSection(),
-        User: &pb.User{
-        	Firstname: userDbResp.GetFirstname(),
-        	Lastname:  userDbResp.GetLastname(),
-        	Email:     userDbResp.GetEmail(),
-        	},
-    }
-}
\ No newline at end of file
+		Id:        booking.GetUid(),
+		From:      booking.GetOrigin(),
+		To:        booking.GetDestination(),
+		Price:     booking.GetPrice(),
+		Seat:      booking.GetSeat().GetNumber(),
+		Section:   booking.GetSeat().GetSection(),
+		User:      transformV2Passenger(booking.GetPassenger()),
+		Departure: booking.GetDepartureTime(),
+		Pnr:       booking.GetPnr(),
+		Etag:      booking.GetEtag(),
+	}
+}
+
+func transformV2Bookings(bookings []*bookingv2.Booking) *pb.BookingListResponse {
+	response := &pb.BookingListResponse{}
+	for _, booking := range bookings {
+		response.Bookings = append(response.Bookings, transformV2Booking(booking))
+	}
+	return response
+}
+
+func transformV2Passenger(passenger *bookingv2.Passenger) *pb.User {
+	return &pb.User{
+		Firstname: passenger.GetGivenName(),
+		Lastname:  passenger.GetFamilyName(),
+		Email:     passenger.GetEmail(),
+	}
+}
+
+// bookingEtag is the opaque form of a ticket version handed to clients
+func bookingEtag(version int64) string {
+	return strconv.FormatInt(version, 10)
+}
+
+// checkEtag rejects a change made against an outdated copy of the booking
+func checkEtag(booking *pb.BookingDbResponse, etag string) error {
+	if etag != "" && etag != bookingEtag(booking.GetVersion()) {
+		return concurrentModificationError(booking)
+	}
+	return nil
+}
+
+func concurrentModificationError(booking *pb.BookingDbResponse) error {
+	return status.Errorf(codes.Aborted, "Booking %s was changed by another request, read it again and retry", booking.GetPnr())
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTicket(row rowScanner, response *pb.BookingDbResponse) error {
+	return row.Scan(&response.Id, &response.From, &response.To, &response.Price, &response.Seat, &response.Section,
+		&response.Userid, &response.DepartureAt, &response.Pnr, &response.Version)
+}
+
+func scanUser(row rowScanner, user *pb.User) error {
+	return row.Scan(&user.Id, &user.Firstname, &user.Lastname, &user.Email)
+}
+
+func departureTimestamp(departureAt int64) *timestamppb.Timestamp {
+	if departureAt == 0 {
+		return nil
+	}
+	return timestamppb.New(time.Unix(departureAt, 0))
+}
+
+// nullableUnix stores unset times as NULL rather than the epoch
+func nullableUnix(unix int64) sql.NullInt64 {
+	return sql.NullInt64{Int64: unix, Valid: unix != 0}
+}
@@ -1,9 +1,14 @@
-package api
+package api_test
 
 import (
 	pb "ticket-booking-app/domain"
     "ticket-booking-app/server/api"
 	"github.com/stretchr/testify/assert"
+	_ "github.com/mattn/go-sqlite3"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"database/sql"
+	"strings"
 	"testing"
 	"context"
 )
@@ -11,13 +16,13 @@ import (
 const(
     FIRST_NAME = "testFirstName"
     LAST_NAME = "testLastName"
-    EMAIL = "testEmail@test.com"
+    EMAIL = "testemail@test.com"
 )
 
 func TestShouldCreateTrainBooking(t *testing.T) {
     request := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
 	response := createTrainBookingResponse(FIRST_NAME, LAST_NAME, EMAIL)
-	booking := createMockTrainBooking(request)
+	booking := createMockTrainBooking(t, request)
 
     assert.Equal(t, response.GetUser(), booking.GetUser(), "Booking creation is not working as expected")
 }
@@ -25,7 +30,7 @@ func TestShouldCreateTrainBooking(t *testing.T) {
 func TestShouldReturnBookingByUser(t *testing.T) {
 	request := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
 
-    bookingService := api.NewBookingService()
+    bookingService := api.NewBookingService(newTestDatabase(t))
     _, err := bookingService.CreateBooking(context.TODO(), request)
     if err != nil {
        	t.Errorf("Error in creating booking %v ", err)
@@ -44,8 +49,24 @@ func TestShouldReturnBookingByUser(t *testing.T) {
     assert.Equal(t, response.GetUser(), got.GetUser(), "Can't retrieve existing booking")
 }
 
-func createMockTrainBooking(request *pb.BookingRequest) (*pb.BookingResponse) {
-    bookingService := api.NewBookingService()
+func newTestDatabase(t *testing.T) *sql.DB {
+    t.Helper()
+    db, err := sql.Open("sqlite3", ":memory:")
+    if err != nil {
+        t.Fatalf("Error in opening database %v ", err)
+    }
+    // every connection to :memory: is a separate database
+    db.SetMaxOpenConns(1)
+    t.Cleanup(func() { db.Close() })
+
+    if err := api.MigrateDatabase(db); err != nil {
+        t.Fatalf("Error in migrating database %v ", err)
+    }
+    return db
+}
+
+func createMockTrainBooking(t *testing.T, request *pb.BookingRequest) (*pb.BookingResponse) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
     booking, err := bookingService.CreateBooking(context.TODO(), request)
     if err != nil {
     	return nil
@@ -79,3 +100,106 @@ func createNewTrainBookingRequest(fName, lName, email string) (*pb.BookingReques
            		},
            	};
 }
+
+func TestShouldAddressBookingsByIdOrPnr(t *testing.T) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    first, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    secondRequest := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+    secondRequest.To = "Paris"
+    second, err := bookingService.CreateBooking(context.TODO(), secondRequest)
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    assert.Len(t, first.GetPnr(), 6)
+
+    _, err = bookingService.GetBookingByUser(context.TODO(), &pb.GetBookingByUserRequest{User: first.GetUser()})
+    assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Ambiguous booking should not be picked arbitrarily")
+
+    got, err := bookingService.GetBooking(context.TODO(), &pb.GetBookingRequest{BookingId: strings.ToLower(second.GetPnr())})
+    if err != nil {
+        t.Fatalf("Error in retrieving booking %v ", err)
+    }
+    assert.Equal(t, second.GetId(), got.GetId())
+
+    newSeat := int32(0)
+    for newSeat == first.GetSeat() || newSeat == second.GetSeat() {
+        newSeat++
+    }
+    modified, err := bookingService.ModifySeatByUser(context.TODO(), &pb.SeatModificationRequest{BookingId: first.GetId(),
+        User: first.GetUser(), Section: "A", Seat: newSeat})
+    if err != nil {
+        t.Fatalf("Error in modifying booking %v ", err)
+    }
+    assert.Equal(t, first.GetPnr(), modified.GetPnr())
+    got, _ = bookingService.GetBooking(context.TODO(), &pb.GetBookingRequest{BookingId: first.GetId()})
+    assert.Equal(t, newSeat, got.GetSeat(), "Seat change should be stored")
+
+    _, err = bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: second.GetPnr(),
+        User: &pb.User{Email: "someone@else.com"}})
+    assert.Equal(t, codes.NotFound, status.Code(err), "Booking of another user should not be cancelled")
+
+    if _, err := bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: second.GetPnr(), User: second.GetUser()}); err != nil {
+        t.Fatalf("Error in cancelling booking %v ", err)
+    }
+    remaining, err := bookingService.ListMyBookings(context.TODO(), &pb.ListMyBookingsRequest{User: first.GetUser()})
+    if err != nil {
+        t.Fatalf("Error in listing bookings %v ", err)
+    }
+    assert.Len(t, remaining.GetBookings(), 1)
+    assert.Equal(t, first.GetId(), remaining.GetBookings()[0].GetId())
+}
+
+func TestShouldRejectChangesToOutdatedBooking(t *testing.T) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    booking, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    staleEtag := booking.GetEtag()
+
+    newSeat := (booking.GetSeat() + 1) % api.MaxSeatsPerSection
+    modified, err := bookingService.ModifySeatByUser(context.TODO(), &pb.SeatModificationRequest{BookingId: booking.GetPnr(),
+        Section: booking.GetSection(), Seat: newSeat, Etag: staleEtag, User: &pb.User{Email: EMAIL}})
+    if err != nil {
+        t.Fatalf("Error in modifying booking %v ", err)
+    }
+    assert.NotEqual(t, staleEtag, modified.GetEtag(), "Modification should change the etag")
+
+    _, err = bookingService.ModifySeatByUser(context.TODO(), &pb.SeatModificationRequest{BookingId: booking.GetPnr(),
+        Section: booking.GetSection(), Seat: booking.GetSeat(), Etag: staleEtag, User: &pb.User{Email: EMAIL}})
+    assert.Equal(t, codes.Aborted, status.Code(err), "Modification with outdated etag should be rejected")
+
+    _, err = bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: booking.GetPnr(), Etag: staleEtag, User: &pb.User{Email: EMAIL}})
+    assert.Equal(t, codes.Aborted, status.Code(err), "Cancellation with outdated etag should be rejected")
+
+    _, err = bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: booking.GetPnr(), Etag: modified.GetEtag(), User: &pb.User{Email: EMAIL}})
+    assert.NoError(t, err)
+}
+
+func TestShouldRequirePassengerToChangeBookingByPnr(t *testing.T) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    booking, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    newSeat := (booking.GetSeat() + 1) % api.MaxSeatsPerSection
+
+    _, err = bookingService.ModifySeatByUser(context.TODO(), &pb.SeatModificationRequest{BookingId: booking.GetPnr(),
+        Section: booking.GetSection(), Seat: newSeat})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "The PNR alone shouldn't be enough to change a booking")
+    _, err = bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: booking.GetPnr(),
+        User: &pb.User{Email: "someone@test.com"}})
+    assert.Equal(t, codes.NotFound, status.Code(err), "Bookings of other passengers shouldn't be found")
+    _, err = bookingService.GetETicket(context.TODO(), &pb.GetETicketRequest{BookingId: booking.GetId()})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "The e-ticket should only be given to the passenger")
+
+    _, err = bookingService.ModifySeatByUser(api.WithActor(context.TODO(), "service:support"), &pb.SeatModificationRequest{
+        BookingId: booking.GetPnr(), Section: booking.GetSection(), Seat: newSeat})
+    assert.NoError(t, err, "Verified services should change any booking")
+    _, err = bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: booking.GetPnr(),
+        User: &pb.User{Email: strings.ToUpper(EMAIL)}})
+    assert.NoError(t, err)
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"bytes"
+	"context"
+)
+
+const (
+	// eTicketChunkSize keeps every streamed message well below the gRPC size limit
+	eTicketChunkSize = 32 * 1024
+	maxGroupETickets = 500
+)
+
+func (b *BookingService) GetETicket(ctx context.Context, req *pb.GetETicketRequest) (*pb.ETicket, error) {
+	if req.GetBookingId() == "" && req.GetUser().GetEmail() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Booking id, PNR or user email is required")
+	}
+	booking, dbUser, err := resolveBooking(withContext(ctx, b.db), req.GetBookingId(), req.GetUser())
+	if err != nil {
+		return nil, err
+	}
+	// the e-ticket carries the passenger's name and a valid token
+	if req.GetBookingId() != "" {
+		if err := authorizeBookingOwner(ctx, req.GetBookingId(), req.GetUser().GetEmail(), dbUser); err != nil {
+			return nil, err
+		}
+	}
+	page, err := b.newETicketPage(booking, dbUser)
+	if err != nil {
+		return nil, err
+	}
+
+	var pdf bytes.Buffer
+	if err := renderETicketPdf(&pdf, []*eTicketPage{page}); err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while rendering e-ticket: %v", err)
+	}
+	return &pb.ETicket{
+		BookingId: booking.GetId(),
+		Pnr:       booking.GetPnr(),
+		Token:     page.token,
+		Filename:  "eticket-" + booking.GetPnr() + ".pdf",
+		Pdf:       pdf.Bytes(),
+	}, nil
+}
+
+// StreamGroupETicket sends a single PDF with a page per booking in chunks, as
+// it is written.
+func (b *BookingService) StreamGroupETicket(req *pb.GetGroupETicketRequest, stream grpc.ServerStreamingServer[pb.ETicketChunk]) error {
+	var bookings []*pb.BookingDbResponse
+	var users []*pb.User
+	if len(req.GetBookingIds()) == 0 {
+		if req.GetUser().GetEmail() == "" {
+			return status.Errorf(codes.InvalidArgument, "Booking ids or user email are required")
+		}
+		dbUser, isUserExists := retrieveUserIfExists(withContext(stream.Context(), b.db), req.GetUser().GetEmail())
+		if !isUserExists {
+			return status.Errorf(codes.NotFound, "No booking exists with email %s", req.GetUser().GetEmail())
+		}
+		tickets, err := queryTickets(withContext(stream.Context(), b.db), "t_user_id = ? AND t_status = ? ORDER BY t_departure_at, t_created_at", dbUser.GetId(), TicketStatusBooked)
+		if err != nil {
+			return err
+		}
+		for range tickets {
+			users = append(users, dbUser)
+		}
+		bookings = tickets
+	}
+	for _, bookingId := range req.GetBookingIds() {
+		booking, dbUser, err := resolveBooking(withContext(stream.Context(), b.db), bookingId, req.GetUser())
+		if err != nil {
+			return err
+		}
+		if err := authorizeBookingOwner(stream.Context(), bookingId, req.GetUser().GetEmail(), dbUser); err != nil {
+			return err
+		}
+		bookings = append(bookings, booking)
+		users = append(users, dbUser)
+	}
+	if len(bookings) == 0 {
+		return status.Errorf(codes.NotFound, "No booking exists with email %s", req.GetUser().GetEmail())
+	}
+	if len(bookings) > maxGroupETickets {
+		return status.Errorf(codes.InvalidArgument, "At most %d bookings fit on a group e-ticket", maxGroupETickets)
+	}
+
+	pages := make([]*eTicketPage, len(bookings))
+	for i, booking := range bookings {
+		page, err := b.newETicketPage(booking, users[i])
+		if err != nil {
+			return err
+		}
+		pages[i] = page
+	}
+
+	writer := &eTicketChunkWriter{stream: stream, first: &pb.ETicketChunk{
+		Filename:    "eticket-group-" + bookings[0].GetPnr() + ".pdf",
+		TicketCount: int32(len(pages)),
+	}}
+	if err := renderETicketPdf(writer, pages); err != nil {
+		if _, isStatus := status.FromError(err); isStatus {
+			return err
+		}
+		return status.Errorf(codes.Internal, "Error while rendering e-ticket: %v", err)
+	}
+	return writer.flush()
+}
+
+func (b *BookingService) newETicketPage(booking *pb.BookingDbResponse, user *pb.User) (*eTicketPage, error) {
+	token, err := b.ticketSigner.IssueTicketToken(booking)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while signing ticket: %v", err)
+	}
+	return &eTicketPage{booking: booking, user: user, token: token}, nil
+}
+
+// eTicketChunkWriter sends everything written to it as ETicketChunk messages
+type eTicketChunkWriter struct {
+	stream  grpc.ServerStreamingServer[pb.ETicketChunk]
+	first   *pb.ETicketChunk
+	pending []byte
+}
+
+func (w *eTicketChunkWriter) Write(data []byte) (int, error) {
+	w.pending = append(w.pending, data...)
+	for len(w.pending) >= eTicketChunkSize {
+		if err := w.send(w.pending[:eTicketChunkSize]); err != nil {
+			return 0, err
+		}
+		w.pending = w.pending[eTicketChunkSize:]
+	}
+	return len(data), nil
+}
+
+func (w *eTicketChunkWriter) flush() error {
+	if len(w.pending) == 0 && w.first == nil {
+		return nil
+	}
+	err := w.send(w.pending)
+	w.pending = nil
+	return err
+}
+
+func (w *eTicketChunkWriter) send(data []byte) error {
+	chunk := &pb.ETicketChunk{}
+	if w.first != nil {
+		chunk, w.first = w.first, nil
+	}
+	chunk.Data = append([]byte(nil), data...)
+	return w.stream.Send(chunk)
+}
@@ -0,0 +1,94 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/grpc"
+	"crypto/ed25519"
+	"encoding/base64"
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestShouldRenderSignedETicket(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    booking, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+
+    eTicket, err := bookingService.GetETicket(context.TODO(), &pb.GetETicketRequest{BookingId: booking.GetPnr(), User: &pb.User{Email: EMAIL}})
+    if err != nil {
+        t.Fatalf("Error in getting e-ticket %v ", err)
+    }
+    assert.True(t, bytes.HasPrefix(eTicket.GetPdf(), []byte("%PDF-")), "E-ticket should be a PDF")
+    assert.Equal(t, "eticket-"+booking.GetPnr()+".pdf", eTicket.GetFilename())
+
+    parts := strings.Split(eTicket.GetToken(), ".")
+    if !assert.Len(t, parts, 2) {
+        return
+    }
+    payload, _ := base64.RawURLEncoding.DecodeString(parts[0])
+    signature, _ := base64.RawURLEncoding.DecodeString(parts[1])
+    var token pb.TicketToken
+    if err := proto.Unmarshal(payload, &token); err != nil {
+        t.Fatalf("Error in decoding token %v ", err)
+    }
+    assert.Equal(t, booking.GetId(), token.GetTicketId())
+    assert.Equal(t, booking.GetSeat(), token.GetSeat())
+
+    var seed []byte
+    if err := db.QueryRow("SELECT s_private_key FROM ticket_signing_keys WHERE s_key_id = ?", token.GetKeyId()).Scan(&seed); err != nil {
+        t.Fatalf("Error in reading signing key %v ", err)
+    }
+    publicKey := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+    assert.True(t, ed25519.Verify(publicKey, payload, signature), "Token should be signed by the active key")
+}
+
+type eTicketStream struct {
+    grpc.ServerStream
+    chunks []*pb.ETicketChunk
+}
+
+func (s *eTicketStream) Send(chunk *pb.ETicketChunk) error {
+    s.chunks = append(s.chunks, chunk)
+    return nil
+}
+
+func (s *eTicketStream) Context() context.Context {
+    return context.TODO()
+}
+
+func TestShouldStreamGroupETicket(t *testing.T) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    destinations := []string{"Paris", "Lyon", "Lille", "Nice", "Brest", "Nantes"}
+    for _, destination := range destinations {
+        request := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+        request.To = destination
+        if _, err := bookingService.CreateBooking(context.TODO(), request); err != nil {
+            t.Fatalf("Error in creating booking %v ", err)
+        }
+    }
+
+    stream := &eTicketStream{}
+    err := bookingService.StreamGroupETicket(&pb.GetGroupETicketRequest{User: &pb.User{Email: EMAIL}}, stream)
+    if err != nil {
+        t.Fatalf("Error in streaming e-ticket %v ", err)
+    }
+    assert.Greater(t, len(stream.chunks), 1, "Large e-tickets should be sent in several chunks")
+    assert.EqualValues(t, len(destinations), stream.chunks[0].GetTicketCount())
+    assert.Empty(t, stream.chunks[1].GetFilename(), "Only the first chunk carries the metadata")
+
+    var pdf bytes.Buffer
+    for _, chunk := range stream.chunks {
+        pdf.Write(chunk.GetData())
+    }
+    assert.True(t, bytes.HasPrefix(pdf.Bytes(), []byte("%PDF-")))
+    assert.True(t, bytes.Contains(pdf.Bytes(), []byte("%%EOF")), "Stream should contain the complete PDF")
+    assert.Equal(t, len(destinations), bytes.Count(pdf.Bytes(), []byte("/Type /Page\n")))
+}
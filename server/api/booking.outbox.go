@@ -0,0 +1,77 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"github.com/google/uuid"
+	"time"
+)
+
+const (
+	BookingEventCreated      = "booking.created"
+	BookingEventSeatModified = "booking.seat_modified"
+	BookingEventCancelled    = "booking.cancelled"
+)
+
+// recordBookingEvent writes a domain event to the outbox. Like audit entries it
+// must run on the transaction of the ticket change, so an event exists exactly
+// when the change was committed. The webhook dispatcher delivers it later.
+func recordBookingEvent(db dbExecutor, eventType string, booking *pb.BookingDbResponse, user *pb.User) error {
+	now := time.Now()
+	event := &pb.BookingEvent{
+		Id:         uuid.NewString(),
+		Type:       eventType,
+		OccurredAt: timestamppb.New(now),
+		Booking:    transformDbResponseToBookingResponse(booking, user),
+	}
+	payload, err := protojson.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = db.Exec("INSERT INTO outbox_events (e_id, e_type, e_resource, e_payload, e_created_at) VALUES (?, ?, ?, ?, ?)",
+		event.GetId(), eventType, booking.GetId(), string(payload), now.Unix())
+	return err
+}
+
+// scrubBookingEvents removes the passenger's details from the events of the
+// user's tickets, delivered or not, once the account is deleted. The events
+// keep the user id and stay in the outbox for their deliveries.
+func scrubBookingEvents(db dbExecutor, userId string) error {
+	rows, err := db.Query("SELECT e_seq, e_payload FROM outbox_events WHERE e_resource IN (SELECT t_id FROM tickets WHERE t_user_id = ?)", userId)
+	if err != nil {
+		return err
+	}
+	payloads := map[int64]string{}
+	for rows.Next() {
+		var seq int64
+		var payload string
+		if err := rows.Scan(&seq, &payload); err != nil {
+			rows.Close()
+			return err
+		}
+		payloads[seq] = payload
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for seq, payload := range payloads {
+		var event pb.BookingEvent
+		if err := protojson.Unmarshal([]byte(payload), &event); err != nil {
+			return err
+		}
+		if event.Booking != nil {
+			event.Booking.User = &pb.User{Id: userId}
+		}
+		scrubbed, err := protojson.Marshal(&event)
+		if err != nil {
+			return err
+		}
+		if _, err := db.Exec("UPDATE outbox_events SET e_payload = ? WHERE e_seq = ?", string(scrubbed), seq); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"math/big"
+	"strings"
+)
+
+const (
+	pnrLength = 6
+	// no 0/O or 1/I so PNRs can be read out over the phone
+	pnrAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+	pnrAttempts = 5
+)
+
+func newPnr() (string, error) {
+	pnr := make([]byte, pnrLength)
+	for i := range pnr {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(pnrAlphabet))))
+		if err != nil {
+			return "", err
+		}
+		pnr[i] = pnrAlphabet[n.Int64()]
+	}
+	return string(pnr), nil
+}
+
+func normalizePnr(pnr string) string {
+	return strings.ToUpper(strings.TrimSpace(pnr))
+}
+
+// insertTicketWithPnr runs the ticket INSERT with a fresh PNR appended to args,
+// drawing a new PNR if it collides with an existing one.
+func insertTicketWithPnr(db dbExecutor, query string, args ...any) (string, error) {
+	var err error
+	for attempt := 0; attempt < pnrAttempts; attempt++ {
+		var pnr string
+		if pnr, err = newPnr(); err != nil {
+			return "", err
+		}
+		if _, err = db.Exec(query, append(args, pnr)...); !isUniqueViolation(err) {
+			return pnr, err
+		}
+	}
+	return "", err
+}
+
+// backfillTicketPnrs gives the tickets booked before PNRs existed one each,
+// drawn like the PNRs of new bookings.
+func backfillTicketPnrs(tx *sql.Tx) error {
+	rows, err := tx.Query("SELECT t_id FROM tickets WHERE t_pnr IS NULL")
+	if err != nil {
+		return err
+	}
+	var ticketIds []string
+	for rows.Next() {
+		var ticketId string
+		if err := rows.Scan(&ticketId); err != nil {
+			rows.Close()
+			return err
+		}
+		ticketIds = append(ticketIds, ticketId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, ticketId := range ticketIds {
+		for attempt := 0; ; attempt++ {
+			pnr, err := newPnr()
+			if err != nil {
+				return err
+			}
+			_, err = tx.Exec("UPDATE tickets SET t_pnr = ? WHERE t_id = ?", pnr, ticketId)
+			if err == nil {
+				break
+			}
+			if !isUniqueViolation(err) || attempt == pnrAttempts-1 {
+				return err
+			}
+		}
+	}
+	return nil
+}
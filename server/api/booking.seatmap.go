@@ -0,0 +1,165 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// DefaultSeatHoldTTL is how long HoldSeat keeps a seat by default
+	DefaultSeatHoldTTL = 2 * time.Minute
+
+	// ErrorDomain is the domain of the ErrorInfo details of the errors
+	ErrorDomain = "ticket-booking-app"
+
+	// watchPollInterval is how often watchers look for new events in the outbox
+	watchPollInterval = 500 * time.Millisecond
+	watchBatchSize    = 100
+)
+
+// GetSeatMap returns the layout of the train with the seats that are taken.
+func (b *BookingService) GetSeatMap(ctx context.Context, req *pb.GetSeatMapRequest) (*pb.SeatMap, error) {
+	seatMap := &pb.SeatMap{}
+	for _, section := range b.seatAllocator.Sections() {
+		if req.GetSection() != "" && req.GetSection() != section.Name {
+			continue
+		}
+		seatMap.Sections = append(seatMap.Sections, &pb.SectionSeats{
+			Name:     section.Name,
+			Seats:    section.Seats,
+			Occupied: b.seatAllocator.OccupiedSeats(section.Name),
+			Held:     b.seatAllocator.HeldSeats(section.Name),
+		})
+	}
+	if len(seatMap.GetSections()) == 0 {
+		return nil, status.Errorf(codes.NotFound, "No section exists with name %s", req.GetSection())
+	}
+	return seatMap, nil
+}
+
+// HoldSeat keeps a free seat for the caller, who books it or moves a booking to
+// it by passing the hold id. Holds aren't stored, they end with the server.
+// Callers without an identity are told apart by their address.
+func (b *BookingService) HoldSeat(ctx context.Context, req *pb.HoldSeatRequest) (*pb.SeatHold, error) {
+	holder := callerKey(ctx)
+	holdId, expires, err := b.seatAllocator.HoldSeat(req.GetSeat(), req.GetSection(), holder, b.maxSeatHolds, b.seatHoldTTL)
+	if errors.Is(err, TooManySeatHolds) {
+		return nil, quotaExceeded(fmt.Sprintf("At most %d seats may be held at once", b.maxSeatHolds),
+			&errdetails.QuotaFailure_Violation{
+				Subject:     "seat_holds:" + holder,
+				Description: fmt.Sprintf("%d of %d seats are held", b.maxSeatHolds, b.maxSeatHolds),
+			}, 0)
+	}
+	if err != nil {
+		return nil, seatNotAvailable(req.GetSeat(), req.GetSection())
+	}
+	slog.InfoContext(ctx, "Held seat", "hold_id", holdId, "seat", req.GetSeat(), "section", req.GetSection())
+	return &pb.SeatHold{HoldId: holdId, Section: req.GetSection(), Seat: req.GetSeat(), ExpiresAt: timestamppb.New(expires)}, nil
+}
+
+func (b *BookingService) ReleaseSeatHold(ctx context.Context, req *pb.ReleaseSeatHoldRequest) (*pb.ReleaseSeatHoldResponse, error) {
+	if err := b.seatAllocator.ReleaseHold(req.GetHoldId()); err != nil {
+		return nil, seatHoldNotFound(req.GetHoldId())
+	}
+	return &pb.ReleaseSeatHoldResponse{}, nil
+}
+
+func seatNotAvailable(seat int32, section string) error {
+	return withReason(status.Newf(codes.InvalidArgument, "Seat number %d in section %s is not available", seat, section),
+		pb.ErrorReason_SEAT_NOT_AVAILABLE)
+}
+
+func seatHoldNotFound(holdId string) error {
+	return withReason(status.Newf(codes.FailedPrecondition, "Seat hold %s doesn't exist or has expired", holdId),
+		pb.ErrorReason_SEAT_HOLD_NOT_FOUND)
+}
+
+// withReason details st with the reason, which clients tell apart from other
+// errors of the same code
+func withReason(st *status.Status, reason pb.ErrorReason) error {
+	detailed, err := st.WithDetails(&errdetails.ErrorInfo{Reason: reason.String(), Domain: ErrorDomain})
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// WatchBookings streams the events of the booking changes committed after the
+// call started. Events are read from the outbox, so watchers see the same
+// changes in the same order as webhooks. Like webhooks, only services get the
+// passengers and PNRs; other callers get the type, section and seat.
+func (b *BookingService) WatchBookings(req *pb.WatchBookingsRequest, stream grpc.ServerStreamingServer[pb.BookingEvent]) error {
+	ctx := stream.Context()
+	seatsOnly := requireServiceActor(ctx) != nil
+	var lastSeq int64
+	if err := b.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(e_seq), 0) FROM outbox_events").Scan(&lastSeq); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(watchPollInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case <-b.watchesClosed:
+			return status.Errorf(codes.Unavailable, "Server is shutting down, watch again")
+		case <-ticker.C:
+		}
+		events, seq, err := b.readEventsAfter(ctx, lastSeq)
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if seatsOnly {
+				event = &pb.BookingEvent{Type: event.GetType(),
+					Booking: &pb.BookingResponse{Section: event.GetBooking().GetSection(), Seat: event.GetBooking().GetSeat()}}
+			}
+			if err := stream.Send(event); err != nil {
+				return err
+			}
+		}
+		lastSeq = seq
+	}
+}
+
+// CloseWatches ends the WatchBookings streams, which would otherwise keep a
+// graceful shutdown waiting until its timeout.
+func (b *BookingService) CloseWatches() {
+	b.closeWatches.Do(func() { close(b.watchesClosed) })
+}
+
+// readEventsAfter returns the outbox events following seq and the sequence
+// number of the last one.
+func (b *BookingService) readEventsAfter(ctx context.Context, seq int64) ([]*pb.BookingEvent, int64, error) {
+	rows, err := b.db.QueryContext(ctx, "SELECT e_seq, e_payload FROM outbox_events WHERE e_seq > ? ORDER BY e_seq LIMIT ?", seq, watchBatchSize)
+	if err != nil {
+		return nil, seq, err
+	}
+	defer rows.Close()
+
+	var events []*pb.BookingEvent
+	for rows.Next() {
+		var payload string
+		if err := rows.Scan(&seq, &payload); err != nil {
+			return nil, seq, err
+		}
+		event := &pb.BookingEvent{}
+		if err := protojson.Unmarshal([]byte(payload), event); err != nil {
+			slog.ErrorContext(ctx, "Skipping unreadable outbox event", "seq", seq, "error", err)
+			continue
+		}
+		events = append(events, event)
+	}
+	return events, seq, rows.Err()
+}
@@ -0,0 +1,204 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestShouldReturnSeatMapWithTakenSeats(t *testing.T) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    allocator, _ := api.NewSeatAllocatorWithLayout([]api.Section{{Name: "Front", Seats: 4}, {Name: "Back", Seats: 6}}, api.AllocationSequential)
+    bookingService.SetSeatAllocator(allocator)
+    for _, destination := range []string{"Paris", "Lyon"} {
+        request := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+        request.To = destination
+        if _, err := bookingService.CreateBooking(context.TODO(), request); err != nil {
+            t.Fatalf("Error in creating booking %v ", err)
+        }
+    }
+
+    seatMap, err := bookingService.GetSeatMap(context.TODO(), &pb.GetSeatMapRequest{})
+    if err != nil {
+        t.Fatalf("Error in getting seat map %v ", err)
+    }
+    if !assert.Len(t, seatMap.GetSections(), 2) {
+        return
+    }
+    assert.Equal(t, "Front", seatMap.GetSections()[0].GetName(), "Sections should be in layout order")
+    assert.EqualValues(t, 4, seatMap.GetSections()[0].GetSeats())
+    assert.Equal(t, []int32{0, 1}, seatMap.GetSections()[0].GetOccupied())
+    assert.Empty(t, seatMap.GetSections()[1].GetOccupied())
+
+    seatMap, err = bookingService.GetSeatMap(context.TODO(), &pb.GetSeatMapRequest{Section: "Back"})
+    assert.NoError(t, err)
+    assert.Len(t, seatMap.GetSections(), 1)
+    _, err = bookingService.GetSeatMap(context.TODO(), &pb.GetSeatMapRequest{Section: "Roof"})
+    assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+type watchStream struct {
+    grpc.ServerStream
+    ctx    context.Context
+    events chan *pb.BookingEvent
+}
+
+func (s *watchStream) Send(event *pb.BookingEvent) error {
+    s.events <- event
+    return nil
+}
+
+func (s *watchStream) Context() context.Context {
+    return s.ctx
+}
+
+func TestShouldStreamBookingChangesToWatchers(t *testing.T) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    earlier, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+
+    ctx, cancel := context.WithCancel(api.WithActor(context.Background(), "service:departures-board"))
+    defer cancel()
+    stream := &watchStream{ctx: ctx, events: make(chan *pb.BookingEvent, 10)}
+    watchErr := make(chan error, 1)
+    go func() { watchErr <- bookingService.WatchBookings(&pb.WatchBookingsRequest{}, stream) }()
+
+    // bookings are made until the watch, which starts concurrently, reports one
+    var event *pb.BookingEvent
+    for attempt := 0; event == nil; attempt++ {
+        if attempt == 5 {
+            t.Fatalf("Error in watching bookings, no event received")
+        }
+        request := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+        request.To = fmt.Sprintf("Stop %d", attempt)
+        if _, err := bookingService.CreateBooking(context.TODO(), request); err != nil {
+            t.Fatalf("Error in creating booking %v ", err)
+        }
+        select {
+        case event = <-stream.events:
+        case <-time.After(time.Second):
+        }
+    }
+    assert.Equal(t, api.BookingEventCreated, event.GetType())
+    assert.NotEqual(t, earlier.GetPnr(), event.GetBooking().GetPnr(), "Changes from before the watch shouldn't be sent")
+
+    cancel()
+    assert.Equal(t, codes.Canceled, status.Code(<-watchErr))
+
+    bookingService.CloseWatches()
+    err = bookingService.WatchBookings(&pb.WatchBookingsRequest{}, &watchStream{ctx: context.Background()})
+    assert.Equal(t, codes.Unavailable, status.Code(err), "Watches should end when the server shuts down")
+}
+
+func TestShouldOnlyStreamSeatsToAnonymousWatchers(t *testing.T) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    ctx, cancel := context.WithCancel(context.Background())
+    defer cancel()
+    stream := &watchStream{ctx: ctx, events: make(chan *pb.BookingEvent, 10)}
+    go bookingService.WatchBookings(&pb.WatchBookingsRequest{}, stream)
+
+    var event *pb.BookingEvent
+    for attempt := 0; event == nil; attempt++ {
+        if attempt == 5 {
+            t.Fatalf("Error in watching bookings, no event received")
+        }
+        request := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+        request.To = fmt.Sprintf("Stop %d", attempt)
+        if _, err := bookingService.CreateBooking(context.TODO(), request); err != nil {
+            t.Fatalf("Error in creating booking %v ", err)
+        }
+        select {
+        case event = <-stream.events:
+        case <-time.After(time.Second):
+        }
+    }
+    assert.Equal(t, api.BookingEventCreated, event.GetType())
+    assert.NotEmpty(t, event.GetBooking().GetSection(), "Anonymous watchers should still see which seat changed")
+    assert.Empty(t, event.GetBooking().GetPnr(), "Anonymous watchers shouldn't get the PNR")
+    assert.Nil(t, event.GetBooking().GetUser(), "Anonymous watchers shouldn't get the passenger")
+}
+
+func TestShouldBookAndMoveToHeldSeats(t *testing.T) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    allocator, _ := api.NewSeatAllocatorWithLayout([]api.Section{{Name: "A", Seats: 10}}, api.AllocationSequential)
+    bookingService.SetSeatAllocator(allocator)
+
+    hold, err := bookingService.HoldSeat(context.TODO(), &pb.HoldSeatRequest{Section: "A", Seat: 0})
+    if err != nil {
+        t.Fatalf("Error in holding seat %v ", err)
+    }
+    _, err = bookingService.HoldSeat(context.TODO(), &pb.HoldSeatRequest{Section: "A", Seat: 0})
+    assert.Equal(t, codes.InvalidArgument, status.Code(err), "A held seat can't be held again")
+    if assert.Len(t, status.Convert(err).Details(), 1) {
+        assert.Equal(t, pb.ErrorReason_SEAT_NOT_AVAILABLE.String(), status.Convert(err).Details()[0].(*errdetails.ErrorInfo).GetReason())
+    }
+    seatMap, _ := bookingService.GetSeatMap(context.TODO(), &pb.GetSeatMapRequest{})
+    assert.Equal(t, []int32{0}, seatMap.GetSections()[0].GetHeld())
+
+    allocated, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    assert.EqualValues(t, 1, allocated.GetSeat(), "Held seats shouldn't be allocated")
+
+    request := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, "held@test.com")
+    request.HoldId = hold.GetHoldId()
+    booking, err := bookingService.CreateBooking(context.TODO(), request)
+    if err != nil {
+        t.Fatalf("Error in booking held seat %v ", err)
+    }
+    assert.EqualValues(t, 0, booking.GetSeat())
+    _, err = bookingService.ReleaseSeatHold(context.TODO(), &pb.ReleaseSeatHoldRequest{HoldId: hold.GetHoldId()})
+    assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Holds end when they are booked")
+
+    hold, _ = bookingService.HoldSeat(context.TODO(), &pb.HoldSeatRequest{Section: "A", Seat: 7})
+    _, err = bookingService.ModifySeatByUser(context.TODO(), &pb.SeatModificationRequest{BookingId: booking.GetPnr(), HoldId: hold.GetHoldId(),
+        User: &pb.User{Email: "held@test.com"}})
+    assert.NoError(t, err)
+    seatMap, _ = bookingService.GetSeatMap(context.TODO(), &pb.GetSeatMapRequest{})
+    assert.Equal(t, []int32{1, 7}, seatMap.GetSections()[0].GetOccupied())
+    assert.Empty(t, seatMap.GetSections()[0].GetHeld())
+
+    bookingService.SetSeatHoldTTL(0)
+    hold, _ = bookingService.HoldSeat(context.TODO(), &pb.HoldSeatRequest{Section: "A", Seat: 2})
+    request = createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, "late@test.com")
+    request.HoldId = hold.GetHoldId()
+    _, err = bookingService.CreateBooking(context.TODO(), request)
+    assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Expired holds can't be booked")
+}
+
+func TestShouldLimitTheSeatsACallerHolds(t *testing.T) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    bookingService.SetMaxSeatHoldsPerCaller(2)
+    for _, seat := range []int32{0, 1} {
+        if _, err := bookingService.HoldSeat(context.TODO(), &pb.HoldSeatRequest{Section: "A", Seat: seat}); err != nil {
+            t.Fatalf("Error in holding seat %v ", err)
+        }
+    }
+    _, err := bookingService.HoldSeat(context.TODO(), &pb.HoldSeatRequest{Section: "A", Seat: 2})
+    assert.Equal(t, codes.ResourceExhausted, status.Code(err), "A caller shouldn't hold more seats than the limit")
+    claimed := metadata.NewIncomingContext(context.TODO(), metadata.Pairs(api.ActorHeader, "someone-else"))
+    _, err = bookingService.HoldSeat(claimed, &pb.HoldSeatRequest{Section: "A", Seat: 2})
+    assert.Equal(t, codes.ResourceExhausted, status.Code(err), "An unverified x-actor shouldn't get its own limit")
+
+    hold, err := bookingService.HoldSeat(api.WithActor(context.TODO(), "service:kiosk-3"), &pb.HoldSeatRequest{Section: "A", Seat: 2})
+    if err != nil {
+        t.Fatalf("Error in holding seat for another caller %v ", err)
+    }
+    if _, err := bookingService.ReleaseSeatHold(context.TODO(), &pb.ReleaseSeatHoldRequest{HoldId: hold.GetHoldId()}); err != nil {
+        t.Fatalf("Error in releasing seat hold %v ", err)
+    }
+    _, err = bookingService.HoldSeat(context.TODO(), &pb.HoldSeatRequest{Section: "A", Seat: 3})
+    assert.Equal(t, codes.ResourceExhausted, status.Code(err), "Holds of other callers shouldn't free the limit")
+}
@@ -0,0 +1,497 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	bookingv2 "ticket-booking-app/domain/booking/v2"
+	"ticket-booking-app/logging"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"github.com/google/uuid"
+	"context"
+	"encoding/base64"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	bookingNamePrefix = "bookings/"
+
+	defaultBookingPageSize = 50
+	maxBookingPageSize     = 1000
+
+	bookingRecordColumns = "tickets.rowid, " + ticketColumns + ", t_status, COALESCE(t_created_at, 0), " + userColumns
+)
+
+// BookingServiceV2 serves the booking.v2 API. It shares the database, seat
+// allocator, notifications and metrics of the BookingService it belongs to,
+// whose v1 methods are adapters over it.
+type BookingServiceV2 struct {
+	bookings *BookingService
+}
+
+// bookingRecord is a ticket of any status with its passenger
+type bookingRecord struct {
+	rowId     int64
+	ticket    *pb.BookingDbResponse
+	user      *pb.User
+	status    string
+	createdAt int64
+}
+
+func (s *BookingServiceV2) CreateBooking(ctx context.Context, req *bookingv2.CreateBookingRequest) (*bookingv2.Booking, error) {
+	booking := req.GetBooking()
+	if booking.GetOrigin() == "" || booking.GetDestination() == "" || booking.GetPrice() == 0 || booking.GetPassenger() == nil {
+		slog.InfoContext(ctx, "Invalid create booking request", "from", booking.GetOrigin(), "to", booking.GetDestination(), "price", booking.GetPrice())
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid create booking request")
+	}
+	if !isValidEmail(booking.GetPassenger().GetEmail()) {
+		return nil, status.Errorf(codes.InvalidArgument, "Passenger email %q is not valid", booking.GetPassenger().GetEmail())
+	}
+	if booking.GetSeat() != nil && req.GetSeatHoldId() != "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Either a seat or a seat hold can be booked, not both")
+	}
+	b := s.bookings
+	passenger := &pb.User{Firstname: booking.GetPassenger().GetGivenName(), Lastname: booking.GetPassenger().GetFamilyName(),
+		Email: booking.GetPassenger().GetEmail()}
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	//check if user exists before inserting new record, users are identified by email only
+	dbUser, isUserExists := retrieveUserIfExists(tx, passenger.GetEmail())
+	if !isUserExists {
+		newUserId, userDbErr := insertUser(tx, passenger, "")
+		if isUniqueViolation(userDbErr) {
+			return nil, status.Errorf(codes.Aborted, "User with email %s was added concurrently, retry the booking", passenger.GetEmail())
+		}
+		if userDbErr != nil {
+			return nil, userDbErr
+		}
+		dbUser = &pb.User{Id: newUserId, Firstname: passenger.GetFirstname(), Lastname: passenger.GetLastname(),
+			Email: normalizeEmail(passenger.GetEmail())}
+		slog.InfoContext(ctx, "Added new user", "user_id", newUserId, logging.Email(passenger.GetEmail()))
+	}
+	userId := dbUser.GetId()
+
+	var departureAt int64
+	if booking.GetDepartureTime() != nil {
+		departureAt = booking.GetDepartureTime().AsTime().Unix()
+	}
+
+	//check if booking already exists for user with requested location details
+	dbBooking, isBookingExists := retrieveBookingIfExists(tx, userId, booking.GetOrigin(), booking.GetDestination(), departureAt)
+	if isBookingExists {
+		slog.InfoContext(ctx, "Ticket already exists", "from", dbBooking.GetFrom(), "to", dbBooking.GetTo(), "user_id", userId,
+			"pnr", dbBooking.GetPnr())
+		return nil, status.Errorf(codes.AlreadyExists, "Ticket from %s to %s already exists with PNR %s", dbBooking.GetFrom(),
+			dbBooking.GetTo(), dbBooking.GetPnr())
+	}
+	if err := b.checkActiveBookings(tx, userId, booking.GetOrigin(), booking.GetDestination()); err != nil {
+		return nil, err
+	}
+	var seat int32
+	var section string
+	switch {
+	case req.GetSeatHoldId() != "":
+		seat, section, err = b.claimSeatHold(ctx, req.GetSeatHoldId())
+		if err != nil {
+			return nil, seatHoldNotFound(req.GetSeatHoldId())
+		}
+	case booking.GetSeat() != nil:
+		seat, section = booking.GetSeat().GetNumber(), booking.GetSeat().GetSection()
+		if err := b.allocateSpecificSeat(ctx, seat, section); err != nil {
+			return nil, seatNotAvailable(seat, section)
+		}
+	default:
+		seat, section, err = b.allocateSeat(ctx)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Error while allocating seat: %v", err)
+		}
+	}
+
+	ticketId := uuid.NewString()
+	createdAt := time.Now().Unix()
+	pnr, dbErr := insertTicketWithPnr(tx, "INSERT INTO tickets (t_id, t_from, t_to, t_price, t_seat, t_section, t_user_id, t_status, t_departure_at, t_created_at, t_pnr) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		ticketId, booking.GetOrigin(), booking.GetDestination(), booking.GetPrice(), seat, section, userId, TicketStatusBooked,
+		nullableUnix(departureAt), createdAt)
+	ticket := &pb.BookingDbResponse{Id: ticketId, From: booking.GetOrigin(), To: booking.GetDestination(), Price: booking.GetPrice(),
+		Seat: seat, Section: section, Userid: userId, DepartureAt: departureAt, Pnr: pnr, Version: 1}
+	if dbErr == nil {
+		dbErr = appendAuditEntry(ctx, tx, AuditActionBookingCreate, ticketId, nil, ticket)
+	}
+	if dbErr == nil {
+		dbErr = recordBookingEvent(tx, BookingEventCreated, ticket, dbUser)
+	}
+	if dbErr == nil {
+		dbErr = tx.Commit()
+	}
+	if dbErr != nil {
+		b.seatAllocator.DeallocateSeat(seat, section)
+		return nil, dbErr
+	}
+	slog.InfoContext(ctx, "Booked new ticket", "pnr", pnr, "from", booking.GetOrigin(), "to", booking.GetDestination(), "user_id", userId,
+		"seat", seat, "section", section)
+	b.metrics.countBooking(BookingOperationCreate)
+	b.notify(NotificationBooked, ticket, nil, dbUser)
+
+	return bookingToV2(&bookingRecord{ticket: ticket, user: dbUser, status: TicketStatusBooked, createdAt: createdAt}), nil
+}
+
+// GetBooking returns cancelled bookings too, see Booking.state
+func (s *BookingServiceV2) GetBooking(ctx context.Context, req *bookingv2.GetBookingRequest) (*bookingv2.Booking, error) {
+	id, err := parseBookingName(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReadMask(req.GetReadMask()); err != nil {
+		return nil, err
+	}
+	record, err := findBookingRecord(withContext(ctx, s.bookings.db), id)
+	if err != nil {
+		return nil, err
+	}
+	return applyReadMask(bookingToV2(record), req.GetReadMask()), nil
+}
+
+// ListBookings pages by the rowid of the tickets, so bookings made or
+// cancelled while paging don't shift the pages.
+func (s *BookingServiceV2) ListBookings(ctx context.Context, req *bookingv2.ListBookingsRequest) (*bookingv2.ListBookingsResponse, error) {
+	pageSize := int(req.GetPageSize())
+	switch {
+	case pageSize < 0:
+		return nil, status.Errorf(codes.InvalidArgument, "Page size can't be negative")
+	case pageSize == 0:
+		pageSize = defaultBookingPageSize
+	case pageSize > maxBookingPageSize:
+		pageSize = maxBookingPageSize
+	}
+	after, err := decodeBookingPageToken(req.GetPageToken())
+	if err != nil {
+		return nil, err
+	}
+	if err := checkReadMask(req.GetReadMask()); err != nil {
+		return nil, err
+	}
+
+	where := []string{"tickets.rowid > ?"}
+	args := []any{after}
+	if !req.GetShowCancelled() {
+		where = append(where, "t_status = ?")
+		args = append(args, TicketStatusBooked)
+	}
+	if req.GetSection() != "" {
+		where = append(where, "t_section = ?")
+		args = append(args, req.GetSection())
+	}
+	if req.GetPassengerEmail() != "" {
+		where = append(where, "u_user_email = ?")
+		args = append(args, normalizeEmail(req.GetPassengerEmail()))
+	}
+	// one more than the page is read to tell whether another page follows
+	records, err := queryBookingRecords(withContext(ctx, s.bookings.db), strings.Join(where, " AND ")+" ORDER BY tickets.rowid LIMIT ?",
+		append(args, pageSize+1)...)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in listing bookings", "error", err)
+		return nil, err
+	}
+
+	response := &bookingv2.ListBookingsResponse{}
+	if len(records) > pageSize {
+		records = records[:pageSize]
+		response.NextPageToken = encodeBookingPageToken(records[pageSize-1].rowId)
+	}
+	for _, record := range records {
+		response.Bookings = append(response.Bookings, applyReadMask(bookingToV2(record), req.GetReadMask()))
+	}
+	return response, nil
+}
+
+func (s *BookingServiceV2) UpdateBooking(ctx context.Context, req *bookingv2.UpdateBookingRequest) (*bookingv2.Booking, error) {
+	id, err := parseBookingName(req.GetBooking().GetName())
+	if err != nil {
+		return nil, err
+	}
+	updateSection, updateNumber, err := seatUpdatePaths(req.GetUpdateMask())
+	if err != nil {
+		return nil, err
+	}
+	requested := req.GetBooking().GetSeat()
+	if requested == nil && req.GetSeatHoldId() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Seat or seat hold is required")
+	}
+	b := s.bookings
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	record, err := findActiveBookingRecord(ctx, tx, id, req.GetBooking().GetEtag(), req.GetPassengerEmail())
+	if err != nil {
+		return nil, err
+	}
+	booking := record.ticket
+
+	seat, section := booking.GetSeat(), booking.GetSection()
+	if req.GetSeatHoldId() != "" {
+		// a held seat is always free, so it can't be the booking's own seat
+		seat, section, err = b.claimSeatHold(ctx, req.GetSeatHoldId())
+		if err != nil {
+			return nil, seatHoldNotFound(req.GetSeatHoldId())
+		}
+		if requested != nil && (requested.GetSection() != section || requested.GetNumber() != seat) {
+			b.seatAllocator.DeallocateSeat(seat, section)
+			return nil, status.Errorf(codes.InvalidArgument, "Seat hold %s is for seat %d in section %s", req.GetSeatHoldId(), seat, section)
+		}
+	} else {
+		if updateSection {
+			section = requested.GetSection()
+		}
+		if updateNumber {
+			seat = requested.GetNumber()
+		}
+		if booking.GetSection() == section && booking.GetSeat() == seat {
+			return nil, status.Errorf(codes.InvalidArgument, "Old and new seats can't be same")
+		}
+		if allocationErr := b.allocateSpecificSeat(ctx, seat, section); allocationErr != nil {
+			return nil, seatNotAvailable(seat, section)
+		}
+	}
+
+	modified := proto.Clone(booking).(*pb.BookingDbResponse)
+	modified.Seat = seat
+	modified.Section = section
+	modified.Version++
+	if dbErr := updateSeat(ctx, tx, booking, modified, record.user); dbErr != nil {
+		b.seatAllocator.DeallocateSeat(seat, section)
+		return nil, dbErr
+	}
+	b.seatAllocator.DeallocateSeat(booking.GetSeat(), booking.GetSection())
+	b.metrics.countBooking(BookingOperationModifySeat)
+	b.notify(NotificationSeatChanged, modified, booking, record.user)
+
+	record.ticket = modified
+	return bookingToV2(record), nil
+}
+
+// DeleteBooking cancels the booking. Tickets are kept as cancelled so the
+// audit log and reports can refer to them.
+func (s *BookingServiceV2) DeleteBooking(ctx context.Context, req *bookingv2.DeleteBookingRequest) (*bookingv2.Booking, error) {
+	id, err := parseBookingName(req.GetName())
+	if err != nil {
+		return nil, err
+	}
+	b := s.bookings
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	record, err := findActiveBookingRecord(ctx, tx, id, req.GetEtag(), req.GetPassengerEmail())
+	if err != nil {
+		return nil, err
+	}
+	booking := record.ticket
+	if err := cancelTicket(ctx, tx, booking, record.user); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	b.seatAllocator.DeallocateSeat(booking.GetSeat(), booking.GetSection())
+	slog.InfoContext(ctx, "Cancelled booking", "pnr", booking.GetPnr(), "user_id", record.user.GetId())
+	b.metrics.countBooking(BookingOperationCancel)
+	b.notify(NotificationCancelled, booking, nil, record.user)
+
+	cancelled := proto.Clone(booking).(*pb.BookingDbResponse)
+	cancelled.Version++
+	record.ticket = cancelled
+	record.status = TicketStatusCancelled
+	return bookingToV2(record), nil
+}
+
+// findActiveBookingRecord finds the booking to be changed, rejecting changes
+// by anyone but its passenger or a verified service, changes made against an
+// outdated etag and changes of cancelled bookings.
+func findActiveBookingRecord(ctx context.Context, db dbExecutor, id, etag, passengerEmail string) (*bookingRecord, error) {
+	record, err := findBookingRecord(db, id)
+	if err != nil {
+		return nil, err
+	}
+	if err := authorizeBookingOwner(ctx, id, passengerEmail, record.user); err != nil {
+		return nil, err
+	}
+	if err := checkEtag(record.ticket, etag); err != nil {
+		return nil, err
+	}
+	if record.status != TicketStatusBooked {
+		return nil, status.Errorf(codes.FailedPrecondition, "Booking %s is cancelled", record.ticket.GetPnr())
+	}
+	return record, nil
+}
+
+// findBookingRecord finds the booking by PNR or ticket id, whatever its status
+func findBookingRecord(db dbExecutor, id string) (*bookingRecord, error) {
+	records, err := queryBookingRecords(db, "t_id = ? OR t_pnr = ?", id, normalizePnr(id))
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, status.Errorf(codes.NotFound, "No booking exists with id %s", id)
+	}
+	return records[0], nil
+}
+
+// queryBookingRecords returns the bookings matching the given WHERE clause,
+// which may refer to the columns of tickets and users
+func queryBookingRecords(db dbExecutor, where string, args ...any) ([]*bookingRecord, error) {
+	rows, err := db.Query("SELECT "+bookingRecordColumns+" FROM tickets JOIN users ON u_id = t_user_id WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []*bookingRecord
+	for rows.Next() {
+		ticket, user := &pb.BookingDbResponse{}, &pb.User{}
+		record := &bookingRecord{ticket: ticket, user: user}
+		if err := rows.Scan(&record.rowId, &ticket.Id, &ticket.From, &ticket.To, &ticket.Price, &ticket.Seat, &ticket.Section,
+			&ticket.Userid, &ticket.DepartureAt, &ticket.Pnr, &ticket.Version, &record.status, &record.createdAt,
+			&user.Id, &user.Firstname, &user.Lastname, &user.Email); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func bookingToV2(record *bookingRecord) *bookingv2.Booking {
+	ticket := record.ticket
+	state := bookingv2.BookingState_BOOKING_STATE_BOOKED
+	if record.status == TicketStatusCancelled {
+		state = bookingv2.BookingState_BOOKING_STATE_CANCELLED
+	}
+	var createTime *timestamppb.Timestamp
+	if record.createdAt != 0 {
+		createTime = timestamppb.New(time.Unix(record.createdAt, 0))
+	}
+	return &bookingv2.Booking{
+		Name:          bookingNamePrefix + ticket.GetPnr(),
+		Uid:           ticket.GetId(),
+		Pnr:           ticket.GetPnr(),
+		Origin:        ticket.GetFrom(),
+		Destination:   ticket.GetTo(),
+		Price:         ticket.GetPrice(),
+		Seat:          &bookingv2.Seat{Section: ticket.GetSection(), Number: ticket.GetSeat()},
+		Passenger:     &bookingv2.Passenger{GivenName: record.user.GetFirstname(), FamilyName: record.user.GetLastname(), Email: record.user.GetEmail()},
+		DepartureTime: departureTimestamp(ticket.GetDepartureAt()),
+		State:         state,
+		CreateTime:    createTime,
+		Etag:          bookingEtag(ticket.GetVersion()),
+	}
+}
+
+// parseBookingName returns the PNR or ticket id of a bookings/{pnr} name
+func parseBookingName(name string) (string, error) {
+	id, found := strings.CutPrefix(name, bookingNamePrefix)
+	if !found || id == "" || strings.Contains(id, "/") {
+		return "", status.Errorf(codes.InvalidArgument, "Invalid booking name %q, expected bookings/{pnr}", name)
+	}
+	return id, nil
+}
+
+// seatUpdatePaths tells which parts of the seat an update mask changes. The
+// seat is the only field that can be updated, so it is the default.
+func seatUpdatePaths(mask *fieldmaskpb.FieldMask) (section, number bool, err error) {
+	if len(mask.GetPaths()) == 0 {
+		return true, true, nil
+	}
+	for _, path := range mask.GetPaths() {
+		switch path {
+		case "seat":
+			section, number = true, true
+		case "seat.section":
+			section = true
+		case "seat.number":
+			number = true
+		default:
+			return false, false, status.Errorf(codes.InvalidArgument, "Field %s can't be updated, only the seat can", path)
+		}
+	}
+	return section, number, nil
+}
+
+func checkReadMask(mask *fieldmaskpb.FieldMask) error {
+	if mask != nil && !mask.IsValid(&bookingv2.Booking{}) {
+		return status.Errorf(codes.InvalidArgument, "Invalid read mask %v", mask.GetPaths())
+	}
+	return nil
+}
+
+// applyReadMask clears the fields of booking that mask doesn't name, an empty
+// mask keeps every field
+func applyReadMask(booking *bookingv2.Booking, mask *fieldmaskpb.FieldMask) *bookingv2.Booking {
+	if len(mask.GetPaths()) > 0 {
+		pruneMessage(booking.ProtoReflect(), mask.GetPaths())
+	}
+	return booking
+}
+
+func pruneMessage(message protoreflect.Message, paths []string) {
+	kept := make(map[protoreflect.Name]bool)
+	nested := make(map[protoreflect.Name][]string)
+	for _, path := range paths {
+		name, rest, isNested := strings.Cut(path, ".")
+		if isNested {
+			nested[protoreflect.Name(name)] = append(nested[protoreflect.Name(name)], rest)
+		} else {
+			kept[protoreflect.Name(name)] = true
+		}
+	}
+
+	var cleared []protoreflect.FieldDescriptor
+	message.Range(func(field protoreflect.FieldDescriptor, value protoreflect.Value) bool {
+		switch {
+		case kept[field.Name()]:
+		case nested[field.Name()] != nil && field.Message() != nil && field.Cardinality() != protoreflect.Repeated:
+			pruneMessage(value.Message(), nested[field.Name()])
+		default:
+			cleared = append(cleared, field)
+		}
+		return true
+	})
+	for _, field := range cleared {
+		message.Clear(field)
+	}
+}
+
+// booking page tokens are the rowid of the last ticket of the previous page
+func encodeBookingPageToken(rowId int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(rowId, 10)))
+}
+
+func decodeBookingPageToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(token)
+	if err == nil {
+		var rowId int64
+		if rowId, err = strconv.ParseInt(string(decoded), 10, 64); err == nil {
+			return rowId, nil
+		}
+	}
+	return 0, status.Errorf(codes.InvalidArgument, "Invalid page token %s", token)
+}
@@ -0,0 +1,135 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+	bookingv2 "ticket-booking-app/domain/booking/v2"
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+	"context"
+	"strings"
+	"testing"
+)
+
+func newV2BookingRequest(email string) *bookingv2.CreateBookingRequest {
+    return &bookingv2.CreateBookingRequest{Booking: &bookingv2.Booking{Origin: "London", Destination: "France", Price: 20,
+        Passenger: &bookingv2.Passenger{GivenName: FIRST_NAME, FamilyName: LAST_NAME, Email: email}}}
+}
+
+func TestShouldManageBookingThroughV2Resources(t *testing.T) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    allocator, _ := api.NewSeatAllocatorWithLayout([]api.Section{{Name: "A", Seats: 10}}, api.AllocationSequential)
+    bookingService.SetSeatAllocator(allocator)
+    v2 := bookingService.V2()
+
+    request := newV2BookingRequest(EMAIL)
+    request.Booking.Seat = &bookingv2.Seat{Section: "A", Number: 3}
+    created, err := v2.CreateBooking(context.TODO(), request)
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    assert.Equal(t, "bookings/"+created.GetPnr(), created.GetName())
+    assert.EqualValues(t, 3, created.GetSeat().GetNumber(), "The requested seat should be booked")
+    assert.Equal(t, bookingv2.BookingState_BOOKING_STATE_BOOKED, created.GetState())
+    assert.NotNil(t, created.GetCreateTime())
+
+    got, err := v2.GetBooking(context.TODO(), &bookingv2.GetBookingRequest{Name: "bookings/" + strings.ToLower(created.GetPnr()),
+        ReadMask: &fieldmaskpb.FieldMask{Paths: []string{"name", "seat.number"}}})
+    if err != nil {
+        t.Fatalf("Error in getting booking %v ", err)
+    }
+    assert.Equal(t, created.GetName(), got.GetName())
+    assert.EqualValues(t, 3, got.GetSeat().GetNumber())
+    assert.Empty(t, got.GetSeat().GetSection(), "Fields outside the read mask should be cleared")
+    assert.Nil(t, got.GetPassenger())
+    _, err = v2.GetBooking(context.TODO(), &bookingv2.GetBookingRequest{Name: created.GetPnr()})
+    assert.Equal(t, codes.InvalidArgument, status.Code(err), "Names should start with bookings/")
+
+    _, err = v2.UpdateBooking(context.TODO(), &bookingv2.UpdateBookingRequest{
+        Booking: &bookingv2.Booking{Name: created.GetName(), Price: 10}, UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"price"}},
+        PassengerEmail: EMAIL})
+    assert.Equal(t, codes.InvalidArgument, status.Code(err), "Only the seat should be updatable")
+    updated, err := v2.UpdateBooking(context.TODO(), &bookingv2.UpdateBookingRequest{
+        Booking: &bookingv2.Booking{Name: created.GetName(), Etag: created.GetEtag(), Seat: &bookingv2.Seat{Number: 7}},
+        UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"seat.number"}}, PassengerEmail: EMAIL})
+    if err != nil {
+        t.Fatalf("Error in updating booking %v ", err)
+    }
+    assert.Equal(t, "A", updated.GetSeat().GetSection(), "The section should be kept")
+    assert.EqualValues(t, 7, updated.GetSeat().GetNumber())
+    assert.NotEqual(t, created.GetEtag(), updated.GetEtag())
+    assert.Equal(t, []int32{7}, allocator.OccupiedSeats("A"))
+
+    _, err = v2.DeleteBooking(context.TODO(), &bookingv2.DeleteBookingRequest{Name: created.GetName(), Etag: created.GetEtag(), PassengerEmail: EMAIL})
+    assert.Equal(t, codes.Aborted, status.Code(err), "Outdated etags should be rejected")
+    deleted, err := v2.DeleteBooking(context.TODO(), &bookingv2.DeleteBookingRequest{Name: "bookings/" + created.GetUid(), PassengerEmail: EMAIL})
+    if err != nil {
+        t.Fatalf("Error in deleting booking %v ", err)
+    }
+    assert.Equal(t, bookingv2.BookingState_BOOKING_STATE_CANCELLED, deleted.GetState())
+    assert.Empty(t, allocator.OccupiedSeats("A"))
+
+    got, err = v2.GetBooking(context.TODO(), &bookingv2.GetBookingRequest{Name: created.GetName()})
+    if err != nil {
+        t.Fatalf("Error in getting booking %v ", err)
+    }
+    assert.Equal(t, bookingv2.BookingState_BOOKING_STATE_CANCELLED, got.GetState(), "Cancelled bookings should stay readable")
+    assert.Equal(t, deleted.GetEtag(), got.GetEtag())
+    _, err = v2.DeleteBooking(context.TODO(), &bookingv2.DeleteBookingRequest{Name: created.GetName(), PassengerEmail: EMAIL})
+    assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+    _, err = bookingService.GetBooking(context.TODO(), &pb.GetBookingRequest{BookingId: created.GetPnr()})
+    assert.Equal(t, codes.NotFound, status.Code(err), "v1 should not return cancelled bookings")
+}
+
+func TestShouldPageThroughV2Bookings(t *testing.T) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    v2 := bookingService.V2()
+    var created []*bookingv2.Booking
+    for _, email := range []string{"first@test.com", "second@test.com", "third@test.com"} {
+        booking, err := v2.CreateBooking(context.TODO(), newV2BookingRequest(email))
+        if err != nil {
+            t.Fatalf("Error in creating booking %v ", err)
+        }
+        created = append(created, booking)
+    }
+    if _, err := v2.DeleteBooking(context.TODO(), &bookingv2.DeleteBookingRequest{Name: created[1].GetName(), PassengerEmail: "second@test.com"}); err != nil {
+        t.Fatalf("Error in deleting booking %v ", err)
+    }
+
+    first, err := v2.ListBookings(context.TODO(), &bookingv2.ListBookingsRequest{PageSize: 2, ShowCancelled: true})
+    if err != nil {
+        t.Fatalf("Error in listing bookings %v ", err)
+    }
+    if assert.Len(t, first.GetBookings(), 2) {
+        assert.Equal(t, created[0].GetName(), first.GetBookings()[0].GetName(), "Bookings should be listed in the order they were made")
+        assert.Equal(t, bookingv2.BookingState_BOOKING_STATE_CANCELLED, first.GetBookings()[1].GetState())
+    }
+    assert.NotEmpty(t, first.GetNextPageToken())
+    second, err := v2.ListBookings(context.TODO(), &bookingv2.ListBookingsRequest{PageSize: 2, ShowCancelled: true,
+        PageToken: first.GetNextPageToken()})
+    if err != nil {
+        t.Fatalf("Error in listing bookings %v ", err)
+    }
+    if assert.Len(t, second.GetBookings(), 1) {
+        assert.Equal(t, created[2].GetName(), second.GetBookings()[0].GetName())
+    }
+    assert.Empty(t, second.GetNextPageToken(), "The last page should have no token")
+
+    active, err := v2.ListBookings(context.TODO(), &bookingv2.ListBookingsRequest{})
+    if err != nil {
+        t.Fatalf("Error in listing bookings %v ", err)
+    }
+    assert.Len(t, active.GetBookings(), 2, "Cancelled bookings should only be listed on request")
+    mine, err := v2.ListBookings(context.TODO(), &bookingv2.ListBookingsRequest{PassengerEmail: "THIRD@test.com"})
+    if err != nil {
+        t.Fatalf("Error in listing bookings %v ", err)
+    }
+    if assert.Len(t, mine.GetBookings(), 1) {
+        assert.Equal(t, created[2].GetName(), mine.GetBookings()[0].GetName())
+    }
+
+    _, err = v2.ListBookings(context.TODO(), &bookingv2.ListBookingsRequest{PageToken: "not a token"})
+    assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
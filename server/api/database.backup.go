@@ -0,0 +1,278 @@
+package api
+
+import (
+	"github.com/mattn/go-sqlite3"
+	"database/sql"
+	"database/sql/driver"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	backupPrefix = "ticket_booking-"
+	backupSuffix = ".db"
+	// backup names sort in the order they were taken
+	backupTimeLayout = "20060102T150405.000Z"
+)
+
+var ErrBackupNotFound = errors.New("backup not found")
+
+// BackupFile is a backup in the backup directory
+type BackupFile struct {
+	Name      string
+	Size      int64
+	CreatedAt time.Time
+}
+
+// BackupDatabase copies db to path with the SQLite online backup API. The copy
+// is made in one step, which sees a consistent state while writers wait for it
+// instead of restarting it. It is written next to path and renamed once its
+// integrity is checked, so path never holds a partial backup.
+func BackupDatabase(ctx context.Context, db *sql.DB, path string) error {
+	partial := path + ".partial"
+	os.Remove(partial)
+	err := copyDatabase(ctx, db, partial, false)
+	if err == nil {
+		_, err = VerifyBackup(partial)
+	}
+	if err != nil {
+		os.Remove(partial)
+		return err
+	}
+	return os.Rename(partial, path)
+}
+
+// RestoreDatabase verifies the backup at path and copies it over db with the
+// SQLite online backup API. Other connections of db see the restored data
+// once the copy is done. It returns the schema version of the backup, db has
+// to be migrated when it is older than the current schema.
+func RestoreDatabase(ctx context.Context, db *sql.DB, path string) (int, error) {
+	version, err := VerifyBackup(path)
+	if err != nil {
+		return 0, err
+	}
+	return version, copyDatabase(ctx, db, path, true)
+}
+
+// VerifyBackup checks the integrity of the database at path and that this
+// server knows its schema version, which it returns.
+func VerifyBackup(path string) (int, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+	backup, err := sql.Open("sqlite3", "file:"+path+"?mode=ro")
+	if err != nil {
+		return 0, err
+	}
+	defer backup.Close()
+
+	var result string
+	if err := backup.QueryRow("PRAGMA integrity_check").Scan(&result); err != nil {
+		return 0, fmt.Errorf("integrity check of %s: %w", filepath.Base(path), err)
+	}
+	if result != "ok" {
+		return 0, fmt.Errorf("integrity check of %s failed: %s", filepath.Base(path), result)
+	}
+	version, err := SchemaVersion(backup)
+	if err != nil {
+		return 0, fmt.Errorf("%s is not a booking database: %w", filepath.Base(path), err)
+	}
+	if version == 0 || version > len(schemaMigrations) {
+		return version, fmt.Errorf("schema version %d of %s is not supported, this server knows versions 1 to %d",
+			version, filepath.Base(path), len(schemaMigrations))
+	}
+	return version, nil
+}
+
+// copyDatabase copies db to the database file at path, or the file over db
+// when restore is set.
+func copyDatabase(ctx context.Context, db *sql.DB, path string, restore bool) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	file, err := sql.Open("sqlite3", path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	fileConn, err := file.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer fileConn.Close()
+
+	return conn.Raw(func(dbDriverConn any) error {
+		return fileConn.Raw(func(fileDriverConn any) error {
+			source, err := sqliteConn(dbDriverConn)
+			if err != nil {
+				return err
+			}
+			destination, err := sqliteConn(fileDriverConn)
+			if err != nil {
+				return err
+			}
+			if restore {
+				source, destination = destination, source
+			}
+			backup, err := destination.Backup("main", source, "main")
+			if err != nil {
+				return err
+			}
+			if _, err := backup.Step(-1); err != nil {
+				backup.Finish()
+				return err
+			}
+			return backup.Finish()
+		})
+	})
+}
+
+// sqliteConn returns the connection of the sqlite3 driver behind a connection
+// of OpenInstrumentedDB or of the plain driver.
+func sqliteConn(driverConn any) (*sqlite3.SQLiteConn, error) {
+	if wrapped, ok := driverConn.(interface{ Unwrap() driver.Conn }); ok {
+		driverConn = wrapped.Unwrap()
+	}
+	conn, ok := driverConn.(*sqlite3.SQLiteConn)
+	if !ok {
+		return nil, fmt.Errorf("driver connection %T is not a SQLite connection", driverConn)
+	}
+	return conn, nil
+}
+
+// BackupManager keeps the backups of the booking database in a directory and
+// deletes all but the newest keep of them after every backup.
+type BackupManager struct {
+	db   *sql.DB
+	dir  string
+	keep int
+}
+
+// BackupName is the name of a backup taken at t, which the backup directory
+// lists in the order the backups were taken.
+func BackupName(t time.Time) string {
+	return backupPrefix + t.UTC().Format(backupTimeLayout) + backupSuffix
+}
+
+// NewBackupManager stores backups in dir, keeping all of them when keep is 0.
+func NewBackupManager(db *sql.DB, dir string, keep int) (*BackupManager, error) {
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+	return &BackupManager{db: db, dir: dir, keep: keep}, nil
+}
+
+// Backup takes a backup named after the current time and removes the backups
+// beyond the ones kept.
+func (m *BackupManager) Backup(ctx context.Context) (BackupFile, error) {
+	name := BackupName(time.Now())
+	if err := BackupDatabase(ctx, m.db, filepath.Join(m.dir, name)); err != nil {
+		return BackupFile{}, err
+	}
+	backup, err := m.backupFile(name)
+	if err != nil {
+		return BackupFile{}, err
+	}
+	if err := m.prune(); err != nil {
+		slog.WarnContext(ctx, "Old backups could not be removed", "dir", m.dir, "error", err)
+	}
+	return backup, nil
+}
+
+// List returns the backups, newest first
+func (m *BackupManager) List() ([]BackupFile, error) {
+	entries, err := os.ReadDir(m.dir)
+	if err != nil {
+		return nil, err
+	}
+	var backups []BackupFile
+	for _, entry := range entries {
+		if entry.IsDir() || !isBackupName(entry.Name()) {
+			continue
+		}
+		backup, err := m.backupFile(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		backups = append(backups, backup)
+	}
+	slices.SortFunc(backups, func(a, b BackupFile) int { return strings.Compare(b.Name, a.Name) })
+	return backups, nil
+}
+
+// Restore copies the named backup over the database, see RestoreDatabase.
+func (m *BackupManager) Restore(ctx context.Context, name string) (BackupFile, int, error) {
+	if !isBackupName(name) {
+		return BackupFile{}, 0, ErrBackupNotFound
+	}
+	backup, err := m.backupFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return BackupFile{}, 0, ErrBackupNotFound
+	}
+	if err != nil {
+		return BackupFile{}, 0, err
+	}
+	version, err := RestoreDatabase(ctx, m.db, filepath.Join(m.dir, name))
+	return backup, version, err
+}
+
+// Run takes a backup every interval until ctx is done
+func (m *BackupManager) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			backup, err := m.Backup(ctx)
+			if err != nil {
+				slog.ErrorContext(ctx, "Scheduled backup failed", "dir", m.dir, "error", err)
+				continue
+			}
+			slog.InfoContext(ctx, "Database backed up", "backup", backup.Name, "size", backup.Size)
+		}
+	}
+}
+
+func (m *BackupManager) prune() error {
+	if m.keep <= 0 {
+		return nil
+	}
+	backups, err := m.List()
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, backup := range backups[min(m.keep, len(backups)):] {
+		errs = append(errs, os.Remove(filepath.Join(m.dir, backup.Name)))
+	}
+	return errors.Join(errs...)
+}
+
+func (m *BackupManager) backupFile(name string) (BackupFile, error) {
+	info, err := os.Stat(filepath.Join(m.dir, name))
+	if err != nil {
+		return BackupFile{}, err
+	}
+	createdAt, err := time.Parse(backupTimeLayout, strings.TrimSuffix(strings.TrimPrefix(name, backupPrefix), backupSuffix))
+	if err != nil {
+		createdAt = info.ModTime()
+	}
+	return BackupFile{Name: name, Size: info.Size(), CreatedAt: createdAt}, nil
+}
+
+// isBackupName rejects paths, so only files of the backup directory can be
+// restored
+func isBackupName(name string) bool {
+	return filepath.Base(name) == name && strings.HasPrefix(name, backupPrefix) && strings.HasSuffix(name, backupSuffix)
+}
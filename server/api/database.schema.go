@@ -0,0 +1,228 @@
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"log/slog"
+)
+
+type schemaMigration struct {
+	statements string
+	// precondition, when set, runs inside the migration transaction first
+	precondition func(tx *sql.Tx) error
+	// after, when set, runs inside the migration transaction after the statements
+	after func(tx *sql.Tx) error
+}
+
+// schemaMigrations are applied in order, each one exactly once. The index of a
+// migration plus one is the schema version recorded in schema_migrations, so
+// new changes must always be appended and never edited in place.
+var schemaMigrations = []schemaMigration{
+	// 1: original tables
+	{statements: `CREATE TABLE IF NOT EXISTS tickets (
+		t_id TEXT PRIMARY KEY,
+		t_from TEXT,
+		t_to TEXT,
+		t_price INTEGER,
+		t_seat INTEGER,
+		t_section TEXT,
+		t_user_id TEXT
+	);
+	CREATE TABLE IF NOT EXISTS users (
+		u_id TEXT PRIMARY KEY,
+		u_user_fname TEXT,
+		u_user_lname TEXT,
+		u_user_email TEXT
+	);`},
+
+	// 2: user accounts and booking lifecycle
+	{statements: `ALTER TABLE users ADD COLUMN u_status TEXT NOT NULL DEFAULT 'ACTIVE';
+	ALTER TABLE users ADD COLUMN u_email_verified INTEGER NOT NULL DEFAULT 0;
+	ALTER TABLE users ADD COLUMN u_verification_code TEXT;
+	ALTER TABLE users ADD COLUMN u_created_at INTEGER;
+	ALTER TABLE tickets ADD COLUMN t_status TEXT NOT NULL DEFAULT 'BOOKED';
+	ALTER TABLE tickets ADD COLUMN t_departure_at INTEGER;
+	ALTER TABLE tickets ADD COLUMN t_created_at INTEGER;
+	CREATE INDEX IF NOT EXISTS idx_tickets_user ON tickets (t_user_id);
+	CREATE INDEX IF NOT EXISTS idx_users_email ON users (u_user_email);`},
+
+	// 3: one user per normalized email, users without one keep none
+	{statements: `UPDATE users SET u_user_email = NULLIF(LOWER(TRIM(u_user_email)), '') WHERE u_user_email IS NOT NULL;
+	DROP INDEX IF EXISTS idx_users_email;
+	CREATE UNIQUE INDEX idx_users_email ON users (u_user_email);`,
+		precondition: requireNoDuplicateUsers},
+
+	// 4: human-friendly booking references
+	{statements: `ALTER TABLE tickets ADD COLUMN t_pnr TEXT;
+	CREATE UNIQUE INDEX idx_tickets_pnr ON tickets (t_pnr);`,
+		after: backfillTicketPnrs},
+
+	// 5: stored responses of mutating RPCs for replaying retries
+	{statements: `CREATE TABLE idempotency_keys (
+		k_key TEXT NOT NULL,
+		k_method TEXT NOT NULL,
+		k_request_hash TEXT NOT NULL,
+		k_response BLOB,
+		k_created_at INTEGER NOT NULL,
+		k_expires_at INTEGER NOT NULL,
+		PRIMARY KEY (k_key, k_method)
+	);
+	CREATE INDEX idx_idempotency_keys_expiry ON idempotency_keys (k_expires_at);`},
+
+	// 6: optimistic concurrency for bookings
+	{statements: `ALTER TABLE tickets ADD COLUMN t_version INTEGER NOT NULL DEFAULT 1;`},
+
+	// 7: append-only, hash chained audit log
+	{statements: `CREATE TABLE audit_log (
+		a_seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		a_at INTEGER NOT NULL,
+		a_actor TEXT NOT NULL,
+		a_action TEXT NOT NULL,
+		a_resource TEXT NOT NULL,
+		a_before TEXT,
+		a_after TEXT,
+		a_client_addr TEXT,
+		a_prev_hash TEXT NOT NULL,
+		a_hash TEXT NOT NULL
+	);
+	CREATE INDEX idx_audit_log_resource ON audit_log (a_resource);
+	CREATE TRIGGER audit_log_no_update BEFORE UPDATE ON audit_log
+	BEGIN
+		SELECT RAISE(ABORT, 'audit_log is append-only');
+	END;
+	CREATE TRIGGER audit_log_no_delete BEFORE DELETE ON audit_log
+	BEGIN
+		SELECT RAISE(ABORT, 'audit_log is append-only');
+	END;`},
+
+	// 8: outbox of booking events and their delivery to webhooks
+	{statements: `CREATE TABLE outbox_events (
+		e_seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		e_id TEXT NOT NULL UNIQUE,
+		e_type TEXT NOT NULL,
+		e_resource TEXT NOT NULL,
+		e_payload TEXT NOT NULL,
+		e_created_at INTEGER NOT NULL,
+		e_dispatched_at INTEGER
+	);
+	CREATE INDEX idx_outbox_events_pending ON outbox_events (e_dispatched_at);
+	CREATE TABLE webhooks (
+		w_id TEXT PRIMARY KEY,
+		w_url TEXT NOT NULL,
+		w_secret TEXT NOT NULL,
+		w_event_types TEXT NOT NULL DEFAULT '',
+		w_active INTEGER NOT NULL DEFAULT 1,
+		w_created_at INTEGER NOT NULL
+	);
+	CREATE TABLE webhook_deliveries (
+		d_id TEXT PRIMARY KEY,
+		d_event_seq INTEGER NOT NULL REFERENCES outbox_events (e_seq),
+		d_webhook_id TEXT NOT NULL REFERENCES webhooks (w_id),
+		d_status TEXT NOT NULL DEFAULT 'PENDING',
+		d_attempts INTEGER NOT NULL DEFAULT 0,
+		d_next_attempt_at INTEGER NOT NULL,
+		d_last_error TEXT,
+		d_delivered_at INTEGER,
+		UNIQUE (d_event_seq, d_webhook_id)
+	);
+	CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (d_status, d_next_attempt_at);`},
+
+	// 9: keys signing the tokens printed on e-tickets
+	{statements: `CREATE TABLE ticket_signing_keys (
+		s_key_id TEXT PRIMARY KEY,
+		s_private_key BLOB NOT NULL,
+		s_created_at INTEGER NOT NULL,
+		s_retired_at INTEGER
+	);`},
+
+	// 10: check-in of tickets by conductors
+	{statements: `ALTER TABLE tickets ADD COLUMN t_checked_in_at INTEGER;
+	ALTER TABLE tickets ADD COLUMN t_checked_in_by TEXT;`},
+
+	// 11: wrong verification codes entered since the code was issued
+	{statements: `ALTER TABLE users ADD COLUMN u_verification_attempts INTEGER NOT NULL DEFAULT 0;`},
+
+	// 12: idempotency keys scoped to the caller, stored responses keep the scope
+	// of callers that didn't have one
+	{statements: `CREATE TABLE idempotency_keys_scoped (
+		k_scope TEXT NOT NULL,
+		k_key TEXT NOT NULL,
+		k_method TEXT NOT NULL,
+		k_request_hash TEXT NOT NULL,
+		k_response BLOB,
+		k_created_at INTEGER NOT NULL,
+		k_expires_at INTEGER NOT NULL,
+		PRIMARY KEY (k_scope, k_key, k_method)
+	);
+	INSERT INTO idempotency_keys_scoped SELECT '', k_key, k_method, k_request_hash, k_response, k_created_at, k_expires_at
+		FROM idempotency_keys WHERE k_response IS NOT NULL;
+	DROP TABLE idempotency_keys;
+	ALTER TABLE idempotency_keys_scoped RENAME TO idempotency_keys;
+	CREATE INDEX idx_idempotency_keys_expiry ON idempotency_keys (k_expires_at);`},
+}
+
+// MigrateDatabase brings the schema of db up to the latest version.
+func MigrateDatabase(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY)`); err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	current, err := SchemaVersion(db)
+	if err != nil {
+		return err
+	}
+
+	for i := current; i < len(schemaMigrations); i++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if precondition := schemaMigrations[i].precondition; precondition != nil {
+			if err := precondition(tx); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("schema migration %d: %w", i+1, err)
+			}
+		}
+		if _, err := tx.Exec(schemaMigrations[i].statements); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("schema migration %d: %w", i+1, err)
+		}
+		if after := schemaMigrations[i].after; after != nil {
+			if err := after(tx); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("schema migration %d: %w", i+1, err)
+			}
+		}
+		if _, err := tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", i+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+		slog.Info("Applied schema migration", "version", i+1)
+	}
+	return nil
+}
+
+// SchemaVersion returns the highest migration applied to db.
+func SchemaVersion(db *sql.DB) (int, error) {
+	var version sql.NullInt64
+	if err := db.QueryRow("SELECT MAX(version) FROM schema_migrations").Scan(&version); err != nil {
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+	return int(version.Int64), nil
+}
+
+func requireNoDuplicateUsers(tx *sql.Tx) error {
+	var duplicates int
+	err := tx.QueryRow(`SELECT COUNT(*) FROM (SELECT 1 FROM users WHERE u_user_email IS NOT NULL AND TRIM(u_user_email) <> ''
+		GROUP BY LOWER(TRIM(u_user_email)) HAVING COUNT(*) > 1)`).Scan(&duplicates)
+	if err != nil {
+		return err
+	}
+	if duplicates > 0 {
+		return fmt.Errorf("%d emails belong to more than one user, run `go run ./admin merge-users` first", duplicates)
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"github.com/go-pdf/fpdf"
+	"github.com/skip2/go-qrcode"
+	"strings"
+	"time"
+	"fmt"
+	"io"
+)
+
+// eTicketPage is one booking printed on an e-ticket
+type eTicketPage struct {
+	booking *pb.BookingDbResponse
+	user    *pb.User
+	token   string
+}
+
+// renderETicketPdf writes a PDF with one page per ticket to w. Every page
+// carries the journey details and a QR code of the signed ticket token.
+func renderETicketPdf(w io.Writer, pages []*eTicketPage) error {
+	pdf := fpdf.New("P", "mm", "A5", "")
+	pdf.SetTitle("E-ticket", false)
+	pdf.SetCreationDate(time.Now())
+	// core fonts are latin-1, names are translated and unknown runes dropped
+	translate := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for _, page := range pages {
+		pdf.AddPage()
+		pageWidth, _ := pdf.GetPageSize()
+		left, _, right, _ := pdf.GetMargins()
+		width := pageWidth - left - right
+
+		pdf.SetFont("Helvetica", "B", 20)
+		pdf.CellFormat(width, 12, "E-ticket", "", 1, "L", false, 0, "")
+		pdf.SetFont("Helvetica", "", 10)
+		pdf.CellFormat(width, 6, "Booking reference "+page.booking.GetPnr(), "", 1, "L", false, 0, "")
+		pdf.Ln(4)
+
+		rows := [][2]string{
+			{"Passenger", strings.TrimSpace(page.user.GetFirstname() + " " + page.user.GetLastname())},
+			{"From", page.booking.GetFrom()},
+			{"To", page.booking.GetTo()},
+			{"Seat", fmt.Sprintf("%d, section %s", page.booking.GetSeat(), page.booking.GetSection())},
+		}
+		if page.booking.GetDepartureAt() != 0 {
+			rows = append(rows, [2]string{"Departure", time.Unix(page.booking.GetDepartureAt(), 0).UTC().Format("Mon 02 Jan 2006 15:04 MST")})
+		}
+		rows = append(rows, [2]string{"Ticket id", page.booking.GetId()})
+		for _, row := range rows {
+			pdf.SetFont("Helvetica", "B", 11)
+			pdf.CellFormat(28, 8, row[0], "", 0, "L", false, 0, "")
+			pdf.SetFont("Helvetica", "", 11)
+			pdf.CellFormat(width-28, 8, translate(row[1]), "", 1, "L", false, 0, "")
+		}
+
+		pdf.Ln(6)
+		if err := drawQRCode(pdf, page.token, left+(width-70)/2, pdf.GetY(), 70); err != nil {
+			return err
+		}
+		pdf.SetY(pdf.GetY() + 72)
+		pdf.SetFont("Helvetica", "", 8)
+		pdf.CellFormat(width, 5, "Show this code to the conductor on board.", "", 1, "C", false, 0, "")
+	}
+	return pdf.Output(w)
+}
+
+// drawQRCode draws the QR code of content as vector squares of size by size mm
+func drawQRCode(pdf *fpdf.Fpdf, content string, x, y, size float64) error {
+	code, err := qrcode.New(content, qrcode.Medium)
+	if err != nil {
+		return err
+	}
+	bitmap := code.Bitmap()
+	module := size / float64(len(bitmap))
+	pdf.SetFillColor(0, 0, 0)
+	for row, modules := range bitmap {
+		for column, dark := range modules {
+			if dark {
+				pdf.Rect(x+float64(column)*module, y+float64(row)*module, module, module, "F")
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package api
+
+import (
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"database/sql"
+	"context"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// ReadinessMonitor publishes the readiness of the server through the standard
+// grpc.health.v1 service. The server is SERVING once the seat allocator is warm
+// and while the database answers pings.
+type ReadinessMonitor struct {
+	db       *sql.DB
+	health   *health.Server
+	services []string
+	warm     atomic.Bool
+
+	Interval    time.Duration
+	PingTimeout time.Duration
+}
+
+// NewReadinessMonitor reports the overall status ("") and the status of each
+// of services, all NOT_SERVING until the first successful check.
+func NewReadinessMonitor(db *sql.DB, healthServer *health.Server, services ...string) *ReadinessMonitor {
+	m := &ReadinessMonitor{
+		db:          db,
+		health:      healthServer,
+		services:    append([]string{""}, services...),
+		Interval:    5 * time.Second,
+		PingTimeout: 2 * time.Second,
+	}
+	m.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	return m
+}
+
+// SetWarm records that the seat allocator has been warmed up.
+func (m *ReadinessMonitor) SetWarm() {
+	m.warm.Store(true)
+}
+
+// Check pings the database, updates the published status and reports whether
+// the server is ready.
+func (m *ReadinessMonitor) Check(ctx context.Context) bool {
+	ready := m.warm.Load()
+	if ready {
+		pingCtx, cancel := context.WithTimeout(ctx, m.PingTimeout)
+		defer cancel()
+		if err := m.db.PingContext(pingCtx); err != nil {
+			slog.WarnContext(ctx, "Readiness database ping failed", "error", err)
+			ready = false
+		}
+	}
+
+	if ready {
+		m.setStatus(healthpb.HealthCheckResponse_SERVING)
+	} else {
+		m.setStatus(healthpb.HealthCheckResponse_NOT_SERVING)
+	}
+	return ready
+}
+
+// Run checks readiness every Interval until ctx is done.
+func (m *ReadinessMonitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.Interval)
+	defer ticker.Stop()
+	for {
+		m.Check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *ReadinessMonitor) setStatus(servingStatus healthpb.HealthCheckResponse_ServingStatus) {
+	for _, service := range m.services {
+		m.health.SetServingStatus(service, servingStatus)
+	}
+}
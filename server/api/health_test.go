@@ -0,0 +1,66 @@
+package api_test
+
+import (
+    "ticket-booking-app/server/api"
+	pb "ticket-booking-app/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	"google.golang.org/grpc/status"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"github.com/stretchr/testify/assert"
+	"context"
+	"testing"
+)
+
+func TestShouldWarmUpSeatAllocatorFromBookedTickets(t *testing.T) {
+    db := newTestDatabase(t)
+    first, err := api.NewBookingService(db).CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+
+    // a restarted server starts with an empty allocator
+    restarted := api.NewBookingService(db)
+    seats, err := restarted.WarmUp(context.TODO())
+    if err != nil {
+        t.Fatalf("Error in warming up %v ", err)
+    }
+    assert.Equal(t, 1, seats)
+
+    second, err := restarted.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, "second@test.com"))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    assert.False(t, first.GetSeat() == second.GetSeat() && first.GetSection() == second.GetSection(),
+        "Booked seat should not be allocated again after warm up")
+
+    _, err = restarted.ModifySeatByUser(context.TODO(), &pb.SeatModificationRequest{BookingId: second.GetId(),
+        Seat: first.GetSeat(), Section: first.GetSection(), User: &pb.User{Email: "second@test.com"}})
+    assert.Equal(t, codes.InvalidArgument, status.Code(err), "Booked seat should not be available after warm up")
+}
+
+func TestShouldReportReadinessThroughHealthService(t *testing.T) {
+    db := newTestDatabase(t)
+    healthServer := health.NewServer()
+    readiness := api.NewReadinessMonitor(db, healthServer, "booking.BookingService")
+
+    checkStatus := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+        response, err := healthServer.Check(context.TODO(), &healthpb.HealthCheckRequest{Service: service})
+        if err != nil {
+            t.Fatalf("Error in checking health %v ", err)
+        }
+        return response.GetStatus()
+    }
+
+    assert.False(t, readiness.Check(context.TODO()), "Server should not be ready before warm up")
+    assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkStatus(""))
+
+    readiness.SetWarm()
+    assert.True(t, readiness.Check(context.TODO()))
+    assert.Equal(t, healthpb.HealthCheckResponse_SERVING, checkStatus(""))
+    assert.Equal(t, healthpb.HealthCheckResponse_SERVING, checkStatus("booking.BookingService"))
+
+    db.Close()
+    assert.False(t, readiness.Check(context.TODO()), "Server should not be ready without a database")
+    assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, checkStatus("booking.BookingService"))
+}
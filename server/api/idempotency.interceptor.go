@@ -0,0 +1,228 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	bookingv2 "ticket-booking-app/domain/booking/v2"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/anypb"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+const (
+	// IdempotencyKeyHeader is the metadata key clients set on retried mutations
+	IdempotencyKeyHeader = "idempotency-key"
+	// IdempotencyReplayedHeader is set on responses that were replayed from a previous call
+	IdempotencyReplayedHeader = "idempotency-replayed"
+
+	DefaultIdempotencyKeyTTL = 24 * time.Hour
+
+	// idempotencyLease is how long a call in progress holds its key. The key of
+	// a call whose response couldn't be stored, e.g. because the server stopped,
+	// is free again afterwards.
+	idempotencyLease = time.Minute
+)
+
+// mutatingMethods are the RPCs that honour an idempotency key
+var mutatingMethods = map[string]bool{
+	pb.BookingService_CreateBooking_FullMethodName:           true,
+	pb.BookingService_ModifySeatByUser_FullMethodName:        true,
+	pb.BookingService_RemoveBookingByUser_FullMethodName:     true,
+	pb.UserService_RegisterUser_FullMethodName:               true,
+	pb.UserService_UpdateUserProfile_FullMethodName:          true,
+	pb.UserService_VerifyEmail_FullMethodName:                true,
+	pb.UserService_ResendVerificationCode_FullMethodName:     true,
+	pb.UserService_DeleteAccount_FullMethodName:              true,
+	pb.WebhookService_RegisterWebhook_FullMethodName:         true,
+	pb.WebhookService_DeleteWebhook_FullMethodName:           true,
+	pb.WebhookService_ReplayWebhookDeliveries_FullMethodName: true,
+	pb.TicketService_ValidateTicket_FullMethodName:           true,
+	pb.TicketService_RotateTicketSigningKey_FullMethodName:   true,
+	pb.TicketService_RetireTicketSigningKey_FullMethodName:   true,
+	bookingv2.BookingService_CreateBooking_FullMethodName:    true,
+	bookingv2.BookingService_UpdateBooking_FullMethodName:    true,
+	bookingv2.BookingService_DeleteBooking_FullMethodName:    true,
+}
+
+// NewIdempotencyInterceptor stores the first successful response of a mutating
+// RPC under its idempotency key and replays it for retries carrying the same
+// key and request. Keys are scoped to the caller, and reusing a key with a
+// different request is rejected. Keys expire after ttl; failed calls release
+// their key so they can be retried.
+func NewIdempotencyInterceptor(db *sql.DB, ttl time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		key := idempotencyKeyFromContext(ctx)
+		message, isMessage := req.(proto.Message)
+		if key == "" || !isMessage || !mutatingMethods[info.FullMethod] {
+			return handler(ctx, req)
+		}
+
+		requestHash, err := hashRequest(message)
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Error while hashing request: %v", err)
+		}
+		scope := callerKey(ctx)
+		replay, claimedAt, err := claimIdempotencyKey(ctx, db, scope, key, info.FullMethod, requestHash)
+		if err != nil {
+			return nil, err
+		}
+		if replay != nil {
+			if err := restoreSecrets(ctx, db, replay); err != nil {
+				return nil, err
+			}
+			grpc.SetHeader(ctx, metadata.Pairs(IdempotencyReplayedHeader, "true"))
+			return replay, nil
+		}
+
+		response, handlerErr := handler(ctx, req)
+		// the key is released or completed even when the caller has gone away
+		ctx = context.WithoutCancel(ctx)
+		if handlerErr != nil {
+			releaseIdempotencyKey(ctx, db, scope, key, info.FullMethod, claimedAt)
+			return nil, handlerErr
+		}
+
+		if err := storeIdempotentResponse(ctx, db, scope, key, info.FullMethod, claimedAt, response, ttl); err != nil {
+			// a retry runs the call again rather than waiting for a response
+			// that will never be stored
+			slog.ErrorContext(ctx, "Error in storing response for idempotency key", "key", key, "error", err)
+			releaseIdempotencyKey(ctx, db, scope, key, info.FullMethod, claimedAt)
+		}
+		return response, nil
+	}
+}
+
+// releaseIdempotencyKey frees the key claimed at claimedAt, unless its lease
+// expired and another call claimed it since
+func releaseIdempotencyKey(ctx context.Context, db *sql.DB, scope, key, method string, claimedAt int64) {
+	if _, err := db.ExecContext(ctx, "DELETE FROM idempotency_keys WHERE k_scope = ? AND k_key = ? AND k_method = ? AND k_created_at = ?",
+		scope, key, method, claimedAt); err != nil {
+		slog.ErrorContext(ctx, "Error in releasing idempotency key", "key", key, "error", err)
+	}
+}
+
+func idempotencyKeyFromContext(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(IdempotencyKeyHeader); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+func hashRequest(message proto.Message) (string, error) {
+	body, err := proto.MarshalOptions{Deterministic: true}.Marshal(message)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// claimIdempotencyKey records the key for a new call, leased for
+// idempotencyLease, and returns when it was claimed. The stored response is
+// returned instead when the key was already used for the same request.
+func claimIdempotencyKey(ctx context.Context, db *sql.DB, scope, key, method, requestHash string) (proto.Message, int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer tx.Rollback()
+
+	now := time.Now().Unix()
+	if _, err := tx.Exec("DELETE FROM idempotency_keys WHERE k_expires_at <= ?", now); err != nil {
+		return nil, 0, err
+	}
+
+	var storedHash string
+	var storedResponse []byte
+	row := tx.QueryRow("SELECT k_request_hash, k_response FROM idempotency_keys WHERE k_scope = ? AND k_key = ? AND k_method = ?", scope, key, method)
+	switch err := row.Scan(&storedHash, &storedResponse); err {
+	case sql.ErrNoRows:
+		if _, err := tx.Exec("INSERT INTO idempotency_keys (k_scope, k_key, k_method, k_request_hash, k_created_at, k_expires_at) VALUES (?, ?, ?, ?, ?, ?)",
+			scope, key, method, requestHash, now, now+int64(idempotencyLease.Seconds())); err != nil {
+			return nil, 0, err
+		}
+		return nil, now, tx.Commit()
+	case nil:
+	default:
+		return nil, 0, err
+	}
+
+	if storedHash != requestHash {
+		return nil, 0, status.Errorf(codes.InvalidArgument, "Idempotency key %s was already used with a different request", key)
+	}
+	if storedResponse == nil {
+		return nil, 0, withReason(status.Newf(codes.Aborted, "Request with idempotency key %s is still in progress", key),
+			pb.ErrorReason_IDEMPOTENCY_KEY_IN_PROGRESS)
+	}
+
+	var stored anypb.Any
+	if err := proto.Unmarshal(storedResponse, &stored); err != nil {
+		return nil, 0, err
+	}
+	replay, err := stored.UnmarshalNew()
+	return replay, 0, err
+}
+
+// storeIdempotentResponse completes the key claimed at claimedAt with the
+// response, which is kept for ttl from the start of the call. It fails when
+// the lease expired and another call claimed the key since.
+func storeIdempotentResponse(ctx context.Context, db *sql.DB, scope, key, method string, claimedAt int64, response any, ttl time.Duration) error {
+	message, ok := response.(proto.Message)
+	if !ok {
+		return nil
+	}
+	packed, err := anypb.New(withoutSecrets(message))
+	if err != nil {
+		return err
+	}
+	body, err := proto.Marshal(packed)
+	if err != nil {
+		return err
+	}
+	result, err := db.ExecContext(ctx, `UPDATE idempotency_keys SET k_response = ?, k_expires_at = k_created_at + ?
+		WHERE k_scope = ? AND k_key = ? AND k_method = ? AND k_created_at = ? AND k_response IS NULL`,
+		body, int64(ttl.Seconds()), scope, key, method, claimedAt)
+	if err != nil {
+		return err
+	}
+	if stored, _ := result.RowsAffected(); stored == 0 {
+		return fmt.Errorf("lease of idempotency key %s expired", key)
+	}
+	return nil
+}
+
+// withoutSecrets returns the response as it may be stored. The secret of a
+// registered webhook is only kept in the webhooks table.
+func withoutSecrets(response proto.Message) proto.Message {
+	webhook, isWebhook := response.(*pb.Webhook)
+	if !isWebhook || webhook.GetSecret() == "" {
+		return response
+	}
+	stored := proto.Clone(webhook).(*pb.Webhook)
+	stored.Secret = ""
+	return stored
+}
+
+// restoreSecrets reads the secrets withoutSecrets removed back into a replayed
+// response.
+func restoreSecrets(ctx context.Context, db *sql.DB, response proto.Message) error {
+	webhook, isWebhook := response.(*pb.Webhook)
+	if !isWebhook {
+		return nil
+	}
+	err := db.QueryRowContext(ctx, "SELECT w_secret FROM webhooks WHERE w_id = ? AND w_active = 1", webhook.GetId()).Scan(&webhook.Secret)
+	if err == sql.ErrNoRows {
+		return status.Errorf(codes.NotFound, "No webhook exists with id %s", webhook.GetId())
+	}
+	return err
+}
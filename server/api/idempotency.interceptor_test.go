@@ -0,0 +1,158 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+	"ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"testing"
+	"context"
+	"net"
+	"time"
+)
+
+func TestShouldReplayResponseForRetriedCreateBooking(t *testing.T) {
+	db := newTestDatabase(t)
+	bookingService := api.NewBookingService(db)
+	interceptor := api.NewIdempotencyInterceptor(db, time.Hour)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.BookingService_CreateBooking_FullMethodName}
+
+	calls := 0
+	handler := func(ctx context.Context, req any) (any, error) {
+		calls++
+		return bookingService.CreateBooking(ctx, req.(*pb.BookingRequest))
+	}
+	ctx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs(api.IdempotencyKeyHeader, "retry-key"))
+	request := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+
+	first, err := interceptor(ctx, request, info, handler)
+	if err != nil {
+		t.Fatalf("Error in creating booking %v ", err)
+	}
+	retried, err := interceptor(ctx, createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL), info, handler)
+	if err != nil {
+		t.Fatalf("Error in retrying booking %v ", err)
+	}
+	assert.Equal(t, 1, calls, "Retry should not reach the service")
+	assert.Equal(t, first.(*pb.BookingResponse).GetPnr(), retried.(*pb.BookingResponse).GetPnr())
+
+	changed := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+	changed.To = "Paris"
+	_, err = interceptor(ctx, changed, info, handler)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err), "Reused key with another request should be rejected")
+}
+
+func TestShouldReleaseIdempotencyKeyOfFailedCall(t *testing.T) {
+	db := newTestDatabase(t)
+	interceptor := api.NewIdempotencyInterceptor(db, time.Hour)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.BookingService_RemoveBookingByUser_FullMethodName}
+	ctx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs(api.IdempotencyKeyHeader, "failing-key"))
+	request := &pb.RemoveBookingByUserRequest{BookingId: "ABC123"}
+
+	calls := 0
+	failing := func(ctx context.Context, req any) (any, error) {
+		calls++
+		return nil, status.Errorf(codes.Unavailable, "try again")
+	}
+	for i := 0; i < 2; i++ {
+		_, err := interceptor(ctx, request, info, failing)
+		assert.Equal(t, codes.Unavailable, status.Code(err))
+	}
+	assert.Equal(t, 2, calls, "Failed call should be retried by the service")
+}
+
+func TestShouldAcceptExpiredIdempotencyKeyAgain(t *testing.T) {
+	db := newTestDatabase(t)
+	interceptor := api.NewIdempotencyInterceptor(db, -time.Second)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.BookingService_RemoveBookingByUser_FullMethodName}
+	ctx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs(api.IdempotencyKeyHeader, "expired-key"))
+
+	calls := 0
+	handler := func(ctx context.Context, req any) (any, error) {
+		calls++
+		return &pb.RemoveBookingResponse{}, nil
+	}
+	interceptor(ctx, &pb.RemoveBookingByUserRequest{BookingId: "ABC123"}, info, handler)
+	interceptor(ctx, &pb.RemoveBookingByUserRequest{BookingId: "XYZ789"}, info, handler)
+	assert.Equal(t, 2, calls, "Expired key should be usable again")
+}
+
+func TestShouldScopeIdempotencyKeysToTheCaller(t *testing.T) {
+	db := newTestDatabase(t)
+	interceptor := api.NewIdempotencyInterceptor(db, time.Hour)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.BookingService_RemoveBookingByUser_FullMethodName}
+	request := &pb.RemoveBookingByUserRequest{BookingId: "ABC123"}
+
+	calls := 0
+	handler := func(ctx context.Context, req any) (any, error) {
+		calls++
+		return &pb.RemoveBookingResponse{}, nil
+	}
+	for _, actor := range []string{"service:desk", "service:desk", "service:app"} {
+		ctx := metadata.NewIncomingContext(api.WithActor(context.TODO(), actor), metadata.Pairs(api.IdempotencyKeyHeader, "shared-key"))
+		if _, err := interceptor(ctx, request, info, handler); err != nil {
+			t.Fatalf("Error in calling with idempotency key %v ", err)
+		}
+	}
+	assert.Equal(t, 2, calls, "Callers should not see each other's keys")
+
+	for _, address := range []string{"203.0.113.1", "203.0.113.2"} {
+		ctx := peer.NewContext(context.TODO(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(address), Port: 40000}})
+		ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(api.IdempotencyKeyHeader, "shared-key", api.ActorHeader, "service:desk"))
+		if _, err := interceptor(ctx, request, info, handler); err != nil {
+			t.Fatalf("Error in calling with idempotency key %v ", err)
+		}
+	}
+	assert.Equal(t, 4, calls, "An unverified x-actor shouldn't share the keys of that actor")
+}
+
+func TestShouldNotStoreResponseOverTheClaimOfAnotherCall(t *testing.T) {
+	db := newTestDatabase(t)
+	interceptor := api.NewIdempotencyInterceptor(db, time.Hour)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.BookingService_RemoveBookingByUser_FullMethodName}
+	ctx := metadata.NewIncomingContext(context.TODO(), metadata.Pairs(api.IdempotencyKeyHeader, "slow-key"))
+
+	slow := func(ctx context.Context, req any) (any, error) {
+		// the lease ran out and another call claimed the key meanwhile
+		mustExec(t, db, "UPDATE idempotency_keys SET k_created_at = k_created_at + 60")
+		return &pb.RemoveBookingResponse{}, nil
+	}
+	if _, err := interceptor(ctx, &pb.RemoveBookingByUserRequest{BookingId: "ABC123"}, info, slow); err != nil {
+		t.Fatalf("Error in calling with idempotency key %v ", err)
+	}
+	assert.Equal(t, 1, countRows(t, db, "SELECT COUNT(*) FROM idempotency_keys WHERE k_response IS NULL"),
+		"The claim of the other call should be left alone")
+}
+
+func TestShouldNotStoreWebhookSecretsWithIdempotencyKeys(t *testing.T) {
+	db := newTestDatabase(t)
+	webhookService := api.NewWebhookService(db)
+	webhookService.SetURLPolicy(api.WebhookURLPolicy{AllowedHosts: []string{"hooks.example.com"}})
+	interceptor := api.NewIdempotencyInterceptor(db, time.Hour)
+	info := &grpc.UnaryServerInfo{FullMethod: pb.WebhookService_RegisterWebhook_FullMethodName}
+	handler := func(ctx context.Context, req any) (any, error) {
+		return webhookService.RegisterWebhook(ctx, req.(*pb.RegisterWebhookRequest))
+	}
+	ctx := metadata.NewIncomingContext(api.WithActor(context.TODO(), "service:ops"), metadata.Pairs(api.IdempotencyKeyHeader, "webhook-key"))
+	request := &pb.RegisterWebhookRequest{Url: "https://hooks.example.com/bookings"}
+
+	registered, err := interceptor(ctx, request, info, handler)
+	if err != nil {
+		t.Fatalf("Error in registering webhook %v ", err)
+	}
+	secret := registered.(*pb.Webhook).GetSecret()
+	assert.NotEmpty(t, secret)
+	assert.Zero(t, countRows(t, db, "SELECT COUNT(*) FROM idempotency_keys WHERE INSTR(k_response, ?) > 0", secret),
+		"The secret should not be stored with the response")
+
+	replayed, err := interceptor(ctx, request, info, handler)
+	if err != nil {
+		t.Fatalf("Error in retrying webhook registration %v ", err)
+	}
+	assert.Equal(t, registered.(*pb.Webhook).GetId(), replayed.(*pb.Webhook).GetId())
+	assert.Equal(t, secret, replayed.(*pb.Webhook).GetSecret(), "Replays should read the secret back")
+}
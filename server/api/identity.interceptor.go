@@ -0,0 +1,96 @@
+package api
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"crypto/x509"
+	"context"
+	"strings"
+)
+
+// ServiceActorPrefix marks actors authenticated by a client certificate
+const ServiceActorPrefix = "service:"
+
+// IdentityVerifier maps verified client certificates to service identities.
+// Identities maps certificate common names to identity names; when it is empty
+// the common name is the identity, otherwise certificates it doesn't list are
+// rejected. Certificates for which IsRelay returns true belong to local relays
+// such as the REST gateway, whose calls keep the identity claimed in x-actor.
+type IdentityVerifier struct {
+	Identities map[string]string
+	IsRelay    func(cert *x509.Certificate) bool
+}
+
+// NewIdentityInterceptor attaches the identity of the client certificate to the
+// context as the actor, see WithActor.
+func NewIdentityInterceptor(verifier *IdentityVerifier) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := verifier.authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// NewIdentityStreamInterceptor is NewIdentityInterceptor for streaming RPCs.
+func NewIdentityStreamInterceptor(verifier *IdentityVerifier) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := verifier.authenticate(stream.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func (v *IdentityVerifier) authenticate(ctx context.Context) (context.Context, error) {
+	p, ok := peer.FromContext(ctx)
+	if !ok {
+		return ctx, nil
+	}
+	tlsInfo, isTLS := p.AuthInfo.(credentials.TLSInfo)
+	if !isTLS || len(tlsInfo.State.PeerCertificates) == 0 {
+		// without a certificate the caller stays unverified
+		return ctx, nil
+	}
+	cert := tlsInfo.State.PeerCertificates[0]
+	if v.IsRelay != nil && v.IsRelay(cert) {
+		return ctx, nil
+	}
+
+	identity := cert.Subject.CommonName
+	if len(v.Identities) > 0 {
+		mapped, known := v.Identities[identity]
+		if !known {
+			return nil, status.Errorf(codes.PermissionDenied, "Client certificate %s is not mapped to a service identity", identity)
+		}
+		identity = mapped
+	}
+	if identity == "" {
+		return nil, status.Errorf(codes.Unauthenticated, "Client certificate has no common name")
+	}
+	return WithActor(ctx, ServiceActorPrefix+identity), nil
+}
+
+// requireServiceActor rejects callers that aren't a service identified by its
+// client certificate.
+func requireServiceActor(ctx context.Context) error {
+	if actor, _ := ctx.Value(actorContextKey{}).(string); !strings.HasPrefix(actor, ServiceActorPrefix) {
+		return status.Errorf(codes.PermissionDenied, "Only services with a client certificate may call this method")
+	}
+	return nil
+}
+
+// contextServerStream is a stream whose handler sees ctx as its context
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
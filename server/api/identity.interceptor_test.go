@@ -0,0 +1,72 @@
+package api_test
+
+import (
+    "ticket-booking-app/server/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"github.com/stretchr/testify/assert"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"context"
+	"net"
+	"testing"
+)
+
+func contextWithClientCertificate(commonName string) (context.Context, *x509.Certificate) {
+    cert := &x509.Certificate{Subject: pkix.Name{CommonName: commonName}, Raw: []byte(commonName)}
+    ctx := peer.NewContext(context.TODO(), &peer.Peer{
+        Addr:     &net.TCPAddr{IP: net.IPv4(10, 0, 0, 7), Port: 4000},
+        AuthInfo: credentials.TLSInfo{State: tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}}},
+    })
+    ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(api.ActorHeader, "agent-7"))
+    return ctx, cert
+}
+
+func actorSeenByHandler(t *testing.T, interceptor grpc.UnaryServerInterceptor, ctx context.Context) (string, error) {
+    t.Helper()
+    var actor string
+    _, err := interceptor(ctx, nil, &grpc.UnaryServerInfo{FullMethod: "/booking.BookingService/CreateBooking"},
+        func(ctx context.Context, req any) (any, error) {
+            actor = api.ActorFromContext(ctx)
+            return nil, nil
+        })
+    return actor, err
+}
+
+func TestShouldMapClientCertificatesToServiceIdentities(t *testing.T) {
+    gatewayCtx, gatewayCert := contextWithClientCertificate("booking.internal")
+    interceptor := api.NewIdentityInterceptor(&api.IdentityVerifier{
+        Identities: map[string]string{"conductor-handheld": "conductor"},
+        IsRelay:    func(cert *x509.Certificate) bool { return cert == gatewayCert },
+    })
+
+    ctx, _ := contextWithClientCertificate("conductor-handheld")
+    actor, err := actorSeenByHandler(t, interceptor, ctx)
+    assert.NoError(t, err)
+    assert.Equal(t, "service:conductor", actor, "Certificate identity should replace the claimed actor")
+
+    ctx, _ = contextWithClientCertificate("unknown-service")
+    _, err = actorSeenByHandler(t, interceptor, ctx)
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "Unmapped certificates should be rejected")
+
+    actor, err = actorSeenByHandler(t, interceptor, gatewayCtx)
+    assert.NoError(t, err)
+    assert.Equal(t, "unverified:agent-7", actor, "Relayed calls should keep the claimed actor")
+
+    actor, err = actorSeenByHandler(t, interceptor, metadata.NewIncomingContext(context.TODO(), metadata.Pairs(api.ActorHeader, "agent-7")))
+    assert.NoError(t, err)
+    assert.Equal(t, "unverified:agent-7", actor, "Callers without certificates should stay unverified")
+}
+
+func TestShouldUseCommonNameWithoutIdentityMapping(t *testing.T) {
+    interceptor := api.NewIdentityInterceptor(&api.IdentityVerifier{})
+    ctx, _ := contextWithClientCertificate("reporting-job")
+    actor, err := actorSeenByHandler(t, interceptor, ctx)
+    assert.NoError(t, err)
+    assert.Equal(t, "service:reporting-job", actor)
+}
@@ -0,0 +1,180 @@
+package api
+
+import (
+	"github.com/mattn/go-sqlite3"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"database/sql"
+	"database/sql/driver"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// OpenInstrumentedDB opens a SQLite database whose statements and
+// transactions are timed in metrics and traced as spans of the calling RPC.
+func OpenInstrumentedDB(dsn string, metrics *Metrics) *sql.DB {
+	return sql.OpenDB(&instrumentedConnector{dsn: dsn, driver: &sqlite3.SQLiteDriver{}, metrics: metrics})
+}
+
+type instrumentedConnector struct {
+	dsn     string
+	driver  driver.Driver
+	metrics *Metrics
+}
+
+func (c *instrumentedConnector) Connect(ctx context.Context) (driver.Conn, error) {
+	conn, err := c.driver.Open(c.dsn)
+	if err != nil {
+		return nil, err
+	}
+	contextConn, ok := conn.(contextConn)
+	if !ok {
+		conn.Close()
+		return nil, fmt.Errorf("driver connection %T doesn't support contexts", conn)
+	}
+	return &instrumentedConn{conn: contextConn, metrics: c.metrics}, nil
+}
+
+func (c *instrumentedConnector) Driver() driver.Driver {
+	return c.driver
+}
+
+// contextConn is what the sqlite3 driver's connections implement
+type contextConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+}
+
+// instrumentedConn times statements run directly on the connection, which is
+// how database/sql runs them when no statement is prepared explicitly. Query
+// timings end when the first row can be read.
+//
+// Transactions begun with a traced context get a span, and the statements of
+// the transaction become its children even when they are run without a
+// context, as the helpers taking a dbExecutor do.
+type instrumentedConn struct {
+	conn    contextConn
+	metrics *Metrics
+	txCtx   context.Context
+}
+
+// Unwrap returns the driver's connection, e.g. for sql.Conn.Raw callers that
+// need the *sqlite3.SQLiteConn.
+func (c *instrumentedConn) Unwrap() driver.Conn {
+	return c.conn
+}
+
+func (c *instrumentedConn) Prepare(query string) (driver.Stmt, error) {
+	return c.conn.Prepare(query)
+}
+
+func (c *instrumentedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	return c.conn.PrepareContext(ctx, query)
+}
+
+func (c *instrumentedConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *instrumentedConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *instrumentedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	defer c.metrics.observeSQL("begin", time.Now())
+	ctx, span := startSQLSpan(ctx, "TRANSACTION")
+	tx, err := c.conn.BeginTx(ctx, opts)
+	if err != nil {
+		endSQLSpan(span, err)
+		return nil, err
+	}
+	c.txCtx = ctx
+	return &instrumentedTx{tx: tx, conn: c, span: span}, nil
+}
+
+func (c *instrumentedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	defer c.metrics.observeSQL("exec", time.Now())
+	ctx, span := startSQLSpan(c.traceContext(ctx), sqlOperation(query), semconv.DBQueryText(query))
+	result, err := c.conn.ExecContext(ctx, query, args)
+	endSQLSpan(span, err)
+	return result, err
+}
+
+func (c *instrumentedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	defer c.metrics.observeSQL("query", time.Now())
+	ctx, span := startSQLSpan(c.traceContext(ctx), sqlOperation(query), semconv.DBQueryText(query))
+	rows, err := c.conn.QueryContext(ctx, query, args)
+	endSQLSpan(span, err)
+	return rows, err
+}
+
+func (c *instrumentedConn) Ping(ctx context.Context) error {
+	return c.conn.Ping(ctx)
+}
+
+// traceContext is ctx, or the context of the open transaction when ctx isn't traced
+func (c *instrumentedConn) traceContext(ctx context.Context) context.Context {
+	if c.txCtx != nil && !trace.SpanContextFromContext(ctx).IsValid() {
+		return c.txCtx
+	}
+	return ctx
+}
+
+type instrumentedTx struct {
+	tx   driver.Tx
+	conn *instrumentedConn
+	span trace.Span
+}
+
+func (t *instrumentedTx) Commit() error {
+	defer t.conn.metrics.observeSQL("commit", time.Now())
+	err := t.tx.Commit()
+	t.end("commit", err)
+	return err
+}
+
+func (t *instrumentedTx) Rollback() error {
+	defer t.conn.metrics.observeSQL("rollback", time.Now())
+	err := t.tx.Rollback()
+	t.end("rollback", err)
+	return err
+}
+
+func (t *instrumentedTx) end(outcome string, err error) {
+	t.conn.txCtx = nil
+	t.span.SetAttributes(attribute.String("db.transaction.outcome", outcome))
+	endSQLSpan(t.span, err)
+}
+
+// startSQLSpan only starts a span within a trace, so that migrations and
+// background work don't each become traces of their own.
+func startSQLSpan(ctx context.Context, name string, attributes ...attribute.KeyValue) (context.Context, trace.Span) {
+	parent := trace.SpanFromContext(ctx)
+	if !parent.SpanContext().IsValid() {
+		return ctx, parent
+	}
+	attributes = append(attributes, semconv.DBSystemSqlite)
+	return tracer().Start(ctx, name, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+}
+
+func endSQLSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	span.End()
+}
+
+// sqlOperation names statement spans by their first keyword, e.g. SELECT
+func sqlOperation(query string) string {
+	operation, _, _ := strings.Cut(strings.TrimSpace(query), " ")
+	return strings.ToUpper(operation)
+}
@@ -0,0 +1,154 @@
+package api
+
+import (
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"database/sql"
+	"context"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	BookingOperationCreate     = "create"
+	BookingOperationModifySeat = "modify_seat"
+	BookingOperationCancel     = "cancel"
+	BookingOperationImport     = "import"
+)
+
+// Metrics are the Prometheus metrics of the server, served by Handler.
+// A nil *Metrics records nothing.
+type Metrics struct {
+	registry          *prometheus.Registry
+	rpcHandled        *prometheus.CounterVec
+	rpcDuration       *prometheus.HistogramVec
+	bookingOperations *prometheus.CounterVec
+	sqlDuration       *prometheus.HistogramVec
+}
+
+func NewMetrics() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		rpcHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "RPCs completed on the server, by method and status code.",
+		}, []string{"grpc_service", "grpc_method", "grpc_code"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Time taken by the server to handle RPCs.",
+			Buckets: prometheus.ExponentialBuckets(0.001, 2.5, 10),
+		}, []string{"grpc_service", "grpc_method"}),
+		bookingOperations: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ticket_booking_operations_total",
+			Help: "Committed booking changes, by operation.",
+		}, []string{"operation"}),
+		sqlDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ticket_booking_sql_duration_seconds",
+			Help:    "Time taken by SQL statements and transaction control, by operation.",
+			Buckets: prometheus.ExponentialBuckets(0.0001, 3, 10),
+		}, []string{"operation"}),
+	}
+	for _, operation := range []string{BookingOperationCreate, BookingOperationModifySeat, BookingOperationCancel, BookingOperationImport} {
+		m.bookingOperations.WithLabelValues(operation)
+	}
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.rpcHandled, m.rpcDuration, m.bookingOperations, m.sqlDuration,
+	)
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format.
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// RegisterSeatAllocator exports the occupancy and capacity of every section of allocator.
+func (m *Metrics) RegisterSeatAllocator(allocator *SeatAllocator) {
+	m.registry.MustRegister(&occupancyCollector{allocator: allocator})
+}
+
+// RegisterDatabase exports the connection pool statistics of db.
+func (m *Metrics) RegisterDatabase(db *sql.DB) {
+	m.registry.MustRegister(collectors.NewDBStatsCollector(db, "ticket_booking"))
+}
+
+func (m *Metrics) countBooking(operation string) {
+	if m == nil {
+		return
+	}
+	m.bookingOperations.WithLabelValues(operation).Inc()
+}
+
+func (m *Metrics) observeSQL(operation string, started time.Time) {
+	if m == nil {
+		return
+	}
+	m.sqlDuration.WithLabelValues(operation).Observe(time.Since(started).Seconds())
+}
+
+func (m *Metrics) observeRPC(fullMethod string, started time.Time, err error) {
+	service, method := splitMethodName(fullMethod)
+	m.rpcHandled.WithLabelValues(service, method, status.Code(err).String()).Inc()
+	m.rpcDuration.WithLabelValues(service, method).Observe(time.Since(started).Seconds())
+}
+
+// NewMetricsInterceptor counts and times every unary RPC by its status code.
+func NewMetricsInterceptor(m *Metrics) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		started := time.Now()
+		resp, err := handler(ctx, req)
+		m.observeRPC(info.FullMethod, started, err)
+		return resp, err
+	}
+}
+
+// NewMetricsStreamInterceptor is NewMetricsInterceptor for streaming RPCs.
+func NewMetricsStreamInterceptor(m *Metrics) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		started := time.Now()
+		err := handler(srv, stream)
+		m.observeRPC(info.FullMethod, started, err)
+		return err
+	}
+}
+
+// splitMethodName splits "/package.Service/Method"
+func splitMethodName(fullMethod string) (string, string) {
+	service, method, found := strings.Cut(strings.TrimPrefix(fullMethod, "/"), "/")
+	if !found {
+		return "unknown", "unknown"
+	}
+	return service, method
+}
+
+var (
+	occupiedSeatsDesc = prometheus.NewDesc("ticket_booking_section_occupied_seats",
+		"Seats currently allocated in a section.", []string{"section"}, nil)
+	capacitySeatsDesc = prometheus.NewDesc("ticket_booking_section_capacity_seats",
+		"Seats in a section.", []string{"section"}, nil)
+)
+
+// occupancyCollector reads the allocator on every scrape, so the gauges can't
+// drift from the seats actually handed out.
+type occupancyCollector struct {
+	allocator *SeatAllocator
+}
+
+func (c *occupancyCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- occupiedSeatsDesc
+	ch <- capacitySeatsDesc
+}
+
+func (c *occupancyCollector) Collect(ch chan<- prometheus.Metric) {
+	occupancy := c.allocator.Occupancy()
+	for _, section := range c.allocator.Sections() {
+		ch <- prometheus.MustNewConstMetric(occupiedSeatsDesc, prometheus.GaugeValue, float64(occupancy[section.Name]), section.Name)
+		ch <- prometheus.MustNewConstMetric(capacitySeatsDesc, prometheus.GaugeValue, float64(section.Seats), section.Name)
+	}
+}
@@ -0,0 +1,80 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"github.com/stretchr/testify/assert"
+	"net/http/httptest"
+	"context"
+	"io"
+	"testing"
+)
+
+func scrapeMetrics(t *testing.T, metrics *api.Metrics) string {
+    t.Helper()
+    recorder := httptest.NewRecorder()
+    metrics.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+    body, _ := io.ReadAll(recorder.Body)
+    return string(body)
+}
+
+func TestShouldExportBookingAndOccupancyMetrics(t *testing.T) {
+    metrics := api.NewMetrics()
+    db := api.OpenInstrumentedDB(":memory:", metrics)
+    db.SetMaxOpenConns(1)
+    t.Cleanup(func() { db.Close() })
+    if err := api.MigrateDatabase(db); err != nil {
+        t.Fatalf("Error in migrating database %v ", err)
+    }
+    metrics.RegisterDatabase(db)
+
+    allocator, _ := api.NewSeatAllocatorWithLayout([]api.Section{{Name: "A", Seats: 4}}, api.AllocationSequential)
+    bookingService := api.NewBookingService(db)
+    bookingService.SetSeatAllocator(allocator)
+    bookingService.SetMetrics(metrics)
+    metrics.RegisterSeatAllocator(allocator)
+
+    first, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    if _, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, "second@test.com")); err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    if _, err := bookingService.ModifySeatByUser(context.TODO(), &pb.SeatModificationRequest{BookingId: first.GetId(), Section: "A", Seat: 3, User: &pb.User{Email: EMAIL}}); err != nil {
+        t.Fatalf("Error in modifying booking %v ", err)
+    }
+    if _, err := bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: first.GetId(), User: &pb.User{Email: EMAIL}}); err != nil {
+        t.Fatalf("Error in cancelling booking %v ", err)
+    }
+
+    scraped := scrapeMetrics(t, metrics)
+    assert.Contains(t, scraped, `ticket_booking_operations_total{operation="create"} 2`)
+    assert.Contains(t, scraped, `ticket_booking_operations_total{operation="modify_seat"} 1`)
+    assert.Contains(t, scraped, `ticket_booking_operations_total{operation="cancel"} 1`)
+    assert.Contains(t, scraped, `ticket_booking_section_occupied_seats{section="A"} 1`)
+    assert.Contains(t, scraped, `ticket_booking_section_capacity_seats{section="A"} 4`)
+    assert.Contains(t, scraped, `ticket_booking_sql_duration_seconds_count{operation="commit"}`)
+    assert.Contains(t, scraped, `ticket_booking_sql_duration_seconds_count{operation="exec"}`)
+    assert.Contains(t, scraped, "go_sql_max_open_connections{db_name=\"ticket_booking\"} 1")
+}
+
+func TestShouldCountRPCsByStatusCode(t *testing.T) {
+    metrics := api.NewMetrics()
+    interceptor := api.NewMetricsInterceptor(metrics)
+    info := &grpc.UnaryServerInfo{FullMethod: "/booking.BookingService/GetBookingByUser"}
+
+    for _, err := range []error{nil, nil, status.Errorf(codes.NotFound, "No booking")} {
+        interceptor(context.TODO(), nil, info, func(ctx context.Context, req any) (any, error) {
+            return nil, err
+        })
+    }
+
+    scraped := scrapeMetrics(t, metrics)
+    assert.Contains(t, scraped, `grpc_server_handled_total{grpc_code="OK",grpc_method="GetBookingByUser",grpc_service="booking.BookingService"} 2`)
+    assert.Contains(t, scraped, `grpc_server_handled_total{grpc_code="NotFound",grpc_method="GetBookingByUser",grpc_service="booking.BookingService"} 1`)
+    assert.Contains(t, scraped, `grpc_server_handling_seconds_count{grpc_method="GetBookingByUser",grpc_service="booking.BookingService"} 3`)
+}
@@ -0,0 +1,92 @@
+package api
+
+import (
+	"ticket-booking-app/logging"
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// NotificationQueue sends notifications in the background so RPCs never wait
+// for the mail server. Failed sends are retried with exponential backoff;
+// when the queue is full new notifications are dropped and logged.
+type NotificationQueue struct {
+	notifier      Notifier
+	notifications chan *Notification
+	workers       sync.WaitGroup
+	mu            sync.RWMutex
+	closed        bool
+
+	MaxAttempts int
+	BaseBackoff time.Duration
+	SendTimeout time.Duration
+}
+
+func NewNotificationQueue(notifier Notifier, size int) *NotificationQueue {
+	return &NotificationQueue{
+		notifier:      notifier,
+		notifications: make(chan *Notification, size),
+		MaxAttempts:   5,
+		BaseBackoff:   time.Second,
+		SendTimeout:   30 * time.Second,
+	}
+}
+
+// Start launches the workers that send queued notifications until Close.
+func (q *NotificationQueue) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		q.workers.Add(1)
+		go func() {
+			defer q.workers.Done()
+			for notification := range q.notifications {
+				q.send(notification)
+			}
+		}()
+	}
+}
+
+// Enqueue queues notification without blocking and reports whether it was accepted.
+func (q *NotificationQueue) Enqueue(notification *Notification) bool {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		return false
+	}
+	select {
+	case q.notifications <- notification:
+		return true
+	default:
+		slog.Warn("Notification queue is full, dropping notification", "subject", notification.Subject, logging.Email(notification.To))
+		return false
+	}
+}
+
+// Close stops accepting notifications and waits for the queued ones to be sent.
+func (q *NotificationQueue) Close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.notifications)
+	}
+	q.mu.Unlock()
+	q.workers.Wait()
+}
+
+func (q *NotificationQueue) send(notification *Notification) {
+	wait := q.BaseBackoff
+	for attempt := 1; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), q.SendTimeout)
+		err := q.notifier.Send(ctx, notification)
+		cancel()
+		if err == nil {
+			return
+		}
+		if attempt >= q.MaxAttempts {
+			slog.Error("Giving up on notification", "subject", notification.Subject, logging.Email(notification.To), "attempts", attempt, "error", err)
+			return
+		}
+		time.Sleep(wait)
+		wait *= 2
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+	"strings"
+	"embed"
+	"fmt"
+	"time"
+)
+
+const (
+	NotificationBooked      = "booked"
+	NotificationSeatChanged = "seat_changed"
+	NotificationCancelled   = "cancelled"
+	NotificationVerifyEmail = "verify_email"
+)
+
+var notificationSubjects = map[string]string{
+	NotificationBooked:      "Your booking %s is confirmed",
+	NotificationSeatChanged: "Your seat for booking %s was changed",
+	NotificationCancelled:   "Your booking %s was cancelled",
+	// the subject is logged, so it must not carry the code
+	NotificationVerifyEmail: "Verify your email address",
+}
+
+//go:embed templates/notifications.*.tmpl
+var notificationTemplateFiles embed.FS
+
+var (
+	textNotificationTemplates = texttemplate.Must(texttemplate.ParseFS(notificationTemplateFiles, "templates/notifications.txt.tmpl"))
+	htmlNotificationTemplates = htmltemplate.Must(htmltemplate.ParseFS(notificationTemplateFiles, "templates/notifications.html.tmpl"))
+)
+
+// bookingNotificationData is what the notification templates can refer to
+type bookingNotificationData struct {
+	Name            string
+	Pnr             string
+	From            string
+	To              string
+	Departure       string
+	Seat            int32
+	Section         string
+	Price           int32
+	PreviousSeat    int32
+	PreviousSection string
+}
+
+// renderBookingNotification renders the kind of notification for booking owned
+// by user. previous is the booking before a seat change and may be nil.
+func renderBookingNotification(kind string, booking, previous *pb.BookingDbResponse, user *pb.User) (*Notification, error) {
+	data := bookingNotificationData{
+		Name:    strings.TrimSpace(user.GetFirstname() + " " + user.GetLastname()),
+		Pnr:     booking.GetPnr(),
+		From:    booking.GetFrom(),
+		To:      booking.GetTo(),
+		Seat:    booking.GetSeat(),
+		Section: booking.GetSection(),
+		Price:   booking.GetPrice(),
+	}
+	if booking.GetDepartureAt() != 0 {
+		data.Departure = time.Unix(booking.GetDepartureAt(), 0).UTC().Format("Mon 02 Jan 2006 15:04 MST")
+	}
+	if previous != nil {
+		data.PreviousSeat = previous.GetSeat()
+		data.PreviousSection = previous.GetSection()
+	}
+
+	var text, html strings.Builder
+	if err := textNotificationTemplates.ExecuteTemplate(&text, kind, data); err != nil {
+		return nil, err
+	}
+	if err := htmlNotificationTemplates.ExecuteTemplate(&html, kind, data); err != nil {
+		return nil, err
+	}
+	return &Notification{
+		To:      user.GetEmail(),
+		Subject: fmt.Sprintf(notificationSubjects[kind], booking.GetPnr()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// verificationNotificationData is what the verify_email templates can refer to
+type verificationNotificationData struct {
+	Name string
+	Code string
+}
+
+// renderVerificationNotification renders the email carrying the code that
+// verifies the email of user
+func renderVerificationNotification(user *pb.User, code string) (*Notification, error) {
+	data := verificationNotificationData{
+		Name: strings.TrimSpace(user.GetFirstname() + " " + user.GetLastname()),
+		Code: code,
+	}
+
+	var text, html strings.Builder
+	if err := textNotificationTemplates.ExecuteTemplate(&text, NotificationVerifyEmail, data); err != nil {
+		return nil, err
+	}
+	if err := htmlNotificationTemplates.ExecuteTemplate(&html, NotificationVerifyEmail, data); err != nil {
+		return nil, err
+	}
+	return &Notification{
+		To:      user.GetEmail(),
+		Subject: notificationSubjects[NotificationVerifyEmail],
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
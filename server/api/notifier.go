@@ -0,0 +1,105 @@
+package api
+
+import (
+	"ticket-booking-app/logging"
+	"github.com/google/uuid"
+	"mime/multipart"
+	"net/textproto"
+	"net/smtp"
+	"bytes"
+	"context"
+	"fmt"
+	"log/slog"
+	"mime"
+	"time"
+)
+
+// Notification is a rendered message for one recipient.
+type Notification struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Notifier delivers notifications to passengers.
+type Notifier interface {
+	Send(ctx context.Context, notification *Notification) error
+}
+
+// LogNotifier only logs notifications, for running without a mail server.
+type LogNotifier struct{}
+
+func (LogNotifier) Send(ctx context.Context, notification *Notification) error {
+	slog.InfoContext(ctx, "Notification", logging.Email(notification.To), "subject", notification.Subject)
+	return nil
+}
+
+// SMTPNotifier sends notifications as multipart text and HTML emails. STARTTLS
+// is used when the server offers it; auth may be nil for local servers.
+type SMTPNotifier struct {
+	addr string
+	from string
+	auth smtp.Auth
+}
+
+func NewSMTPNotifier(addr, from string, auth smtp.Auth) *SMTPNotifier {
+	return &SMTPNotifier{
+		addr: addr,
+		from: from,
+		auth: auth,
+	}
+}
+
+func (s *SMTPNotifier) Send(ctx context.Context, notification *Notification) error {
+	message, err := buildEmail(s.from, notification)
+	if err != nil {
+		return err
+	}
+
+	// net/smtp has no context support, so give up waiting for it instead
+	done := make(chan error, 1)
+	go func() {
+		done <- smtp.SendMail(s.addr, s.auth, s.from, []string{notification.To}, message)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func buildEmail(from string, notification *Notification) ([]byte, error) {
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", notification.Text},
+		{"text/html; charset=utf-8", notification.HTML},
+	} {
+		writer, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"8bit"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if _, err := writer.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	var message bytes.Buffer
+	fmt.Fprintf(&message, "From: %s\r\n", from)
+	fmt.Fprintf(&message, "To: %s\r\n", notification.To)
+	fmt.Fprintf(&message, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", notification.Subject))
+	fmt.Fprintf(&message, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	fmt.Fprintf(&message, "Message-ID: <%s@ticket-booking-app>\r\n", uuid.NewString())
+	fmt.Fprintf(&message, "MIME-Version: 1.0\r\n")
+	fmt.Fprintf(&message, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", parts.Boundary())
+	message.Write(body.Bytes())
+	return message.Bytes(), nil
+}
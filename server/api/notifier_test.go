@@ -0,0 +1,157 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"net/textproto"
+	"net/mail"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"sync"
+	"net"
+	"time"
+)
+
+// testSMTPServer accepts mail on a local port and keeps the raw messages
+type testSMTPServer struct {
+    listener net.Listener
+    mu       sync.Mutex
+    messages []string
+    received chan struct{}
+}
+
+func newTestSMTPServer(t *testing.T) *testSMTPServer {
+    listener, err := net.Listen("tcp", "127.0.0.1:0")
+    if err != nil {
+        t.Fatalf("Error in starting SMTP server %v ", err)
+    }
+    server := &testSMTPServer{listener: listener, received: make(chan struct{}, 10)}
+    t.Cleanup(func() { listener.Close() })
+    go func() {
+        for {
+            conn, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            go server.serve(conn)
+        }
+    }()
+    return server
+}
+
+func (s *testSMTPServer) serve(conn net.Conn) {
+    defer conn.Close()
+    session := textproto.NewConn(conn)
+    session.PrintfLine("220 localhost ESMTP")
+    for {
+        line, err := session.ReadLine()
+        if err != nil {
+            return
+        }
+        switch command := strings.ToUpper(strings.SplitN(line, " ", 2)[0]); command {
+        case "EHLO", "HELO", "MAIL", "RCPT", "RSET", "NOOP":
+            session.PrintfLine("250 OK")
+        case "DATA":
+            session.PrintfLine("354 Go ahead")
+            message, err := session.ReadDotBytes()
+            if err != nil {
+                return
+            }
+            s.mu.Lock()
+            s.messages = append(s.messages, string(message))
+            s.mu.Unlock()
+            session.PrintfLine("250 Queued")
+            s.received <- struct{}{}
+        case "QUIT":
+            session.PrintfLine("221 Bye")
+            return
+        default:
+            session.PrintfLine("502 Not implemented")
+        }
+    }
+}
+
+func (s *testSMTPServer) waitForMessages(t *testing.T, count int) []string {
+    t.Helper()
+    for i := 0; i < count; i++ {
+        select {
+        case <-s.received:
+        case <-time.After(5 * time.Second):
+            t.Fatalf("Expected %d emails, got %d", count, i)
+        }
+    }
+    s.mu.Lock()
+    defer s.mu.Unlock()
+    return append([]string(nil), s.messages...)
+}
+
+func TestShouldEmailPassengersAboutTheirBookings(t *testing.T) {
+    smtpServer := newTestSMTPServer(t)
+    queue := api.NewNotificationQueue(api.NewSMTPNotifier(smtpServer.listener.Addr().String(), "bookings@test.com", nil), 10)
+    queue.Start(1)
+    defer queue.Close()
+
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    bookingService.SetNotificationQueue(queue)
+    booking, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    newSeat := (booking.GetSeat() + 1) % api.MaxSeatsPerSection
+    if _, err := bookingService.ModifySeatByUser(context.TODO(), &pb.SeatModificationRequest{BookingId: booking.GetPnr(),
+        Section: booking.GetSection(), Seat: newSeat, User: &pb.User{Email: EMAIL}}); err != nil {
+        t.Fatalf("Error in modifying booking %v ", err)
+    }
+    if _, err := bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: booking.GetPnr(), User: &pb.User{Email: EMAIL}}); err != nil {
+        t.Fatalf("Error in cancelling booking %v ", err)
+    }
+
+    messages := smtpServer.waitForMessages(t, 3)
+    var subjects []string
+    for _, raw := range messages {
+        message, err := mail.ReadMessage(strings.NewReader(raw))
+        if err != nil {
+            t.Fatalf("Error in parsing email %v ", err)
+        }
+        assert.Equal(t, EMAIL, message.Header.Get("To"))
+        assert.Contains(t, message.Header.Get("Content-Type"), "multipart/alternative")
+        subjects = append(subjects, message.Header.Get("Subject"))
+    }
+    assert.Equal(t, []string{"Your booking " + booking.GetPnr() + " is confirmed", "Your seat for booking " + booking.GetPnr() + " was changed",
+        "Your booking " + booking.GetPnr() + " was cancelled"}, subjects)
+    assert.Contains(t, messages[0], "Hello "+FIRST_NAME+" "+LAST_NAME)
+    assert.Contains(t, messages[0], "<strong>"+booking.GetPnr()+"</strong>", "HTML part should be included")
+}
+
+type flakyNotifier struct {
+    mu       sync.Mutex
+    failures int
+    sent     []*api.Notification
+}
+
+func (f *flakyNotifier) Send(ctx context.Context, notification *api.Notification) error {
+    f.mu.Lock()
+    defer f.mu.Unlock()
+    if f.failures > 0 {
+        f.failures--
+        return errors.New("mail server unavailable")
+    }
+    f.sent = append(f.sent, notification)
+    return nil
+}
+
+func TestShouldRetryFailedNotifications(t *testing.T) {
+    notifier := &flakyNotifier{failures: 2}
+    queue := api.NewNotificationQueue(notifier, 10)
+    queue.BaseBackoff = time.Millisecond
+    queue.Start(1)
+
+    assert.True(t, queue.Enqueue(&api.Notification{To: EMAIL, Subject: "retried"}))
+    queue.Close()
+
+    assert.Len(t, notifier.sent, 1, "Notification should be sent after the mail server recovers")
+    assert.False(t, queue.Enqueue(&api.Notification{To: EMAIL}), "Closed queue should not accept notifications")
+}
@@ -0,0 +1,237 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	bookingv2 "ticket-booking-app/domain/booking/v2"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// RateLimitKeyActor limits every authenticated identity on its own; calls
+	// without one aren't limited by it
+	RateLimitKeyActor = "actor"
+	// RateLimitKeyEmail limits the calls about every passenger email
+	RateLimitKeyEmail = "email"
+	// RateLimitKeyIP limits every client address
+	RateLimitKeyIP = "ip"
+	// RateLimitKeyAPIKey limits every client application by its x-api-key
+	RateLimitKeyAPIKey = "api_key"
+
+	// APIKeyHeader identifies the client application
+	APIKeyHeader = "x-api-key"
+
+	// RateLimitAnyMethod is the method of limits that apply to every RPC
+	RateLimitAnyMethod = "*"
+
+	// buckets idle for this long are full again and are forgotten
+	rateLimitSweepInterval = time.Minute
+)
+
+// RateLimit is a token bucket per value of Key for the calls to Method, which
+// is a method name such as CreateBooking, a full name such as
+// booking.BookingService/CreateBooking, or "*" for every RPC. Burst calls are
+// allowed at once, and Rate calls per second after that.
+type RateLimit struct {
+	Method string
+	Key    string
+	Rate   float64
+	Burst  int
+}
+
+func (l *RateLimit) matches(fullMethod string) bool {
+	if l.Method == RateLimitAnyMethod {
+		return true
+	}
+	service, method := splitMethodName(fullMethod)
+	return l.Method == method || l.Method == service+"/"+method
+}
+
+// RateLimiter is safe for concurrent use
+type RateLimiter struct {
+	mu        sync.Mutex
+	limits    []RateLimit
+	buckets   map[rateLimitBucketKey]*tokenBucket
+	lastSweep time.Time
+}
+
+type rateLimitBucketKey struct {
+	limit int
+	value string
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func NewRateLimiter(limits []RateLimit) (*RateLimiter, error) {
+	for _, limit := range limits {
+		switch limit.Key {
+		case RateLimitKeyActor, RateLimitKeyEmail, RateLimitKeyIP, RateLimitKeyAPIKey:
+		default:
+			return nil, fmt.Errorf("unknown rate limit key %q", limit.Key)
+		}
+		if limit.Method == "" || limit.Rate <= 0 || limit.Burst <= 0 {
+			return nil, fmt.Errorf("rate limit of %s by %s needs a method, a positive rate and a positive burst", limit.Method, limit.Key)
+		}
+	}
+	return &RateLimiter{
+		limits:    append([]RateLimit(nil), limits...),
+		buckets:   make(map[rateLimitBucketKey]*tokenBucket),
+		lastSweep: time.Now(),
+	}, nil
+}
+
+// Allow takes a token from every bucket of the call, or from none when one of
+// them is empty. Then the limit that was hit and the time until its bucket has
+// a token again are returned.
+func (l *RateLimiter) Allow(ctx context.Context, fullMethod string, req any) (*RateLimit, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	l.sweep(now)
+
+	var buckets []*tokenBucket
+	var exceeded *RateLimit
+	var retryAfter time.Duration
+	for i := range l.limits {
+		limit := &l.limits[i]
+		if !limit.matches(fullMethod) {
+			continue
+		}
+		value := rateLimitKeyValue(ctx, limit.Key, req)
+		if value == "" {
+			continue
+		}
+		key := rateLimitBucketKey{limit: i, value: value}
+		bucket, ok := l.buckets[key]
+		if !ok {
+			bucket = &tokenBucket{tokens: float64(limit.Burst), updated: now}
+			l.buckets[key] = bucket
+		}
+		bucket.tokens = math.Min(float64(limit.Burst), bucket.tokens+now.Sub(bucket.updated).Seconds()*limit.Rate)
+		bucket.updated = now
+		if bucket.tokens < 1 {
+			if wait := time.Duration((1 - bucket.tokens) / limit.Rate * float64(time.Second)); wait > retryAfter {
+				exceeded, retryAfter = limit, wait
+			}
+			continue
+		}
+		buckets = append(buckets, bucket)
+	}
+	if exceeded != nil {
+		return exceeded, retryAfter
+	}
+	for _, bucket := range buckets {
+		bucket.tokens--
+	}
+	return nil, 0
+}
+
+// sweep forgets the buckets that have refilled since they were last used
+func (l *RateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < rateLimitSweepInterval {
+		return
+	}
+	l.lastSweep = now
+	for key, bucket := range l.buckets {
+		limit := l.limits[key.limit]
+		if bucket.tokens+now.Sub(bucket.updated).Seconds()*limit.Rate >= float64(limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+func rateLimitKeyValue(ctx context.Context, key string, req any) string {
+	switch key {
+	case RateLimitKeyActor:
+		if actor := ActorFromContext(ctx); actor != anonymousActor && !strings.HasPrefix(actor, "unverified:") {
+			return actor
+		}
+	case RateLimitKeyEmail:
+		if withUser, ok := req.(interface{ GetUser() *pb.User }); ok {
+			return normalizeEmail(withUser.GetUser().GetEmail())
+		}
+		if withBooking, ok := req.(interface{ GetBooking() *bookingv2.Booking }); ok {
+			return normalizeEmail(withBooking.GetBooking().GetPassenger().GetEmail())
+		}
+	case RateLimitKeyIP:
+		return clientHost(ctx)
+	case RateLimitKeyAPIKey:
+		md, _ := metadata.FromIncomingContext(ctx)
+		if values := md.Get(APIKeyHeader); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return ""
+}
+
+// NewRateLimitInterceptor rejects calls beyond the limits of limiter with
+// ResourceExhausted, with the time after which they may be retried.
+func NewRateLimitInterceptor(limiter *RateLimiter) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if limit, retryAfter := limiter.Allow(ctx, info.FullMethod, req); limit != nil {
+			return nil, rateLimitExceeded(limit, retryAfter)
+		}
+		return handler(ctx, req)
+	}
+}
+
+// NewRateLimitStreamInterceptor is NewRateLimitInterceptor for streaming RPCs,
+// whose requests aren't known yet, so limits by email don't apply.
+func NewRateLimitStreamInterceptor(limiter *RateLimiter) grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if limit, retryAfter := limiter.Allow(stream.Context(), info.FullMethod, nil); limit != nil {
+			return rateLimitExceeded(limit, retryAfter)
+		}
+		return handler(srv, stream)
+	}
+}
+
+func rateLimitExceeded(limit *RateLimit, retryAfter time.Duration) error {
+	retryAfter = retryAfter.Truncate(time.Millisecond) + time.Millisecond
+	return quotaExceeded(
+		fmt.Sprintf("Rate limit exceeded, retry after %v", retryAfter),
+		&errdetails.QuotaFailure_Violation{
+			Subject:     "rate_limit:" + limit.Key,
+			Description: fmt.Sprintf("%s allows %d calls at once and %g per second per %s", limit.Method, limit.Burst, limit.Rate, limit.Key),
+		},
+		retryAfter)
+}
+
+// quotaExceeded is a ResourceExhausted error detailing the violated quota, and
+// when the call may be retried if retryAfter is set.
+func quotaExceeded(message string, violation *errdetails.QuotaFailure_Violation, retryAfter time.Duration) error {
+	st := status.New(codes.ResourceExhausted, message)
+	details := []protoadapt.MessageV1{&errdetails.QuotaFailure{Violations: []*errdetails.QuotaFailure_Violation{violation}}}
+	if retryAfter > 0 {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	}
+	detailed, err := st.WithDetails(details...)
+	if err != nil {
+		return st.Err()
+	}
+	return detailed.Err()
+}
+
+// retryDelay returns the retry delay of a ResourceExhausted error
+func retryDelay(err error) (time.Duration, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		if retryInfo, ok := detail.(*errdetails.RetryInfo); ok {
+			return retryInfo.GetRetryDelay().AsDuration(), true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,94 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"github.com/stretchr/testify/assert"
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+func callWithRateLimit(interceptor grpc.UnaryServerInterceptor, ip, method, email string) error {
+    ctx := peer.NewContext(context.TODO(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(ip), Port: 4000}})
+    req := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, email)
+    _, err := interceptor(ctx, req, &grpc.UnaryServerInfo{FullMethod: "/booking.BookingService/" + method},
+        func(ctx context.Context, req any) (any, error) { return nil, nil })
+    return err
+}
+
+func TestShouldRateLimitCallsPerKeyWithRetryAfter(t *testing.T) {
+    limiter, err := api.NewRateLimiter([]api.RateLimit{
+        {Method: "CreateBooking", Key: api.RateLimitKeyIP, Rate: 0.001, Burst: 3},
+        {Method: "booking.BookingService/CreateBooking", Key: api.RateLimitKeyEmail, Rate: 0.001, Burst: 1},
+    })
+    if err != nil {
+        t.Fatalf("Error in creating rate limiter %v ", err)
+    }
+    interceptor := api.NewRateLimitInterceptor(limiter)
+
+    assert.NoError(t, callWithRateLimit(interceptor, "10.0.0.1", "CreateBooking", "first@test.com"))
+    err = callWithRateLimit(interceptor, "10.0.0.1", "CreateBooking", "First@test.com")
+    assert.Equal(t, codes.ResourceExhausted, status.Code(err), "Emails should be limited however they are written")
+
+    var retryInfo *errdetails.RetryInfo
+    var quotaFailure *errdetails.QuotaFailure
+    for _, detail := range status.Convert(err).Details() {
+        switch detail := detail.(type) {
+        case *errdetails.RetryInfo:
+            retryInfo = detail
+        case *errdetails.QuotaFailure:
+            quotaFailure = detail
+        }
+    }
+    if assert.NotNil(t, retryInfo) && assert.NotNil(t, quotaFailure) {
+        assert.InDelta(t, 1000, retryInfo.GetRetryDelay().AsDuration().Seconds(), 1)
+        assert.Equal(t, "rate_limit:email", quotaFailure.GetViolations()[0].GetSubject())
+    }
+
+    // the rejected call took no token of the address, which has two left
+    assert.NoError(t, callWithRateLimit(interceptor, "10.0.0.1", "CreateBooking", "second@test.com"))
+    assert.NoError(t, callWithRateLimit(interceptor, "10.0.0.1", "CreateBooking", "third@test.com"))
+    err = callWithRateLimit(interceptor, "10.0.0.1", "CreateBooking", "fourth@test.com")
+    assert.Equal(t, codes.ResourceExhausted, status.Code(err), "New emails shouldn't get around the limit of the address")
+
+    assert.NoError(t, callWithRateLimit(interceptor, "10.0.0.2", "CreateBooking", "fifth@test.com"), "Other addresses have their own bucket")
+    assert.NoError(t, callWithRateLimit(interceptor, "10.0.0.1", "GetBookingByUser", "first@test.com"), "Other RPCs shouldn't be limited")
+
+    _, err = api.NewRateLimiter([]api.RateLimit{{Method: "*", Key: "cookie", Rate: 1, Burst: 1}})
+    assert.Error(t, err)
+}
+
+func TestShouldLimitActiveBookingsPerJourney(t *testing.T) {
+    bookingService := api.NewBookingService(newTestDatabase(t))
+    bookingService.SetMaxActiveBookingsPerJourney(2)
+
+    bookOn := func(day int) (*pb.BookingResponse, error) {
+        request := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+        request.Departure = timestamppb.New(time.Date(2030, 1, day, 9, 0, 0, 0, time.UTC))
+        return bookingService.CreateBooking(context.TODO(), request)
+    }
+    first, err := bookOn(1)
+    assert.NoError(t, err)
+    _, err = bookOn(2)
+    assert.NoError(t, err)
+    _, err = bookOn(3)
+    assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+    other := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+    other.To = "Belgium"
+    _, err = bookingService.CreateBooking(context.TODO(), other)
+    assert.NoError(t, err, "Other journeys should have their own limit")
+
+    _, err = bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: first.GetId(), User: &pb.User{Email: EMAIL}})
+    assert.NoError(t, err)
+    _, err = bookOn(3)
+    assert.NoError(t, err, "Cancelled bookings shouldn't count")
+}
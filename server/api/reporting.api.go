@@ -0,0 +1,281 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+// ReportingService aggregates the bookings into occupancy, revenue,
+// cancellation and lead time reports. Only services may read them.
+type ReportingService struct {
+	db *sql.DB
+	// bookingService knows the seats of every section
+	bookingService *BookingService
+}
+
+func NewReportingService(dbInstance *sql.DB, bookingService *BookingService) *ReportingService {
+	return &ReportingService{
+		db: dbInstance,
+		bookingService: bookingService,
+	}
+}
+
+// GetOccupancyReport returns the booked seats of every section of the
+// journeys departing in the requested time.
+func (r *ReportingService) GetOccupancyReport(ctx context.Context, req *pb.ReportRequest) (*pb.OccupancyReport, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	where, args, err := reportFilter(req, "t_departure_at")
+	if err != nil {
+		return nil, err
+	}
+	where = append(where, "t_status = ?")
+	args = append(args, TicketStatusBooked)
+
+	type journey struct {
+		from, to  string
+		departure sql.NullInt64
+		booked    map[string]int64
+		sections  []string
+	}
+	var journeys []*journey
+	err = r.query(ctx, "SELECT COALESCE(t_from, ''), COALESCE(t_to, ''), t_departure_at, COALESCE(t_section, ''), COUNT(*) FROM tickets WHERE "+
+		strings.Join(where, " AND ")+" GROUP BY 1, 2, 3, 4 ORDER BY 3, 1, 2, 4", args, func(rows *sql.Rows) error {
+		var from, to, section string
+		var departure sql.NullInt64
+		var booked int64
+		if err := rows.Scan(&from, &to, &departure, &section, &booked); err != nil {
+			return err
+		}
+		if len(journeys) == 0 || journeys[len(journeys)-1].from != from || journeys[len(journeys)-1].to != to ||
+			journeys[len(journeys)-1].departure != departure {
+			journeys = append(journeys, &journey{from: from, to: to, departure: departure, booked: map[string]int64{}})
+		}
+		current := journeys[len(journeys)-1]
+		current.booked[section] = booked
+		current.sections = append(current.sections, section)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// every section of the layout is reported, sections that were removed from
+	// it only while they still have bookings
+	layout := map[string]int32{}
+	var sections []string
+	for _, section := range r.bookingService.seatAllocator.Sections() {
+		if req.GetSection() == "" || req.GetSection() == section.Name {
+			layout[section.Name] = section.Seats
+			sections = append(sections, section.Name)
+		}
+	}
+	report := &pb.OccupancyReport{}
+	for _, journey := range journeys {
+		journeySections := append([]string{}, sections...)
+		for _, section := range journey.sections {
+			if _, ok := layout[section]; !ok {
+				journeySections = append(journeySections, section)
+			}
+		}
+		for _, section := range journeySections {
+			row := &pb.OccupancyRow{
+				From:    journey.from,
+				To:      journey.to,
+				Section: section,
+				Seats:   layout[section],
+				Booked:  journey.booked[section],
+			}
+			if journey.departure.Valid {
+				row.Departure = timestamppb.New(time.Unix(journey.departure.Int64, 0))
+			}
+			if row.Seats > 0 {
+				row.LoadFactor = roundReportValue(float64(row.Booked) / float64(row.Seats))
+			}
+			report.Rows = append(report.Rows, row)
+		}
+	}
+	return report, nil
+}
+
+// GetRevenueReport returns the revenue of the tickets still booked per period
+// they were booked in, route and section.
+func (r *ReportingService) GetRevenueReport(ctx context.Context, req *pb.ReportRequest) (*pb.RevenueReport, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	where, args, err := reportFilter(req, "t_created_at")
+	if err != nil {
+		return nil, err
+	}
+	period, err := reportPeriod(req.GetInterval(), "t_created_at")
+	if err != nil {
+		return nil, err
+	}
+	where = append(where, "t_status = ?")
+	args = append(args, TicketStatusBooked)
+
+	report := &pb.RevenueReport{}
+	err = r.query(ctx, "SELECT "+period+", COALESCE(t_from, ''), COALESCE(t_to, ''), COALESCE(t_section, ''), COUNT(*), COALESCE(SUM(t_price), 0) FROM tickets WHERE "+
+		strings.Join(where, " AND ")+" GROUP BY 1, 2, 3, 4 ORDER BY 1, 2, 3, 4", args, func(rows *sql.Rows) error {
+		row := &pb.RevenueRow{}
+		if err := rows.Scan(&row.Period, &row.From, &row.To, &row.Section, &row.Tickets, &row.Revenue); err != nil {
+			return err
+		}
+		report.Rows = append(report.Rows, row)
+		report.TotalRevenue += row.Revenue
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetCancellationReport returns the share of the bookings made per period and
+// route that were cancelled since.
+func (r *ReportingService) GetCancellationReport(ctx context.Context, req *pb.ReportRequest) (*pb.CancellationReport, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	where, args, err := reportFilter(req, "t_created_at")
+	if err != nil {
+		return nil, err
+	}
+	period, err := reportPeriod(req.GetInterval(), "t_created_at")
+	if err != nil {
+		return nil, err
+	}
+	args = append([]any{TicketStatusCancelled}, args...)
+
+	report := &pb.CancellationReport{}
+	err = r.query(ctx, "SELECT "+period+", COALESCE(t_from, ''), COALESCE(t_to, ''), COUNT(*), SUM(t_status = ?) FROM tickets WHERE "+
+		strings.Join(where, " AND ")+" GROUP BY 1, 2, 3 ORDER BY 1, 2, 3", args, func(rows *sql.Rows) error {
+		row := &pb.CancellationRow{}
+		if err := rows.Scan(&row.Period, &row.From, &row.To, &row.Bookings, &row.Cancelled); err != nil {
+			return err
+		}
+		row.CancellationRate = roundReportValue(float64(row.Cancelled) / float64(row.Bookings))
+		report.Rows = append(report.Rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// GetLeadTimeReport returns how long before departure the bookings made per
+// period and route were made on average. Bookings made after their departure,
+// like imported history, are left out.
+func (r *ReportingService) GetLeadTimeReport(ctx context.Context, req *pb.ReportRequest) (*pb.LeadTimeReport, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	where, args, err := reportFilter(req, "t_created_at")
+	if err != nil {
+		return nil, err
+	}
+	period, err := reportPeriod(req.GetInterval(), "t_created_at")
+	if err != nil {
+		return nil, err
+	}
+	where = append(where, "t_departure_at >= t_created_at")
+
+	report := &pb.LeadTimeReport{}
+	err = r.query(ctx, "SELECT "+period+", COALESCE(t_from, ''), COALESCE(t_to, ''), COUNT(*), AVG(t_departure_at - t_created_at) FROM tickets WHERE "+
+		strings.Join(where, " AND ")+" GROUP BY 1, 2, 3 ORDER BY 1, 2, 3", args, func(rows *sql.Rows) error {
+		row := &pb.LeadTimeRow{}
+		var leadTimeSeconds float64
+		if err := rows.Scan(&row.Period, &row.From, &row.To, &row.Bookings, &leadTimeSeconds); err != nil {
+			return err
+		}
+		row.AverageLeadTimeHours = roundReportValue(leadTimeSeconds / time.Hour.Seconds())
+		report.Rows = append(report.Rows, row)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// query calls scan with every row of the result
+func (r *ReportingService) query(ctx context.Context, query string, args []any, scan func(*sql.Rows) error) error {
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return status.Errorf(codes.Internal, "Error while building report: %v", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return status.Errorf(codes.Internal, "Error while building report: %v", err)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return status.Errorf(codes.Internal, "Error while building report: %v", err)
+	}
+	return nil
+}
+
+// reportFilter returns the conditions selecting the tickets of req, with since
+// and until applied to timeColumn.
+func reportFilter(req *pb.ReportRequest, timeColumn string) ([]string, []any, error) {
+	if req.GetSince() != nil && req.GetUntil() != nil && !req.GetSince().AsTime().Before(req.GetUntil().AsTime()) {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "Since must be before until")
+	}
+	where := []string{"1 = 1"}
+	var args []any
+	if req.GetSince() != nil {
+		where = append(where, timeColumn+" >= ?")
+		args = append(args, req.GetSince().GetSeconds())
+	}
+	if req.GetUntil() != nil {
+		where = append(where, timeColumn+" < ?")
+		args = append(args, req.GetUntil().GetSeconds())
+	}
+	if req.GetFrom() != "" {
+		where = append(where, "t_from = ?")
+		args = append(args, req.GetFrom())
+	}
+	if req.GetTo() != "" {
+		where = append(where, "t_to = ?")
+		args = append(args, req.GetTo())
+	}
+	if req.GetSection() != "" {
+		where = append(where, "t_section = ?")
+		args = append(args, req.GetSection())
+	}
+	return where, args, nil
+}
+
+// reportPeriod returns the SQL expression of the first day of the period of
+// the unix time in column, empty when the time isn't known.
+func reportPeriod(interval pb.ReportInterval, column string) (string, error) {
+	var modifiers string
+	switch interval {
+	case pb.ReportInterval_REPORT_INTERVAL_UNSPECIFIED, pb.ReportInterval_REPORT_INTERVAL_DAY:
+	case pb.ReportInterval_REPORT_INTERVAL_WEEK:
+		// the next Sunday, unless it is one, and back to the Monday before it
+		modifiers = ", 'weekday 0', '-6 days'"
+	case pb.ReportInterval_REPORT_INTERVAL_MONTH:
+		modifiers = ", 'start of month'"
+	default:
+		return "", status.Errorf(codes.InvalidArgument, "Unknown report interval %v", interval)
+	}
+	return fmt.Sprintf("COALESCE(date(%s, 'unixepoch'%s), '')", column, modifiers), nil
+}
+
+// roundReportValue keeps four decimals, which is plenty for rates and hours
+func roundReportValue(value float64) float64 {
+	return math.Round(value*10000) / 10000
+}
@@ -0,0 +1,144 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+)
+
+var reportDeparture = time.Date(2026, 11, 2, 9, 30, 0, 0, time.UTC)
+
+// bookForReport books a seat to Paris and backdates the booking to bookedAt
+func bookForReport(t *testing.T, db *sql.DB, bookingService *api.BookingService, email string, departure, bookedAt time.Time) *pb.BookingResponse {
+    t.Helper()
+    request := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, email)
+    request.To = "Paris"
+    request.Departure = timestamppb.New(departure)
+    booking, err := bookingService.CreateBooking(context.TODO(), request)
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    if _, err := db.Exec("UPDATE tickets SET t_created_at = ? WHERE t_id = ?", bookedAt.Unix(), booking.GetId()); err != nil {
+        t.Fatalf("Error in backdating booking %v ", err)
+    }
+    return booking
+}
+
+func TestShouldReportOccupancyPerSectionOfEveryJourney(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    allocator, _ := api.NewSeatAllocatorWithLayout([]api.Section{{Name: "A", Seats: 4}, {Name: "B", Seats: 2}}, api.AllocationSequential)
+    bookingService.SetSeatAllocator(allocator)
+    reportingService := api.NewReportingService(db, bookingService)
+    backOffice := api.WithActor(context.TODO(), "service:back-office")
+
+    bookedAt := reportDeparture.AddDate(0, 0, -7)
+    first := bookForReport(t, db, bookingService, "first@test.com", reportDeparture, bookedAt)
+    bookForReport(t, db, bookingService, "second@test.com", reportDeparture, bookedAt)
+    bookForReport(t, db, bookingService, "third@test.com", reportDeparture, bookedAt)
+    bookForReport(t, db, bookingService, "fourth@test.com", reportDeparture.AddDate(0, 0, 1), bookedAt)
+    if _, err := bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: first.GetPnr(), User: first.GetUser()}); err != nil {
+        t.Fatalf("Error in removing booking %v ", err)
+    }
+
+    report, err := reportingService.GetOccupancyReport(backOffice, &pb.ReportRequest{})
+    if err != nil {
+        t.Fatalf("Error in getting occupancy report %v ", err)
+    }
+    if assert.Len(t, report.GetRows(), 4, "Every section of both journeys should be reported") {
+        assert.Equal(t, "A", report.GetRows()[0].GetSection())
+        assert.EqualValues(t, 2, report.GetRows()[0].GetBooked(), "Cancelled bookings don't occupy seats")
+        assert.EqualValues(t, 4, report.GetRows()[0].GetSeats())
+        assert.Equal(t, 0.5, report.GetRows()[0].GetLoadFactor())
+        assert.Equal(t, reportDeparture, report.GetRows()[0].GetDeparture().AsTime())
+        assert.Equal(t, "B", report.GetRows()[1].GetSection())
+        assert.EqualValues(t, 0, report.GetRows()[1].GetBooked())
+        assert.Equal(t, 0.25, report.GetRows()[2].GetLoadFactor())
+    }
+
+    report, err = reportingService.GetOccupancyReport(backOffice, &pb.ReportRequest{
+        Since: timestamppb.New(reportDeparture.AddDate(0, 0, 1)), Section: "A"})
+    if err != nil {
+        t.Fatalf("Error in getting occupancy report %v ", err)
+    }
+    if assert.Len(t, report.GetRows(), 1) {
+        assert.EqualValues(t, 1, report.GetRows()[0].GetBooked())
+    }
+
+    _, err = reportingService.GetOccupancyReport(backOffice, &pb.ReportRequest{
+        Since: timestamppb.New(reportDeparture), Until: timestamppb.New(reportDeparture)})
+    assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestShouldReportRevenueCancellationsAndLeadTimesPerPeriod(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    reportingService := api.NewReportingService(db, bookingService)
+    backOffice := api.WithActor(context.TODO(), "service:back-office")
+
+    // Tuesday and Thursday of one week, and the Monday after
+    tuesday := time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)
+    bookForReport(t, db, bookingService, "first@test.com", tuesday.Add(48*time.Hour), tuesday)
+    cancelled := bookForReport(t, db, bookingService, "second@test.com", tuesday.Add(24*time.Hour), tuesday.AddDate(0, 0, 2))
+    bookForReport(t, db, bookingService, "third@test.com", reportDeparture, tuesday.AddDate(0, 0, 6))
+    if _, err := bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: cancelled.GetPnr(), User: cancelled.GetUser()}); err != nil {
+        t.Fatalf("Error in removing booking %v ", err)
+    }
+    weekly := &pb.ReportRequest{Interval: pb.ReportInterval_REPORT_INTERVAL_WEEK}
+
+    revenue, err := reportingService.GetRevenueReport(backOffice, weekly)
+    if err != nil {
+        t.Fatalf("Error in getting revenue report %v ", err)
+    }
+    if assert.Len(t, revenue.GetRows(), 2) {
+        assert.Equal(t, "2026-10-19", revenue.GetRows()[0].GetPeriod(), "Weeks should start on Monday")
+        assert.Equal(t, "Paris", revenue.GetRows()[0].GetTo())
+        assert.EqualValues(t, 1, revenue.GetRows()[0].GetTickets(), "Cancelled tickets earn no revenue")
+        assert.EqualValues(t, 20, revenue.GetRows()[0].GetRevenue())
+        assert.Equal(t, "2026-10-26", revenue.GetRows()[1].GetPeriod())
+    }
+    assert.EqualValues(t, 40, revenue.GetTotalRevenue())
+
+    cancellations, err := reportingService.GetCancellationReport(backOffice, weekly)
+    if err != nil {
+        t.Fatalf("Error in getting cancellation report %v ", err)
+    }
+    if assert.Len(t, cancellations.GetRows(), 2) {
+        assert.EqualValues(t, 2, cancellations.GetRows()[0].GetBookings())
+        assert.EqualValues(t, 1, cancellations.GetRows()[0].GetCancelled())
+        assert.Equal(t, 0.5, cancellations.GetRows()[0].GetCancellationRate())
+        assert.Equal(t, 0.0, cancellations.GetRows()[1].GetCancellationRate())
+    }
+
+    leadTimes, err := reportingService.GetLeadTimeReport(backOffice, &pb.ReportRequest{Until: timestamppb.New(tuesday.AddDate(0, 0, 6))})
+    if err != nil {
+        t.Fatalf("Error in getting lead time report %v ", err)
+    }
+    if assert.Len(t, leadTimes.GetRows(), 1, "The booking made after its departure should be left out") {
+        assert.Equal(t, "2026-10-20", leadTimes.GetRows()[0].GetPeriod())
+        assert.EqualValues(t, 1, leadTimes.GetRows()[0].GetBookings())
+        assert.Equal(t, 48.0, leadTimes.GetRows()[0].GetAverageLeadTimeHours())
+    }
+}
+
+func TestShouldOnlyReportToServices(t *testing.T) {
+    db := newTestDatabase(t)
+    reportingService := api.NewReportingService(db, api.NewBookingService(db))
+    for _, ctx := range []context.Context{context.TODO(), api.WithActor(context.TODO(), "user:someone")} {
+        _, err := reportingService.GetOccupancyReport(ctx, &pb.ReportRequest{})
+        assert.Equal(t, codes.PermissionDenied, status.Code(err))
+        _, err = reportingService.GetRevenueReport(ctx, &pb.ReportRequest{})
+        assert.Equal(t, codes.PermissionDenied, status.Code(err))
+        _, err = reportingService.GetCancellationReport(ctx, &pb.ReportRequest{})
+        assert.Equal(t, codes.PermissionDenied, status.Code(err))
+        _, err = reportingService.GetLeadTimeReport(ctx, &pb.ReportRequest{})
+        assert.Equal(t, codes.PermissionDenied, status.Code(err))
+    }
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// writeReportCSV writes the rows of a report as CSV, with the field names of
+// the rows as header. Departures are RFC 3339 and empty when unset.
+func writeReportCSV(w io.Writer, report proto.Message) error {
+	rowsField := report.ProtoReflect().Descriptor().Fields().ByName("rows")
+	if rowsField == nil || !rowsField.IsList() || rowsField.Message() == nil {
+		return fmt.Errorf("%s has no rows", report.ProtoReflect().Descriptor().FullName())
+	}
+	columns := rowsField.Message().Fields()
+
+	writer := csv.NewWriter(w)
+	header := make([]string, columns.Len())
+	for i := range header {
+		header[i] = string(columns.Get(i).Name())
+	}
+	writer.Write(header)
+
+	rows := report.ProtoReflect().Get(rowsField).List()
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Get(i).Message()
+		record := make([]string, columns.Len())
+		for j := range record {
+			record[j] = reportCSVValue(row, columns.Get(j))
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func reportCSVValue(row protoreflect.Message, field protoreflect.FieldDescriptor) string {
+	value := row.Get(field)
+	switch field.Kind() {
+	case protoreflect.MessageKind:
+		if !row.Has(field) || field.Message().FullName() != "google.protobuf.Timestamp" {
+			return ""
+		}
+		fields := field.Message().Fields()
+		seconds := value.Message().Get(fields.ByName("seconds")).Int()
+		return time.Unix(seconds, 0).UTC().Format(time.RFC3339)
+	case protoreflect.DoubleKind, protoreflect.FloatKind:
+		return strconv.FormatFloat(value.Float(), 'f', -1, 64)
+	case protoreflect.EnumKind:
+		if enumValue := field.Enum().Values().ByNumber(value.Enum()); enumValue != nil {
+			return string(enumValue.Name())
+		}
+		return strconv.Itoa(int(value.Enum()))
+	default:
+		return value.String()
+	}
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"ticket-booking-app/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"github.com/google/uuid"
+	"context"
+)
+
+// RequestIdHeader carries the correlation id of a request, in both directions
+const RequestIdHeader = "x-request-id"
+
+const maxRequestIdLength = 128
+
+// NewRequestIdInterceptor takes the correlation id of every request from its
+// x-request-id metadata, or generates one, and returns it in the response
+// header. The id is logged with every record logged with the request context
+// and recorded on the span of the RPC.
+func NewRequestIdInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, requestId := requestIdContext(ctx)
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIdHeader, requestId))
+		return handler(ctx, req)
+	}
+}
+
+// NewRequestIdStreamInterceptor is NewRequestIdInterceptor for streaming RPCs.
+func NewRequestIdStreamInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, requestId := requestIdContext(stream.Context())
+		stream.SetHeader(metadata.Pairs(RequestIdHeader, requestId))
+		return handler(srv, &contextServerStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+func requestIdContext(ctx context.Context) (context.Context, string) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var requestId string
+	if values := md.Get(RequestIdHeader); len(values) > 0 && isValidRequestId(values[0]) {
+		requestId = values[0]
+	} else {
+		requestId = uuid.NewString()
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("request.id", requestId))
+	return logging.WithRequestId(ctx, requestId), requestId
+}
+
+// isValidRequestId keeps ids that could forge log lines or bloat them out of the logs
+func isValidRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > maxRequestIdLength {
+		return false
+	}
+	for _, c := range requestId {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-' || c == '_' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
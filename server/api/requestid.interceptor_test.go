@@ -0,0 +1,61 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/test/bufconn"
+	"context"
+	"net"
+	"testing"
+)
+
+func TestShouldReturnTheRequestIdInResponseHeaders(t *testing.T) {
+    listener := bufconn.Listen(1 << 20)
+    server := grpc.NewServer(grpc.ChainUnaryInterceptor(api.NewRequestIdInterceptor()))
+    pb.RegisterBookingServiceServer(server, api.NewBookingService(newTestDatabase(t)))
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    conn, err := grpc.NewClient("passthrough:///bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }))
+    if err != nil {
+        t.Fatalf("Error in connecting to server %v ", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+    client := pb.NewBookingServiceClient(conn)
+
+    requestIdOf := func(ctx context.Context) string {
+        var header metadata.MD
+        if _, err := client.GetBookingsBySection(ctx, &pb.GetBookingsBySectionRequest{Section: "A"}, grpc.Header(&header)); err != nil {
+            t.Fatalf("Error in listing bookings %v ", err)
+        }
+        values := header.Get(api.RequestIdHeader)
+        if len(values) != 1 {
+            t.Fatalf("Expected one request id, got %v ", values)
+        }
+        return values[0]
+    }
+
+    given := metadata.AppendToOutgoingContext(context.TODO(), api.RequestIdHeader, "checkout-42")
+    assert.Equal(t, "checkout-42", requestIdOf(given), "The caller's request id should be kept")
+
+    generated := requestIdOf(context.TODO())
+    assert.Len(t, generated, 36, "A request id should be generated when there is none")
+    assert.NotEqual(t, generated, requestIdOf(context.TODO()))
+
+    forged := metadata.AppendToOutgoingContext(context.TODO(), api.RequestIdHeader, `x" level=ERROR msg="forged`)
+    assert.Len(t, requestIdOf(forged), 36, "Request ids that could forge log lines should be replaced")
+}
+
+func TestShouldEchoTheRequestIdOverREST(t *testing.T) {
+    gateway := newTestRESTGateway(t)
+    resp, _ := restCall(t, "GET", gateway.URL+"/openapi.json", "", map[string]string{"X-Request-Id": "checkout-42"})
+    assert.Equal(t, "checkout-42", resp.Header.Get("X-Request-Id"))
+
+    resp, _ = restCall(t, "GET", gateway.URL+"/openapi.json", "", nil)
+    assert.Len(t, resp.Header.Get("X-Request-Id"), 36)
+}
@@ -0,0 +1,417 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"github.com/google/uuid"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// forwardedHeaders are passed from HTTP requests to gRPC metadata
+var forwardedHeaders = map[string]string{
+	"Idempotency-Key": IdempotencyKeyHeader,
+	"X-Actor":         ActorHeader,
+	"Authorization":   "authorization",
+	"X-Request-Id":    RequestIdHeader,
+	"X-Api-Key":       APIKeyHeader,
+}
+
+// restRoute maps an HTTP route onto an RPC of service, BookingService when
+// empty. Path wildcards are copied into the fields named by pathFields, the
+// query parameters listed in query into the field of the same name and the
+// body, when there is one, into the request. Other query parameters are
+// rejected. Routes with a download filename answer requests that
+// accept text/csv with the rows of the response as CSV.
+type restRoute struct {
+	pattern    string
+	service    string
+	rpc        string
+	summary    string
+	pathFields map[string]string
+	query      []string
+	body       bool
+	created    bool
+	download   string
+	handler    unaryRestHandler
+}
+
+type unaryRestHandler struct {
+	newRequest func() proto.Message
+	response   protoreflect.MessageDescriptor
+	call       func(ctx context.Context, req proto.Message, opts ...grpc.CallOption) (proto.Message, error)
+}
+
+func unaryRest[Req, Resp proto.Message](newRequest func() Req, rpc func(context.Context, Req, ...grpc.CallOption) (Resp, error)) unaryRestHandler {
+	var response Resp
+	return unaryRestHandler{
+		newRequest: func() proto.Message { return newRequest() },
+		response:   response.ProtoReflect().Descriptor(),
+		call: func(ctx context.Context, req proto.Message, opts ...grpc.CallOption) (proto.Message, error) {
+			return rpc(ctx, req.(Req), opts...)
+		},
+	}
+}
+
+// RESTGateway serves BookingService and the reports of ReportingService as
+// HTTP/JSON. Calls go through conn, so they pass the same interceptors as gRPC
+// clients.
+type RESTGateway struct {
+	client pb.BookingServiceClient
+	routes []*restRoute
+	mux    *http.ServeMux
+}
+
+func NewRESTGateway(conn grpc.ClientConnInterface) *RESTGateway {
+	client := pb.NewBookingServiceClient(conn)
+	reporting := pb.NewReportingServiceClient(conn)
+	reportQuery := []string{"since", "until", "from", "to", "section", "interval"}
+	gateway := &RESTGateway{client: client, mux: http.NewServeMux()}
+	gateway.routes = []*restRoute{
+		{pattern: "POST /v1/bookings", rpc: "CreateBooking", summary: "Book a seat", body: true, created: true,
+			handler: unaryRest(func() *pb.BookingRequest { return &pb.BookingRequest{} }, client.CreateBooking)},
+		{pattern: "GET /v1/bookings/{booking_id}", rpc: "GetBooking", summary: "Get a booking by ticket id or PNR",
+			pathFields: map[string]string{"booking_id": "booking_id"},
+			handler:    unaryRest(func() *pb.GetBookingRequest { return &pb.GetBookingRequest{} }, client.GetBooking)},
+		{pattern: "PATCH /v1/bookings/{booking_id}/seat", rpc: "ModifySeatByUser", summary: "Move a booking to another seat", body: true,
+			pathFields: map[string]string{"booking_id": "booking_id"},
+			handler:    unaryRest(func() *pb.SeatModificationRequest { return &pb.SeatModificationRequest{} }, client.ModifySeatByUser)},
+		{pattern: "DELETE /v1/bookings/{booking_id}", rpc: "RemoveBookingByUser", summary: "Cancel a booking",
+			pathFields: map[string]string{"booking_id": "booking_id"}, query: []string{"user.email", "etag"},
+			handler:    unaryRest(func() *pb.RemoveBookingByUserRequest { return &pb.RemoveBookingByUserRequest{} }, client.RemoveBookingByUser)},
+		{pattern: "GET /v1/bookings/{booking_id}/eticket", rpc: "GetETicket", summary: "Get the e-ticket, as PDF when requested with Accept: application/pdf",
+			pathFields: map[string]string{"booking_id": "booking_id"}, query: []string{"user.email"},
+			handler:    unaryRest(func() *pb.GetETicketRequest { return &pb.GetETicketRequest{} }, client.GetETicket)},
+		{pattern: "GET /v1/sections/{section}/bookings", rpc: "GetBookingsBySection", summary: "List the bookings of a section",
+			pathFields: map[string]string{"section": "section"},
+			handler:    unaryRest(func() *pb.GetBookingsBySectionRequest { return &pb.GetBookingsBySectionRequest{} }, client.GetBookingsBySection)},
+		{pattern: "GET /v1/seatmap", rpc: "GetSeatMap", summary: "Get the seats of every section, or of one", query: []string{"section"},
+			handler: unaryRest(func() *pb.GetSeatMapRequest { return &pb.GetSeatMapRequest{} }, client.GetSeatMap)},
+		{pattern: "POST /v1/seatmap/holds", rpc: "HoldSeat", summary: "Keep a free seat for a booking", body: true, created: true,
+			handler: unaryRest(func() *pb.HoldSeatRequest { return &pb.HoldSeatRequest{} }, client.HoldSeat)},
+		{pattern: "DELETE /v1/seatmap/holds/{hold_id}", rpc: "ReleaseSeatHold", summary: "Release a held seat",
+			pathFields: map[string]string{"hold_id": "hold_id"},
+			handler:    unaryRest(func() *pb.ReleaseSeatHoldRequest { return &pb.ReleaseSeatHoldRequest{} }, client.ReleaseSeatHold)},
+		{pattern: "GET /v1/users/{email}/bookings", rpc: "ListMyBookings", summary: "List the active bookings of a user",
+			pathFields: map[string]string{"email": "user.email"},
+			handler:    unaryRest(func() *pb.ListMyBookingsRequest { return &pb.ListMyBookingsRequest{} }, client.ListMyBookings)},
+		{pattern: "GET /v1/users/{email}/booking", rpc: "GetBookingByUser", summary: "Get the only booking of a user",
+			pathFields: map[string]string{"email": "user.email"},
+			handler:    unaryRest(func() *pb.GetBookingByUserRequest { return &pb.GetBookingByUserRequest{} }, client.GetBookingByUser)},
+		{pattern: "GET /v1/reports/occupancy", service: "ReportingService", rpc: "GetOccupancyReport", summary: "Get the load factor of every section per journey",
+			query: reportQuery, download: "occupancy.csv",
+			handler: unaryRest(func() *pb.ReportRequest { return &pb.ReportRequest{} }, reporting.GetOccupancyReport)},
+		{pattern: "GET /v1/reports/revenue", service: "ReportingService", rpc: "GetRevenueReport", summary: "Get the revenue per period, route and section",
+			query: reportQuery, download: "revenue.csv",
+			handler: unaryRest(func() *pb.ReportRequest { return &pb.ReportRequest{} }, reporting.GetRevenueReport)},
+		{pattern: "GET /v1/reports/cancellations", service: "ReportingService", rpc: "GetCancellationReport", summary: "Get the cancellation rate per period and route",
+			query: reportQuery, download: "cancellations.csv",
+			handler: unaryRest(func() *pb.ReportRequest { return &pb.ReportRequest{} }, reporting.GetCancellationReport)},
+		{pattern: "GET /v1/reports/lead-times", service: "ReportingService", rpc: "GetLeadTimeReport", summary: "Get the average time from booking to departure per period and route",
+			query: reportQuery, download: "lead-times.csv",
+			handler: unaryRest(func() *pb.ReportRequest { return &pb.ReportRequest{} }, reporting.GetLeadTimeReport)},
+	}
+	for _, route := range gateway.routes {
+		gateway.mux.HandleFunc(route.pattern, gateway.serveRoute(route))
+	}
+	gateway.mux.HandleFunc("GET /v1/users/{email}/etickets", gateway.serveGroupETicket)
+	gateway.mux.HandleFunc("GET /openapi.json", gateway.serveOpenAPI)
+	return gateway
+}
+
+// ServeHTTP answers every request with its X-Request-Id, generating one when
+// the caller didn't send a usable one.
+func (g *RESTGateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !isValidRequestId(r.Header.Get("X-Request-Id")) {
+		r.Header.Set("X-Request-Id", uuid.NewString())
+	}
+	w.Header().Set("X-Request-Id", r.Header.Get("X-Request-Id"))
+	g.mux.ServeHTTP(w, r)
+}
+
+func (g *RESTGateway) serveRoute(route *restRoute) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		req := route.handler.newRequest()
+		if err := bindRestRequest(r, route, req); err != nil {
+			writeRestError(w, status.Errorf(codes.InvalidArgument, "%v", err))
+			return
+		}
+
+		var header metadata.MD
+		response, err := route.handler.call(outgoingRestContext(r), req, grpc.Header(&header))
+		if err != nil {
+			writeRestError(w, err)
+			return
+		}
+		if values := header.Get(IdempotencyReplayedHeader); len(values) > 0 {
+			w.Header().Set("Idempotency-Replayed", values[0])
+		}
+		if etag := messageString(response, "etag"); etag != "" {
+			w.Header().Set("ETag", strconv.Quote(etag))
+		}
+
+		if eTicket, ok := response.(*pb.ETicket); ok && strings.Contains(r.Header.Get("Accept"), "application/pdf") {
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", eTicket.GetFilename()))
+			w.Write(eTicket.GetPdf())
+			return
+		}
+		if route.download != "" && strings.Contains(r.Header.Get("Accept"), "text/csv") {
+			var body bytes.Buffer
+			if err := writeReportCSV(&body, response); err != nil {
+				writeRestError(w, status.Errorf(codes.Internal, "Error while encoding response: %v", err))
+				return
+			}
+			w.Header().Set("Content-Type", "text/csv")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", route.download))
+			w.Write(body.Bytes())
+			return
+		}
+		statusCode := http.StatusOK
+		if route.created {
+			statusCode = http.StatusCreated
+		}
+		writeRestMessage(w, statusCode, response)
+	}
+}
+
+// serveGroupETicket streams the group e-ticket PDF of a user as it arrives
+func (g *RESTGateway) serveGroupETicket(w http.ResponseWriter, r *http.Request) {
+	req := &pb.GetGroupETicketRequest{BookingIds: r.URL.Query()["booking_id"], User: &pb.User{Email: r.PathValue("email")}}
+	stream, err := g.client.StreamGroupETicket(outgoingRestContext(r), req)
+	if err != nil {
+		writeRestError(w, err)
+		return
+	}
+	for started := false; ; started = true {
+		chunk, err := stream.Recv()
+		if err == io.EOF {
+			return
+		}
+		if err != nil {
+			if !started {
+				writeRestError(w, err)
+			} else {
+				slog.ErrorContext(r.Context(), "Error in streaming group e-ticket", "request_id", r.Header.Get("X-Request-Id"), "error", err)
+			}
+			return
+		}
+		if !started {
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", chunk.GetFilename()))
+		}
+		w.Write(chunk.GetData())
+		if flusher, ok := w.(http.Flusher); ok {
+			flusher.Flush()
+		}
+	}
+}
+
+// outgoingRestContext continues the caller's trace from the traceparent header,
+// so the RPC spans of the gateway connection join it.
+func outgoingRestContext(r *http.Request) context.Context {
+	ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	md := metadata.MD{}
+	for header, key := range forwardedHeaders {
+		if value := r.Header.Get(header); value != "" {
+			md.Set(key, value)
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		md.Set(ForwardedForHeader, host)
+	}
+	return metadata.NewOutgoingContext(ctx, md)
+}
+
+func bindRestRequest(r *http.Request, route *restRoute, req proto.Message) error {
+	if route.body {
+		body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+		if err != nil {
+			return err
+		}
+		if len(body) > 0 {
+			if err := protojson.Unmarshal(body, req); err != nil {
+				return fmt.Errorf("Invalid request body: %v", err)
+			}
+		}
+	}
+	for name, values := range r.URL.Query() {
+		if !slices.Contains(route.query, name) {
+			return fmt.Errorf("Unknown parameter %s", name)
+		}
+		if err := setMessageField(req.ProtoReflect(), name, values[0]); err != nil {
+			return err
+		}
+	}
+	if ifMatch := r.Header.Get("If-Match"); ifMatch != "" && req.ProtoReflect().Descriptor().Fields().ByName("etag") != nil {
+		if err := setMessageField(req.ProtoReflect(), "etag", strings.Trim(ifMatch, `"`)); err != nil {
+			return err
+		}
+	}
+	for wildcard, field := range route.pathFields {
+		if err := setMessageField(req.ProtoReflect(), field, r.PathValue(wildcard)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setMessageField sets the scalar field at the dotted path, e.g. user.email,
+// creating the messages along the way. Names may be proto or JSON names.
+func setMessageField(message protoreflect.Message, path, value string) error {
+	names := strings.Split(path, ".")
+	for i, name := range names {
+		fields := message.Descriptor().Fields()
+		field := fields.ByName(protoreflect.Name(name))
+		if field == nil {
+			field = fields.ByJSONName(name)
+		}
+		if field == nil || field.IsList() || field.IsMap() {
+			return fmt.Errorf("Unknown parameter %s", path)
+		}
+		if i < len(names)-1 {
+			if field.Kind() != protoreflect.MessageKind {
+				return fmt.Errorf("Unknown parameter %s", path)
+			}
+			message = message.Mutable(field).Message()
+			continue
+		}
+
+		var fieldValue protoreflect.Value
+		switch field.Kind() {
+		case protoreflect.StringKind:
+			fieldValue = protoreflect.ValueOfString(value)
+		case protoreflect.BoolKind:
+			parsed, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("Parameter %s must be a boolean", path)
+			}
+			fieldValue = protoreflect.ValueOfBool(parsed)
+		case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind:
+			parsed, err := strconv.ParseInt(value, 10, 32)
+			if err != nil {
+				return fmt.Errorf("Parameter %s must be an integer", path)
+			}
+			fieldValue = protoreflect.ValueOfInt32(int32(parsed))
+		case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind:
+			parsed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("Parameter %s must be an integer", path)
+			}
+			fieldValue = protoreflect.ValueOfInt64(parsed)
+		case protoreflect.EnumKind:
+			enumValue := field.Enum().Values().ByName(protoreflect.Name(value))
+			if enumValue == nil {
+				return fmt.Errorf("Parameter %s must be one of %s", path, enumNames(field.Enum()))
+			}
+			fieldValue = protoreflect.ValueOfEnum(enumValue.Number())
+		case protoreflect.MessageKind:
+			if field.Message().FullName() != "google.protobuf.Timestamp" {
+				return fmt.Errorf("Parameter %s can't be set from the url", path)
+			}
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return fmt.Errorf("Parameter %s must be an RFC 3339 time", path)
+			}
+			fieldValue = protoreflect.ValueOfMessage(timestamppb.New(parsed).ProtoReflect())
+		default:
+			return fmt.Errorf("Parameter %s can't be set from the url", path)
+		}
+		message.Set(field, fieldValue)
+	}
+	return nil
+}
+
+func enumNames(enum protoreflect.EnumDescriptor) string {
+	var names []string
+	for i := 0; i < enum.Values().Len(); i++ {
+		names = append(names, string(enum.Values().Get(i).Name()))
+	}
+	return strings.Join(names, ", ")
+}
+
+func messageString(message proto.Message, name string) string {
+	field := message.ProtoReflect().Descriptor().Fields().ByName(protoreflect.Name(name))
+	if field == nil || field.Kind() != protoreflect.StringKind {
+		return ""
+	}
+	return message.ProtoReflect().Get(field).String()
+}
+
+func writeRestMessage(w http.ResponseWriter, statusCode int, message proto.Message) {
+	body, err := protojson.Marshal(message)
+	if err != nil {
+		writeRestError(w, status.Errorf(codes.Internal, "Error while encoding response: %v", err))
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	w.Write(body)
+}
+
+// writeRestError writes err as a google.rpc.Status JSON body with the HTTP
+// status matching its gRPC code.
+func writeRestError(w http.ResponseWriter, err error) {
+	if errors.Is(err, context.Canceled) {
+		err = status.Error(codes.Canceled, err.Error())
+	}
+	grpcStatus := status.Convert(err)
+	body, _ := protojson.Marshal(grpcStatus.Proto())
+	if delay, ok := retryDelay(err); ok {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(delay.Seconds()))))
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(HTTPStatusFromCode(grpcStatus.Code()))
+	w.Write(body)
+}
+
+// HTTPStatusFromCode maps gRPC status codes to HTTP status codes the same way
+// Google APIs do, see google/rpc/code.proto.
+func HTTPStatusFromCode(code codes.Code) int {
+	switch code {
+	case codes.OK:
+		return http.StatusOK
+	case codes.Canceled:
+		return 499
+	case codes.InvalidArgument, codes.OutOfRange, codes.FailedPrecondition:
+		return http.StatusBadRequest
+	case codes.DeadlineExceeded:
+		return http.StatusGatewayTimeout
+	case codes.NotFound:
+		return http.StatusNotFound
+	case codes.AlreadyExists, codes.Aborted:
+		return http.StatusConflict
+	case codes.PermissionDenied:
+		return http.StatusForbidden
+	case codes.Unauthenticated:
+		return http.StatusUnauthorized
+	case codes.ResourceExhausted:
+		return http.StatusTooManyRequests
+	case codes.Unimplemented:
+		return http.StatusNotImplemented
+	case codes.Unavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,187 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/encoding/protojson"
+	"net/http/httptest"
+	"encoding/json"
+	"net/http"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"net"
+	"io"
+)
+
+func newTestRESTGateway(t *testing.T) *httptest.Server {
+    return newTestRESTGatewayAs(t, "")
+}
+
+// newTestRESTGatewayAs is newTestRESTGateway whose calls are made as actor, as
+// if the identity interceptor had verified it
+func newTestRESTGatewayAs(t *testing.T, actor string) *httptest.Server {
+    db := newTestDatabase(t)
+    listener := bufconn.Listen(1 << 20)
+    asActor := func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+        if actor != "" {
+            ctx = api.WithActor(ctx, actor)
+        }
+        return handler(ctx, req)
+    }
+    server := grpc.NewServer(grpc.ChainUnaryInterceptor(asActor, api.NewIdempotencyInterceptor(db, api.DefaultIdempotencyKeyTTL)))
+    bookingService := api.NewBookingService(db)
+    // seats are allocated in order so reports know the section of a booking
+    allocator, _ := api.NewSeatAllocatorWithLayout(api.DefaultSections, api.AllocationSequential)
+    bookingService.SetSeatAllocator(allocator)
+    pb.RegisterBookingServiceServer(server, bookingService)
+    pb.RegisterReportingServiceServer(server, api.NewReportingService(db, bookingService))
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    conn, err := grpc.NewClient("passthrough:///bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }))
+    if err != nil {
+        t.Fatalf("Error in connecting to server %v ", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+
+    gateway := httptest.NewServer(api.NewRESTGateway(conn))
+    t.Cleanup(gateway.Close)
+    return gateway
+}
+
+func restCall(t *testing.T, method, url, body string, headers map[string]string) (*http.Response, []byte) {
+    t.Helper()
+    req, _ := http.NewRequest(method, url, strings.NewReader(body))
+    for header, value := range headers {
+        req.Header.Set(header, value)
+    }
+    resp, err := http.DefaultClient.Do(req)
+    if err != nil {
+        t.Fatalf("Error in calling %s %s %v ", method, url, err)
+    }
+    defer resp.Body.Close()
+    responseBody, _ := io.ReadAll(resp.Body)
+    return resp, responseBody
+}
+
+func TestShouldServeBookingsOverREST(t *testing.T) {
+    gateway := newTestRESTGateway(t)
+    createBody := `{"from": "London", "to": "France", "price": 20, "user": {"firstname": "` + FIRST_NAME + `", "lastname": "` +
+        LAST_NAME + `", "email": "` + EMAIL + `"}}`
+
+    resp, body := restCall(t, http.MethodPost, gateway.URL+"/v1/bookings", createBody, map[string]string{"Idempotency-Key": "create-1"})
+    assert.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+    var booking pb.BookingResponse
+    if err := protojson.Unmarshal(body, &booking); err != nil {
+        t.Fatalf("Error in decoding booking %v ", err)
+    }
+    assert.NotEmpty(t, booking.GetPnr())
+    assert.Equal(t, `"`+booking.GetEtag()+`"`, resp.Header.Get("ETag"))
+
+    resp, _ = restCall(t, http.MethodPost, gateway.URL+"/v1/bookings", createBody, map[string]string{"Idempotency-Key": "create-1"})
+    assert.Equal(t, "true", resp.Header.Get("Idempotency-Replayed"), "Idempotency key should be forwarded")
+
+    resp, body = restCall(t, http.MethodGet, gateway.URL+"/v1/bookings/"+strings.ToLower(booking.GetPnr()), "", nil)
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.Contains(t, string(body), booking.GetId())
+
+    resp, body = restCall(t, http.MethodGet, gateway.URL+"/v1/sections/"+booking.GetSection()+"/bookings", "", nil)
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    assert.Contains(t, string(body), booking.GetId())
+
+    resp, _ = restCall(t, http.MethodGet, gateway.URL+"/v1/bookings/"+booking.GetPnr()+"?section=A", "", nil)
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "Undeclared query parameters should be rejected")
+
+    newSeat := (booking.GetSeat() + 1) % api.MaxSeatsPerSection
+    seatBody := `{"section": "` + booking.GetSection() + `", "seat": ` + strconv.Itoa(int(newSeat)) + `, "user": {"email": "` + EMAIL + `"}}`
+    resp, body = restCall(t, http.MethodPatch, gateway.URL+"/v1/bookings/"+booking.GetPnr()+"/seat", seatBody,
+        map[string]string{"If-Match": resp.Header.Get("ETag")})
+    assert.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+
+    resp, body = restCall(t, http.MethodPost, gateway.URL+"/v1/bookings", strings.Replace(createBody, "France", "Paris", 1), nil)
+    assert.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+    resp, _ = restCall(t, http.MethodGet, gateway.URL+"/v1/users/"+EMAIL+"/booking", "", nil)
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode, "FailedPrecondition should map to 400")
+
+    resp, _ = restCall(t, http.MethodDelete, gateway.URL+"/v1/bookings/"+booking.GetPnr(), "", nil)
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode, "The PNR alone shouldn't be enough to cancel")
+    resp, body = restCall(t, http.MethodDelete, gateway.URL+"/v1/bookings/"+booking.GetPnr()+"?user.email="+EMAIL, "",
+        map[string]string{"If-Match": `"` + booking.GetEtag() + `"`})
+    assert.Equal(t, http.StatusConflict, resp.StatusCode, "Outdated etag should map to 409")
+    var errorStatus map[string]any
+    json.Unmarshal(body, &errorStatus)
+    assert.EqualValues(t, 10, errorStatus["code"], "Error body should carry the gRPC code")
+
+    resp, _ = restCall(t, http.MethodDelete, gateway.URL+"/v1/bookings/"+booking.GetPnr()+"?user.email="+EMAIL, "", nil)
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+    resp, _ = restCall(t, http.MethodGet, gateway.URL+"/v1/bookings/"+booking.GetPnr(), "", nil)
+    assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+    resp, _ = restCall(t, http.MethodPost, gateway.URL+"/v1/bookings", `{"from": `, nil)
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestShouldDownloadReportsAsCSV(t *testing.T) {
+    gateway := newTestRESTGatewayAs(t, "service:back-office")
+    createBody := `{"from": "London", "to": "France", "price": 20, "departure": "2026-11-02T09:30:00Z", "user": {"firstname": "` +
+        FIRST_NAME + `", "lastname": "` + LAST_NAME + `", "email": "` + EMAIL + `"}}`
+    resp, body := restCall(t, http.MethodPost, gateway.URL+"/v1/bookings", createBody, nil)
+    assert.Equal(t, http.StatusCreated, resp.StatusCode, string(body))
+
+    resp, body = restCall(t, http.MethodGet, gateway.URL+"/v1/reports/occupancy?since=2026-11-01T00:00:00Z&section=A", "",
+        map[string]string{"Accept": "text/csv"})
+    assert.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+    assert.Equal(t, "text/csv", resp.Header.Get("Content-Type"))
+    assert.Contains(t, resp.Header.Get("Content-Disposition"), "occupancy.csv")
+    lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+    if assert.Len(t, lines, 2) {
+        assert.Equal(t, "from,to,departure,section,seats,booked,load_factor", lines[0])
+        assert.Equal(t, "London,France,2026-11-02T09:30:00Z,A,20,1,0.05", lines[1])
+    }
+
+    resp, body = restCall(t, http.MethodGet, gateway.URL+"/v1/reports/revenue?interval=REPORT_INTERVAL_MONTH", "", nil)
+    assert.Equal(t, http.StatusOK, resp.StatusCode, string(body))
+    var revenue pb.RevenueReport
+    if err := protojson.Unmarshal(body, &revenue); err != nil {
+        t.Fatalf("Error in decoding revenue report %v ", err)
+    }
+    assert.EqualValues(t, 20, revenue.GetTotalRevenue())
+
+    resp, _ = restCall(t, http.MethodGet, gateway.URL+"/v1/reports/revenue?interval=YEARLY", "", nil)
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+    resp, _ = restCall(t, http.MethodGet, gateway.URL+"/v1/reports/revenue?since=yesterday", "", nil)
+    assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+    resp, _ = restCall(t, http.MethodGet, newTestRESTGateway(t).URL+"/v1/reports/revenue", "", nil)
+    assert.Equal(t, http.StatusForbidden, resp.StatusCode, "Reports should only be served to services")
+}
+
+func TestShouldServeOpenAPIDocument(t *testing.T) {
+    gateway := newTestRESTGateway(t)
+    resp, body := restCall(t, http.MethodGet, gateway.URL+"/openapi.json", "", nil)
+    assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+    var document struct {
+        OpenAPI    string                               `json:"openapi"`
+        Paths      map[string]map[string]map[string]any `json:"paths"`
+        Components struct {
+            Schemas map[string]map[string]any `json:"schemas"`
+        } `json:"components"`
+    }
+    if err := json.Unmarshal(body, &document); err != nil {
+        t.Fatalf("Error in decoding OpenAPI document %v ", err)
+    }
+    assert.Equal(t, "3.0.3", document.OpenAPI)
+    assert.Equal(t, "CreateBooking", document.Paths["/v1/bookings"]["post"]["operationId"])
+    assert.Contains(t, document.Paths["/v1/sections/{section}/bookings"], "get")
+    assert.Contains(t, document.Components.Schemas, "BookingRequest")
+    assert.Contains(t, document.Components.Schemas["BookingResponse"]["properties"], "departure")
+    assert.Equal(t, []any{"ReportingService"}, document.Paths["/v1/reports/revenue"]["get"]["tags"])
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// serveOpenAPI serves an OpenAPI 3 document generated from the routes and the
+// descriptors of their messages, so it can't drift from the gateway.
+func (g *RESTGateway) serveOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(g.OpenAPI())
+}
+
+// OpenAPI returns the OpenAPI document of the gateway.
+func (g *RESTGateway) OpenAPI() map[string]any {
+	schemas := map[string]any{}
+	paths := map[string]map[string]any{}
+
+	for _, route := range g.routes {
+		method, path, _ := strings.Cut(route.pattern, " ")
+		request := route.handler.newRequest().ProtoReflect().Descriptor()
+
+		var parameters []any
+		for wildcard, field := range route.pathFields {
+			parameters = append(parameters, map[string]any{
+				"name": wildcard, "in": "path", "required": true,
+				"schema": fieldPathSchema(request, field, schemas),
+			})
+		}
+		for _, field := range route.query {
+			parameters = append(parameters, map[string]any{
+				"name": field, "in": "query",
+				"schema": fieldPathSchema(request, field, schemas),
+			})
+		}
+
+		successCode := "200"
+		if route.created {
+			successCode = "201"
+		}
+		service := route.service
+		if service == "" {
+			service = "BookingService"
+		}
+		success := jsonContent("Success", messageSchemaRef(route.handler.response, schemas))
+		if route.download != "" {
+			success["description"] = "Success, the rows as CSV when requested with Accept: text/csv"
+			success["content"].(map[string]any)["text/csv"] = map[string]any{"schema": map[string]any{"type": "string"}}
+		}
+		operation := map[string]any{
+			"operationId": route.rpc,
+			"summary":     route.summary,
+			"tags":        []string{service},
+			"responses": map[string]any{
+				successCode: success,
+				"default":   jsonContent("Error, with the gRPC status code and message", map[string]any{"$ref": "#/components/schemas/Status"}),
+			},
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if route.body {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content":  map[string]any{"application/json": map[string]any{"schema": messageSchemaRef(request, schemas)}},
+			}
+		}
+		if paths[path] == nil {
+			paths[path] = map[string]any{}
+		}
+		paths[path][strings.ToLower(method)] = operation
+	}
+
+	paths["/v1/users/{email}/etickets"] = map[string]any{"get": map[string]any{
+		"operationId": "StreamGroupETicket",
+		"summary":     "Download one PDF with the e-tickets of the given or all active bookings of a user",
+		"tags":        []string{"BookingService"},
+		"parameters": []any{
+			map[string]any{"name": "email", "in": "path", "required": true, "schema": map[string]any{"type": "string"}},
+			map[string]any{"name": "booking_id", "in": "query", "schema": map[string]any{"type": "array", "items": map[string]any{"type": "string"}}},
+		},
+		"responses": map[string]any{
+			"200":     map[string]any{"description": "PDF", "content": map[string]any{"application/pdf": map[string]any{}}},
+			"default": jsonContent("Error, with the gRPC status code and message", map[string]any{"$ref": "#/components/schemas/Status"}),
+		},
+	}}
+
+	schemas["Status"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"code":    map[string]any{"type": "integer", "format": "int32", "description": "gRPC status code"},
+			"message": map[string]any{"type": "string"},
+		},
+	}
+	return map[string]any{
+		"openapi": "3.0.3",
+		"info": map[string]any{
+			"title":   "Ticket booking API",
+			"version": "v1",
+		},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+func jsonContent(description string, schema map[string]any) map[string]any {
+	return map[string]any{
+		"description": description,
+		"content":     map[string]any{"application/json": map[string]any{"schema": schema}},
+	}
+}
+
+// fieldPathSchema returns the schema of the scalar at a dotted field path
+func fieldPathSchema(message protoreflect.MessageDescriptor, path string, schemas map[string]any) map[string]any {
+	names := strings.Split(path, ".")
+	for _, name := range names[:len(names)-1] {
+		message = message.Fields().ByName(protoreflect.Name(name)).Message()
+	}
+	return fieldSchema(message.Fields().ByName(protoreflect.Name(names[len(names)-1])), schemas)
+}
+
+// messageSchemaRef adds the schema of message, and of the messages it refers
+// to, to schemas and returns a reference to it.
+func messageSchemaRef(message protoreflect.MessageDescriptor, schemas map[string]any) map[string]any {
+	if message.FullName() == "google.protobuf.Timestamp" {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+	name := string(message.Name())
+	ref := map[string]any{"$ref": "#/components/schemas/" + name}
+	if _, exists := schemas[name]; exists {
+		return ref
+	}
+
+	properties := map[string]any{}
+	// reserve the name first so recursive messages terminate
+	schemas[name] = map[string]any{"type": "object", "properties": properties}
+	fields := message.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		field := fields.Get(i)
+		schema := fieldSchema(field, schemas)
+		if field.IsList() {
+			schema = map[string]any{"type": "array", "items": schema}
+		}
+		properties[field.JSONName()] = schema
+	}
+	return ref
+}
+
+// fieldSchema follows the protojson mapping, e.g. 64 bit integers are strings
+func fieldSchema(field protoreflect.FieldDescriptor, schemas map[string]any) map[string]any {
+	switch field.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageSchemaRef(field.Message(), schemas)
+	case protoreflect.EnumKind:
+		var values []string
+		enumValues := field.Enum().Values()
+		for i := 0; i < enumValues.Len(); i++ {
+			values = append(values, string(enumValues.Get(i).Name()))
+		}
+		return map[string]any{"type": "string", "enum": values}
+	case protoreflect.BoolKind:
+		return map[string]any{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind, protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]any{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind, protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		return map[string]any{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return map[string]any{"type": "number"}
+	case protoreflect.BytesKind:
+		return map[string]any{"type": "string", "format": "byte"}
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
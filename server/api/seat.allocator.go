@@ -1,97 +1,296 @@
 package api
 
 import (
+	"github.com/google/uuid"
 	"errors"
+	"fmt"
 	"math/rand"
+	"slices"
+	"sync"
+	"time"
 )
 
+// MaxSeatsPerSection is the number of seats of each section in the default layout
 const MaxSeatsPerSection = 20
 
+const (
+	// AllocationRandom picks a random free seat in a random section with free seats
+	AllocationRandom = "random"
+	// AllocationSequential fills the sections in order, lowest seat number first
+	AllocationSequential = "sequential"
+)
+
 var MaxSeatsLimitReached = errors.New("Max seat limit reached")
 var SeatNotAvailable = errors.New("Seat is already booked")
+var SeatHoldNotFound = errors.New("Seat hold doesn't exist or has expired")
+var TooManySeatHolds = errors.New("Too many seats are held")
+
+// Section is a section of the train; its seats are numbered from 0
+type Section struct {
+	Name  string
+	Seats int32
+}
 
+// DefaultSections is the layout of sections A and B with 20 seats each
+var DefaultSections = []Section{{Name: "A", Seats: MaxSeatsPerSection}, {Name: "B", Seats: MaxSeatsPerSection}}
+
+// SeatAllocator is safe for concurrent use
 type SeatAllocator struct {
-	occupiedSeatsSectionA map[int32]bool
-	occupiedSeatsSectionB map[int32]bool
-	sections              [2]string
+	mu            sync.Mutex
+	sections      []Section
+	strategy      string
+	occupiedSeats map[string]map[int32]bool
+	// held seats are neither allocated nor available until their hold is
+	// claimed, released or expires
+	holds     map[string]*seatHold
+	heldSeats map[string]map[int32]string
+}
+
+type seatHold struct {
+	holder  string
+	section string
+	seat    int32
+	expires time.Time
 }
 
 // helps to create new seat allocator
 func NewSeatAllocator() *SeatAllocator {
-	return &SeatAllocator{
-		occupiedSeatsSectionA: make(map[int32]bool),
-		occupiedSeatsSectionB: make(map[int32]bool),
-		sections:              [2]string{"A", "B"},
+	allocator, _ := NewSeatAllocatorWithLayout(DefaultSections, AllocationRandom)
+	return allocator
+}
+
+// NewSeatAllocatorWithLayout creates an allocator for the given sections that
+// allocates seats with strategy.
+func NewSeatAllocatorWithLayout(sections []Section, strategy string) (*SeatAllocator, error) {
+	if strategy != AllocationRandom && strategy != AllocationSequential {
+		return nil, fmt.Errorf("unknown seat allocation strategy %q", strategy)
 	}
+	if len(sections) == 0 {
+		return nil, errors.New("at least one section is required")
+	}
+	occupiedSeats := make(map[string]map[int32]bool)
+	heldSeats := make(map[string]map[int32]string)
+	for _, section := range sections {
+		if section.Name == "" || section.Seats <= 0 {
+			return nil, fmt.Errorf("section %q must have a name and seats", section.Name)
+		}
+		if _, exists := occupiedSeats[section.Name]; exists {
+			return nil, fmt.Errorf("section %q is defined twice", section.Name)
+		}
+		occupiedSeats[section.Name] = make(map[int32]bool)
+		heldSeats[section.Name] = make(map[int32]string)
+	}
+	return &SeatAllocator{
+		sections:      append([]Section(nil), sections...),
+		strategy:      strategy,
+		occupiedSeats: occupiedSeats,
+		holds:         make(map[string]*seatHold),
+		heldSeats:     heldSeats,
+	}, nil
 }
 
 func (s *SeatAllocator) AllocateSeat() (int32, string, error) {
+	seatNumber, section, _, err := s.allocateSeat()
+	return seatNumber, section, err
+}
+
+// allocateSeat also returns the number of seats that were tried
+func (s *SeatAllocator) allocateSeat() (int32, string, int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireHolds(time.Now())
 
 	section, err := s.findSection()
 	if err != nil {
-		return 0, "", err
+		return 0, "", 0, err
 	}
 
-	seatNumber := rand.Int31n(MaxSeatsPerSection)
-	if section == "A" {
-		if _, ok := s.occupiedSeatsSectionA[seatNumber]; ok {
-			return s.AllocateSeat()
+	occupied := s.occupiedSeats[section.Name]
+	taken := func(seat int32) bool {
+		_, held := s.heldSeats[section.Name][seat]
+		return occupied[seat] || held
+	}
+	var seatNumber int32
+	attempts := 1
+	if s.strategy == AllocationSequential {
+		for taken(seatNumber) {
+			seatNumber++
+			attempts++
 		}
-
-		s.occupiedSeatsSectionA[seatNumber] = true
-	} else if section == "B" {
-		if _, ok := s.occupiedSeatsSectionB[seatNumber]; ok {
-			return s.AllocateSeat()
+	} else {
+		seatNumber = rand.Int31n(section.Seats)
+		for taken(seatNumber) {
+			seatNumber = rand.Int31n(section.Seats)
+			attempts++
 		}
-
-		s.occupiedSeatsSectionB[seatNumber] = true
 	}
-	return seatNumber, section, nil
+	occupied[seatNumber] = true
+	return seatNumber, section.Name, attempts, nil
 }
 
 func (s *SeatAllocator) DeallocateSeat(seatNumber int32, section string) {
-	if section == "A" {
-		delete(s.occupiedSeatsSectionA, seatNumber)
-	} else if section == "B" {
-		delete(s.occupiedSeatsSectionB, seatNumber)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if occupied, ok := s.occupiedSeats[section]; ok {
+		delete(occupied, seatNumber)
 	}
 }
 
 func (s *SeatAllocator) AllocateSpecificSeat(seatNumber int32, section string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireHolds(time.Now())
 	if !s.isSeatAvailable(seatNumber, section) {
 		return SeatNotAvailable
 	}
-	if section == "A" {
-		s.occupiedSeatsSectionA[seatNumber] = true
-	} else if section == "B" {
-		s.occupiedSeatsSectionB[seatNumber] = true
+	s.occupiedSeats[section][seatNumber] = true
+	return nil
+}
+
+// Sections returns the layout of the train
+func (s *SeatAllocator) Sections() []Section {
+	return append([]Section(nil), s.sections...)
+}
+
+// Occupancy returns the number of allocated seats of each section
+func (s *SeatAllocator) Occupancy() map[string]int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	occupancy := make(map[string]int, len(s.occupiedSeats))
+	for section, occupied := range s.occupiedSeats {
+		occupancy[section] = len(occupied)
 	}
+	return occupancy
+}
+
+// OccupiedSeats returns the allocated seats of section in ascending order
+func (s *SeatAllocator) OccupiedSeats(section string) []int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	seats := make([]int32, 0, len(s.occupiedSeats[section]))
+	for seat := range s.occupiedSeats[section] {
+		seats = append(seats, seat)
+	}
+	slices.Sort(seats)
+	return seats
+}
+
+// HoldSeat keeps a free seat from being allocated for ttl, unless the hold
+// is claimed or released before. The hold id is returned with its expiry.
+// holder may have at most maxHolds holds at once, 0 for no limit.
+func (s *SeatAllocator) HoldSeat(seatNumber int32, section, holder string, maxHolds int, ttl time.Duration) (string, time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	s.expireHolds(now)
+	if maxHolds > 0 && s.countHolds(holder) >= maxHolds {
+		return "", time.Time{}, TooManySeatHolds
+	}
+	if !s.isSeatAvailable(seatNumber, section) {
+		return "", time.Time{}, SeatNotAvailable
+	}
+	holdId := uuid.NewString()
+	hold := &seatHold{holder: holder, section: section, seat: seatNumber, expires: now.Add(ttl)}
+	s.holds[holdId] = hold
+	s.heldSeats[section][seatNumber] = holdId
+	return holdId, hold.expires, nil
+}
+
+// ClaimHold allocates the seat of a hold, which ends the hold.
+func (s *SeatAllocator) ClaimHold(holdId string) (int32, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireHolds(time.Now())
+	hold, ok := s.holds[holdId]
+	if !ok {
+		return 0, "", SeatHoldNotFound
+	}
+	s.releaseHold(holdId)
+	s.occupiedSeats[hold.section][hold.seat] = true
+	return hold.seat, hold.section, nil
+}
+
+// ReleaseHold makes the seat of a hold available again
+func (s *SeatAllocator) ReleaseHold(holdId string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireHolds(time.Now())
+	if _, ok := s.holds[holdId]; !ok {
+		return SeatHoldNotFound
+	}
+	s.releaseHold(holdId)
 	return nil
 }
 
-func (s *SeatAllocator) isSeatAvailable(seatNumber int32, section string) bool {
-    isSeatAvailable := false
-	if section == "A" && len(s.occupiedSeatsSectionA) < MaxSeatsPerSection {
-		if _, ok := s.occupiedSeatsSectionA[seatNumber]; !ok {
-			isSeatAvailable = true
+// HeldSeats returns the held seats of section in ascending order
+func (s *SeatAllocator) HeldSeats(section string) []int32 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.expireHolds(time.Now())
+	seats := make([]int32, 0, len(s.heldSeats[section]))
+	for seat := range s.heldSeats[section] {
+		seats = append(seats, seat)
+	}
+	slices.Sort(seats)
+	return seats
+}
+
+// replaceSeats takes over the allocated and held seats of other, which has
+// the same layout
+func (s *SeatAllocator) replaceSeats(other *SeatAllocator) {
+	other.mu.Lock()
+	defer other.mu.Unlock()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.occupiedSeats, s.holds, s.heldSeats = other.occupiedSeats, other.holds, other.heldSeats
+}
+
+func (s *SeatAllocator) releaseHold(holdId string) {
+	hold := s.holds[holdId]
+	delete(s.heldSeats[hold.section], hold.seat)
+	delete(s.holds, holdId)
+}
+
+func (s *SeatAllocator) countHolds(holder string) int {
+	count := 0
+	for _, hold := range s.holds {
+		if hold.holder == holder {
+			count++
 		}
-	} else if section == "B" && len(s.occupiedSeatsSectionB) < MaxSeatsPerSection {
-		if _, ok := s.occupiedSeatsSectionB[seatNumber]; !ok {
-			isSeatAvailable = true
+	}
+	return count
+}
+
+func (s *SeatAllocator) expireHolds(now time.Time) {
+	for holdId, hold := range s.holds {
+		if !now.Before(hold.expires) {
+			s.releaseHold(holdId)
 		}
 	}
+}
 
-	return isSeatAvailable
+func (s *SeatAllocator) isSeatAvailable(seatNumber int32, section string) bool {
+	for _, candidate := range s.sections {
+		if candidate.Name == section {
+			_, held := s.heldSeats[section][seatNumber]
+			return seatNumber >= 0 && seatNumber < candidate.Seats && !s.occupiedSeats[section][seatNumber] && !held
+		}
+	}
+	return false
 }
 
-func (s *SeatAllocator) findSection() (string, error) {
-	if len(s.occupiedSeatsSectionA) < MaxSeatsPerSection && len(s.occupiedSeatsSectionB) < MaxSeatsPerSection {
-		return s.sections[rand.Intn(2)], nil
-	} else if len(s.occupiedSeatsSectionA) < MaxSeatsPerSection {
-		return "A", nil
-	} else if len(s.occupiedSeatsSectionB) < MaxSeatsPerSection {
-		return "B", nil
-	} else {
-		return "", MaxSeatsLimitReached
+func (s *SeatAllocator) findSection() (Section, error) {
+	var available []Section
+	for _, section := range s.sections {
+		if int32(len(s.occupiedSeats[section.Name])+len(s.heldSeats[section.Name])) < section.Seats {
+			available = append(available, section)
+		}
+	}
+	if len(available) == 0 {
+		return Section{}, MaxSeatsLimitReached
+	}
+	if s.strategy == AllocationSequential {
+		return available[0], nil
 	}
+	return available[rand.Intn(len(available))], nil
 }
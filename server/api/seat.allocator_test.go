@@ -0,0 +1,39 @@
+package api_test
+
+import (
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestShouldAllocateSeatsSequentiallyInLayoutOrder(t *testing.T) {
+    allocator, err := api.NewSeatAllocatorWithLayout([]api.Section{{Name: "Front", Seats: 2}, {Name: "Back", Seats: 1}}, api.AllocationSequential)
+    if err != nil {
+        t.Fatalf("Error in creating allocator %v ", err)
+    }
+
+    var allocated []string
+    for i := 0; i < 3; i++ {
+        seat, section, err := allocator.AllocateSeat()
+        if err != nil {
+            t.Fatalf("Error in allocating seat %v ", err)
+        }
+        allocated = append(allocated, section+string(rune('0'+seat)))
+    }
+    assert.Equal(t, []string{"Front0", "Front1", "Back0"}, allocated)
+
+    _, _, err = allocator.AllocateSeat()
+    assert.Equal(t, api.MaxSeatsLimitReached, err)
+
+    allocator.DeallocateSeat(1, "Front")
+    assert.Equal(t, api.SeatNotAvailable, allocator.AllocateSpecificSeat(2, "Front"), "Seats outside the section should not exist")
+    assert.Equal(t, api.SeatNotAvailable, allocator.AllocateSpecificSeat(0, "A"), "Unknown sections should not have seats")
+    assert.NoError(t, allocator.AllocateSpecificSeat(1, "Front"))
+}
+
+func TestShouldRejectInvalidSeatLayout(t *testing.T) {
+    _, err := api.NewSeatAllocatorWithLayout(api.DefaultSections, "nearest")
+    assert.Error(t, err)
+    _, err = api.NewSeatAllocatorWithLayout([]api.Section{{Name: "A", Seats: 2}, {Name: "A", Seats: 3}}, api.AllocationRandom)
+    assert.Error(t, err)
+}
@@ -0,0 +1,168 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"database/sql"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+const (
+	AuditActionTicketCheckIn          = "ticket.check_in"
+	AuditActionTicketSigningKeyRotate = "ticket_signing_key.rotate"
+	AuditActionTicketSigningKeyRetire = "ticket_signing_key.retire"
+)
+
+// TicketService lets conductors validate e-tickets and manages the keys their
+// tokens are signed with. It shares the signer of the booking service. Only
+// services with a client certificate, such as the conductors' scanners, may
+// check tickets in or change the keys.
+type TicketService struct {
+	bookingService *BookingService
+	db             *sql.DB
+}
+
+func NewTicketService(dbInstance *sql.DB, bookingService *BookingService) *TicketService {
+	return &TicketService{
+		bookingService: bookingService,
+		db:             dbInstance,
+	}
+}
+
+// ValidateTicket checks a scanned token and checks the ticket in on its first
+// valid scan. Problems with the ticket are reported in the result rather than
+// as errors; a seat mismatch still checks the ticket in.
+func (t *TicketService) ValidateTicket(ctx context.Context, req *pb.ValidateTicketRequest) (*pb.ValidateTicketResponse, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	token, err := t.bookingService.ticketSigner.VerifyTicketToken(req.GetToken())
+	if errors.Is(err, ErrInvalidTicketToken) {
+		slog.WarnContext(ctx, "Rejected ticket token with invalid signature")
+		return &pb.ValidateTicketResponse{Result: pb.TicketValidationResult_TICKET_INVALID_SIGNATURE}, nil
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while verifying ticket token: %v", err)
+	}
+
+	tx, err := t.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var booking pb.BookingDbResponse
+	var ticketStatus, checkedInBy string
+	var checkedInAt int64
+	row := tx.QueryRow("SELECT "+ticketColumns+", t_status, COALESCE(t_checked_in_at, 0), COALESCE(t_checked_in_by, '') FROM tickets WHERE t_id = ?",
+		token.GetTicketId())
+	err = row.Scan(&booking.Id, &booking.From, &booking.To, &booking.Price, &booking.Seat, &booking.Section, &booking.Userid,
+		&booking.DepartureAt, &booking.Pnr, &booking.Version, &ticketStatus, &checkedInAt, &checkedInBy)
+	if err == sql.ErrNoRows {
+		return &pb.ValidateTicketResponse{Result: pb.TicketValidationResult_TICKET_NOT_FOUND, TicketId: token.GetTicketId()}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	response := &pb.ValidateTicketResponse{
+		TicketId:  booking.GetId(),
+		Pnr:       booking.GetPnr(),
+		From:      booking.GetFrom(),
+		To:        booking.GetTo(),
+		Seat:      booking.GetSeat(),
+		Section:   booking.GetSection(),
+		Departure: departureTimestamp(booking.GetDepartureAt()),
+	}
+	if user, err := findUserById(tx, booking.GetUserid()); err == nil {
+		response.Passenger = strings.TrimSpace(user.GetFirstname() + " " + user.GetLastname())
+	}
+
+	switch {
+	case ticketStatus == TicketStatusCancelled:
+		response.Result = pb.TicketValidationResult_TICKET_CANCELLED
+		return response, nil
+	case checkedInAt != 0:
+		response.Result = pb.TicketValidationResult_TICKET_ALREADY_CHECKED_IN
+		response.CheckedInAt = timestamppb.New(time.Unix(checkedInAt, 0))
+		response.CheckedInBy = checkedInBy
+		slog.InfoContext(ctx, "Ticket scanned again", "pnr", booking.GetPnr(), "checked_in_at", response.GetCheckedInAt().AsTime())
+		return response, nil
+	}
+
+	response.Result = pb.TicketValidationResult_TICKET_VALID
+	// the token shows the seat at the time the e-ticket was issued
+	tokenMismatch := token.GetSeat() != booking.GetSeat() || token.GetSection() != booking.GetSection()
+	seatedMismatch := req.GetSection() != "" && (req.GetSeat() != booking.GetSeat() || req.GetSection() != booking.GetSection())
+	if tokenMismatch || seatedMismatch {
+		response.Result = pb.TicketValidationResult_TICKET_SEAT_MISMATCH
+	}
+
+	now := time.Now().Unix()
+	actor := ActorFromContext(ctx)
+	result, err := tx.Exec("UPDATE tickets SET t_checked_in_at = ?, t_checked_in_by = ? WHERE t_id = ? AND t_checked_in_at IS NULL",
+		now, actor, booking.GetId())
+	if err != nil {
+		return nil, err
+	}
+	if updated, _ := result.RowsAffected(); updated == 0 {
+		// checked in concurrently by another scan
+		return nil, status.Errorf(codes.Aborted, "Ticket %s was checked in concurrently, scan it again", booking.GetPnr())
+	}
+	response.CheckedInAt = timestamppb.New(time.Unix(now, 0))
+	response.CheckedInBy = actor
+	if err := appendAuditEntry(ctx, tx, AuditActionTicketCheckIn, booking.GetId(), nil, response); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// ListTicketSigningKeys publishes the public keys so tokens can also be
+// verified offline.
+func (t *TicketService) ListTicketSigningKeys(ctx context.Context, req *pb.ListTicketSigningKeysRequest) (*pb.ListTicketSigningKeysResponse, error) {
+	keys, err := t.bookingService.ticketSigner.Keys(req.GetIncludeRetired())
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while reading ticket signing keys: %v", err)
+	}
+	return &pb.ListTicketSigningKeysResponse{Keys: keys}, nil
+}
+
+func (t *TicketService) RotateTicketSigningKey(ctx context.Context, req *pb.RotateTicketSigningKeyRequest) (*pb.TicketSigningKey, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	key, err := t.bookingService.ticketSigner.Rotate()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while rotating ticket signing key: %v", err)
+	}
+	if err := appendAuditEntry(ctx, t.db, AuditActionTicketSigningKeyRotate, key.GetKeyId(), nil, key); err != nil {
+		slog.ErrorContext(ctx, "Error in auditing key rotation", "key_id", key.GetKeyId(), "error", err)
+	}
+	return key, nil
+}
+
+func (t *TicketService) RetireTicketSigningKey(ctx context.Context, req *pb.RetireTicketSigningKeyRequest) (*pb.TicketSigningKey, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	key, err := t.bookingService.ticketSigner.Retire(req.GetKeyId())
+	if errors.Is(err, ErrTicketSigningKeyNotFound) {
+		return nil, status.Errorf(codes.NotFound, "No active ticket signing key exists with id %s", req.GetKeyId())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while retiring ticket signing key: %v", err)
+	}
+	if err := appendAuditEntry(ctx, t.db, AuditActionTicketSigningKeyRetire, key.GetKeyId(), nil, key); err != nil {
+		slog.ErrorContext(ctx, "Error in auditing key retirement", "key_id", key.GetKeyId(), "error", err)
+	}
+	return key, nil
+}
@@ -0,0 +1,134 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"context"
+	"testing"
+)
+
+func createTicketForValidation(t *testing.T, bookingService *api.BookingService, destination string) (*pb.BookingResponse, string) {
+    t.Helper()
+    request := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+    request.To = destination
+    booking, err := bookingService.CreateBooking(context.TODO(), request)
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    eTicket, err := bookingService.GetETicket(context.TODO(), &pb.GetETicketRequest{BookingId: booking.GetId(), User: &pb.User{Email: EMAIL}})
+    if err != nil {
+        t.Fatalf("Error in getting e-ticket %v ", err)
+    }
+    return booking, eTicket.GetToken()
+}
+
+func validateTicket(t *testing.T, ticketService *api.TicketService, req *pb.ValidateTicketRequest) *pb.ValidateTicketResponse {
+    t.Helper()
+    response, err := ticketService.ValidateTicket(api.WithActor(context.TODO(), "service:conductor-12"), req)
+    if err != nil {
+        t.Fatalf("Error in validating ticket %v ", err)
+    }
+    return response
+}
+
+func TestShouldCheckInValidTicketOnce(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    ticketService := api.NewTicketService(db, bookingService)
+    booking, token := createTicketForValidation(t, bookingService, "Paris")
+
+    _, err := ticketService.ValidateTicket(context.TODO(), &pb.ValidateTicketRequest{Token: token})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "Only the conductors' scanners should check tickets in")
+    first := validateTicket(t, ticketService, &pb.ValidateTicketRequest{Token: token})
+    assert.Equal(t, pb.TicketValidationResult_TICKET_VALID, first.GetResult())
+    assert.Equal(t, booking.GetPnr(), first.GetPnr())
+    assert.Equal(t, FIRST_NAME+" "+LAST_NAME, first.GetPassenger())
+    assert.Equal(t, "service:conductor-12", first.GetCheckedInBy())
+
+    again := validateTicket(t, ticketService, &pb.ValidateTicketRequest{Token: token})
+    assert.Equal(t, pb.TicketValidationResult_TICKET_ALREADY_CHECKED_IN, again.GetResult(), "Reused ticket should be flagged")
+    assert.Equal(t, first.GetCheckedInAt().AsTime(), again.GetCheckedInAt().AsTime())
+
+    tampered := []byte(token)
+    if tampered[len(tampered)-10] == 'A' {
+        tampered[len(tampered)-10] = 'B'
+    } else {
+        tampered[len(tampered)-10] = 'A'
+    }
+    forged := validateTicket(t, ticketService, &pb.ValidateTicketRequest{Token: string(tampered)})
+    assert.Equal(t, pb.TicketValidationResult_TICKET_INVALID_SIGNATURE, forged.GetResult())
+    malformed := validateTicket(t, ticketService, &pb.ValidateTicketRequest{Token: "not-a-token"})
+    assert.Equal(t, pb.TicketValidationResult_TICKET_INVALID_SIGNATURE, malformed.GetResult())
+
+    cancelled, cancelledToken := createTicketForValidation(t, bookingService, "Lyon")
+    if _, err := bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: cancelled.GetId(), User: &pb.User{Email: EMAIL}}); err != nil {
+        t.Fatalf("Error in cancelling booking %v ", err)
+    }
+    assert.Equal(t, pb.TicketValidationResult_TICKET_CANCELLED,
+        validateTicket(t, ticketService, &pb.ValidateTicketRequest{Token: cancelledToken}).GetResult())
+}
+
+func TestShouldFlagMismatchedSeat(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    ticketService := api.NewTicketService(db, bookingService)
+
+    seated, seatedToken := createTicketForValidation(t, bookingService, "Paris")
+    wrongSeat := validateTicket(t, ticketService, &pb.ValidateTicketRequest{Token: seatedToken,
+        Seat: (seated.GetSeat() + 1) % api.MaxSeatsPerSection, Section: seated.GetSection()})
+    assert.Equal(t, pb.TicketValidationResult_TICKET_SEAT_MISMATCH, wrongSeat.GetResult(), "Passenger in another seat should be flagged")
+
+    moved, staleToken := createTicketForValidation(t, bookingService, "Lyon")
+    newSeat := int32(0)
+    for newSeat == moved.GetSeat() || newSeat == seated.GetSeat() {
+        newSeat++
+    }
+    if _, err := bookingService.ModifySeatByUser(context.TODO(), &pb.SeatModificationRequest{BookingId: moved.GetId(),
+        Section: "A", Seat: newSeat, User: &pb.User{Email: EMAIL}}); err != nil {
+        t.Fatalf("Error in modifying booking %v ", err)
+    }
+    stale := validateTicket(t, ticketService, &pb.ValidateTicketRequest{Token: staleToken})
+    assert.Equal(t, pb.TicketValidationResult_TICKET_SEAT_MISMATCH, stale.GetResult(), "Token of the old seat should be flagged")
+    assert.Equal(t, newSeat, stale.GetSeat(), "Response should show the booked seat")
+}
+
+func TestShouldRotateTicketSigningKeys(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    ticketService := api.NewTicketService(db, bookingService)
+    _, oldToken := createTicketForValidation(t, bookingService, "Paris")
+
+    keys, err := ticketService.ListTicketSigningKeys(context.TODO(), &pb.ListTicketSigningKeysRequest{})
+    if err != nil {
+        t.Fatalf("Error in listing keys %v ", err)
+    }
+    oldKey := keys.GetKeys()[0]
+    _, err = ticketService.RotateTicketSigningKey(context.TODO(), &pb.RotateTicketSigningKeyRequest{})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "Only services should rotate keys")
+    _, err = ticketService.RetireTicketSigningKey(api.WithActor(context.TODO(), "agent-7"), &pb.RetireTicketSigningKeyRequest{KeyId: oldKey.GetKeyId()})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "Only services should retire keys")
+
+    ctx := api.WithActor(context.TODO(), "service:key-manager")
+    newKey, err := ticketService.RotateTicketSigningKey(ctx, &pb.RotateTicketSigningKeyRequest{})
+    if err != nil {
+        t.Fatalf("Error in rotating key %v ", err)
+    }
+    assert.True(t, newKey.GetActive())
+    assert.NotEqual(t, oldKey.GetKeyId(), newKey.GetKeyId())
+
+    _, newToken := createTicketForValidation(t, bookingService, "Lyon")
+    if _, err := ticketService.RetireTicketSigningKey(ctx, &pb.RetireTicketSigningKeyRequest{KeyId: oldKey.GetKeyId()}); err != nil {
+        t.Fatalf("Error in retiring key %v ", err)
+    }
+    assert.Equal(t, pb.TicketValidationResult_TICKET_INVALID_SIGNATURE,
+        validateTicket(t, ticketService, &pb.ValidateTicketRequest{Token: oldToken}).GetResult(), "Tokens of retired keys should be rejected")
+    assert.Equal(t, pb.TicketValidationResult_TICKET_VALID,
+        validateTicket(t, ticketService, &pb.ValidateTicketRequest{Token: newToken}).GetResult())
+
+    keys, _ = ticketService.ListTicketSigningKeys(context.TODO(), &pb.ListTicketSigningKeysRequest{})
+    assert.Len(t, keys.GetKeys(), 1, "Retired keys should not be published")
+    assert.Len(t, keys.GetKeys()[0].GetPublicKey(), 32)
+}
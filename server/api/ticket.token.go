@@ -0,0 +1,228 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"crypto/ed25519"
+	"crypto/rand"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+const signingKeyColumns = "s_key_id, s_private_key, s_created_at, COALESCE(s_retired_at, 0)"
+
+var ErrInvalidTicketToken = errors.New("Ticket token is invalid")
+var ErrTicketSigningKeyNotFound = errors.New("Ticket signing key not found")
+
+// TicketSigner issues the tokens printed on e-tickets. A token is the
+// serialized TicketToken and its ed25519 signature, both base64url encoded and
+// joined by a dot, so it can be checked offline with the public key.
+type TicketSigner struct {
+	db *sql.DB
+
+	mu    sync.Mutex
+	keyId string
+	key   ed25519.PrivateKey
+}
+
+func NewTicketSigner(dbInstance *sql.DB) *TicketSigner {
+	return &TicketSigner{
+		db: dbInstance,
+	}
+}
+
+// IssueTicketToken signs the journey and seat of booking.
+func (s *TicketSigner) IssueTicketToken(booking *pb.BookingDbResponse) (string, error) {
+	keyId, key, err := s.signingKey()
+	if err != nil {
+		return "", err
+	}
+	payload, err := proto.Marshal(&pb.TicketToken{
+		KeyId:       keyId,
+		TicketId:    booking.GetId(),
+		From:        booking.GetFrom(),
+		To:          booking.GetTo(),
+		Seat:        booking.GetSeat(),
+		Section:     booking.GetSection(),
+		DepartureAt: booking.GetDepartureAt(),
+		IssuedAt:    time.Now().Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	signature := ed25519.Sign(key, payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// VerifyTicketToken checks the signature of token against the key it names
+// and returns its content. Tokens of retired or unknown keys are rejected.
+func (s *TicketSigner) VerifyTicketToken(token string) (*pb.TicketToken, error) {
+	encodedPayload, encodedSignature, found := strings.Cut(token, ".")
+	if !found {
+		return nil, ErrInvalidTicketToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return nil, ErrInvalidTicketToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSignature)
+	if err != nil {
+		return nil, ErrInvalidTicketToken
+	}
+	var content pb.TicketToken
+	if err := proto.Unmarshal(payload, &content); err != nil {
+		return nil, ErrInvalidTicketToken
+	}
+
+	var seed []byte
+	err = s.db.QueryRow("SELECT s_private_key FROM ticket_signing_keys WHERE s_key_id = ? AND s_retired_at IS NULL",
+		content.GetKeyId()).Scan(&seed)
+	if err == sql.ErrNoRows {
+		return nil, ErrInvalidTicketToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	publicKey := ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	if !ed25519.Verify(publicKey, payload, signature) {
+		return nil, ErrInvalidTicketToken
+	}
+	return &content, nil
+}
+
+// Rotate creates a new key that signs all tokens from now on. Earlier keys
+// still verify the tokens they signed until they are retired.
+func (s *TicketSigner) Rotate() (*pb.TicketSigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	keyId, seed, err := createTicketSigningKey(s.db)
+	if err != nil {
+		return nil, err
+	}
+	s.keyId, s.key = keyId, ed25519.NewKeyFromSeed(seed)
+	return s.findKey(keyId)
+}
+
+// Retire stops accepting tokens signed with keyId. Retiring the active key
+// makes the newest remaining key active, or a new one when none is left.
+func (s *TicketSigner) Retire(keyId string) (*pb.TicketSigningKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, err := s.db.Exec("UPDATE ticket_signing_keys SET s_retired_at = ? WHERE s_key_id = ? AND s_retired_at IS NULL",
+		time.Now().Unix(), keyId)
+	if err != nil {
+		return nil, err
+	}
+	if retired, _ := result.RowsAffected(); retired == 0 {
+		return nil, ErrTicketSigningKeyNotFound
+	}
+	if keyId == s.keyId {
+		s.keyId, s.key = "", nil
+	}
+	return s.findKey(keyId)
+}
+
+// reload forgets the cached signing key, e.g. after the database was restored,
+// so that the next token is signed with the newest key of the database.
+func (s *TicketSigner) reload() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.keyId, s.key = "", nil
+}
+
+// Keys lists the signing keys, newest first, with their public half only.
+func (s *TicketSigner) Keys(includeRetired bool) ([]*pb.TicketSigningKey, error) {
+	activeKeyId, _, err := s.signingKey()
+	if err != nil {
+		return nil, err
+	}
+	query := "SELECT " + signingKeyColumns + " FROM ticket_signing_keys"
+	if !includeRetired {
+		query += " WHERE s_retired_at IS NULL"
+	}
+	rows, err := s.db.Query(query + " ORDER BY s_created_at DESC, rowid DESC")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []*pb.TicketSigningKey
+	for rows.Next() {
+		key, err := scanSigningKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		key.Active = key.GetKeyId() == activeKeyId
+		keys = append(keys, key)
+	}
+	return keys, rows.Err()
+}
+
+func (s *TicketSigner) findKey(keyId string) (*pb.TicketSigningKey, error) {
+	key, err := scanSigningKey(s.db.QueryRow("SELECT "+signingKeyColumns+" FROM ticket_signing_keys WHERE s_key_id = ?", keyId))
+	if err != nil {
+		return nil, err
+	}
+	key.Active = key.GetKeyId() == s.keyId
+	return key, nil
+}
+
+func scanSigningKey(row rowScanner) (*pb.TicketSigningKey, error) {
+	var key pb.TicketSigningKey
+	var seed []byte
+	var createdAt, retiredAt int64
+	if err := row.Scan(&key.KeyId, &seed, &createdAt, &retiredAt); err != nil {
+		return nil, err
+	}
+	key.PublicKey = ed25519.NewKeyFromSeed(seed).Public().(ed25519.PublicKey)
+	key.CreatedAt = timestamppb.New(time.Unix(createdAt, 0))
+	if retiredAt != 0 {
+		key.RetiredAt = timestamppb.New(time.Unix(retiredAt, 0))
+	}
+	return &key, nil
+}
+
+// signingKey returns the newest key that is not retired, creating the first
+// key when there is none.
+func (s *TicketSigner) signingKey() (string, ed25519.PrivateKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.key != nil {
+		return s.keyId, s.key, nil
+	}
+
+	var keyId string
+	var seed []byte
+	err := s.db.QueryRow(`SELECT s_key_id, s_private_key FROM ticket_signing_keys WHERE s_retired_at IS NULL
+		ORDER BY s_created_at DESC, rowid DESC LIMIT 1`).Scan(&keyId, &seed)
+	if err == sql.ErrNoRows {
+		keyId, seed, err = createTicketSigningKey(s.db)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+	s.keyId, s.key = keyId, ed25519.NewKeyFromSeed(seed)
+	return s.keyId, s.key, nil
+}
+
+// createTicketSigningKey stores a new key; only its seed is kept
+func createTicketSigningKey(db dbExecutor) (string, []byte, error) {
+	seed := make([]byte, ed25519.SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return "", nil, err
+	}
+	id := make([]byte, 4)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	keyId := hex.EncodeToString(id)
+	_, err := db.Exec("INSERT INTO ticket_signing_keys (s_key_id, s_private_key, s_created_at) VALUES (?, ?, ?)",
+		keyId, seed, time.Now().Unix())
+	return keyId, seed, err
+}
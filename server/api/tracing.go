@@ -0,0 +1,59 @@
+package api
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	otelcodes "go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"context"
+)
+
+// tracer is looked up through the global provider on every use, so spans go to
+// whichever provider the server or a test installed last.
+func tracer() trace.Tracer {
+	return otel.Tracer("ticket-booking-app/server/api")
+}
+
+// allocateSeat allocates a seat in a span that records how many seats the
+// allocator had to try before finding a free one.
+func (b *BookingService) allocateSeat(ctx context.Context) (int32, string, error) {
+	_, span := tracer().Start(ctx, "SeatAllocator.AllocateSeat",
+		trace.WithAttributes(attribute.String("seat.strategy", b.seatAllocator.strategy)))
+	defer span.End()
+
+	seat, section, attempts, err := b.seatAllocator.allocateSeat()
+	if err != nil {
+		span.SetStatus(otelcodes.Error, err.Error())
+		return seat, section, err
+	}
+	span.SetAttributes(attribute.Int("seat.attempts", attempts), attribute.String("seat.section", section),
+		attribute.Int("seat.number", int(seat)))
+	return seat, section, nil
+}
+
+// allocateSpecificSeat is allocateSeat for a seat the passenger chose.
+func (b *BookingService) allocateSpecificSeat(ctx context.Context, seat int32, section string) error {
+	_, span := tracer().Start(ctx, "SeatAllocator.AllocateSpecificSeat")
+	defer span.End()
+	span.SetAttributes(attribute.String("seat.section", section), attribute.Int("seat.number", int(seat)))
+
+	err := b.seatAllocator.AllocateSpecificSeat(seat, section)
+	if err != nil {
+		span.SetStatus(otelcodes.Error, err.Error())
+	}
+	return err
+}
+
+// claimSeatHold is allocateSeat for a seat held by HoldSeat.
+func (b *BookingService) claimSeatHold(ctx context.Context, holdId string) (int32, string, error) {
+	_, span := tracer().Start(ctx, "SeatAllocator.ClaimHold")
+	defer span.End()
+
+	seat, section, err := b.seatAllocator.ClaimHold(holdId)
+	if err != nil {
+		span.SetStatus(otelcodes.Error, err.Error())
+		return seat, section, err
+	}
+	span.SetAttributes(attribute.String("seat.section", section), attribute.Int("seat.number", int(seat)))
+	return seat, section, nil
+}
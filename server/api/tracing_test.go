@@ -0,0 +1,90 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+    "ticket-booking-app/telemetry"
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"context"
+	"net"
+	"testing"
+)
+
+func TestShouldTraceRPCsWithSQLAndSeatAllocationSpans(t *testing.T) {
+    exporter := tracetest.NewInMemoryExporter()
+    provider := telemetry.Setup("ticket-booking-test", exporter, 1)
+    t.Cleanup(func() { provider.Shutdown(context.Background()) })
+
+    db := api.OpenInstrumentedDB(":memory:", nil)
+    db.SetMaxOpenConns(1)
+    t.Cleanup(func() { db.Close() })
+    if err := api.MigrateDatabase(db); err != nil {
+        t.Fatalf("Error in migrating database %v ", err)
+    }
+
+    listener := bufconn.Listen(1 << 20)
+    server := grpc.NewServer(grpc.StatsHandler(otelgrpc.NewServerHandler()))
+    pb.RegisterBookingServiceServer(server, api.NewBookingService(db))
+    go server.Serve(listener)
+    t.Cleanup(server.Stop)
+
+    conn, err := grpc.NewClient("passthrough:///bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+        grpc.WithStatsHandler(otelgrpc.NewClientHandler()),
+        grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }))
+    if err != nil {
+        t.Fatalf("Error in connecting to server %v ", err)
+    }
+    t.Cleanup(func() { conn.Close() })
+
+    ctx, root := otel.Tracer("test").Start(context.Background(), "client")
+    _, err = pb.NewBookingServiceClient(conn).CreateBooking(ctx, createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    root.End()
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    provider.ForceFlush(context.Background())
+
+    spans := exporter.GetSpans()
+    byId := map[trace.SpanID]tracetest.SpanStub{}
+    for _, span := range spans {
+        byId[span.SpanContext.SpanID()] = span
+        assert.Equal(t, root.SpanContext().TraceID(), span.SpanContext.TraceID(), "Span %s should continue the client's trace", span.Name)
+    }
+    // parentNames lists the names of the ancestors of span, nearest first
+    parentNames := func(span tracetest.SpanStub) []string {
+        var names []string
+        for parent, ok := byId[span.Parent.SpanID()]; ok; parent, ok = byId[parent.Parent.SpanID()] {
+            names = append(names, parent.Name)
+        }
+        return names
+    }
+
+    var serverSpan, allocation *tracetest.SpanStub
+    statements := map[string]bool{}
+    for i, span := range spans {
+        switch {
+        case span.Name == "booking.BookingService/CreateBooking" && span.SpanKind == trace.SpanKindServer:
+            serverSpan = &spans[i]
+        case span.Name == "SeatAllocator.AllocateSeat":
+            allocation = &spans[i]
+        case span.Name == "SELECT" || span.Name == "INSERT":
+            statements[span.Name] = true
+            assert.Equal(t, []string{"TRANSACTION", "booking.BookingService/CreateBooking", "booking.BookingService/CreateBooking", "client"},
+                parentNames(span), "Statements should be children of the transaction of the RPC")
+        }
+    }
+    if !assert.NotNil(t, serverSpan, "The RPC should have a server span") || !assert.NotNil(t, allocation) {
+        return
+    }
+    assert.Equal(t, serverSpan.SpanContext.SpanID(), allocation.Parent.SpanID(), "Seat allocation should be a child of the RPC")
+    assert.Contains(t, allocation.Attributes, attribute.Int("seat.attempts", 1))
+    assert.True(t, statements["SELECT"] && statements["INSERT"], "Every statement should have a span")
+}
@@ -0,0 +1,361 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"github.com/google/uuid"
+	"github.com/mattn/go-sqlite3"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+const (
+	UserStatusActive  = "ACTIVE"
+	UserStatusDeleted = "DELETED"
+
+	userProfileColumns = "u_id, u_user_fname, u_user_lname, COALESCE(u_user_email, ''), u_email_verified"
+
+	// wrong codes after which a verification code is locked
+	maxVerificationAttempts = 5
+)
+
+// dbExecutor is satisfied by both *sql.DB and *sql.Tx
+type dbExecutor interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+// contextExecutor runs the statements of a dbExecutor with the context of the
+// RPC, so they are traced as its children.
+type contextExecutor struct {
+	ctx context.Context
+	db  *sql.DB
+}
+
+func withContext(ctx context.Context, db *sql.DB) dbExecutor {
+	return contextExecutor{ctx: ctx, db: db}
+}
+
+func (e contextExecutor) Exec(query string, args ...any) (sql.Result, error) {
+	return e.db.ExecContext(e.ctx, query, args...)
+}
+
+func (e contextExecutor) Query(query string, args ...any) (*sql.Rows, error) {
+	return e.db.QueryContext(e.ctx, query, args...)
+}
+
+func (e contextExecutor) QueryRow(query string, args ...any) *sql.Row {
+	return e.db.QueryRowContext(e.ctx, query, args...)
+}
+
+// UserService manages the accounts stored in the users table. Bookings are
+// always owned by a row in this table.
+type UserService struct {
+	bookingService *BookingService
+	db             *sql.DB
+}
+
+func NewUserService(dbInstance *sql.DB, bookingService *BookingService) *UserService {
+	return &UserService{
+		bookingService: bookingService,
+		db:             dbInstance,
+	}
+}
+
+func (u *UserService) RegisterUser(ctx context.Context, req *pb.RegisterUserRequest) (*pb.UserProfile, error) {
+	user := req.GetUser()
+	if user.GetFirstname() == "" || user.GetLastname() == "" || user.GetEmail() == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "First name, last name and email are required")
+	}
+	if !isValidEmail(user.GetEmail()) {
+		return nil, status.Errorf(codes.InvalidArgument, "Email %q is not valid", user.GetEmail())
+	}
+
+	if _, err := findActiveUserIdByEmail(withContext(ctx, u.db), user.GetEmail()); err == nil {
+		return nil, status.Errorf(codes.AlreadyExists, "User with email %s already exists", user.GetEmail())
+	}
+
+	code, err := newVerificationCode()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while generating verification code: %v", err)
+	}
+	userId, err := insertUser(withContext(ctx, u.db), user, code)
+	if isUniqueViolation(err) {
+		return nil, status.Errorf(codes.AlreadyExists, "User with email %s already exists", user.GetEmail())
+	}
+	if err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "Registered new user", "user_id", userId)
+	u.sendVerificationCode(ctx, &pb.User{Id: userId, Firstname: user.GetFirstname(), Lastname: user.GetLastname(),
+		Email: normalizeEmail(user.GetEmail())}, code)
+
+	return u.getUserProfile(ctx, userId)
+}
+
+func (u *UserService) GetUserProfile(ctx context.Context, req *pb.GetUserProfileRequest) (*pb.UserProfile, error) {
+	return u.getUserProfile(ctx, req.GetUserId())
+}
+
+func (u *UserService) UpdateUserProfile(ctx context.Context, req *pb.UpdateUserProfileRequest) (*pb.UserProfile, error) {
+	profile, err := u.getUserProfile(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	email := normalizeEmail(req.GetEmail())
+	emailChanged := email != "" && email != profile.GetEmail()
+	if emailChanged {
+		if _, err := findActiveUserIdByEmail(withContext(ctx, u.db), email); err == nil {
+			return nil, status.Errorf(codes.AlreadyExists, "User with email %s already exists", email)
+		}
+	}
+
+	if req.GetFirstname() != "" {
+		profile.Firstname = req.GetFirstname()
+	}
+	if req.GetLastname() != "" {
+		profile.Lastname = req.GetLastname()
+	}
+	if _, err := u.db.ExecContext(ctx, "UPDATE users SET u_user_fname = ?, u_user_lname = ? WHERE u_id = ?",
+		profile.GetFirstname(), profile.GetLastname(), profile.GetId()); err != nil {
+		return nil, err
+	}
+
+	if emailChanged {
+		code, err := newVerificationCode()
+		if err != nil {
+			return nil, status.Errorf(codes.Internal, "Error while generating verification code: %v", err)
+		}
+		if _, err := u.db.ExecContext(ctx, `UPDATE users SET u_user_email = ?, u_email_verified = 0, u_verification_code = ?,
+			u_verification_attempts = 0 WHERE u_id = ?`, email, code, profile.GetId()); err != nil {
+			if isUniqueViolation(err) {
+				return nil, status.Errorf(codes.AlreadyExists, "User with email %s already exists", email)
+			}
+			return nil, err
+		}
+		slog.InfoContext(ctx, "Email changed", "user_id", profile.GetId())
+		u.sendVerificationCode(ctx, &pb.User{Id: profile.GetId(), Firstname: profile.GetFirstname(), Lastname: profile.GetLastname(),
+			Email: email}, code)
+	}
+
+	return u.getUserProfile(ctx, profile.GetId())
+}
+
+// VerifyEmail counts every attempt before comparing the code, so concurrent
+// guesses can't get past the lock.
+func (u *UserService) VerifyEmail(ctx context.Context, req *pb.VerifyEmailRequest) (*pb.UserProfile, error) {
+	var code sql.NullString
+	var attempts int
+	row := u.db.QueryRowContext(ctx, "SELECT u_verification_code, u_verification_attempts FROM users WHERE u_id = ? AND u_status = ?",
+		req.GetUserId(), UserStatusActive)
+	if err := row.Scan(&code, &attempts); err != nil {
+		return nil, status.Errorf(codes.NotFound, "No user exists with id %s", req.GetUserId())
+	}
+	if !code.Valid {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid verification code")
+	}
+	result, err := u.db.ExecContext(ctx, `UPDATE users SET u_verification_attempts = u_verification_attempts + 1
+		WHERE u_id = ? AND u_verification_attempts < ?`, req.GetUserId(), maxVerificationAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if counted, _ := result.RowsAffected(); counted == 0 {
+		slog.WarnContext(ctx, "Verification code is locked", "user_id", req.GetUserId())
+		return nil, status.Errorf(codes.FailedPrecondition, "Verification code is locked after %d wrong attempts, request a new one",
+			maxVerificationAttempts)
+	}
+	if subtle.ConstantTimeCompare([]byte(code.String), []byte(req.GetVerificationCode())) != 1 {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid verification code")
+	}
+
+	if _, err := u.db.ExecContext(ctx, "UPDATE users SET u_email_verified = 1, u_verification_code = NULL, u_verification_attempts = 0 WHERE u_id = ?",
+		req.GetUserId()); err != nil {
+		return nil, err
+	}
+	return u.getUserProfile(ctx, req.GetUserId())
+}
+
+// ResendVerificationCode replaces the code of an unverified email, which also
+// unlocks it.
+func (u *UserService) ResendVerificationCode(ctx context.Context, req *pb.ResendVerificationCodeRequest) (*pb.UserProfile, error) {
+	profile, err := u.getUserProfile(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+	if profile.GetEmailVerified() {
+		return nil, status.Errorf(codes.FailedPrecondition, "Email %s is already verified", profile.GetEmail())
+	}
+
+	code, err := newVerificationCode()
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while generating verification code: %v", err)
+	}
+	if _, err := u.db.ExecContext(ctx, "UPDATE users SET u_verification_code = ?, u_verification_attempts = 0 WHERE u_id = ?",
+		code, profile.GetId()); err != nil {
+		return nil, err
+	}
+	slog.InfoContext(ctx, "Verification code reissued", "user_id", profile.GetId())
+	u.sendVerificationCode(ctx, &pb.User{Id: profile.GetId(), Firstname: profile.GetFirstname(), Lastname: profile.GetLastname(),
+		Email: profile.GetEmail()}, code)
+	return profile, nil
+}
+
+func (u *UserService) ListMyBookings(ctx context.Context, req *pb.ListMyBookingsRequest) (*pb.BookingListResponse, error) {
+	return u.bookingService.ListMyBookings(ctx, req)
+}
+
+// DeleteAccount cancels bookings that have not departed yet (or have no
+// departure time) and keeps past tickets for reporting, linked to a user row
+// whose personal data has been removed.
+func (u *UserService) DeleteAccount(ctx context.Context, req *pb.DeleteAccountRequest) (*pb.DeleteAccountResponse, error) {
+	profile, err := u.getUserProfile(ctx, req.GetUserId())
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := u.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	tickets, err := queryTickets(tx, "t_user_id = ? AND t_status = ?", profile.GetId(), TicketStatusBooked)
+	if err != nil {
+		return nil, err
+	}
+
+	owner := &pb.User{Id: profile.GetId(), Firstname: profile.GetFirstname(), Lastname: profile.GetLastname(), Email: profile.GetEmail()}
+	now := time.Now().Unix()
+	var cancelled []*pb.BookingDbResponse
+	response := &pb.DeleteAccountResponse{}
+	for _, ticket := range tickets {
+		if ticket.GetDepartureAt() != 0 && ticket.GetDepartureAt() <= now {
+			response.AnonymizedBookings++
+			continue
+		}
+		if err := cancelTicket(ctx, tx, ticket, owner); err != nil {
+			return nil, err
+		}
+		cancelled = append(cancelled, ticket)
+		response.CancelledBookings++
+	}
+
+	// the events of the cancellations above are scrubbed before they are delivered
+	if err := scrubBookingEvents(tx, profile.GetId()); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(`UPDATE users SET u_user_fname = '', u_user_lname = '', u_user_email = NULL,
+		u_verification_code = NULL, u_email_verified = 0, u_status = ? WHERE u_id = ?`, UserStatusDeleted, profile.GetId()); err != nil {
+		return nil, err
+	}
+	// the entry keeps no personal data, only the id of the removed account
+	if err := appendAuditEntry(ctx, tx, AuditActionAccountDelete, profile.GetId(), nil, response); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	for _, ticket := range cancelled {
+		u.bookingService.seatAllocator.DeallocateSeat(ticket.GetSeat(), ticket.GetSection())
+		u.bookingService.metrics.countBooking(BookingOperationCancel)
+	}
+	slog.InfoContext(ctx, "Deleted account", "user_id", profile.GetId(), "cancelled_bookings", response.GetCancelledBookings())
+	return response, nil
+}
+
+// sendVerificationCode emails the code to user. The code is never logged, it
+// only reaches the user through the notification queue.
+func (u *UserService) sendVerificationCode(ctx context.Context, user *pb.User, code string) {
+	queue := u.bookingService.notifications
+	if queue == nil {
+		slog.WarnContext(ctx, "Verification code not sent, notifications are not configured", "user_id", user.GetId())
+		return
+	}
+	notification, err := renderVerificationNotification(user, code)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error in rendering verification email", "user_id", user.GetId(), "error", err)
+		return
+	}
+	queue.Enqueue(notification)
+}
+
+func (u *UserService) getUserProfile(ctx context.Context, userId string) (*pb.UserProfile, error) {
+	var profile pb.UserProfile
+	row := u.db.QueryRowContext(ctx, "SELECT "+userProfileColumns+" FROM users WHERE u_id = ? AND u_status = ?", userId, UserStatusActive)
+	if err := row.Scan(&profile.Id, &profile.Firstname, &profile.Lastname, &profile.Email, &profile.EmailVerified); err != nil {
+		return nil, status.Errorf(codes.NotFound, "No user exists with id %s", userId)
+	}
+	return &profile, nil
+}
+
+// normalizeEmail gives the canonical form under which emails are stored and looked up
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// isValidEmail reports whether email is a bare address, users being told
+// apart by it
+func isValidEmail(email string) bool {
+	address, err := mail.ParseAddress(email)
+	return err == nil && address.Address == strings.TrimSpace(email)
+}
+
+func isUniqueViolation(err error) bool {
+	var sqliteErr sqlite3.Error
+	return errors.As(err, &sqliteErr) && sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique
+}
+
+func findActiveUserIdByEmail(db dbExecutor, email string) (string, error) {
+	var userId string
+	err := db.QueryRow("SELECT u_id FROM users WHERE u_user_email = ? AND u_status = ?", normalizeEmail(email), UserStatusActive).Scan(&userId)
+	return userId, err
+}
+
+// insertUser adds a new active user. An empty verificationCode is stored as NULL.
+func insertUser(db dbExecutor, user *pb.User, verificationCode string) (string, error) {
+	userId := uuid.NewString()
+	_, err := db.Exec(`INSERT INTO users (u_id, u_user_fname, u_user_lname, u_user_email, u_status, u_verification_code, u_created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)`, userId, user.GetFirstname(), user.GetLastname(), normalizeEmail(user.GetEmail()), UserStatusActive,
+		sql.NullString{String: verificationCode, Valid: verificationCode != ""}, time.Now().Unix())
+	return userId, err
+}
+
+// queryTickets returns the tickets matching the given WHERE clause
+func queryTickets(db dbExecutor, where string, args ...any) ([]*pb.BookingDbResponse, error) {
+	rows, err := db.Query("SELECT "+ticketColumns+" FROM tickets WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tickets []*pb.BookingDbResponse
+	for rows.Next() {
+		var ticket pb.BookingDbResponse
+		if err := scanTicket(rows, &ticket); err != nil {
+			return nil, err
+		}
+		tickets = append(tickets, &ticket)
+	}
+	return tickets, rows.Err()
+}
+
+func newVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
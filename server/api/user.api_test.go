@@ -0,0 +1,170 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+	"ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"database/sql"
+	"regexp"
+	"testing"
+	"context"
+	"time"
+)
+
+func TestShouldRegisterAndRetrieveUser(t *testing.T) {
+	userService, _, _ := newTestUserService(t)
+
+	registered, err := userService.RegisterUser(context.TODO(), &pb.RegisterUserRequest{User: &pb.User{Firstname: FIRST_NAME, Lastname: LAST_NAME, Email: EMAIL}})
+	if err != nil {
+		t.Fatalf("Error in registering user %v ", err)
+	}
+	assert.False(t, registered.GetEmailVerified())
+
+	got, err := userService.GetUserProfile(context.TODO(), &pb.GetUserProfileRequest{UserId: registered.GetId()})
+	if err != nil {
+		t.Fatalf("Error in retrieving user %v ", err)
+	}
+	assert.Equal(t, EMAIL, got.GetEmail())
+
+	_, err = userService.RegisterUser(context.TODO(), &pb.RegisterUserRequest{User: &pb.User{Firstname: FIRST_NAME, Lastname: LAST_NAME, Email: EMAIL}})
+	assert.Equal(t, codes.AlreadyExists, status.Code(err), "Duplicate email should be rejected")
+}
+
+func TestShouldReverifyChangedEmail(t *testing.T) {
+	userService, _, db := newTestUserService(t)
+	registered := registerTestUser(t, userService)
+
+	updated, err := userService.UpdateUserProfile(context.TODO(), &pb.UpdateUserProfileRequest{UserId: registered.GetId(), Email: "changed@test.com"})
+	if err != nil {
+		t.Fatalf("Error in updating user %v ", err)
+	}
+	assert.Equal(t, "changed@test.com", updated.GetEmail())
+	assert.False(t, updated.GetEmailVerified(), "Changed email must be verified again")
+
+	_, err = userService.VerifyEmail(context.TODO(), &pb.VerifyEmailRequest{UserId: registered.GetId(), VerificationCode: "wrong"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	verified, err := userService.VerifyEmail(context.TODO(), &pb.VerifyEmailRequest{UserId: registered.GetId(), VerificationCode: verificationCode(t, db, registered.GetId())})
+	if err != nil {
+		t.Fatalf("Error in verifying email %v ", err)
+	}
+	assert.True(t, verified.GetEmailVerified())
+}
+
+func TestShouldCancelFutureBookingsOnAccountDeletion(t *testing.T) {
+	userService, bookingService, db := newTestUserService(t)
+	registered := registerTestUser(t, userService)
+
+	future := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+	future.Departure = timestamppb.New(time.Now().Add(24 * time.Hour))
+	past := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)
+	past.To = "Paris"
+	past.Departure = timestamppb.New(time.Now().Add(-24 * time.Hour))
+	for _, request := range []*pb.BookingRequest{future, past} {
+		if _, err := bookingService.CreateBooking(context.TODO(), request); err != nil {
+			t.Fatalf("Error in creating booking %v ", err)
+		}
+	}
+
+	myBookings, err := userService.ListMyBookings(context.TODO(), &pb.ListMyBookingsRequest{UserId: registered.GetId()})
+	if err != nil {
+		t.Fatalf("Error in listing bookings %v ", err)
+	}
+	assert.Len(t, myBookings.GetBookings(), 2)
+
+	deleted, err := userService.DeleteAccount(context.TODO(), &pb.DeleteAccountRequest{UserId: registered.GetId()})
+	if err != nil {
+		t.Fatalf("Error in deleting account %v ", err)
+	}
+	assert.Equal(t, int32(1), deleted.GetCancelledBookings())
+	assert.Equal(t, int32(1), deleted.GetAnonymizedBookings())
+
+	_, err = userService.GetUserProfile(context.TODO(), &pb.GetUserProfileRequest{UserId: registered.GetId()})
+	assert.Equal(t, codes.NotFound, status.Code(err), "Deleted account should not be found")
+
+	var email sql.NullString
+	db.QueryRow("SELECT u_user_email FROM users WHERE u_id = ?", registered.GetId()).Scan(&email)
+	assert.False(t, email.Valid, "Deleted account should not keep the email")
+	assert.Equal(t, 3, countRows(t, db, "SELECT COUNT(*) FROM outbox_events"), "Events should be kept for their deliveries")
+	assert.Zero(t, countRows(t, db, "SELECT COUNT(*) FROM outbox_events WHERE INSTR(e_payload, ?) > 0 OR INSTR(e_payload, ?) > 0", EMAIL, LAST_NAME),
+		"Events should not keep the passenger's details")
+}
+
+func newTestUserService(t *testing.T) (*api.UserService, *api.BookingService, *sql.DB) {
+	db := newTestDatabase(t)
+	bookingService := api.NewBookingService(db)
+	return api.NewUserService(db, bookingService), bookingService, db
+}
+
+func registerTestUser(t *testing.T, userService *api.UserService) *pb.UserProfile {
+	registered, err := userService.RegisterUser(context.TODO(), &pb.RegisterUserRequest{User: &pb.User{Firstname: FIRST_NAME, Lastname: LAST_NAME, Email: EMAIL}})
+	if err != nil {
+		t.Fatalf("Error in registering user %v ", err)
+	}
+	return registered
+}
+
+func verificationCode(t *testing.T, db *sql.DB, userId string) string {
+	var code string
+	if err := db.QueryRow("SELECT u_verification_code FROM users WHERE u_id = ?", userId).Scan(&code); err != nil {
+		t.Fatalf("Error in reading verification code %v ", err)
+	}
+	return code
+}
+
+// recordingNotifier hands the sent notifications to the test
+type recordingNotifier chan *api.Notification
+
+func (r recordingNotifier) Send(ctx context.Context, notification *api.Notification) error {
+	r <- notification
+	return nil
+}
+
+func emailedVerificationCode(t *testing.T, sent recordingNotifier) string {
+	t.Helper()
+	select {
+	case notification := <-sent:
+		assert.NotContains(t, notification.Subject, "code is", "The subject is logged and must not carry the code")
+		code := regexp.MustCompile(`\d{6}`).FindString(notification.Text)
+		if code == "" {
+			t.Fatalf("Error in reading verification code from %q ", notification.Text)
+		}
+		return code
+	case <-time.After(5 * time.Second):
+		t.Fatalf("Error in waiting for verification email")
+		return ""
+	}
+}
+
+func TestShouldEmailVerificationCodeAndLockItAfterWrongAttempts(t *testing.T) {
+	userService, bookingService, _ := newTestUserService(t)
+	sent := make(recordingNotifier, 10)
+	queue := api.NewNotificationQueue(sent, 10)
+	queue.Start(1)
+	t.Cleanup(queue.Close)
+	bookingService.SetNotificationQueue(queue)
+
+	registered := registerTestUser(t, userService)
+	code := emailedVerificationCode(t, sent)
+	for i := 0; i < 5; i++ {
+		_, err := userService.VerifyEmail(context.TODO(), &pb.VerifyEmailRequest{UserId: registered.GetId(), VerificationCode: "000000"})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	}
+	_, err := userService.VerifyEmail(context.TODO(), &pb.VerifyEmailRequest{UserId: registered.GetId(), VerificationCode: code})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "The code should be locked after 5 wrong attempts")
+
+	if _, err := userService.ResendVerificationCode(context.TODO(), &pb.ResendVerificationCodeRequest{UserId: registered.GetId()}); err != nil {
+		t.Fatalf("Error in resending verification code %v ", err)
+	}
+	verified, err := userService.VerifyEmail(context.TODO(), &pb.VerifyEmailRequest{UserId: registered.GetId(),
+		VerificationCode: emailedVerificationCode(t, sent)})
+	if err != nil {
+		t.Fatalf("Error in verifying email %v ", err)
+	}
+	assert.True(t, verified.GetEmailVerified())
+	_, err = userService.ResendVerificationCode(context.TODO(), &pb.ResendVerificationCodeRequest{UserId: registered.GetId()})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err), "Verified emails need no code")
+}
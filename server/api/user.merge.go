@@ -0,0 +1,76 @@
+package api
+
+import (
+	"database/sql"
+)
+
+// MergedUser describes one group of users that shared a normalized email.
+type MergedUser struct {
+	Email        string
+	SurvivorId   string
+	MergedIds    []string
+	TicketsMoved int64
+}
+
+// MergeDuplicateUsers finds users whose emails are equal once normalized and
+// merges each group into its oldest row: tickets are reassigned to the
+// survivor, the other rows are removed and the survivor keeps the normalized
+// email. Nothing is written when dryRun is set. Only columns from the original
+// schema are used so the merge can run before the unique email migration.
+func MergeDuplicateUsers(db *sql.DB, dryRun bool) ([]*MergedUser, error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT u_id, LOWER(TRIM(u_user_email)) FROM users
+		WHERE LOWER(TRIM(u_user_email)) IN (SELECT LOWER(TRIM(u_user_email)) FROM users
+			WHERE u_user_email IS NOT NULL AND TRIM(u_user_email) <> '' GROUP BY LOWER(TRIM(u_user_email)) HAVING COUNT(*) > 1)
+		ORDER BY LOWER(TRIM(u_user_email)), rowid`)
+	if err != nil {
+		return nil, err
+	}
+
+	var merged []*MergedUser
+	for rows.Next() {
+		var userId, email string
+		if err := rows.Scan(&userId, &email); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		if len(merged) == 0 || merged[len(merged)-1].Email != email {
+			merged = append(merged, &MergedUser{Email: email, SurvivorId: userId})
+			continue
+		}
+		group := merged[len(merged)-1]
+		group.MergedIds = append(group.MergedIds, userId)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, group := range merged {
+		for _, duplicateId := range group.MergedIds {
+			result, err := tx.Exec("UPDATE tickets SET t_user_id = ? WHERE t_user_id = ?", group.SurvivorId, duplicateId)
+			if err != nil {
+				return nil, err
+			}
+			moved, _ := result.RowsAffected()
+			group.TicketsMoved += moved
+
+			if _, err := tx.Exec("DELETE FROM users WHERE u_id = ?", duplicateId); err != nil {
+				return nil, err
+			}
+		}
+		if _, err := tx.Exec("UPDATE users SET u_user_email = ? WHERE u_id = ?", group.Email, group.SurvivorId); err != nil {
+			return nil, err
+		}
+	}
+
+	if dryRun {
+		return merged, nil
+	}
+	return merged, tx.Commit()
+}
@@ -0,0 +1,140 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+	"ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"database/sql"
+	"testing"
+	"fmt"
+	"context"
+)
+
+func TestShouldMergeUsersWithSameEmail(t *testing.T) {
+	db := newLegacyTestDatabase(t)
+	mustExec(t, db, "INSERT INTO users VALUES ('u1', 'test', 'user', 'Dup@Test.com'), ('u2', 'tset', 'user', 'dup@test.com '), ('u3', 'other', 'user', 'other@test.com')")
+	mustExec(t, db, "INSERT INTO tickets VALUES ('t1', 'London', 'France', 20, 1, 'A', 'u1'), ('t2', 'London', 'Paris', 20, 2, 'A', 'u2')")
+
+	assert.Error(t, api.MigrateDatabase(db), "Unique email migration should refuse duplicate users")
+
+	dryRun, err := api.MergeDuplicateUsers(db, true)
+	if err != nil {
+		t.Fatalf("Error in merging users %v ", err)
+	}
+	assert.Len(t, dryRun, 1)
+	assert.Equal(t, 2, countRows(t, db, "SELECT COUNT(*) FROM users WHERE LOWER(TRIM(u_user_email)) = 'dup@test.com'"), "Dry run should not change data")
+
+	merged, err := api.MergeDuplicateUsers(db, false)
+	if err != nil {
+		t.Fatalf("Error in merging users %v ", err)
+	}
+	assert.Equal(t, "u1", merged[0].SurvivorId)
+	assert.Equal(t, []string{"u2"}, merged[0].MergedIds)
+	assert.Equal(t, int64(1), merged[0].TicketsMoved)
+	assert.Equal(t, 2, countRows(t, db, "SELECT COUNT(*) FROM tickets WHERE t_user_id = 'u1'"))
+
+	if err := api.MigrateDatabase(db); err != nil {
+		t.Fatalf("Error in migrating database %v ", err)
+	}
+}
+
+func TestShouldNotMergeUsersWithoutEmail(t *testing.T) {
+	db := newLegacyTestDatabase(t)
+	mustExec(t, db, "INSERT INTO users VALUES ('u1', 'alice', 'user', ''), ('u2', 'bob', 'user', '  ')")
+
+	merged, err := api.MergeDuplicateUsers(db, false)
+	if err != nil {
+		t.Fatalf("Error in merging users %v ", err)
+	}
+	assert.Empty(t, merged, "Users without an email aren't the same user")
+	if err := api.MigrateDatabase(db); err != nil {
+		t.Fatalf("Error in migrating database %v ", err)
+	}
+	assert.Equal(t, 2, countRows(t, db, "SELECT COUNT(*) FROM users WHERE u_user_email IS NULL"))
+}
+
+func TestShouldRejectBookingsWithoutValidEmail(t *testing.T) {
+	db := newTestDatabase(t)
+	bookingService := api.NewBookingService(db)
+	if _, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest("Alice", LAST_NAME, EMAIL)); err != nil {
+		t.Fatalf("Error in creating booking %v ", err)
+	}
+
+	for _, email := range []string{"", "  ", "bob", "Bob <bob@test.com>"} {
+		request := createNewTrainBookingRequest("Bob", LAST_NAME, email)
+		request.To = "Paris"
+		_, err := bookingService.CreateBooking(context.TODO(), request)
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), "Email %q should be rejected", email)
+	}
+	assert.Equal(t, 1, countRows(t, db, "SELECT COUNT(*) FROM tickets"))
+}
+
+func TestShouldBackfillPnrsOfLegacyTickets(t *testing.T) {
+	db := newLegacyTestDatabase(t)
+	mustExec(t, db, "INSERT INTO users VALUES ('u1', 'test', 'user', 'test@test.com')")
+	for i := 0; i < 50; i++ {
+		mustExec(t, db, "INSERT INTO tickets VALUES (?, 'London', 'France', 20, ?, 'A', 'u1')", fmt.Sprintf("t%d", i), i)
+	}
+
+	if err := api.MigrateDatabase(db); err != nil {
+		t.Fatalf("Error in migrating database %v ", err)
+	}
+	assert.Equal(t, 50, countRows(t, db, "SELECT COUNT(DISTINCT t_pnr) FROM tickets"), "Every ticket should get its own PNR")
+	assert.Zero(t, countRows(t, db, "SELECT COUNT(*) FROM tickets WHERE LENGTH(t_pnr) != 6 OR t_pnr GLOB '*[01IO]*'"),
+		"PNRs should be drawn from the PNR alphabet")
+}
+
+func TestShouldIdentifyUsersByNormalizedEmail(t *testing.T) {
+	db := newTestDatabase(t)
+	bookingService := api.NewBookingService(db)
+
+	first := createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, " TestEmail@Test.com")
+	second := createNewTrainBookingRequest("typo", LAST_NAME, "testemail@test.COM")
+	second.To = "Paris"
+	for _, request := range []*pb.BookingRequest{first, second} {
+		if _, err := bookingService.CreateBooking(context.TODO(), request); err != nil {
+			t.Fatalf("Error in creating booking %v ", err)
+		}
+	}
+	assert.Equal(t, 1, countRows(t, db, "SELECT COUNT(*) FROM users"), "Bookings with the same email should share one user")
+
+	got, err := bookingService.ListMyBookings(context.TODO(), &pb.ListMyBookingsRequest{User: &pb.User{Email: "TESTEMAIL@test.com"}})
+	if err != nil {
+		t.Fatalf("Error in retrieving bookings %v ", err)
+	}
+	assert.Len(t, got.GetBookings(), 2)
+	assert.Equal(t, FIRST_NAME, got.GetBookings()[1].GetUser().GetFirstname())
+	assert.Equal(t, EMAIL, got.GetBookings()[1].GetUser().GetEmail())
+}
+
+// newLegacyTestDatabase creates the tables as they were before schema migrations existed
+func newLegacyTestDatabase(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatalf("Error in opening database %v ", err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	mustExec(t, db, "CREATE TABLE tickets (t_id TEXT PRIMARY KEY, t_from TEXT, t_to TEXT, t_price INTEGER, t_seat INTEGER, t_section TEXT, t_user_id TEXT)")
+	mustExec(t, db, "CREATE TABLE users (u_id TEXT PRIMARY KEY, u_user_fname TEXT, u_user_lname TEXT, u_user_email TEXT)")
+	return db
+}
+
+func mustExec(t *testing.T, db *sql.DB, query string, args ...any) {
+	t.Helper()
+	if _, err := db.Exec(query, args...); err != nil {
+		t.Fatalf("Error in executing %s: %v ", query, err)
+	}
+}
+
+func countRows(t *testing.T, db *sql.DB, query string, args ...any) int {
+	t.Helper()
+	var count int
+	if err := db.QueryRow(query, args...).Scan(&count); err != nil {
+		t.Fatalf("Error in executing %s: %v ", query, err)
+	}
+	return count
+}
@@ -0,0 +1,275 @@
+package api
+
+import (
+	pb "ticket-booking-app/domain"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"github.com/google/uuid"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"context"
+	"net/url"
+	"strings"
+	"slices"
+	"time"
+	"net"
+	"net/netip"
+)
+
+const (
+	AuditActionWebhookRegister = "webhook.register"
+	AuditActionWebhookDelete   = "webhook.delete"
+	AuditActionWebhookReplay   = "webhook.replay"
+)
+
+var bookingEventTypes = map[string]bool{
+	BookingEventCreated:      true,
+	BookingEventSeatModified: true,
+	BookingEventCancelled:    true,
+}
+
+// WebhookService manages the endpoints booking events are delivered to by the
+// WebhookDispatcher. Events carry the passengers' details, so only services
+// with a client certificate may manage webhooks.
+type WebhookService struct {
+	db        *sql.DB
+	urlPolicy WebhookURLPolicy
+}
+
+// WebhookURLPolicy decides which urls webhooks may be registered for. By
+// default only https urls of hosts with public addresses are accepted, so
+// webhooks can't reach into the server's own network.
+type WebhookURLPolicy struct {
+	// AllowedHosts, when not empty, are the only hosts webhooks may point to.
+	// They aren't checked for public addresses.
+	AllowedHosts []string
+	// AllowPrivate accepts http urls and hosts with loopback or private
+	// addresses, for development
+	AllowPrivate bool
+}
+
+func NewWebhookService(dbInstance *sql.DB) *WebhookService {
+	return &WebhookService{
+		db: dbInstance,
+	}
+}
+
+// SetURLPolicy replaces the default policy for the urls of new webhooks.
+func (w *WebhookService) SetURLPolicy(policy WebhookURLPolicy) {
+	w.urlPolicy = policy
+}
+
+func (w *WebhookService) RegisterWebhook(ctx context.Context, req *pb.RegisterWebhookRequest) (*pb.Webhook, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	endpoint, err := url.Parse(req.GetUrl())
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") || endpoint.Host == "" {
+		return nil, status.Errorf(codes.InvalidArgument, "Webhook url must be an absolute http or https url")
+	}
+	if err := w.urlPolicy.check(ctx, endpoint); err != nil {
+		return nil, err
+	}
+	for _, eventType := range req.GetEventTypes() {
+		if !bookingEventTypes[eventType] {
+			return nil, status.Errorf(codes.InvalidArgument, "Unknown event type %s", eventType)
+		}
+	}
+
+	secret := req.GetSecret()
+	if secret == "" {
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return nil, status.Errorf(codes.Internal, "Error while generating webhook secret: %v", err)
+		}
+		secret = hex.EncodeToString(random)
+	}
+
+	webhook := &pb.Webhook{
+		Id:         uuid.NewString(),
+		Url:        endpoint.String(),
+		EventTypes: req.GetEventTypes(),
+		Secret:     secret,
+		CreatedAt:  timestamppb.New(time.Unix(time.Now().Unix(), 0)),
+	}
+
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("INSERT INTO webhooks (w_id, w_url, w_secret, w_event_types, w_created_at) VALUES (?, ?, ?, ?, ?)",
+		webhook.GetId(), webhook.GetUrl(), secret, strings.Join(webhook.GetEventTypes(), ","), webhook.GetCreatedAt().GetSeconds()); err != nil {
+		return nil, err
+	}
+	// the secret stays out of the audit log
+	audited := &pb.Webhook{Id: webhook.GetId(), Url: webhook.GetUrl(), EventTypes: webhook.GetEventTypes(), CreatedAt: webhook.GetCreatedAt()}
+	if err := appendAuditEntry(ctx, tx, AuditActionWebhookRegister, webhook.GetId(), nil, audited); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+// check rejects endpoints the policy doesn't allow. Hosts are resolved, so
+// names pointing at private addresses are rejected too.
+func (p WebhookURLPolicy) check(ctx context.Context, endpoint *url.URL) error {
+	host := strings.ToLower(endpoint.Hostname())
+	if len(p.AllowedHosts) > 0 {
+		if !slices.ContainsFunc(p.AllowedHosts, func(allowed string) bool { return strings.EqualFold(allowed, host) }) {
+			return status.Errorf(codes.PermissionDenied, "Webhooks may not point to %s", host)
+		}
+		return nil
+	}
+	if p.AllowPrivate {
+		return nil
+	}
+	if endpoint.Scheme != "https" {
+		return status.Errorf(codes.InvalidArgument, "Webhook url must be an https url")
+	}
+	addresses, err := net.DefaultResolver.LookupNetIP(ctx, "ip", host)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "Webhook host %s can't be resolved", host)
+	}
+	for _, address := range addresses {
+		if !isPublicAddress(address) {
+			return status.Errorf(codes.PermissionDenied, "Webhooks may not point to %s, it has a private address", host)
+		}
+	}
+	return nil
+}
+
+// isPublicAddress reports whether address is outside of loopback, link-local
+// and private networks
+func isPublicAddress(address netip.Addr) bool {
+	address = address.Unmap()
+	return address.IsGlobalUnicast() && !address.IsPrivate()
+}
+
+// DeleteWebhook deactivates a webhook. Its deliveries are kept and no longer attempted.
+func (w *WebhookService) DeleteWebhook(ctx context.Context, req *pb.DeleteWebhookRequest) (*pb.DeleteWebhookResponse, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE webhooks SET w_active = 0 WHERE w_id = ? AND w_active = 1", req.GetWebhookId())
+	if err != nil {
+		return nil, err
+	}
+	if deleted, _ := result.RowsAffected(); deleted == 0 {
+		return nil, status.Errorf(codes.NotFound, "No webhook exists with id %s", req.GetWebhookId())
+	}
+	if err := appendAuditEntry(ctx, tx, AuditActionWebhookDelete, req.GetWebhookId(), nil, nil); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return &pb.DeleteWebhookResponse{}, nil
+}
+
+func (w *WebhookService) ListWebhookDeliveries(ctx context.Context, req *pb.ListWebhookDeliveriesRequest) (*pb.ListWebhookDeliveriesResponse, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	where := []string{"1 = 1"}
+	var args []any
+	if req.GetWebhookId() != "" {
+		where = append(where, "d_webhook_id = ?")
+		args = append(args, req.GetWebhookId())
+	}
+	if req.GetStatus() != "" {
+		where = append(where, "d_status = ?")
+		args = append(args, req.GetStatus())
+	}
+	if req.GetEventId() != "" {
+		where = append(where, "e_id = ?")
+		args = append(args, req.GetEventId())
+	}
+
+	rows, err := w.db.QueryContext(ctx, `SELECT d_id, d_webhook_id, e_id, e_type, d_status, d_attempts, COALESCE(d_last_error, ''),
+		d_next_attempt_at, COALESCE(d_delivered_at, 0) FROM webhook_deliveries JOIN outbox_events ON e_seq = d_event_seq
+		WHERE `+strings.Join(where, " AND ")+" ORDER BY e_seq, d_webhook_id", args...)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while reading webhook deliveries: %v", err)
+	}
+	defer rows.Close()
+
+	response := &pb.ListWebhookDeliveriesResponse{}
+	for rows.Next() {
+		var delivery pb.WebhookDelivery
+		var nextAttemptAt, deliveredAt int64
+		if err := rows.Scan(&delivery.Id, &delivery.WebhookId, &delivery.EventId, &delivery.EventType, &delivery.Status,
+			&delivery.Attempts, &delivery.LastError, &nextAttemptAt, &deliveredAt); err != nil {
+			return nil, status.Errorf(codes.Internal, "Error while reading webhook deliveries: %v", err)
+		}
+		if delivery.GetStatus() == DeliveryStatusPending {
+			delivery.NextAttemptAt = timestamppb.New(time.Unix(nextAttemptAt, 0))
+		}
+		if deliveredAt != 0 {
+			delivery.DeliveredAt = timestamppb.New(time.Unix(deliveredAt, 0))
+		}
+		response.Deliveries = append(response.Deliveries, &delivery)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, status.Errorf(codes.Internal, "Error while reading webhook deliveries: %v", err)
+	}
+	return response, nil
+}
+
+// ReplayWebhookDeliveries queues deliveries again with a fresh attempt budget.
+// Explicit ids may refer to delivered events, which are then sent once more.
+func (w *WebhookService) ReplayWebhookDeliveries(ctx context.Context, req *pb.ReplayWebhookDeliveriesRequest) (*pb.ReplayWebhookDeliveriesResponse, error) {
+	if err := requireServiceActor(ctx); err != nil {
+		return nil, err
+	}
+	var where string
+	var args []any
+	switch {
+	case len(req.GetDeliveryIds()) > 0:
+		where = "d_id IN (?" + strings.Repeat(", ?", len(req.GetDeliveryIds())-1) + ")"
+		for _, id := range req.GetDeliveryIds() {
+			args = append(args, id)
+		}
+	case req.GetWebhookId() != "":
+		where = "d_webhook_id = ? AND d_status = ?"
+		args = append(args, req.GetWebhookId(), DeliveryStatusDead)
+	default:
+		return nil, status.Errorf(codes.InvalidArgument, "Delivery ids or a webhook id are required")
+	}
+
+	tx, err := w.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.Exec("UPDATE webhook_deliveries SET d_status = ?, d_attempts = 0, d_next_attempt_at = ? WHERE "+where,
+		append([]any{DeliveryStatusPending, time.Now().Unix()}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	replayed, _ := result.RowsAffected()
+	response := &pb.ReplayWebhookDeliveriesResponse{Replayed: int32(replayed)}
+	resource := req.GetWebhookId()
+	if resource == "" {
+		resource = "webhook_deliveries"
+	}
+	if err := appendAuditEntry(ctx, tx, AuditActionWebhookReplay, resource, req, response); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
@@ -0,0 +1,308 @@
+package api
+
+import (
+	"ticket-booking-app/backoff"
+	"github.com/google/uuid"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"syscall"
+	"strings"
+	"time"
+)
+
+const (
+	// WebhookSignatureHeader holds "t=<unix seconds>,v1=<hex HMAC-SHA256 of "<t>.<body>">"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookDeliveryHeader  = "X-Webhook-Delivery"
+
+	DeliveryStatusPending   = "PENDING"
+	DeliveryStatusDelivered = "DELIVERED"
+	DeliveryStatusDead      = "DEAD"
+
+	dispatchBatchSize = 100
+)
+
+// WebhookDispatcher moves booking events from the outbox to the registered
+// webhooks. Every event gets one delivery per matching webhook; failed
+// deliveries are retried with exponential backoff and marked dead once
+// MaxAttempts is reached, after which they can only be replayed.
+type WebhookDispatcher struct {
+	db     *sql.DB
+	client *http.Client
+
+	PollInterval time.Duration
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	MaxBackoff   time.Duration
+}
+
+// NewWebhookDispatcher delivers with client, NewWebhookClient of the default
+// policy when nil.
+func NewWebhookDispatcher(dbInstance *sql.DB, client *http.Client) *WebhookDispatcher {
+	if client == nil {
+		client = NewWebhookClient(WebhookURLPolicy{})
+	}
+	return &WebhookDispatcher{
+		db:           dbInstance,
+		client:       client,
+		PollInterval: time.Second,
+		MaxAttempts:  8,
+		BaseBackoff:  30 * time.Second,
+		MaxBackoff:   time.Hour,
+	}
+}
+
+// NewWebhookClient returns the client that delivers to the webhooks allowed by
+// policy. Redirects aren't followed. Unless the policy allows private
+// addresses or lists the allowed hosts, every connection is checked once the
+// host was resolved, as a host may resolve elsewhere than at registration.
+func NewWebhookClient(policy WebhookURLPolicy) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if !policy.AllowPrivate && len(policy.AllowedHosts) == 0 {
+		dialer := &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second, Control: dialPublicAddress}
+		transport.DialContext = dialer.DialContext
+		// the proxy would be checked instead of the webhook
+		transport.Proxy = nil
+	}
+	return &http.Client{
+		Timeout:       10 * time.Second,
+		Transport:     transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+}
+
+// dialPublicAddress refuses to connect to addresses webhooks may not point to
+func dialPublicAddress(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !isPublicAddress(addrPort.Addr()) {
+		return fmt.Errorf("webhooks may not connect to the private address %s", addrPort.Addr())
+	}
+	return nil
+}
+
+// Run dispatches until ctx is done.
+func (d *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.PollInterval)
+	defer ticker.Stop()
+	for {
+		if err := d.DispatchOnce(ctx); err != nil {
+			slog.ErrorContext(ctx, "Error in dispatching webhooks", "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DispatchOnce fans new outbox events out to the webhooks and attempts every
+// delivery that is due.
+func (d *WebhookDispatcher) DispatchOnce(ctx context.Context) error {
+	if err := d.fanOutEvents(); err != nil {
+		return err
+	}
+	due, err := d.dueDeliveries()
+	if err != nil {
+		return err
+	}
+	for _, delivery := range due {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		deliveryErr := d.deliver(ctx, delivery)
+		if err := d.recordAttempt(delivery, deliveryErr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type pendingDelivery struct {
+	id        string
+	attempts  int
+	url       string
+	secret    string
+	eventType string
+	payload   string
+}
+
+// fanOutEvents creates the deliveries of events that have not been dispatched
+// yet. Webhooks registered later do not receive older events.
+func (d *WebhookDispatcher) fanOutEvents() error {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	type outboxEvent struct {
+		seq       int64
+		eventType string
+	}
+	rows, err := tx.Query("SELECT e_seq, e_type FROM outbox_events WHERE e_dispatched_at IS NULL ORDER BY e_seq LIMIT ?", dispatchBatchSize)
+	if err != nil {
+		return err
+	}
+	var events []outboxEvent
+	for rows.Next() {
+		var event outboxEvent
+		if err := rows.Scan(&event.seq, &event.eventType); err != nil {
+			rows.Close()
+			return err
+		}
+		events = append(events, event)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil || len(events) == 0 {
+		return err
+	}
+
+	webhooks, err := queryWebhooks(tx, "w_active = 1")
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().Unix()
+	for _, event := range events {
+		for _, webhook := range webhooks {
+			if !webhookAccepts(webhook.eventTypes, event.eventType) {
+				continue
+			}
+			if _, err := tx.Exec(`INSERT OR IGNORE INTO webhook_deliveries (d_id, d_event_seq, d_webhook_id, d_status, d_next_attempt_at)
+				VALUES (?, ?, ?, ?, ?)`, uuid.NewString(), event.seq, webhook.id, DeliveryStatusPending, now); err != nil {
+				return err
+			}
+		}
+		if _, err := tx.Exec("UPDATE outbox_events SET e_dispatched_at = ? WHERE e_seq = ?", now, event.seq); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (d *WebhookDispatcher) dueDeliveries() ([]*pendingDelivery, error) {
+	rows, err := d.db.Query(`SELECT d_id, d_attempts, w_url, w_secret, e_type, e_payload FROM webhook_deliveries
+		JOIN webhooks ON w_id = d_webhook_id JOIN outbox_events ON e_seq = d_event_seq
+		WHERE d_status = ? AND d_next_attempt_at <= ? AND w_active = 1 ORDER BY e_seq LIMIT ?`,
+		DeliveryStatusPending, time.Now().Unix(), dispatchBatchSize)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var due []*pendingDelivery
+	for rows.Next() {
+		var delivery pendingDelivery
+		if err := rows.Scan(&delivery.id, &delivery.attempts, &delivery.url, &delivery.secret, &delivery.eventType, &delivery.payload); err != nil {
+			return nil, err
+		}
+		due = append(due, &delivery)
+	}
+	return due, rows.Err()
+}
+
+func (d *WebhookDispatcher) deliver(ctx context.Context, delivery *pendingDelivery) error {
+	body := []byte(delivery.payload)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookEventHeader, delivery.eventType)
+	req.Header.Set(WebhookDeliveryHeader, delivery.id)
+	req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(delivery.secret, time.Now().Unix(), body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded with %s", resp.Status)
+	}
+	return nil
+}
+
+func (d *WebhookDispatcher) recordAttempt(delivery *pendingDelivery, deliveryErr error) error {
+	now := time.Now()
+	attempts := delivery.attempts + 1
+	if deliveryErr == nil {
+		_, err := d.db.Exec("UPDATE webhook_deliveries SET d_status = ?, d_attempts = ?, d_last_error = NULL, d_delivered_at = ? WHERE d_id = ?",
+			DeliveryStatusDelivered, attempts, now.Unix(), delivery.id)
+		return err
+	}
+
+	deliveryStatus := DeliveryStatusPending
+	if attempts >= d.MaxAttempts {
+		deliveryStatus = DeliveryStatusDead
+		slog.Warn("Webhook delivery was dead-lettered", "delivery_id", delivery.id, "url", delivery.url, "attempts", attempts, "error", deliveryErr)
+	}
+	_, err := d.db.Exec("UPDATE webhook_deliveries SET d_status = ?, d_attempts = ?, d_last_error = ?, d_next_attempt_at = ? WHERE d_id = ?",
+		deliveryStatus, attempts, deliveryErr.Error(), now.Add(backoff.Exponential(d.BaseBackoff, d.MaxBackoff, attempts)).Unix(), delivery.id)
+	return err
+}
+
+// SignWebhookPayload returns the value of the signature header for body sent
+// at timestamp. Receivers recompute it with their secret to authenticate calls.
+func SignWebhookPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10) + "."))
+	mac.Write(body)
+	return "t=" + strconv.FormatInt(timestamp, 10) + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func webhookAccepts(eventTypes []string, eventType string) bool {
+	if len(eventTypes) == 0 {
+		return true
+	}
+	for _, accepted := range eventTypes {
+		if accepted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+type registeredWebhook struct {
+	id         string
+	eventTypes []string
+}
+
+func queryWebhooks(db dbExecutor, where string, args ...any) ([]*registeredWebhook, error) {
+	rows, err := db.Query("SELECT w_id, w_event_types FROM webhooks WHERE "+where, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var webhooks []*registeredWebhook
+	for rows.Next() {
+		var webhook registeredWebhook
+		var eventTypes string
+		if err := rows.Scan(&webhook.id, &eventTypes); err != nil {
+			return nil, err
+		}
+		if eventTypes != "" {
+			webhook.eventTypes = strings.Split(eventTypes, ",")
+		}
+		webhooks = append(webhooks, &webhook)
+	}
+	return webhooks, rows.Err()
+}
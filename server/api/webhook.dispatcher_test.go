@@ -0,0 +1,221 @@
+package api_test
+
+import (
+	pb "ticket-booking-app/domain"
+    "ticket-booking-app/server/api"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"net/http/httptest"
+	"net/http"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"sync"
+	"io"
+)
+
+type webhookReceiver struct {
+    mu     sync.Mutex
+    secret string
+    status int
+    events []*pb.BookingEvent
+}
+
+func (r *webhookReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    body, _ := io.ReadAll(req.Body)
+    signature := req.Header.Get(api.WebhookSignatureHeader)
+    timestamp, _ := strconv.ParseInt(strings.TrimPrefix(strings.Split(signature, ",")[0], "t="), 10, 64)
+    if signature != api.SignWebhookPayload(r.secret, timestamp, body) {
+        w.WriteHeader(http.StatusUnauthorized)
+        return
+    }
+    if r.status != http.StatusOK {
+        w.WriteHeader(r.status)
+        return
+    }
+    var event pb.BookingEvent
+    if err := protojson.Unmarshal(body, &event); err != nil || event.GetType() != req.Header.Get(api.WebhookEventHeader) {
+        w.WriteHeader(http.StatusBadRequest)
+        return
+    }
+    r.events = append(r.events, &event)
+}
+
+func (r *webhookReceiver) eventTypes() []string {
+    r.mu.Lock()
+    defer r.mu.Unlock()
+    var types []string
+    for _, event := range r.events {
+        types = append(types, event.GetType())
+    }
+    return types
+}
+
+func TestShouldDeliverSignedBookingEvents(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    webhookService := api.NewWebhookService(db)
+    webhookService.SetURLPolicy(api.WebhookURLPolicy{AllowPrivate: true})
+    ctx := api.WithActor(context.TODO(), "service:partner")
+    receiver := &webhookReceiver{status: http.StatusOK}
+    endpoint := httptest.NewServer(receiver)
+    defer endpoint.Close()
+
+    webhook, err := webhookService.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: endpoint.URL})
+    if err != nil {
+        t.Fatalf("Error in registering webhook %v ", err)
+    }
+    receiver.secret = webhook.GetSecret()
+    cancelledOnly, err := webhookService.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: endpoint.URL + "/cancelled",
+        EventTypes: []string{api.BookingEventCancelled}, Secret: webhook.GetSecret()})
+    if err != nil {
+        t.Fatalf("Error in registering webhook %v ", err)
+    }
+
+    booking, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL))
+    if err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+    newSeat := (booking.GetSeat() + 1) % api.MaxSeatsPerSection
+    if _, err := bookingService.ModifySeatByUser(context.TODO(), &pb.SeatModificationRequest{BookingId: booking.GetPnr(),
+        Section: booking.GetSection(), Seat: newSeat, User: &pb.User{Email: EMAIL}}); err != nil {
+        t.Fatalf("Error in modifying booking %v ", err)
+    }
+    if _, err := bookingService.RemoveBookingByUser(context.TODO(), &pb.RemoveBookingByUserRequest{BookingId: booking.GetPnr(), User: &pb.User{Email: EMAIL}}); err != nil {
+        t.Fatalf("Error in cancelling booking %v ", err)
+    }
+
+    if err := api.NewWebhookDispatcher(db, endpoint.Client()).DispatchOnce(context.TODO()); err != nil {
+        t.Fatalf("Error in dispatching webhooks %v ", err)
+    }
+    assert.Equal(t, []string{api.BookingEventCreated, api.BookingEventSeatModified, api.BookingEventCancelled, api.BookingEventCancelled},
+        receiver.eventTypes())
+    assert.Equal(t, booking.GetPnr(), receiver.events[0].GetBooking().GetPnr())
+    assert.Equal(t, newSeat, receiver.events[1].GetBooking().GetSeat())
+
+    filtered, err := webhookService.ListWebhookDeliveries(ctx, &pb.ListWebhookDeliveriesRequest{WebhookId: cancelledOnly.GetId()})
+    if err != nil {
+        t.Fatalf("Error in listing deliveries %v ", err)
+    }
+    assert.Len(t, filtered.GetDeliveries(), 1, "Webhook should only receive the event types it registered for")
+    assert.Equal(t, api.DeliveryStatusDelivered, filtered.GetDeliveries()[0].GetStatus())
+}
+
+func TestShouldDeadLetterAndReplayFailedDeliveries(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    webhookService := api.NewWebhookService(db)
+    webhookService.SetURLPolicy(api.WebhookURLPolicy{AllowPrivate: true})
+    ctx := api.WithActor(context.TODO(), "service:partner")
+    receiver := &webhookReceiver{status: http.StatusServiceUnavailable}
+    endpoint := httptest.NewServer(receiver)
+    defer endpoint.Close()
+
+    webhook, err := webhookService.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: endpoint.URL})
+    if err != nil {
+        t.Fatalf("Error in registering webhook %v ", err)
+    }
+    receiver.secret = webhook.GetSecret()
+    if _, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)); err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+
+    dispatcher := api.NewWebhookDispatcher(db, endpoint.Client())
+    dispatcher.MaxAttempts = 3
+    dispatcher.BaseBackoff = 0
+    for i := 0; i < 4; i++ {
+        if err := dispatcher.DispatchOnce(context.TODO()); err != nil {
+            t.Fatalf("Error in dispatching webhooks %v ", err)
+        }
+    }
+
+    dead, err := webhookService.ListWebhookDeliveries(ctx, &pb.ListWebhookDeliveriesRequest{Status: api.DeliveryStatusDead})
+    if err != nil {
+        t.Fatalf("Error in listing deliveries %v ", err)
+    }
+    assert.Len(t, dead.GetDeliveries(), 1)
+    assert.EqualValues(t, 3, dead.GetDeliveries()[0].GetAttempts(), "Dead deliveries should not be retried")
+    assert.Contains(t, dead.GetDeliveries()[0].GetLastError(), "503")
+
+    receiver.mu.Lock()
+    receiver.status = http.StatusOK
+    receiver.mu.Unlock()
+    replayed, err := webhookService.ReplayWebhookDeliveries(ctx, &pb.ReplayWebhookDeliveriesRequest{WebhookId: webhook.GetId()})
+    if err != nil {
+        t.Fatalf("Error in replaying deliveries %v ", err)
+    }
+    assert.EqualValues(t, 1, replayed.GetReplayed())
+    if err := dispatcher.DispatchOnce(context.TODO()); err != nil {
+        t.Fatalf("Error in dispatching webhooks %v ", err)
+    }
+    assert.Equal(t, []string{api.BookingEventCreated}, receiver.eventTypes())
+}
+
+func TestShouldOnlyLetServicesRegisterPublicWebhooks(t *testing.T) {
+    webhookService := api.NewWebhookService(newTestDatabase(t))
+    ctx := api.WithActor(context.TODO(), "service:partner")
+
+    _, err := webhookService.RegisterWebhook(context.TODO(), &pb.RegisterWebhookRequest{Url: "https://hooks.example.com/bookings"})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "Callers without a client certificate should be rejected")
+    _, err = webhookService.RegisterWebhook(api.WithActor(context.TODO(), "agent-7"), &pb.RegisterWebhookRequest{Url: "https://hooks.example.com/bookings"})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "Only service identities should manage webhooks")
+
+    _, err = webhookService.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: "http://hooks.example.com/bookings"})
+    assert.Equal(t, codes.InvalidArgument, status.Code(err), "Plain http should be rejected")
+    for _, url := range []string{"https://127.0.0.1/hook", "https://10.0.0.8/hook", "https://[::1]/hook", "https://169.254.169.254/latest"} {
+        _, err = webhookService.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: url})
+        assert.Equal(t, codes.PermissionDenied, status.Code(err), "%s should be rejected", url)
+    }
+
+    webhookService.SetURLPolicy(api.WebhookURLPolicy{AllowedHosts: []string{"hooks.example.com"}})
+    _, err = webhookService.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: "https://other.example.com/bookings"})
+    assert.Equal(t, codes.PermissionDenied, status.Code(err), "Hosts outside the allowed ones should be rejected")
+    _, err = webhookService.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: "https://Hooks.Example.com/bookings"})
+    assert.NoError(t, err)
+}
+
+func TestShouldOnlyDeliverToTheRegisteredPublicAddress(t *testing.T) {
+    db := newTestDatabase(t)
+    bookingService := api.NewBookingService(db)
+    webhookService := api.NewWebhookService(db)
+    webhookService.SetURLPolicy(api.WebhookURLPolicy{AllowPrivate: true})
+    ctx := api.WithActor(context.TODO(), "service:partner")
+    internal := &webhookReceiver{status: http.StatusOK}
+    internalEndpoint := httptest.NewServer(internal)
+    defer internalEndpoint.Close()
+    redirecting := httptest.NewServer(http.RedirectHandler(internalEndpoint.URL, http.StatusFound))
+    defer redirecting.Close()
+
+    webhook, err := webhookService.RegisterWebhook(ctx, &pb.RegisterWebhookRequest{Url: redirecting.URL})
+    if err != nil {
+        t.Fatalf("Error in registering webhook %v ", err)
+    }
+    internal.secret = webhook.GetSecret()
+    if _, err := bookingService.CreateBooking(context.TODO(), createNewTrainBookingRequest(FIRST_NAME, LAST_NAME, EMAIL)); err != nil {
+        t.Fatalf("Error in creating booking %v ", err)
+    }
+
+    if err := api.NewWebhookDispatcher(db, api.NewWebhookClient(api.WebhookURLPolicy{AllowPrivate: true})).DispatchOnce(context.TODO()); err != nil {
+        t.Fatalf("Error in dispatching webhooks %v ", err)
+    }
+    assert.Empty(t, internal.eventTypes(), "Redirects shouldn't be followed")
+
+    mustExec(t, db, "UPDATE webhook_deliveries SET d_next_attempt_at = 0")
+    if err := api.NewWebhookDispatcher(db, nil).DispatchOnce(context.TODO()); err != nil {
+        t.Fatalf("Error in dispatching webhooks %v ", err)
+    }
+    deliveries, err := webhookService.ListWebhookDeliveries(ctx, &pb.ListWebhookDeliveriesRequest{WebhookId: webhook.GetId()})
+    if err != nil {
+        t.Fatalf("Error in listing deliveries %v ", err)
+    }
+    if assert.Len(t, deliveries.GetDeliveries(), 1) {
+        assert.EqualValues(t, 2, deliveries.GetDeliveries()[0].GetAttempts())
+        assert.Contains(t, deliveries.GetDeliveries()[0].GetLastError(), "private address",
+            "Hosts resolving to private addresses at delivery should be refused")
+    }
+}
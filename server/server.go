@@ -2,61 +2,330 @@ package main
 
 import (
 	pb "ticket-booking-app/domain"
+	bookingv2 "ticket-booking-app/domain/booking/v2"
     "ticket-booking-app/server/api"
+    "ticket-booking-app/config"
+    "ticket-booking-app/tlsconfig"
+    "ticket-booking-app/telemetry"
+    "ticket-booking-app/logging"
     "google.golang.org/grpc"
-    _ "github.com/mattn/go-sqlite3"
-	"database/sql"
+    "google.golang.org/grpc/credentials"
+    "google.golang.org/grpc/credentials/insecure"
+    "google.golang.org/grpc/health"
+    healthpb "google.golang.org/grpc/health/grpc_health_v1"
+    "google.golang.org/grpc/reflection"
+    "go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+    "go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"net/smtp"
+	"net/http"
 	"net"
 	"log"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 )
 
 func main() {
-	listener, err := net.Listen("tcp", ":50051")
+	cfg, printOnly, err := config.LoadServer(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	if printOnly {
+		fmt.Print(cfg)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "Effective configuration:\n%s\n", cfg)
+	level, _ := config.ParseLogLevel(cfg.LogLevel)
+	logger, err := logging.New(os.Stderr, cfg.LogFormat, level, cfg.LogRedact)
+	if err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	// the log package writes through the logger too
+	slog.SetDefault(logger)
+
+	exporter, err := telemetry.NewExporter(context.Background(), cfg.Tracing.Exporter, cfg.Tracing.Endpoint, cfg.Tracing.Insecure, os.Stdout)
+	if err != nil {
+		fatal("Failed to create trace exporter", err)
+	}
+	tracerProvider := telemetry.Setup("ticket-booking-server", exporter, cfg.Tracing.SampleRatio)
+
+	// certificates are read again when they change, so they can be renewed
+	// without a restart
+	var certificates *tlsconfig.Reloader
+	// every RPC but the health checks gets a span, continuing the caller's trace
+	serverOptions := []grpc.ServerOption{grpc.StatsHandler(otelgrpc.NewServerHandler(
+		otelgrpc.WithFilter(filters.Not(filters.HealthCheck()))))}
+	// every RPC is counted, including the ones rejected by later interceptors
+	metrics := api.NewMetrics()
+	// and every request has a correlation id before anything is logged for it
+	unaryInterceptors := []grpc.UnaryServerInterceptor{api.NewRequestIdInterceptor(), api.NewMetricsInterceptor(metrics)}
+	streamInterceptors := []grpc.StreamServerInterceptor{api.NewRequestIdStreamInterceptor(), api.NewMetricsStreamInterceptor(metrics)}
+	if cfg.TLS.CertFile != "" {
+		certificates, err = tlsconfig.NewReloader(cfg.TLS.CertFile, cfg.TLS.KeyFile, cfg.TLS.ClientCAFile)
+		if err != nil {
+			fatal("Failed to load TLS certificates", err)
+		}
+		serverOptions = append(serverOptions, grpc.Creds(credentials.NewTLS(tlsconfig.ServerConfig(certificates, cfg.TLS.ClientAuth))))
+		if cfg.TLS.ClientAuth != config.ClientAuthNone {
+			verifier := &api.IdentityVerifier{Identities: cfg.TLS.Identities, IsRelay: certificates.IsOwnCertificate}
+			unaryInterceptors = append(unaryInterceptors, api.NewIdentityInterceptor(verifier))
+			streamInterceptors = append(streamInterceptors, api.NewIdentityStreamInterceptor(verifier))
+		}
+	}
+
+	listener, err := net.Listen("tcp", cfg.ListenAddress)
 	if err != nil {
-		log.Fatalf("Error in listening on port 50051: %v", err)
+		fatal("Error in listening", err, "address", cfg.ListenAddress)
 	}
 
-    db, err := sql.Open("sqlite3", "./ticket_booking.db")
-    if err != nil {
-        log.Fatalf("Failed to open database: %v", err)
+    db := api.OpenInstrumentedDB(cfg.DSN, metrics)
+    metrics.RegisterDatabase(db)
+    if err := api.MigrateDatabase(db); err != nil {
+        fatal("Failed to migrate database", err)
     }
-    defer db.Close()
-    createDatabaseTables(db)
 
     // Start server and register the all APIs
-	server := grpc.NewServer()
+	// the identity is established before calls are rate limited by it, and
+	// rejected calls don't claim idempotency keys
+	if len(cfg.RateLimits) > 0 {
+		var limits []api.RateLimit
+		for _, limit := range cfg.RateLimits {
+			limits = append(limits, api.RateLimit{Method: limit.Method, Key: limit.Key, Rate: limit.Rate, Burst: limit.Burst})
+		}
+		limiter, err := api.NewRateLimiter(limits)
+		if err != nil {
+			fatal("Invalid rate limits", err)
+		}
+		unaryInterceptors = append(unaryInterceptors, api.NewRateLimitInterceptor(limiter))
+		streamInterceptors = append(streamInterceptors, api.NewRateLimitStreamInterceptor(limiter))
+	}
+	unaryInterceptors = append(unaryInterceptors, api.NewIdempotencyInterceptor(db, time.Duration(cfg.IdempotencyKeyTTL)))
+	server := grpc.NewServer(append(serverOptions, grpc.ChainUnaryInterceptor(unaryInterceptors...),
+		grpc.ChainStreamInterceptor(streamInterceptors...))...)
+
+	notifications := api.NewNotificationQueue(newNotifier(cfg.SMTP), 1000)
+	notifications.Start(4)
 
+	var sections []api.Section
+	for _, section := range cfg.Sections {
+		sections = append(sections, api.Section{Name: section.Name, Seats: section.Seats})
+	}
+	allocator, err := api.NewSeatAllocatorWithLayout(sections, cfg.AllocationStrategy)
+	if err != nil {
+		fatal("Invalid seat layout", err)
+	}
 	bookingService := api.NewBookingService(db)
+	bookingService.SetSeatAllocator(allocator)
+	bookingService.SetNotificationQueue(notifications)
+	bookingService.SetMetrics(metrics)
+	bookingService.SetMaxActiveBookingsPerJourney(cfg.MaxActiveBookingsPerJourney)
+	bookingService.SetMaxSeatHoldsPerCaller(cfg.MaxSeatHoldsPerCaller)
+	bookingService.SetSeatHoldTTL(time.Duration(cfg.SeatHoldTTL))
+	metrics.RegisterSeatAllocator(allocator)
 	pb.RegisterBookingServiceServer(server, bookingService)
+	bookingv2.RegisterBookingServiceServer(server, bookingService.V2())
+	pb.RegisterUserServiceServer(server, api.NewUserService(db, bookingService))
+	adminService := api.NewAdminService(db, bookingService)
+	var backups *api.BackupManager
+	if cfg.Backup.Dir != "" {
+		backups, err = api.NewBackupManager(db, cfg.Backup.Dir, cfg.Backup.Keep)
+		if err != nil {
+			fatal("Failed to create backup directory", err, "dir", cfg.Backup.Dir)
+		}
+		adminService.SetBackupManager(backups)
+	}
+	pb.RegisterAdminServiceServer(server, adminService)
+	webhookService := api.NewWebhookService(db)
+	webhookPolicy := api.WebhookURLPolicy{AllowedHosts: cfg.Webhooks.AllowedHosts, AllowPrivate: cfg.Webhooks.AllowPrivate}
+	webhookService.SetURLPolicy(webhookPolicy)
+	pb.RegisterWebhookServiceServer(server, webhookService)
+	pb.RegisterTicketServiceServer(server, api.NewTicketService(db, bookingService))
+	pb.RegisterReportingServiceServer(server, api.NewReportingService(db, bookingService))
+
+	// readiness is reported per service and overall, reflection lets grpcurl
+	// discover the services
+	var services []string
+	for service := range server.GetServiceInfo() {
+		services = append(services, service)
+	}
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(server, healthServer)
+	reflection.Register(server)
+	readiness := api.NewReadinessMonitor(db, healthServer, services...)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// booking events are written to the outbox and delivered in the background
+	background, cancelBackground := context.WithCancel(context.Background())
+	var workers sync.WaitGroup
+	workers.Add(2)
+	go func() {
+		defer workers.Done()
+		api.NewWebhookDispatcher(db, api.NewWebhookClient(webhookPolicy)).Run(background)
+	}()
+	go func() {
+		defer workers.Done()
+		seats, err := bookingService.WarmUp(background)
+		if err != nil {
+			slog.Error("Seat allocator warm up failed, staying not ready", "error", err)
+			return
+		}
+		slog.Info("Seat allocator warmed up", "booked_seats", seats)
+		readiness.SetWarm()
+		readiness.Run(background)
+	}()
+	if backups != nil && cfg.Backup.Interval > 0 {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			backups.Run(background, time.Duration(cfg.Backup.Interval))
+		}()
+	}
+	if certificates != nil {
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			certificates.Run(background, time.Duration(cfg.TLS.ReloadInterval))
+		}()
+	}
+
+	// the REST gateway calls the gRPC server over loopback so both share the interceptors
+	var gateway *http.Server
+	var gatewayConn *grpc.ClientConn
+	if cfg.RESTAddress != "" {
+		creds := insecure.NewCredentials()
+		if certificates != nil {
+			creds = credentials.NewTLS(tlsconfig.LoopbackConfig(certificates))
+		}
+		gatewayConn, err = grpc.NewClient(loopbackAddress(listener.Addr()), grpc.WithTransportCredentials(creds),
+			grpc.WithStatsHandler(otelgrpc.NewClientHandler()))
+		if err != nil {
+			fatal("Failed to connect REST gateway", err)
+		}
+		gateway = &http.Server{Addr: cfg.RESTAddress, Handler: api.NewRESTGateway(gatewayConn)}
+		go func() {
+			slog.Info("REST gateway listening", "address", cfg.RESTAddress)
+			if err := gateway.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("REST gateway failed", "error", err)
+				stop()
+			}
+		}()
+	}
+
+	var metricsServer *http.Server
+	if cfg.MetricsAddress != "" {
+		mux := http.NewServeMux()
+		mux.Handle("GET /metrics", metrics.Handler())
+		metricsServer = &http.Server{Addr: cfg.MetricsAddress, Handler: mux}
+		go func() {
+			slog.Info("Metrics listening", "address", cfg.MetricsAddress)
+			if err := metricsServer.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				slog.Error("Metrics server failed", "error", err)
+				stop()
+			}
+		}()
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		slog.Info("Server started", "address", listener.Addr().String())
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		slog.Error("Server failed", "error", err)
+	case <-ctx.Done():
+		slog.Info("Shutting down, waiting for in-flight requests", "timeout", cfg.ShutdownTimeout.String())
+	}
+	// watchers would keep the graceful stop waiting, they reconnect elsewhere
+	bookingService.CloseWatches()
+	shutdown(server, healthServer, gateway, gatewayConn, time.Duration(cfg.ShutdownTimeout))
+
+	// the dispatcher and readiness checks stop before the queued emails are
+	// sent and the database is closed
+	cancelBackground()
+	workers.Wait()
+	notifications.Close()
+	if metricsServer != nil {
+		metricsServer.Close()
+	}
+	if err := db.Close(); err != nil {
+		slog.Error("Error in closing database", "error", err)
+	}
+	// the spans still buffered are exported before exiting
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), 5*time.Second)
+	if err := tracerProvider.Shutdown(flushCtx); err != nil {
+		slog.Error("Error in flushing traces", "error", err)
+	}
+	cancelFlush()
+	slog.Info("Server stopped")
+}
+
+// shutdown reports NOT_SERVING so no new traffic is routed here, then lets
+// in-flight requests and their transactions finish. Requests still running after
+// timeout are cancelled.
+func shutdown(server *grpc.Server, healthServer *health.Server, gateway *http.Server, gatewayConn *grpc.ClientConn, timeout time.Duration) {
+	healthServer.Shutdown()
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	if gateway != nil {
+		if err := gateway.Shutdown(ctx); err != nil {
+			slog.Error("REST gateway shutdown failed", "error", err)
+		}
+		gatewayConn.Close()
+	}
 
-	log.Printf("Server started successfully. Listening %v", listener.Addr())
-	if err := server.Serve(listener); err != nil {
-		log.Fatalf("Server :: Error : %v", err)
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		slog.Warn("Shutdown timed out, cancelling remaining requests")
+		server.Stop()
+		<-stopped
 	}
 }
 
-func createDatabaseTables(db *sql.DB) {
-     _, err := db.Exec(`CREATE TABLE IF NOT EXISTS tickets (
-            t_id TEXT PRIMARY KEY,
-            t_from TEXT,
-            t_to TEXT,
-            t_price INTEGER,
-            t_seat INTEGER,
-            t_section TEXT,
-            t_user_id TEXT
-        )`)
-     if err != nil {
-        log.Fatalf("Failed to create TICKET table: %v", err)
-     }
-
-      _, dbErr := db.Exec(`CREATE TABLE IF NOT EXISTS users (
-                 u_id TEXT PRIMARY KEY,
-                 u_user_fname TEXT,
-                 u_user_lname TEXT,
-                 u_user_email TEXT
-             )`)
-      if dbErr != nil {
-          log.Fatalf("Failed to create USER table: %v", dbErr)
-      }
+// loopbackAddress is the address the REST gateway dials to reach the gRPC
+// server, which may listen on all interfaces.
+func loopbackAddress(addr net.Addr) string {
+	_, port, _ := net.SplitHostPort(addr.String())
+	return net.JoinHostPort("localhost", port)
 }
 
+// newNotifier sends emails through the SMTP server when one is configured and
+// only logs them otherwise.
+func newNotifier(cfg config.SMTP) api.Notifier {
+	if cfg.Address == "" {
+		slog.Info("smtp.address not set, notifications are only logged")
+		return api.LogNotifier{}
+	}
+	var auth smtp.Auth
+	if cfg.Username != "" {
+		host, _, _ := net.SplitHostPort(cfg.Address)
+		auth = smtp.PlainAuth("", cfg.Username, cfg.Password, host)
+	}
+	return api.NewSMTPNotifier(cfg.Address, cfg.From, auth)
+}
+
+// fatal logs err and exits
+func fatal(msg string, err error, args ...any) {
+	slog.Error(msg, append(args, "error", err)...)
+	os.Exit(1)
+}
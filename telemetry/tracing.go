@@ -0,0 +1,64 @@
+// Package telemetry sets up OpenTelemetry tracing for the server and the client.
+package telemetry
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"context"
+	"fmt"
+	"io"
+)
+
+const (
+	// ExporterNone records no spans
+	ExporterNone = "none"
+	// ExporterOTLP sends spans to an OpenTelemetry collector over gRPC
+	ExporterOTLP = "otlp"
+	// ExporterStdout writes spans as JSON, one per line
+	ExporterStdout = "stdout"
+)
+
+// NewExporter creates the exporter named by kind. endpoint is the host:port
+// of the OTLP collector, reached without TLS when insecure is set. stdout
+// spans are written to out. ExporterNone has no exporter and returns nil.
+func NewExporter(ctx context.Context, kind, endpoint string, insecure bool, out io.Writer) (sdktrace.SpanExporter, error) {
+	switch kind {
+	case ExporterNone:
+		return nil, nil
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithWriter(out))
+	case ExporterOTLP:
+		options := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+		if insecure {
+			options = append(options, otlptracegrpc.WithInsecure())
+		}
+		return otlptracegrpc.New(ctx, options...)
+	}
+	return nil, fmt.Errorf("unknown trace exporter %q", kind)
+}
+
+// Setup installs a tracer provider that batches the spans of serviceName to
+// exporter as the global one, and the W3C trace context and baggage
+// propagators that carry traces across gRPC metadata and HTTP headers. Only
+// sampleRatio of the traces started here are recorded; traces started by a
+// caller follow the caller's decision. A nil exporter records nothing but still
+// propagates the caller's context. Shutting the provider down flushes the
+// pending spans.
+func Setup(serviceName string, exporter sdktrace.SpanExporter, sampleRatio float64) *sdktrace.TracerProvider {
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(serviceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(sampleRatio))),
+	}
+	if exporter != nil {
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+	provider := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+	return provider
+}
@@ -0,0 +1,127 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+const (
+	// ClientAuthNone doesn't ask clients for certificates
+	ClientAuthNone = "none"
+	// ClientAuthOptional verifies client certificates when they are presented
+	ClientAuthOptional = "optional"
+	// ClientAuthRequire rejects connections without a valid client certificate
+	ClientAuthRequire = "require"
+)
+
+// ServerConfig serves the current certificate of certificates. Unless
+// clientAuth is ClientAuthNone, client certificates are verified against the
+// current CA pool of certificates, so a renewed CA applies to new connections.
+// The server's own certificate is accepted as a client certificate, which is
+// how local relays like the REST gateway connect.
+func ServerConfig(certificates *Reloader, clientAuth string) *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if certificate := certificates.Certificate(); certificate != nil {
+				return certificate, nil
+			}
+			return nil, errNoCertificate
+		},
+	}
+	switch clientAuth {
+	case ClientAuthOptional:
+		config.ClientAuth = tls.RequestClientCert
+	case ClientAuthRequire:
+		config.ClientAuth = tls.RequireAnyClientCert
+	default:
+		return config
+	}
+	config.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		return certificates.verifyClient(rawCerts)
+	}
+	return config
+}
+
+func (r *Reloader) verifyClient(rawCerts [][]byte) error {
+	if len(rawCerts) == 0 {
+		// only reachable with ClientAuthOptional
+		return nil
+	}
+	var chain []*x509.Certificate
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return err
+		}
+		chain = append(chain, cert)
+	}
+	if r.IsOwnCertificate(chain[0]) {
+		return nil
+	}
+	pool := r.Pool()
+	if pool == nil {
+		return errors.New("no client CA configured")
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         pool,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	if err != nil {
+		return fmt.Errorf("client certificate %q: %w", chain[0].Subject.CommonName, err)
+	}
+	return nil
+}
+
+// ClientConfig verifies the server against the CA pool of certificates, or the
+// system roots without one, and presents the current certificate of
+// certificates when it has one. serverName overrides the host name the server
+// certificate must be valid for.
+func ClientConfig(certificates *Reloader, serverName string) *tls.Config {
+	config := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		ServerName: serverName,
+		RootCAs:    certificates.Pool(),
+	}
+	if certificates.Certificate() != nil {
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certificates.Certificate(), nil
+		}
+	}
+	return config
+}
+
+// LoopbackConfig lets the server connect to itself: the peer must present
+// exactly the server's current certificate, whatever name it was issued for,
+// and the same certificate is sent when a client certificate is asked for.
+func LoopbackConfig(certificates *Reloader) *tls.Config {
+	return &tls.Config{
+		MinVersion: tls.VersionTLS12,
+		// the certificate is compared byte for byte below, which is stricter
+		// than the chain and host name verification this skips
+		InsecureSkipVerify: true,
+		VerifyPeerCertificate: func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			if len(rawCerts) == 0 {
+				return errNoCertificate
+			}
+			cert, err := x509.ParseCertificate(rawCerts[0])
+			if err != nil {
+				return err
+			}
+			if !certificates.IsOwnCertificate(cert) {
+				return errors.New("loopback peer is not this server")
+			}
+			return nil
+		},
+		GetClientCertificate: func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certificates.Certificate(), nil
+		},
+	}
+}
@@ -0,0 +1,144 @@
+package tlsconfig
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// DevCertificateValidity is how long generated development certificates last
+const DevCertificateValidity = 90 * 24 * time.Hour
+
+// DevCertificates are the files written by GenerateDevCertificates, relative
+// to its directory.
+type DevCertificates struct {
+	CAFile         string
+	ServerCertFile string
+	ServerKeyFile  string
+	// ClientCertFiles and ClientKeyFiles are keyed by client name
+	ClientCertFiles map[string]string
+	ClientKeyFiles  map[string]string
+}
+
+// GenerateDevCertificates writes a throwaway CA, a server certificate for hosts
+// (names or IP addresses) and a client certificate for each of clients, whose
+// name becomes the certificate's common name. They are meant for development
+// and tests only; the CA key is not kept.
+func GenerateDevCertificates(dir string, hosts []string, clients []string) (*DevCertificates, error) {
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("at least one host is required")
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	caTemplate, err := certificateTemplate("Ticket booking development CA")
+	if err != nil {
+		return nil, err
+	}
+	caTemplate.IsCA = true
+	caTemplate.BasicConstraintsValid = true
+	caTemplate.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, caKey.Public(), caKey)
+	if err != nil {
+		return nil, err
+	}
+	ca, err := x509.ParseCertificate(caDER)
+	if err != nil {
+		return nil, err
+	}
+
+	files := &DevCertificates{
+		CAFile:          "ca.pem",
+		ServerCertFile:  "server.pem",
+		ServerKeyFile:   "server-key.pem",
+		ClientCertFiles: map[string]string{},
+		ClientKeyFiles:  map[string]string{},
+	}
+	if err := writePEM(filepath.Join(dir, files.CAFile), "CERTIFICATE", caDER, 0o644); err != nil {
+		return nil, err
+	}
+
+	serverTemplate, err := certificateTemplate(hosts[0])
+	if err != nil {
+		return nil, err
+	}
+	for _, host := range hosts {
+		if ip := net.ParseIP(host); ip != nil {
+			serverTemplate.IPAddresses = append(serverTemplate.IPAddresses, ip)
+		} else {
+			serverTemplate.DNSNames = append(serverTemplate.DNSNames, host)
+		}
+	}
+	// client auth lets the server's certificate identify the REST gateway too
+	serverTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	if err := issueCertificate(dir, files.ServerCertFile, files.ServerKeyFile, serverTemplate, ca, caKey); err != nil {
+		return nil, err
+	}
+
+	for _, client := range clients {
+		clientTemplate, err := certificateTemplate(client)
+		if err != nil {
+			return nil, err
+		}
+		clientTemplate.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}
+		certFile, keyFile := client+".pem", client+"-key.pem"
+		if err := issueCertificate(dir, certFile, keyFile, clientTemplate, ca, caKey); err != nil {
+			return nil, err
+		}
+		files.ClientCertFiles[client] = certFile
+		files.ClientKeyFiles[client] = keyFile
+	}
+	return files, nil
+}
+
+func certificateTemplate(commonName string) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName, Organization: []string{"Ticket booking development"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(DevCertificateValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+	}, nil
+}
+
+func issueCertificate(dir, certFile, keyFile string, template, ca *x509.Certificate, caKey crypto.Signer) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, key.Public(), caKey)
+	if err != nil {
+		return fmt.Errorf("%s: %w", certFile, err)
+	}
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := writePEM(filepath.Join(dir, keyFile), "PRIVATE KEY", keyDER, 0o600); err != nil {
+		return err
+	}
+	return writePEM(filepath.Join(dir, certFile), "CERTIFICATE", der, 0o644)
+}
+
+func writePEM(path, blockType string, der []byte, mode os.FileMode) error {
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), mode)
+}
@@ -0,0 +1,156 @@
+// Package tlsconfig builds the TLS configuration of the server and its clients
+// from PEM files that can be replaced while the server is running.
+package tlsconfig
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Reloader serves a certificate and key pair and, optionally, a pool of CA
+// certificates from files. The files are read again when they change, so
+// certificates can be renewed without a restart.
+type Reloader struct {
+	certFile string
+	keyFile  string
+	caFile   string
+
+	mu          sync.RWMutex
+	certificate *tls.Certificate
+	leaf        *x509.Certificate
+	pool        *x509.CertPool
+	modTimes    []time.Time
+}
+
+// NewReloader loads the files. certFile and keyFile may both be empty when
+// only the CA pool is needed, and caFile may be empty when no pool is needed.
+func NewReloader(certFile, keyFile, caFile string) (*Reloader, error) {
+	r := &Reloader{certFile: certFile, keyFile: keyFile, caFile: caFile}
+	if _, err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload reads the files again if any of them changed since they were last
+// read. On error the previous certificates stay in use.
+func (r *Reloader) Reload() (bool, error) {
+	modTimes, err := r.fileModTimes()
+	if err != nil {
+		return false, err
+	}
+	r.mu.RLock()
+	unchanged := r.modTimes != nil && equalTimes(modTimes, r.modTimes)
+	r.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	var certificate *tls.Certificate
+	var leaf *x509.Certificate
+	if r.certFile != "" {
+		pair, err := tls.LoadX509KeyPair(r.certFile, r.keyFile)
+		if err != nil {
+			return false, err
+		}
+		if leaf, err = x509.ParseCertificate(pair.Certificate[0]); err != nil {
+			return false, err
+		}
+		pair.Leaf = leaf
+		certificate = &pair
+	}
+	var pool *x509.CertPool
+	if r.caFile != "" {
+		content, err := os.ReadFile(r.caFile)
+		if err != nil {
+			return false, err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(content) {
+			return false, fmt.Errorf("%s: no PEM certificates found", r.caFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.certificate, r.leaf, r.pool, r.modTimes = certificate, leaf, pool, modTimes
+	r.mu.Unlock()
+	return true, nil
+}
+
+// Run checks the files for changes every interval until ctx is done.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		reloaded, err := r.Reload()
+		if err != nil {
+			log.Printf("TLS :: Error in reloading certificates, keeping the current ones : %v", err)
+		} else if reloaded {
+			log.Printf("TLS :: Reloaded certificates from %s", r.certFile+" "+r.caFile)
+		}
+	}
+}
+
+// Certificate returns the current certificate, nil without a certificate file.
+func (r *Reloader) Certificate() *tls.Certificate {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.certificate
+}
+
+// Pool returns the current CA pool, nil without a CA file.
+func (r *Reloader) Pool() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pool
+}
+
+// IsOwnCertificate reports whether cert is the current certificate.
+func (r *Reloader) IsOwnCertificate(cert *x509.Certificate) bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.leaf != nil && cert != nil && bytes.Equal(r.leaf.Raw, cert.Raw)
+}
+
+func (r *Reloader) fileModTimes() ([]time.Time, error) {
+	var modTimes []time.Time
+	for _, file := range []string{r.certFile, r.keyFile, r.caFile} {
+		if file == "" {
+			modTimes = append(modTimes, time.Time{})
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			return nil, err
+		}
+		modTimes = append(modTimes, info.ModTime())
+	}
+	return modTimes, nil
+}
+
+func equalTimes(a, b []time.Time) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !a[i].Equal(b[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+var errNoCertificate = errors.New("no certificate configured")
@@ -0,0 +1,131 @@
+package tlsconfig_test
+
+import (
+	"ticket-booking-app/tlsconfig"
+	"github.com/stretchr/testify/assert"
+	"crypto/tls"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// serveTLS accepts connections until the test ends and writes "ok" on every
+// connection whose handshake succeeds.
+func serveTLS(t *testing.T, config *tls.Config) string {
+    t.Helper()
+    listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+    if err != nil {
+        t.Fatalf("Error in listening %v ", err)
+    }
+    t.Cleanup(func() { listener.Close() })
+    go func() {
+        for {
+            conn, err := listener.Accept()
+            if err != nil {
+                return
+            }
+            go func() {
+                defer conn.Close()
+                if conn.(*tls.Conn).Handshake() == nil {
+                    conn.Write([]byte("ok"))
+                }
+            }()
+        }
+    }()
+    return listener.Addr().String()
+}
+
+// exchange reports whether the server accepted the connection; with TLS 1.3 a
+// rejected client certificate only shows when reading.
+func exchange(address string, config *tls.Config) error {
+    conn, err := tls.Dial("tcp", address, config)
+    if err != nil {
+        return err
+    }
+    defer conn.Close()
+    conn.SetDeadline(time.Now().Add(5 * time.Second))
+    _, err = io.ReadAll(conn)
+    return err
+}
+
+func generate(t *testing.T, dir string) *tlsconfig.DevCertificates {
+    t.Helper()
+    files, err := tlsconfig.GenerateDevCertificates(dir, []string{"localhost", "127.0.0.1"}, []string{"conductor"})
+    if err != nil {
+        t.Fatalf("Error in generating certificates %v ", err)
+    }
+    return files
+}
+
+func newReloader(t *testing.T, dir, certFile, keyFile, caFile string) *tlsconfig.Reloader {
+    t.Helper()
+    join := func(file string) string {
+        if file == "" {
+            return ""
+        }
+        return filepath.Join(dir, file)
+    }
+    reloader, err := tlsconfig.NewReloader(join(certFile), join(keyFile), join(caFile))
+    if err != nil {
+        t.Fatalf("Error in loading certificates %v ", err)
+    }
+    return reloader
+}
+
+func TestShouldRequireClientCertificatesFromTheClientCA(t *testing.T) {
+    dir, otherDir := t.TempDir(), t.TempDir()
+    files := generate(t, dir)
+    otherFiles := generate(t, otherDir)
+
+    server := newReloader(t, dir, files.ServerCertFile, files.ServerKeyFile, files.CAFile)
+    address := serveTLS(t, tlsconfig.ServerConfig(server, tlsconfig.ClientAuthRequire))
+
+    client := newReloader(t, dir, files.ClientCertFiles["conductor"], files.ClientKeyFiles["conductor"], files.CAFile)
+    assert.NoError(t, exchange(address, tlsconfig.ClientConfig(client, "localhost")))
+
+    anonymous := newReloader(t, dir, "", "", files.CAFile)
+    assert.Error(t, exchange(address, tlsconfig.ClientConfig(anonymous, "localhost")), "Clients without certificates should be rejected")
+
+    foreign := newReloader(t, otherDir, otherFiles.ClientCertFiles["conductor"], otherFiles.ClientKeyFiles["conductor"], "")
+    foreignConfig := tlsconfig.ClientConfig(foreign, "localhost")
+    foreignConfig.RootCAs = client.Pool()
+    assert.Error(t, exchange(address, foreignConfig), "Certificates of another CA should be rejected")
+
+    assert.NoError(t, exchange(address, tlsconfig.LoopbackConfig(server)), "The server should accept its own certificate")
+    assert.Error(t, exchange(address, tlsconfig.LoopbackConfig(client)), "Loopback should only trust the server itself")
+}
+
+func TestShouldReloadChangedCertificates(t *testing.T) {
+    dir := t.TempDir()
+    files := generate(t, dir)
+    server := newReloader(t, dir, files.ServerCertFile, files.ServerKeyFile, "")
+    address := serveTLS(t, tlsconfig.ServerConfig(server, tlsconfig.ClientAuthNone))
+
+    reloaded, err := server.Reload()
+    assert.NoError(t, err)
+    assert.False(t, reloaded, "Unchanged files should not be read again")
+
+    // a new CA and server certificate replace the old ones in place
+    previous := newReloader(t, dir, "", "", files.CAFile)
+    generate(t, dir)
+    later := time.Now().Add(time.Minute)
+    for _, file := range []string{files.ServerCertFile, files.ServerKeyFile, files.CAFile} {
+        os.Chtimes(filepath.Join(dir, file), later, later)
+    }
+    current := newReloader(t, dir, "", "", files.CAFile)
+
+    assert.NoError(t, exchange(address, tlsconfig.ClientConfig(previous, "localhost")), "Old certificate should be served until reloaded")
+    reloaded, err = server.Reload()
+    assert.NoError(t, err)
+    assert.True(t, reloaded)
+    assert.NoError(t, exchange(address, tlsconfig.ClientConfig(current, "localhost")))
+    assert.Error(t, exchange(address, tlsconfig.ClientConfig(previous, "localhost")))
+
+    os.WriteFile(filepath.Join(dir, files.ServerKeyFile), []byte("broken"), 0o600)
+    os.Chtimes(filepath.Join(dir, files.ServerKeyFile), later.Add(time.Minute), later.Add(time.Minute))
+    _, err = server.Reload()
+    assert.Error(t, err)
+    assert.NoError(t, exchange(address, tlsconfig.ClientConfig(current, "localhost")), "A broken key should not replace a working certificate")
+}